@@ -74,6 +74,13 @@ func Run(conf *config.Configuration, clientSet *client.GenericClientSet, generic
 		time.Sleep(1 * time.Second)
 	}()
 
+	// restore healthz states before components register their checks
+	if conf.HealthzStateFile != "" {
+		if err := general.EnableHealthzStatePersistence(conf.HealthzStateFile); err != nil {
+			klog.Warningf("enable healthz state persistence failed: %v", err)
+		}
+	}
+
 	return startAgent(ctx, genericCtx, conf, GetAgentInitializers())
 }
 
@@ -46,6 +46,7 @@ type BaseOptions struct {
 	NodeAddress        string
 	LockFileName       string
 	LockWaitingEnabled bool
+	HealthzStateFile   string
 
 	CgroupType            string
 	AdditionalCgroupPaths []string
@@ -109,6 +110,9 @@ func (o *BaseOptions) AddFlags(fss *cliflag.NamedFlagSets) {
 	fs.StringVar(&o.LockFileName, "locking-file", o.LockFileName, "The filename used as unique lock")
 	fs.BoolVar(&o.LockWaitingEnabled, "locking-waiting", o.LockWaitingEnabled,
 		"If failed to acquire locking files, still mark agent as healthy")
+	fs.StringVar(&o.HealthzStateFile, "healthz-state-file", o.HealthzStateFile,
+		"The file to persist healthz states across restarts, so that unhealthy checks are restored "+
+			"when registered; empty means persistence is disabled")
 
 	fs.StringVar(&o.CgroupType, "cgroup-type", o.CgroupType, "The cgroup type")
 	fs.StringSliceVar(&o.AdditionalCgroupPaths, "addition-cgroup-paths", o.AdditionalCgroupPaths,
@@ -159,6 +163,7 @@ func (o *BaseOptions) ApplyTo(c *global.BaseConfiguration) error {
 	c.NodeAddress = o.NodeAddress
 	c.LockFileName = o.LockFileName
 	c.LockWaitingEnabled = o.LockWaitingEnabled
+	c.HealthzStateFile = o.HealthzStateFile
 
 	c.ReclaimRelativeRootCgroupPath = o.ReclaimRelativeRootCgroupPath
 	c.GeneralRelativeCgroupPaths = o.GeneralRelativeCgroupPaths
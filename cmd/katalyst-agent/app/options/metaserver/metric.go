@@ -35,6 +35,8 @@ type MetricFetcherOptions struct {
 	DefaultInterval         time.Duration
 	ProvisionerIntervalSecs map[string]int
 
+	MetricStoreStalenessThreshold time.Duration
+
 	*MalachiteOptions
 	*CgroupOptions
 	*KubeletOptions
@@ -80,6 +82,8 @@ func (o *MetricFetcherOptions) AddFlags(fss *cliflag.NamedFlagSets) {
 		"The default metric provisioner collecting interval")
 	fs.StringToIntVar(&o.ProvisionerIntervalSecs, "metric-provisioner-intervals", o.ProvisionerIntervalSecs,
 		"The metric provisioner collecting intervals for each individual provisioner")
+	fs.DurationVar(&o.MetricStoreStalenessThreshold, "metric-store-staleness-threshold", o.MetricStoreStalenessThreshold,
+		"The healthz check of metric store reports not ready if no metric is updated for this period, 0 means no check")
 
 	fs.IntVar(&o.RodanOptions.ServerPort, "rodan-server-port", o.RodanOptions.ServerPort,
 		"The rodan metric provisioner server port")
@@ -95,6 +99,7 @@ func (o *MetricFetcherOptions) ApplyTo(c *metaserver.MetricConfiguration) error
 	for name, secs := range o.ProvisionerIntervalSecs {
 		c.ProvisionerIntervals[name] = time.Second * time.Duration(secs)
 	}
+	c.MetricStoreStalenessThreshold = o.MetricStoreStalenessThreshold
 
 	c.RodanServerPort = o.RodanOptions.ServerPort
 
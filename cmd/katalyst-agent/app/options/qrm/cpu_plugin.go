@@ -41,6 +41,12 @@ type CPUDynamicPolicyOptions struct {
 	EnableCPUIdle                 bool
 	CPUNUMAHintPreferPolicy       string
 	CPUNUMAHintPreferLowThreshold float64
+	AsyncHandlerJitterFactor      float64
+	EnableCheckCPUSet             bool
+	EnableClearResidualState      bool
+	EnableCheckReclaimPoolDrift   bool
+	ReclaimPoolDriftTolerance     int
+	ResidualStateMinMissCount     int
 }
 
 type CPUNativePolicyOptions struct {
@@ -58,6 +64,8 @@ func NewCPUOptions() *CPUOptions {
 			EnableCPUPressureEviction: false,
 			EnableSyncingCPUIdle:      false,
 			EnableCPUIdle:             false,
+			EnableCheckCPUSet:         true,
+			EnableClearResidualState:  true,
 			CPUNUMAHintPreferPolicy:   cpuconsts.CPUNUMAHintPreferPolicySpreading,
 			LoadPressureEvictionSkipPools: []string{
 				state.PoolNameReclaim,
@@ -97,6 +105,18 @@ func (o *CPUOptions) AddFlags(fss *cliflag.NamedFlagSets) {
 		"it decides hint preference calculation strategy")
 	fs.Float64Var(&o.CPUNUMAHintPreferLowThreshold, "cpu-numa-hint-prefer-low-threshold", o.CPUNUMAHintPreferLowThreshold,
 		"it indicates threshold to apply CPUNUMAHintPreferPolicy dynamically, and it's working when CPUNUMAHintPreferPolicy is set to dynamic_packing")
+	fs.Float64Var(&o.AsyncHandlerJitterFactor, "cpu-async-handler-jitter-factor", o.AsyncHandlerJitterFactor,
+		"jitter factor to spread the first fire and intervals of periodical async handlers in cpu plugin, 0 means no jitter")
+	fs.BoolVar(&o.EnableCheckCPUSet, "enable-cpu-check-cpuset", o.EnableCheckCPUSet,
+		"if set false, the async handler checking cpusets of containers in cpu plugin will be disabled")
+	fs.BoolVar(&o.EnableClearResidualState, "enable-cpu-clear-residual-state", o.EnableClearResidualState,
+		"if set false, the async handler clearing residual pods in cpu plugin state will be disabled")
+	fs.BoolVar(&o.EnableCheckReclaimPoolDrift, "enable-cpu-check-reclaim-pool-drift", o.EnableCheckReclaimPoolDrift,
+		"if set true, cpu plugin periodically checks whether cpusets of reclaimed_cores containers or cpu quota of reclaim cgroup drift from the reclaim pool in state")
+	fs.IntVar(&o.ReclaimPoolDriftTolerance, "cpu-reclaim-pool-drift-tolerance", o.ReclaimPoolDriftTolerance,
+		"the max difference in cores between what is applied to reclaimed_cores and the reclaim pool in state before it's reported as drifted")
+	fs.IntVar(&o.ResidualStateMinMissCount, "cpu-residual-state-min-miss-count", o.ResidualStateMinMissCount,
+		"the min number of consecutive checks a pod is missing from pod watcher before its residual state is cleared, 0 means no limit")
 	fs.StringVar(&o.CPUAllocationOption, "cpu-allocation-option",
 		o.CPUAllocationOption, "The allocation option of cpu (packed/distributed). The default value is packed."+
 			"in cases where more than one NUMA node is required to satisfy the allocation.")
@@ -118,5 +138,11 @@ func (o *CPUOptions) ApplyTo(conf *qrmconfig.CPUQRMPluginConfig) error {
 	conf.CPUAllocationOption = o.CPUAllocationOption
 	conf.CPUNUMAHintPreferPolicy = o.CPUNUMAHintPreferPolicy
 	conf.CPUNUMAHintPreferLowThreshold = o.CPUNUMAHintPreferLowThreshold
+	conf.AsyncHandlerJitterFactor = o.AsyncHandlerJitterFactor
+	conf.EnableCheckCPUSet = o.EnableCheckCPUSet
+	conf.EnableClearResidualState = o.EnableClearResidualState
+	conf.EnableCheckReclaimPoolDrift = o.EnableCheckReclaimPoolDrift
+	conf.ReclaimPoolDriftTolerance = o.ReclaimPoolDriftTolerance
+	conf.ResidualStateMinMissCount = o.ResidualStateMinMissCount
 	return nil
 }
@@ -17,6 +17,8 @@ limitations under the License.
 package reporter
 
 import (
+	"time"
+
 	v1 "k8s.io/api/core/v1"
 	cliflag "k8s.io/component-base/cli/flag"
 	pluginapi "k8s.io/kubelet/pkg/apis/resourceplugin/v1alpha1"
@@ -30,6 +32,18 @@ type KubeletPluginOptions struct {
 	EnableReportTopologyPolicy  bool
 	ResourceNameToZoneTypeMap   map[string]string
 	NeedValidationResources     []string
+
+	NUMAAllocationImbalanceThreshold float64
+	TopologyReportHealthzTimeout     time.Duration
+	PodResourcesCallTimeout          time.Duration
+
+	EnableReportTopologyOnlyOnChange bool
+	TopologyForceReportPeriod        time.Duration
+
+	EnableReportContainerLevelTopology bool
+
+	EnableReportTopologylessDevices bool
+	TopologylessDeviceNumaID        int
 }
 
 func NewKubeletPluginOptions() *KubeletPluginOptions {
@@ -46,6 +60,16 @@ func NewKubeletPluginOptions() *KubeletPluginOptions {
 			string(v1.ResourceCPU),
 			string(v1.ResourceMemory),
 		},
+		TopologyReportHealthzTimeout: 5 * time.Minute,
+		PodResourcesCallTimeout:      3 * time.Second,
+
+		EnableReportTopologyOnlyOnChange: false,
+		TopologyForceReportPeriod:        10 * time.Minute,
+
+		EnableReportContainerLevelTopology: false,
+
+		EnableReportTopologylessDevices: false,
+		TopologylessDeviceNumaID:        -1,
 	}
 }
 
@@ -62,6 +86,22 @@ func (o *KubeletPluginOptions) AddFlags(fss *cliflag.NamedFlagSets) {
 		"a map that stores the mapping relationship between resource names to zone types in KCNR (e.g. nvidia.com/gpu=GPU,...)")
 	fs.StringSliceVar(&o.NeedValidationResources, "need-validation-resources", o.NeedValidationResources,
 		"resources need to be validated")
+	fs.Float64Var(&o.NUMAAllocationImbalanceThreshold, "numa-allocation-imbalance-threshold", o.NUMAAllocationImbalanceThreshold,
+		"the threshold of numa allocation imbalance ratio to report healthz warning, zero means disabled")
+	fs.DurationVar(&o.TopologyReportHealthzTimeout, "topology-report-healthz-timeout", o.TopologyReportHealthzTimeout,
+		"the timeout of topology report healthz heartbeat, zero means no timeout check")
+	fs.DurationVar(&o.PodResourcesCallTimeout, "pod-resources-call-timeout", o.PodResourcesCallTimeout,
+		"the timeout of each call to pod resources server, zero means only bounded by the timeout of report cycle")
+	fs.BoolVar(&o.EnableReportTopologyOnlyOnChange, "enable-report-topology-only-on-change", o.EnableReportTopologyOnlyOnChange,
+		"whether to skip reporting topology status if it's not changed since last report")
+	fs.DurationVar(&o.TopologyForceReportPeriod, "topology-force-report-period", o.TopologyForceReportPeriod,
+		"the period to report topology status even if it's not changed, zero means never forcing")
+	fs.BoolVar(&o.EnableReportContainerLevelTopology, "enable-report-container-level-topology", o.EnableReportContainerLevelTopology,
+		"whether to report topology allocations per container (namespace/name/uid/container) instead of per pod")
+	fs.BoolVar(&o.EnableReportTopologylessDevices, "enable-report-topologyless-devices", o.EnableReportTopologylessDevices,
+		"whether to count devices without topology into a synthetic numa zone instead of dropping them")
+	fs.IntVar(&o.TopologylessDeviceNumaID, "topologyless-device-numa-id", o.TopologylessDeviceNumaID,
+		"the id of synthetic numa zone that devices without topology are counted into")
 }
 
 func (o *KubeletPluginOptions) ApplyTo(c *reporter.KubeletPluginConfiguration) error {
@@ -70,6 +110,14 @@ func (o *KubeletPluginOptions) ApplyTo(c *reporter.KubeletPluginConfiguration) e
 	c.EnableReportTopologyPolicy = o.EnableReportTopologyPolicy
 	c.ResourceNameToZoneTypeMap = o.ResourceNameToZoneTypeMap
 	c.NeedValidationResources = o.NeedValidationResources
+	c.NUMAAllocationImbalanceThreshold = o.NUMAAllocationImbalanceThreshold
+	c.TopologyReportHealthzTimeout = o.TopologyReportHealthzTimeout
+	c.PodResourcesCallTimeout = o.PodResourcesCallTimeout
+	c.EnableReportTopologyOnlyOnChange = o.EnableReportTopologyOnlyOnChange
+	c.TopologyForceReportPeriod = o.TopologyForceReportPeriod
+	c.EnableReportContainerLevelTopology = o.EnableReportContainerLevelTopology
+	c.EnableReportTopologylessDevices = o.EnableReportTopologylessDevices
+	c.TopologylessDeviceNumaID = o.TopologylessDeviceNumaID
 
 	return nil
 }
@@ -17,7 +17,10 @@ limitations under the License.
 package cpu
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 	"k8s.io/apimachinery/pkg/util/errors"
@@ -31,10 +34,38 @@ import (
 
 // CPUAdvisorOptions holds the configurations for cpu advisor in qos aware plugin
 type CPUAdvisorOptions struct {
-	CPUProvisionPolicyPriority map[string]string
-	CPUHeadroomPolicyPriority  map[string]string
-	CPUProvisionAssembler      string
-	CPUHeadroomAssembler       string
+	CPUProvisionPolicyPriority    map[string]string
+	CPUHeadroomPolicyPriority     map[string]string
+	CPUProvisionAssembler         string
+	CPUHeadroomAssembler          string
+	CPUAdvisorMetricsBufferSize   int
+	CPUAdvisorMaxReclaimCoreNum   int
+	CPUAdvisorMaxIsolationCoreNum int
+	CPUAdvisorMinSharePoolCoreNum int
+	CPUProvisionPolicyOfPool      map[string]string
+
+	CPUAdvisorClampProvisionByEssentials bool
+
+	CPUAdvisorExtraReservedPoolNames []string
+
+	CPUAdvisorZeroNumaAvailablePolicy string
+
+	EnableAdaptiveReservedForReclaim bool
+	AdaptiveReservedForReclaimMin    int
+	AdaptiveReservedForReclaimMax    int
+
+	CPUAdvisorDrainedNUMAs []int
+
+	CPUAdvisorNUMAEnableReclaim       map[string]string
+	CPUAdvisorNUMAReservedForAllocate map[string]string
+
+	CPUAdvisorMaxHeadroomCores float64
+	CPUAdvisorMaxHeadroomRatio float64
+
+	CPUAdvisorServeDefaultHeadroomBeforeUpdated bool
+	CPUAdvisorDefaultHeadroomCores              float64
+
+	CPUAdvisorFreezeOnStaleMetricsThreshold time.Duration
 
 	*headroom.CPUHeadroomPolicyOptions
 	*provision.CPUProvisionPolicyOptions
@@ -55,12 +86,18 @@ func NewCPUAdvisorOptions() *CPUAdvisorOptions {
 			string(types.QoSRegionTypeIsolation):              string(types.CPUHeadroomPolicyCanonical),
 			string(types.QoSRegionTypeDedicatedNumaExclusive): string(types.CPUHeadroomPolicyCanonical),
 		},
-		CPUProvisionAssembler:     string(types.CPUProvisionAssemblerCommon),
-		CPUHeadroomAssembler:      string(types.CPUHeadroomAssemblerCommon),
-		CPUHeadroomPolicyOptions:  headroom.NewCPUHeadroomPolicyOptions(),
-		CPUProvisionPolicyOptions: provision.NewCPUProvisionPolicyOptions(),
-		CPURegionOptions:          region.NewCPURegionOptions(),
-		CPUIsolationOptions:       NewCPUIsolationOptions(),
+		CPUProvisionAssembler:       string(types.CPUProvisionAssemblerCommon),
+		CPUHeadroomAssembler:        string(types.CPUHeadroomAssemblerCommon),
+		CPUAdvisorMetricsBufferSize: 10,
+		CPUProvisionPolicyOfPool:    map[string]string{},
+		CPUAdvisorNUMAEnableReclaim: map[string]string{},
+		CPUHeadroomPolicyOptions:    headroom.NewCPUHeadroomPolicyOptions(),
+		CPUProvisionPolicyOptions:   provision.NewCPUProvisionPolicyOptions(),
+		CPURegionOptions:            region.NewCPURegionOptions(),
+		CPUIsolationOptions:         NewCPUIsolationOptions(),
+
+		CPUAdvisorZeroNumaAvailablePolicy: string(types.CPUZeroNumaAvailablePolicyNone),
+		CPUAdvisorNUMAReservedForAllocate: map[string]string{},
 	}
 }
 
@@ -76,6 +113,50 @@ func (o *CPUAdvisorOptions) AddFlags(fs *pflag.FlagSet) {
 		"cpu provision assembler for cpu advisor to generate node provision result from region provision results")
 	fs.StringVar(&o.CPUHeadroomAssembler, "cpu-headroom-assembler", o.CPUHeadroomAssembler,
 		"cpu headroom assembler for cpu advisor to generate node headroom from region headroom or node level policy")
+	fs.IntVar(&o.CPUAdvisorMetricsBufferSize, "cpu-advisor-metrics-buffer-size", o.CPUAdvisorMetricsBufferSize,
+		"max number of pending metric batches for cpu advisor to emit asynchronously, the oldest one will be dropped if exceeded")
+	fs.IntVar(&o.CPUAdvisorMaxReclaimCoreNum, "cpu-advisor-max-reclaim-core-num", o.CPUAdvisorMaxReclaimCoreNum,
+		"max total size of reclaim pool across all numas, reserved for reclaim is still honored; 0 means no limit")
+	fs.IntVar(&o.CPUAdvisorMaxIsolationCoreNum, "cpu-advisor-max-isolation-core-num", o.CPUAdvisorMaxIsolationCoreNum,
+		"max size of each isolation region, to avoid isolated pods starving share pools; 0 means no limit")
+	fs.IntVar(&o.CPUAdvisorMinSharePoolCoreNum, "cpu-advisor-min-share-pool-core-num", o.CPUAdvisorMinSharePoolCoreNum,
+		"min size of each non-binding share pool, isolation pools are shrunk to their lower sizes first to keep it; 0 means no floor")
+	fs.BoolVar(&o.CPUAdvisorClampProvisionByEssentials, "cpu-advisor-clamp-provision-by-essentials", o.CPUAdvisorClampProvisionByEssentials,
+		"if set as true, non-reclaimed cpu size of each region is clamped into its resource lower and upper bounds before assembling")
+	fs.StringSliceVar(&o.CPUAdvisorExtraReservedPoolNames, "cpu-advisor-extra-reserved-pool-names", o.CPUAdvisorExtraReservedPoolNames,
+		"names of pools managed out of cpu advisor, whose sizes are excluded from available resource like the reserve pool")
+	fs.StringVar(&o.CPUAdvisorZeroNumaAvailablePolicy, "cpu-advisor-zero-numa-available-policy", o.CPUAdvisorZeroNumaAvailablePolicy,
+		"how to handle numa binding regions on a numa with zero available resource while other numas have, "+
+			"should be one of none, keep-last and error")
+	fs.StringToStringVar(&o.CPUProvisionPolicyOfPool, "cpu-provision-policy-of-pool", o.CPUProvisionPolicyOfPool,
+		"provision policy of share pools to override the default one of share region type, "+
+			"should be formatted as 'batch=canonical,flink=rama'")
+	fs.BoolVar(&o.EnableAdaptiveReservedForReclaim, "cpu-advisor-enable-adaptive-reserved-for-reclaim", o.EnableAdaptiveReservedForReclaim,
+		"if set as true, reserved for reclaim of each numa scales with its cpu idle instead of the static value")
+	fs.IntVar(&o.AdaptiveReservedForReclaimMin, "cpu-advisor-adaptive-reserved-for-reclaim-min", o.AdaptiveReservedForReclaimMin,
+		"min reserved for reclaim of each numa in adaptive mode")
+	fs.IntVar(&o.AdaptiveReservedForReclaimMax, "cpu-advisor-adaptive-reserved-for-reclaim-max", o.AdaptiveReservedForReclaimMax,
+		"max reserved for reclaim of each numa in adaptive mode")
+	fs.IntSliceVar(&o.CPUAdvisorDrainedNUMAs, "cpu-advisor-drained-numas", o.CPUAdvisorDrainedNUMAs,
+		"numas drained for hardware maintenance, no share or reclaim pool will be placed on them")
+	fs.StringToStringVar(&o.CPUAdvisorNUMAEnableReclaim, "cpu-advisor-numa-enable-reclaim", o.CPUAdvisorNUMAEnableReclaim,
+		"per-numa overrides of the node-wide reclaim switch, numas with reclaim disabled only keep reserved for reclaim, "+
+			"should be formatted as '0=false,1=true'")
+	fs.StringToStringVar(&o.CPUAdvisorNUMAReservedForAllocate, "cpu-advisor-numa-reserved-for-allocate", o.CPUAdvisorNUMAReservedForAllocate,
+		"per-numa overrides of cpu cores reserved for allocate, other numas share the node-wide value evenly, "+
+			"should be formatted as '0=4,1=8'")
+	fs.Float64Var(&o.CPUAdvisorMaxHeadroomCores, "cpu-advisor-max-headroom-cores", o.CPUAdvisorMaxHeadroomCores,
+		"max cores of headroom reported by cpu advisor, per-numa headroom is capped in proportion to numa size; 0 means no limit")
+	fs.Float64Var(&o.CPUAdvisorMaxHeadroomRatio, "cpu-advisor-max-headroom-ratio", o.CPUAdvisorMaxHeadroomRatio,
+		"max ratio of headroom reported by cpu advisor to node (or numa) cpus; 0 means no limit")
+	fs.BoolVar(&o.CPUAdvisorServeDefaultHeadroomBeforeUpdated, "cpu-advisor-serve-default-headroom-before-updated",
+		o.CPUAdvisorServeDefaultHeadroomBeforeUpdated,
+		"if set as true, cpu advisor serves default headroom before its first successful update instead of returning error")
+	fs.Float64Var(&o.CPUAdvisorDefaultHeadroomCores, "cpu-advisor-default-headroom-cores", o.CPUAdvisorDefaultHeadroomCores,
+		"cores of headroom served by cpu advisor before its first successful update")
+	fs.DurationVar(&o.CPUAdvisorFreezeOnStaleMetricsThreshold, "cpu-advisor-freeze-on-stale-metrics-threshold",
+		o.CPUAdvisorFreezeOnStaleMetricsThreshold,
+		"if numa or container metrics are older than it, cpu advisor keeps the last result instead of changing pool sizes; 0 means disabled")
 
 	o.CPUHeadroomPolicyOptions.AddFlags(fs)
 	o.CPUProvisionPolicyOptions.AddFlags(fs)
@@ -101,6 +182,47 @@ func (o *CPUAdvisorOptions) ApplyTo(c *cpu.CPUAdvisorConfiguration) error {
 
 	c.ProvisionAssembler = types.CPUProvisionAssemblerName(o.CPUProvisionAssembler)
 	c.HeadroomAssembler = types.CPUHeadroomAssemblerName(o.CPUHeadroomAssembler)
+	c.MetricsBufferSize = o.CPUAdvisorMetricsBufferSize
+	c.MaxReclaimCoreNum = o.CPUAdvisorMaxReclaimCoreNum
+	c.MaxIsolationCoreNum = o.CPUAdvisorMaxIsolationCoreNum
+	c.MinSharePoolCoreNum = o.CPUAdvisorMinSharePoolCoreNum
+	c.ClampProvisionByEssentials = o.CPUAdvisorClampProvisionByEssentials
+	c.ExtraReservedPoolNames = o.CPUAdvisorExtraReservedPoolNames
+	c.ZeroNumaAvailablePolicy = types.CPUZeroNumaAvailablePolicy(o.CPUAdvisorZeroNumaAvailablePolicy)
+	c.EnableAdaptiveReservedForReclaim = o.EnableAdaptiveReservedForReclaim
+	c.AdaptiveReservedForReclaimMin = o.AdaptiveReservedForReclaimMin
+	c.AdaptiveReservedForReclaimMax = o.AdaptiveReservedForReclaimMax
+	c.DrainedNUMAs = o.CPUAdvisorDrainedNUMAs
+	c.MaxHeadroomCores = o.CPUAdvisorMaxHeadroomCores
+	c.MaxHeadroomRatio = o.CPUAdvisorMaxHeadroomRatio
+	c.ServeDefaultHeadroomBeforeUpdated = o.CPUAdvisorServeDefaultHeadroomBeforeUpdated
+	c.DefaultHeadroomCores = o.CPUAdvisorDefaultHeadroomCores
+	c.FreezeOnStaleMetricsThreshold = o.CPUAdvisorFreezeOnStaleMetricsThreshold
+	for poolName, policyName := range o.CPUProvisionPolicyOfPool {
+		c.PoolProvisionPolicies[poolName] = types.CPUProvisionPolicyName(policyName)
+	}
+	for numaStr, enableStr := range o.CPUAdvisorNUMAEnableReclaim {
+		numaID, err := strconv.Atoi(numaStr)
+		if err != nil {
+			return fmt.Errorf("invalid numa id %v in numa enable reclaim: %v", numaStr, err)
+		}
+		enableReclaim, err := strconv.ParseBool(enableStr)
+		if err != nil {
+			return fmt.Errorf("invalid reclaim switch %v of numa %v: %v", enableStr, numaID, err)
+		}
+		c.NUMAEnableReclaim[numaID] = enableReclaim
+	}
+	for numaStr, reservedStr := range o.CPUAdvisorNUMAReservedForAllocate {
+		numaID, err := strconv.Atoi(numaStr)
+		if err != nil {
+			return fmt.Errorf("invalid numa id %v in numa reserved for allocate: %v", numaStr, err)
+		}
+		reserved, err := strconv.ParseFloat(reservedStr, 64)
+		if err != nil || reserved < 0 {
+			return fmt.Errorf("invalid reserved for allocate %v of numa %v", reservedStr, numaID)
+		}
+		c.NUMAReservedForAllocate[numaID] = reserved
+	}
 
 	var errList []error
 	errList = append(errList, o.CPUHeadroomPolicyOptions.ApplyTo(c.CPUHeadroomPolicyConfiguration))
@@ -19,6 +19,7 @@ package cpu
 import (
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/spf13/pflag"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -57,6 +58,16 @@ type CPUIsolationOptions struct {
 	IsolationDisabledPools     []string
 	IsolationForceEnablePools  []string
 	IsolationNonExclusivePools []string
+
+	// IsolationSafetyFailuresToSkip and IsolationSafetySkipCooldown defines that after the given
+	// consecutive safety check failures, updating with isolation is skipped during the cooldown
+	// before probing it again; zero IsolationSafetyFailuresToSkip means never skipping
+	IsolationSafetyFailuresToSkip int
+	IsolationSafetySkipCooldown   time.Duration
+
+	// IsolationMaxRegionsPerNuma caps the number of isolation regions on each numa to bound
+	// fragmentation, and extra isolation candidates are kept in share pool; 0 means no limit
+	IsolationMaxRegionsPerNuma int
 }
 
 // NewCPUIsolationOptions creates a new Options with a default config
@@ -78,6 +89,11 @@ func NewCPUIsolationOptions() *CPUIsolationOptions {
 		IsolationDisabledPools:     []string{},
 		IsolationForceEnablePools:  []string{},
 		IsolationNonExclusivePools: []string{},
+
+		IsolationSafetyFailuresToSkip: 0,
+		IsolationSafetySkipCooldown:   5 * time.Minute,
+
+		IsolationMaxRegionsPerNuma: 0,
 	}
 }
 
@@ -115,6 +131,14 @@ func (o *CPUIsolationOptions) AddFlags(fs *pflag.FlagSet) {
 		"isolation force enable for get given pool")
 	fs.StringArrayVar(&o.IsolationNonExclusivePools, "isolation-non-exclusive-pools", o.IsolationNonExclusivePools,
 		"isolation is non-exclusive for get given pool")
+
+	fs.IntVar(&o.IsolationSafetyFailuresToSkip, "isolation-safety-failures-to-skip", o.IsolationSafetyFailuresToSkip,
+		"skip updating with isolation after it fails safety check for these consecutive times; 0 means never skipping")
+	fs.DurationVar(&o.IsolationSafetySkipCooldown, "isolation-safety-skip-cooldown", o.IsolationSafetySkipCooldown,
+		"the period to skip updating with isolation before probing it again")
+
+	fs.IntVar(&o.IsolationMaxRegionsPerNuma, "isolation-max-regions-per-numa", o.IsolationMaxRegionsPerNuma,
+		"the max number of isolation regions on each numa, extra isolation candidates are kept in share pool; 0 means no limit")
 }
 
 // ApplyTo fills up config with options
@@ -158,5 +182,10 @@ func (o *CPUIsolationOptions) ApplyTo(c *cpu.CPUIsolationConfiguration) error {
 	c.IsolationForceEnablePools = sets.NewString(o.IsolationForceEnablePools...)
 	c.IsolationNonExclusivePools = sets.NewString(o.IsolationNonExclusivePools...)
 
+	c.IsolationSafetyFailuresToSkip = o.IsolationSafetyFailuresToSkip
+	c.IsolationSafetySkipCooldown = o.IsolationSafetySkipCooldown
+
+	c.IsolationMaxRegionsPerNuma = o.IsolationMaxRegionsPerNuma
+
 	return nil
 }
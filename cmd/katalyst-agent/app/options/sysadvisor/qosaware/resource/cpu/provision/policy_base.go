@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 	"k8s.io/apimachinery/pkg/util/errors"
@@ -31,12 +32,16 @@ import (
 type CPUProvisionPolicyOptions struct {
 	PolicyRama                   *PolicyRamaOptions
 	RegionIndicatorTargetOptions map[string]string
+
+	ProvisionFallbackFailedThreshold int
+	ProvisionFallbackCooldown        time.Duration
 }
 
 func NewCPUProvisionPolicyOptions() *CPUProvisionPolicyOptions {
 	return &CPUProvisionPolicyOptions{
 		PolicyRama:                   NewPolicyRamaOptions(),
 		RegionIndicatorTargetOptions: map[string]string{},
+		ProvisionFallbackCooldown:    5 * time.Minute,
 	}
 }
 
@@ -44,6 +49,8 @@ func NewCPUProvisionPolicyOptions() *CPUProvisionPolicyOptions {
 func (o *CPUProvisionPolicyOptions) ApplyTo(c *provisionconfig.CPUProvisionPolicyConfiguration) error {
 	var errList []error
 	errList = append(errList, o.PolicyRama.ApplyTo(c.PolicyRama))
+	c.ProvisionFallbackFailedThreshold = o.ProvisionFallbackFailedThreshold
+	c.ProvisionFallbackCooldown = o.ProvisionFallbackCooldown
 
 	for regionType, targets := range o.RegionIndicatorTargetOptions {
 		regionIndicatorTarget := make([]types.IndicatorTargetConfiguration, 0)
@@ -72,4 +79,8 @@ func (o *CPUProvisionPolicyOptions) AddFlags(fs *pflag.FlagSet) {
 	o.PolicyRama.AddFlags(fs)
 	fs.StringToStringVar(&o.RegionIndicatorTargetOptions, "region-indicator-targets", o.RegionIndicatorTargetOptions,
 		"indicators targets for each region, in format like cpu_sched_wait=400/cpu_iowait_ratio=0.8")
+	fs.IntVar(&o.ProvisionFallbackFailedThreshold, "cpu-provision-fallback-failed-threshold", o.ProvisionFallbackFailedThreshold,
+		"number of consecutive provision failures of a region before falling back to none policy, 0 means never fall back")
+	fs.DurationVar(&o.ProvisionFallbackCooldown, "cpu-provision-fallback-cooldown", o.ProvisionFallbackCooldown,
+		"duration for a region to stay in fallback before retrying its configured provision policies")
 }
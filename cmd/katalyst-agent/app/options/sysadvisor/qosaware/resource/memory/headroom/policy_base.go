@@ -17,27 +17,64 @@ limitations under the License.
 package headroom
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/spf13/pflag"
 	"k8s.io/apimachinery/pkg/util/errors"
 
 	"github.com/kubewharf/katalyst-core/pkg/config/agent/sysadvisor/qosaware/resource/memory/headroom"
 )
 
+const defaultNUMAAwareHealthCheckTimeout = 5 * time.Minute
+
 type MemoryHeadroomPolicyOptions struct {
+	NUMAAwareHealthCheckTimeout   time.Duration
+	NUMAAwareExcludedNUMAs        []int
+	NUMAAwareAllowPartialCoverage bool
+
+	NUMAAwareBlendWeight         float64
+	NUMAAwareBlendBoundedBySafer bool
+
 	MemoryPolicyCanonicalOptions *MemoryPolicyCanonicalOptions
 }
 
 func NewMemoryHeadroomPolicyOptions() *MemoryHeadroomPolicyOptions {
 	return &MemoryHeadroomPolicyOptions{
-		MemoryPolicyCanonicalOptions: NewMemoryPolicyCanonicalOptions(),
+		NUMAAwareHealthCheckTimeout:   defaultNUMAAwareHealthCheckTimeout,
+		NUMAAwareAllowPartialCoverage: true,
+		NUMAAwareBlendWeight:          0.5,
+		NUMAAwareBlendBoundedBySafer:  false,
+		MemoryPolicyCanonicalOptions:  NewMemoryPolicyCanonicalOptions(),
 	}
 }
 
 func (o *MemoryHeadroomPolicyOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.DurationVar(&o.NUMAAwareHealthCheckTimeout, "memory-headroom-numa-aware-health-check-timeout", o.NUMAAwareHealthCheckTimeout,
+		"the duration that numa-aware headroom policy is allowed to be not updated successfully before its healthz check turns not ready")
+	fs.IntSliceVar(&o.NUMAAwareExcludedNUMAs, "memory-headroom-numa-aware-excluded-numas", o.NUMAAwareExcludedNUMAs,
+		"numas whose memory is never reported as reclaimable by numa-aware headroom policy")
+	fs.BoolVar(&o.NUMAAwareAllowPartialCoverage, "memory-headroom-numa-aware-allow-partial-coverage", o.NUMAAwareAllowPartialCoverage,
+		"if set as true, numa-aware headroom policy computes headroom from numas with memory metrics populated, "+
+			"and reports the uncovered ones as zero instead of failing")
+	fs.Float64Var(&o.NUMAAwareBlendWeight, "memory-headroom-numa-aware-blend-weight", o.NUMAAwareBlendWeight,
+		"the weight of numa-aware result in numa-aware-blended headroom policy, the canonical result takes the rest")
+	fs.BoolVar(&o.NUMAAwareBlendBoundedBySafer, "memory-headroom-numa-aware-blend-bounded-by-safer", o.NUMAAwareBlendBoundedBySafer,
+		"if set as true, headroom of each numa in numa-aware-blended policy never exceeds the smaller one of numa-aware and canonical results, "+
+			"which makes the blend weight take no effect")
 	o.MemoryPolicyCanonicalOptions.AddFlags(fs)
 }
 
 func (o *MemoryHeadroomPolicyOptions) ApplyTo(c *headroom.MemoryHeadroomPolicyConfiguration) error {
+	c.NUMAAwareHealthCheckTimeout = o.NUMAAwareHealthCheckTimeout
+	c.NUMAAwareExcludedNUMAs = o.NUMAAwareExcludedNUMAs
+	c.NUMAAwareAllowPartialCoverage = o.NUMAAwareAllowPartialCoverage
+	if o.NUMAAwareBlendWeight < 0 || o.NUMAAwareBlendWeight > 1 {
+		return fmt.Errorf("numa-aware blend weight %v is out of range [0, 1]", o.NUMAAwareBlendWeight)
+	}
+	c.NUMAAwareBlendWeight = o.NUMAAwareBlendWeight
+	c.NUMAAwareBlendBoundedBySafer = o.NUMAAwareBlendBoundedBySafer
+
 	var errList []error
 	errList = append(errList, o.MemoryPolicyCanonicalOptions.ApplyTo(c.MemoryPolicyCanonicalConfiguration))
 	return errors.NewAggregate(errList)
@@ -18,17 +18,29 @@ package plugins
 
 import (
 	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/kubewharf/katalyst-core/pkg/config/agent/sysadvisor/qosaware/resource/memory/plugins"
 )
 
 type CacheReaperOptions struct {
 	MinCacheUtilizationThreshold float64
+	ExcludedPodLabels            map[string]string
+	ExcludedPodAnnotations       map[string]string
+	EnableGraduatedDropCache     bool
+
+	IneffectiveDropCacheTimesToEvict int
+
+	HardDropCacheTarget        resource.QuantityValue
+	SoftDropCacheMaxContainers int
 }
 
 func NewCacheReaperOptions() *CacheReaperOptions {
 	return &CacheReaperOptions{
 		MinCacheUtilizationThreshold: 0.005,
+		ExcludedPodLabels:            map[string]string{},
+		ExcludedPodAnnotations:       map[string]string{},
+		SoftDropCacheMaxContainers:   1,
 	}
 }
 
@@ -36,9 +48,30 @@ func (o *CacheReaperOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.Float64Var(&o.MinCacheUtilizationThreshold, "memory-advisor-min-cache-utilization-threshold", o.MinCacheUtilizationThreshold,
 		"the pod minimum cache usage on a NUMA node, if a pod uses less memory on a NUMA node than this threshold,"+
 			" it's cache won't be dropped by cache-reaper.")
+	fs.StringToStringVar(&o.ExcludedPodLabels, "memory-advisor-cache-reaper-excluded-pod-labels", o.ExcludedPodLabels,
+		"reclaimed pods with any of these labels won't be selected by cache-reaper")
+	fs.StringToStringVar(&o.ExcludedPodAnnotations, "memory-advisor-cache-reaper-excluded-pod-annotations", o.ExcludedPodAnnotations,
+		"reclaimed pods with any of these annotations won't be selected by cache-reaper")
+	fs.BoolVar(&o.EnableGraduatedDropCache, "memory-advisor-cache-reaper-enable-graduated-drop-cache", o.EnableGraduatedDropCache,
+		"if set as true, cache-reaper drops part of the container cache (light/medium/full) according to the target to reclaim")
+	fs.IntVar(&o.IneffectiveDropCacheTimesToEvict, "memory-advisor-cache-reaper-ineffective-drop-cache-times-to-evict",
+		o.IneffectiveDropCacheTimesToEvict, "the number of ineffective drops of a container, i.e. its cache refilled after being dropped, after which "+
+			"cache-reaper advises evicting it along with dropping its cache; 0 means never escalating to eviction")
+	fs.Var(&o.HardDropCacheTarget, "memory-advisor-cache-reaper-hard-drop-cache-target",
+		"the target to reclaim at or above which cache-reaper selects containers until the target is covered, "+
+			"and below which it only drops cache of the containers with the most cache; 0 means always the former")
+	fs.IntVar(&o.SoftDropCacheMaxContainers, "memory-advisor-cache-reaper-soft-drop-cache-max-containers",
+		o.SoftDropCacheMaxContainers, "the max number of containers cache-reaper selects when the target to reclaim "+
+			"is below --memory-advisor-cache-reaper-hard-drop-cache-target")
 }
 
 func (o *CacheReaperOptions) ApplyTo(c *plugins.CacheReaperConfiguration) error {
 	c.MinCacheUtilizationThreshold = o.MinCacheUtilizationThreshold
+	c.ExcludedPodLabels = o.ExcludedPodLabels
+	c.ExcludedPodAnnotations = o.ExcludedPodAnnotations
+	c.EnableGraduatedDropCache = o.EnableGraduatedDropCache
+	c.IneffectiveDropCacheTimesToEvict = o.IneffectiveDropCacheTimesToEvict
+	c.HardDropCacheTarget = o.HardDropCacheTarget.Value()
+	c.SoftDropCacheMaxContainers = o.SoftDropCacheMaxContainers
 	return nil
 }
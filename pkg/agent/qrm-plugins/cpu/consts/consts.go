@@ -31,6 +31,7 @@ const (
 	ClearResidualState         = CPUPluginDynamicPolicyName + "_clear_residual_state"
 	CheckCPUSet                = CPUPluginDynamicPolicyName + "_check_cpuset"
 	SyncCPUIdle                = CPUPluginDynamicPolicyName + "_sync_cpu_idle"
+	CheckReclaimPoolDrift      = CPUPluginDynamicPolicyName + "_check_reclaim_pool_drift"
 	CommunicateWithAdvisor     = CPUPluginDynamicPolicyName + "_communicate_with_advisor"
 )
 
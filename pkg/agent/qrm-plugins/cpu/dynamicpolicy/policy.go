@@ -50,6 +50,8 @@ import (
 	"github.com/kubewharf/katalyst-core/pkg/config/generic"
 	"github.com/kubewharf/katalyst-core/pkg/metaserver"
 	"github.com/kubewharf/katalyst-core/pkg/metrics"
+	cgroupcm "github.com/kubewharf/katalyst-core/pkg/util/cgroup/common"
+	cgroupcmutils "github.com/kubewharf/katalyst-core/pkg/util/cgroup/manager"
 	"github.com/kubewharf/katalyst-core/pkg/util/general"
 	"github.com/kubewharf/katalyst-core/pkg/util/machine"
 	"github.com/kubewharf/katalyst-core/pkg/util/native"
@@ -130,6 +132,19 @@ type DynamicPolicy struct {
 	transitionPeriod              time.Duration
 	cpuNUMAHintPreferPolicy       string
 	cpuNUMAHintPreferLowThreshold float64
+	asyncHandlerJitterFactor      float64
+	enableCheckCPUSet             bool
+	enableClearResidualState      bool
+	enableCheckReclaimPoolDrift   bool
+	reclaimPoolDriftTolerance     int
+	residualStateMinMissCount     int
+	// getContainerCPUSet and getCPUWithRelativePath read cgroup data, and they're only replaced in tests
+	getContainerCPUSet     func(podUID, containerId string) (*cgroupcm.CPUSetStats, error)
+	getCPUWithRelativePath func(relCgroupPath string) (*cgroupcm.CPUStats, error)
+
+	// numaBindingReclaimRelativeRootCgroupPaths are the relative root cgroup paths
+	// of reclaimed_cores for each numa node, keyed by numa id
+	numaBindingReclaimRelativeRootCgroupPaths map[int]string
 }
 
 func NewDynamicPolicy(agentCtx *agent.GenericContext, conf *config.Configuration,
@@ -194,6 +209,14 @@ func NewDynamicPolicy(agentCtx *agent.GenericContext, conf *config.Configuration
 		enableCPUAdvisor:              conf.CPUQRMPluginConfig.EnableCPUAdvisor,
 		cpuNUMAHintPreferPolicy:       conf.CPUQRMPluginConfig.CPUNUMAHintPreferPolicy,
 		cpuNUMAHintPreferLowThreshold: conf.CPUQRMPluginConfig.CPUNUMAHintPreferLowThreshold,
+		asyncHandlerJitterFactor:      conf.CPUQRMPluginConfig.AsyncHandlerJitterFactor,
+		enableCheckCPUSet:             conf.CPUQRMPluginConfig.EnableCheckCPUSet,
+		enableClearResidualState:      conf.CPUQRMPluginConfig.EnableClearResidualState,
+		enableCheckReclaimPoolDrift:   conf.CPUQRMPluginConfig.EnableCheckReclaimPoolDrift,
+		reclaimPoolDriftTolerance:     conf.CPUQRMPluginConfig.ReclaimPoolDriftTolerance,
+		residualStateMinMissCount:     conf.CPUQRMPluginConfig.ResidualStateMinMissCount,
+		getContainerCPUSet:            cgroupcmutils.GetCPUSetForContainer,
+		getCPUWithRelativePath:        cgroupcmutils.GetCPUWithRelativePath,
 		reservedCPUs:                  reservedCPUs,
 		extraStateFileAbsPath:         conf.ExtraStateFileAbsPath,
 		enableSyncingCPUIdle:          conf.CPUQRMPluginConfig.EnableSyncingCPUIdle,
@@ -202,6 +225,8 @@ func NewDynamicPolicy(agentCtx *agent.GenericContext, conf *config.Configuration
 		podDebugAnnoKeys:              conf.PodDebugAnnoKeys,
 		transitionPeriod:              30 * time.Second,
 	}
+	policyImplement.numaBindingReclaimRelativeRootCgroupPaths = cgroupcm.GetNUMABindingReclaimRelativeRootCgroupPaths(
+		conf.ReclaimRelativeRootCgroupPath, agentCtx.CPUDetails.NUMANodes().ToSliceInt())
 
 	// register allocation behaviors for pods with different QoS level
 	policyImplement.allocationHandlers = map[string]util.AllocationHandler{
@@ -254,6 +279,21 @@ func (p *DynamicPolicy) ResourceName() string {
 	return string(v1.ResourceCPU)
 }
 
+// registerAsyncHandler registers the async handler with healthz if it's enabled; otherwise the handler
+// is skipped and its healthz check is kept ready, so that it isn't left stale or restored as unhealthy
+func (p *DynamicPolicy) registerAsyncHandler(handlerName string, enabled bool,
+	handler periodicalhandler.Handler, interval time.Duration,
+) error {
+	if !enabled {
+		general.Infof("%v disabled", handlerName)
+		general.RegisterHeartbeatCheck(handlerName, 0, general.HealthzCheckStateReady, 0)
+		return general.UpdateHealthzState(handlerName, general.HealthzCheckStateReady, "disabled")
+	}
+
+	return periodicalhandler.RegisterJitteredPeriodicalHandlerWithHealthz(handlerName, general.HealthzCheckStateNotReady,
+		qrm.QRMCPUPluginPeriodicalHandlerGroupName, handler, interval, healthCheckTolerationTimes, p.asyncHandlerJitterFactor)
+}
+
 func (p *DynamicPolicy) Start() (err error) {
 	general.Infof("called")
 
@@ -279,18 +319,22 @@ func (p *DynamicPolicy) Start() (err error) {
 		_ = p.emitter.StoreInt64(util.MetricNameHeartBeat, 1, metrics.MetricTypeNameRaw)
 	}, time.Second*30, p.stopCh)
 
-	err = periodicalhandler.RegisterPeriodicalHandlerWithHealthz(cpuconsts.ClearResidualState, general.HealthzCheckStateNotReady,
-		qrm.QRMCPUPluginPeriodicalHandlerGroupName, p.clearResidualState, stateCheckPeriod, healthCheckTolerationTimes)
+	err = p.registerAsyncHandler(cpuconsts.ClearResidualState, p.enableClearResidualState, p.clearResidualState, stateCheckPeriod)
 	if err != nil {
 		general.Errorf("start %v failed,err:%v", cpuconsts.ClearResidualState, err)
 	}
 
-	err = periodicalhandler.RegisterPeriodicalHandlerWithHealthz(cpuconsts.CheckCPUSet, general.HealthzCheckStateNotReady,
-		qrm.QRMCPUPluginPeriodicalHandlerGroupName, p.checkCPUSet, cpusetCheckPeriod, healthCheckTolerationTimes)
+	err = p.registerAsyncHandler(cpuconsts.CheckCPUSet, p.enableCheckCPUSet, p.checkCPUSet, cpusetCheckPeriod)
 	if err != nil {
 		general.Errorf("start %v failed,err:%v", cpuconsts.CheckCPUSet, err)
 	}
 
+	err = p.registerAsyncHandler(cpuconsts.CheckReclaimPoolDrift, p.enableCheckReclaimPoolDrift && p.reclaimRelativeRootCgroupPath != "",
+		p.checkReclaimPoolDrift, cpusetCheckPeriod)
+	if err != nil {
+		general.Errorf("start %v failed,err:%v", cpuconsts.CheckReclaimPoolDrift, err)
+	}
+
 	// start cpu-idle syncing if needed
 	if p.enableSyncingCPUIdle {
 		general.Infof("syncCPUIdle enabled")
@@ -299,8 +343,8 @@ func (p *DynamicPolicy) Start() (err error) {
 			return fmt.Errorf("enable syncing cpu idle but not set reclaiemd relative root cgroup path in configuration")
 		}
 
-		err = periodicalhandler.RegisterPeriodicalHandlerWithHealthz(cpuconsts.SyncCPUIdle, general.HealthzCheckStateNotReady,
-			qrm.QRMCPUPluginPeriodicalHandlerGroupName, p.syncCPUIdle, syncCPUIdlePeriod, healthCheckTolerationTimes)
+		err = periodicalhandler.RegisterJitteredPeriodicalHandlerWithHealthz(cpuconsts.SyncCPUIdle, general.HealthzCheckStateNotReady,
+			qrm.QRMCPUPluginPeriodicalHandlerGroupName, p.syncCPUIdle, syncCPUIdlePeriod, healthCheckTolerationTimes, p.asyncHandlerJitterFactor)
 		if err != nil {
 			general.Errorf("start %v failed,err:%v", cpuconsts.SyncCPUIdle, err)
 		}
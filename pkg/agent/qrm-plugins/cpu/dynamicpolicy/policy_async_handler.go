@@ -19,9 +19,14 @@ package dynamicpolicy
 import (
 	"context"
 	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/kubewharf/katalyst-api/pkg/consts"
@@ -169,6 +174,108 @@ func (p *DynamicPolicy) checkCPUSet(_ *coreconfig.Configuration,
 	general.Infof("finish checkCPUSet")
 }
 
+// checkReclaimPoolDrift emits the difference between the reclaim pool in state and what is actually applied,
+// i.e. cpusets of reclaimed_cores containers and cpu quota of reclaim cgroup, and reports unhealthy if it
+// exceeds the tolerance, which means writing cgroup may fail silently
+func (p *DynamicPolicy) checkReclaimPoolDrift(_ *coreconfig.Configuration,
+	_ interface{},
+	_ *dynamicconfig.DynamicAgentConfiguration,
+	_ metrics.MetricEmitter,
+	_ *metaserver.MetaServer,
+) {
+	general.Infof("exec checkReclaimPoolDrift")
+	var (
+		err      error
+		maxDrift int
+		messages []string
+	)
+
+	defer func() {
+		if err != nil {
+			_ = general.UpdateHealthzStateByError(cpuconsts.CheckReclaimPoolDrift, err)
+		} else if len(messages) > 0 {
+			_ = general.UpdateHealthzState(cpuconsts.CheckReclaimPoolDrift, general.HealthzCheckStateNotReady,
+				strings.Join(messages, "; "))
+		} else {
+			_ = general.UpdateHealthzState(cpuconsts.CheckReclaimPoolDrift, general.HealthzCheckStateReady, "")
+		}
+	}()
+
+	reclaimPoolAllocationInfo := p.state.GetAllocationInfo(state.PoolNameReclaim, state.FakedContainerName)
+	if reclaimPoolAllocationInfo == nil {
+		err = fmt.Errorf("reclaim pool not found in state")
+		return
+	}
+
+	for podUID, containerEntries := range p.state.GetPodEntries() {
+		if containerEntries.IsPoolEntry() {
+			continue
+		}
+
+		for containerName, allocationInfo := range containerEntries {
+			if allocationInfo == nil || !allocationInfo.CheckMainContainer() || !state.CheckReclaimed(allocationInfo) {
+				continue
+			}
+
+			containerId, getErr := p.metaServer.GetContainerID(podUID, containerName)
+			if getErr != nil {
+				general.Errorf("get container id of pod: %s container: %s failed with error: %v", podUID, containerName, getErr)
+				continue
+			}
+
+			cpuSetStats, getErr := p.getContainerCPUSet(podUID, containerId)
+			if getErr != nil {
+				general.Errorf("GetCPUSet of pod: %s container: name(%s), id(%s) failed with error: %v",
+					podUID, containerName, containerId, getErr)
+				continue
+			}
+
+			actualCPUSet, parseErr := machine.Parse(cpuSetStats.CPUs)
+			if parseErr != nil {
+				general.Errorf("parse cpuset: %s of pod: %s container: %s failed with error: %v",
+					cpuSetStats.CPUs, podUID, containerName, parseErr)
+				continue
+			}
+
+			// cpus missing from either side are counted, since a stale cpuset may keep the same size
+			drift := actualCPUSet.Difference(allocationInfo.AllocationResult).Size() +
+				allocationInfo.AllocationResult.Difference(actualCPUSet).Size()
+			maxDrift = general.Max(maxDrift, drift)
+
+			general.Infof("pod: %s/%s, container: %s, state CPUSet: %s, actual CPUSet: %s, drift: %d",
+				allocationInfo.PodNamespace, allocationInfo.PodName, containerName,
+				allocationInfo.AllocationResult.String(), actualCPUSet.String(), drift)
+			if drift > p.reclaimPoolDriftTolerance {
+				messages = append(messages, fmt.Sprintf("pod: %s/%s container: %s has cpuset %s while state is %s",
+					allocationInfo.PodNamespace, allocationInfo.PodName, containerName,
+					actualCPUSet.String(), allocationInfo.AllocationResult.String()))
+			}
+		}
+	}
+
+	// cpu quota of reclaim cgroup limits reclaimed_cores regardless of their cpusets
+	cpuStats, getErr := p.getCPUWithRelativePath(p.reclaimRelativeRootCgroupPath)
+	if getErr != nil {
+		general.Errorf("GetCPU of reclaim cgroup: %s failed with error: %v", p.reclaimRelativeRootCgroupPath, getErr)
+	} else if cpuStats.CpuQuota > 0 && cpuStats.CpuPeriod > 0 {
+		quotaCPUs := int(math.Ceil(float64(cpuStats.CpuQuota) / float64(cpuStats.CpuPeriod)))
+		drift := reclaimPoolAllocationInfo.AllocationResult.Size() - quotaCPUs
+		maxDrift = general.Max(maxDrift, drift)
+
+		general.Infof("reclaim pool in state: %s, cpu quota of reclaim cgroup: %d/%d",
+			reclaimPoolAllocationInfo.AllocationResult.String(), cpuStats.CpuQuota, cpuStats.CpuPeriod)
+		if drift > p.reclaimPoolDriftTolerance {
+			messages = append(messages, fmt.Sprintf("reclaim cgroup is limited to %d cpus by quota while reclaim pool has %d",
+				quotaCPUs, reclaimPoolAllocationInfo.AllocationResult.Size()))
+		}
+	}
+
+	_ = p.emitter.StoreInt64(util.MetricNameReclaimPoolDrift, int64(maxDrift), metrics.MetricTypeNameRaw)
+	if len(messages) > 0 {
+		general.Errorf("reclaim pool drift exceeds tolerance %d: %s", p.reclaimPoolDriftTolerance, strings.Join(messages, "; "))
+	}
+}
+
 // clearResidualState is used to clean residual pods in local state
 func (p *DynamicPolicy) clearResidualState(_ *coreconfig.Configuration,
 	_ interface{},
@@ -228,7 +335,10 @@ func (p *DynamicPolicy) clearResidualState(_ *coreconfig.Configuration,
 			continue
 		}
 
-		if time.Duration(hitCount)*stateCheckPeriod >= maxResidualTime {
+		// both of the residual time and the consecutive misses should be satisfied,
+		// so that a single long outage of pod watcher doesn't purge everything at once
+		if time.Duration(hitCount)*stateCheckPeriod >= maxResidualTime &&
+			hitCount >= int64(p.residualStateMinMissCount) {
 			podsToDelete.Insert(podUID)
 		}
 	}
@@ -290,9 +400,81 @@ func (p *DynamicPolicy) syncCPUIdle(_ *coreconfig.Configuration,
 		return
 	}
 
-	err = cgroupcmutils.ApplyCPUWithRelativePath(p.reclaimRelativeRootCgroupPath, &cgroupcm.CPUData{CpuIdlePtr: &p.enableCPUIdle})
-	if err != nil {
-		general.Errorf("ApplyCPUWithRelativePath in %s with enableCPUIdle: %v in failed with error: %v",
-			p.reclaimRelativeRootCgroupPath, p.enableCPUIdle, err)
+	cgroupPaths := []string{p.reclaimRelativeRootCgroupPath}
+	numaBindingCgroupPaths, reconcileErr := p.reconcileNUMABindingReclaimCgroupPaths()
+	if reconcileErr != nil {
+		general.Errorf("reconcileNUMABindingReclaimCgroupPaths failed with error: %v", reconcileErr)
+	}
+	cgroupPaths = append(cgroupPaths, numaBindingCgroupPaths...)
+
+	var errList []error
+	for _, cgroupPath := range cgroupPaths {
+		applyErr := cgroupcmutils.ApplyCPUWithRelativePath(cgroupPath, &cgroupcm.CPUData{CpuIdlePtr: &p.enableCPUIdle})
+		if applyErr != nil {
+			general.Errorf("ApplyCPUWithRelativePath in %s with enableCPUIdle: %v in failed with error: %v",
+				cgroupPath, p.enableCPUIdle, applyErr)
+			errList = append(errList, applyErr)
+		}
+	}
+	err = utilerrors.NewAggregate(append(errList, reconcileErr))
+}
+
+// reconcileNUMABindingReclaimCgroupPaths makes numa-binding reclaim cgroups consistent with the numa nodes
+// holding dedicated_cores with numa_binding in state, and returns the relative paths that cpu idle should
+// be applied to; missing paths are created, and orphaned ones are reported and skipped.
+func (p *DynamicPolicy) reconcileNUMABindingReclaimCgroupPaths() ([]string, error) {
+	expectedNUMAs := sets.NewInt()
+	for numaID, numaState := range p.state.GetMachineState() {
+		if numaState != nil && numaState.ExistMatchedAllocationInfo(state.CheckDedicatedNUMABinding) {
+			expectedNUMAs.Insert(numaID)
+		}
+	}
+
+	expectedPaths, orphanedPaths, err := reconcileNUMABindingReclaimCgroupPaths(cgroupcm.GetCgroupRootPath(cgroupcm.DefaultSelectedSubsys),
+		p.numaBindingReclaimRelativeRootCgroupPaths, expectedNUMAs)
+	for _, orphanedPath := range orphanedPaths {
+		general.Warningf("found orphaned numa-binding reclaim cgroup: %s, skip syncing cpu idle for it", orphanedPath)
 	}
+	_ = p.emitter.StoreInt64(util.MetricNameOrphanReclaimCgroup, int64(len(orphanedPaths)), metrics.MetricTypeNameRaw)
+
+	return expectedPaths, err
+}
+
+// reconcileNUMABindingReclaimCgroupPaths compares the numa-binding reclaim cgroup paths of expectedNUMAs
+// with those existing under cgroupRootPath; it creates the expected paths that are missing, and returns
+// expected paths along with the orphaned paths that exist but are not expected.
+func reconcileNUMABindingReclaimCgroupPaths(cgroupRootPath string, numaBindingPaths map[int]string,
+	expectedNUMAs sets.Int,
+) (expectedPaths, orphanedPaths []string, err error) {
+	var errList []error
+	for _, numaID := range expectedNUMAs.List() {
+		relativePath, ok := numaBindingPaths[numaID]
+		if !ok {
+			errList = append(errList, fmt.Errorf("numa-binding reclaim cgroup path for numa %d not found", numaID))
+			continue
+		}
+
+		absPath := filepath.Join(cgroupRootPath, relativePath)
+		if !general.IsPathExists(absPath) {
+			general.Infof("numa-binding reclaim cgroup: %s is missing, create it", absPath)
+			if mkErr := general.EnsureDirectory(absPath); mkErr != nil {
+				errList = append(errList, fmt.Errorf("create cgroup %s failed with error: %v", absPath, mkErr))
+				continue
+			}
+		}
+		expectedPaths = append(expectedPaths, relativePath)
+	}
+
+	for numaID, relativePath := range numaBindingPaths {
+		if expectedNUMAs.Has(numaID) {
+			continue
+		}
+
+		if general.IsPathExists(filepath.Join(cgroupRootPath, relativePath)) {
+			orphanedPaths = append(orphanedPaths, relativePath)
+		}
+	}
+	sort.Strings(orphanedPaths)
+
+	return expectedPaths, orphanedPaths, utilerrors.NewAggregate(errList)
 }
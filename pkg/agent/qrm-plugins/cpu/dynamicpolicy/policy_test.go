@@ -32,17 +32,23 @@ import (
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	pluginapi "k8s.io/kubelet/pkg/apis/resourceplugin/v1alpha1"
 	utilfs "k8s.io/kubernetes/pkg/util/filesystem"
+	"k8s.io/utils/clock"
+	testingclock "k8s.io/utils/clock/testing"
 
 	"github.com/kubewharf/katalyst-api/pkg/consts"
+	"github.com/kubewharf/katalyst-core/cmd/katalyst-agent/app/agent/qrm"
 	cpuconsts "github.com/kubewharf/katalyst-core/pkg/agent/qrm-plugins/cpu/consts"
 	"github.com/kubewharf/katalyst-core/pkg/agent/qrm-plugins/cpu/dynamicpolicy/calculator"
 	advisorapi "github.com/kubewharf/katalyst-core/pkg/agent/qrm-plugins/cpu/dynamicpolicy/cpuadvisor"
 	"github.com/kubewharf/katalyst-core/pkg/agent/qrm-plugins/cpu/dynamicpolicy/state"
 	"github.com/kubewharf/katalyst-core/pkg/agent/qrm-plugins/cpu/dynamicpolicy/validator"
 	"github.com/kubewharf/katalyst-core/pkg/agent/qrm-plugins/util"
+	"github.com/kubewharf/katalyst-core/pkg/agent/utilcomponent/periodicalhandler"
+	coreconfig "github.com/kubewharf/katalyst-core/pkg/config"
 	"github.com/kubewharf/katalyst-core/pkg/config/agent/dynamic"
 	"github.com/kubewharf/katalyst-core/pkg/config/generic"
 	"github.com/kubewharf/katalyst-core/pkg/metaserver"
@@ -52,6 +58,7 @@ import (
 	"github.com/kubewharf/katalyst-core/pkg/util/cgroup/common"
 	cgroupcm "github.com/kubewharf/katalyst-core/pkg/util/cgroup/common"
 	cgroupcmutils "github.com/kubewharf/katalyst-core/pkg/util/cgroup/manager"
+	"github.com/kubewharf/katalyst-core/pkg/util/general"
 	"github.com/kubewharf/katalyst-core/pkg/util/machine"
 )
 
@@ -4192,6 +4199,54 @@ func TestClearResidualState(t *testing.T) {
 	dynamicPolicy.clearResidualState(nil, nil, nil, nil, nil)
 }
 
+func TestClearResidualStateWithMinMissCount(t *testing.T) {
+	t.Parallel()
+
+	as := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "checkpoint_TestClearResidualStateWithMinMissCount")
+	as.Nil(err)
+	defer os.RemoveAll(tmpDir)
+
+	cpuTopology, err := machine.GenerateDummyCPUTopology(16, 2, 4)
+	as.Nil(err)
+
+	dynamicPolicy, err := getTestDynamicPolicyWithInitialization(cpuTopology, tmpDir)
+	as.Nil(err)
+
+	missCountForResidualTime := int64(maxResidualTime / stateCheckPeriod)
+	dynamicPolicy.residualStateMinMissCount = int(missCountForResidualTime) * 2
+	dynamicPolicy.residualHitMap = make(map[string]int64)
+
+	reclaimPool := dynamicPolicy.state.GetAllocationInfo(state.PoolNameReclaim, state.FakedContainerName)
+	as.NotNil(reclaimPool)
+	dynamicPolicy.state.SetAllocationInfo("pod1", "c1", &state.AllocationInfo{
+		PodUid:                           "pod1",
+		PodNamespace:                     "pod1",
+		PodName:                          "pod1",
+		ContainerName:                    "c1",
+		ContainerType:                    pluginapi.ContainerType_MAIN.String(),
+		OwnerPoolName:                    state.PoolNameReclaim,
+		AllocationResult:                 reclaimPool.AllocationResult.Clone(),
+		OriginalAllocationResult:         reclaimPool.OriginalAllocationResult.Clone(),
+		TopologyAwareAssignments:         machine.DeepcopyCPUAssignment(reclaimPool.TopologyAwareAssignments),
+		OriginalTopologyAwareAssignments: machine.DeepcopyCPUAssignment(reclaimPool.OriginalTopologyAwareAssignments),
+		QoSLevel:                         consts.PodAnnotationQoSLevelReclaimedCores,
+		RequestQuantity:                  1,
+	})
+
+	// the pod has been missing for max residual time, but not for enough consecutive checks
+	dynamicPolicy.residualHitMap["pod1"] = missCountForResidualTime - 1
+	dynamicPolicy.clearResidualState(nil, nil, nil, nil, nil)
+	as.NotNil(dynamicPolicy.state.GetAllocationInfo("pod1", "c1"))
+	as.Equal(missCountForResidualTime, dynamicPolicy.residualHitMap["pod1"])
+
+	// it's cleared once min miss count is reached
+	dynamicPolicy.residualHitMap["pod1"] = int64(dynamicPolicy.residualStateMinMissCount) - 1
+	dynamicPolicy.clearResidualState(nil, nil, nil, nil, nil)
+	as.Nil(dynamicPolicy.state.GetAllocationInfo("pod1", "c1"))
+}
+
 func TestStart(t *testing.T) {
 	t.Parallel()
 
@@ -4230,6 +4285,165 @@ func TestStop(t *testing.T) {
 	as.Nil(err)
 }
 
+// TestRegisterAsyncHandler doesn't run in parallel since it replaces the clock of the global healthz registry
+func TestRegisterAsyncHandler(t *testing.T) {
+	as := require.New(t)
+
+	fakeClock := testingclock.NewFakePassiveClock(time.Now())
+	general.SetHealthzClockForTest(fakeClock)
+	defer general.SetHealthzClockForTest(clock.RealClock{})
+
+	tmpDir, err := ioutil.TempDir("", "checkpoint_TestRegisterAsyncHandler")
+	as.Nil(err)
+	defer os.RemoveAll(tmpDir)
+
+	cpuTopology, err := machine.GenerateDummyCPUTopology(16, 2, 4)
+	as.Nil(err)
+
+	dynamicPolicy, err := getTestDynamicPolicyWithInitialization(cpuTopology, tmpDir)
+	as.Nil(err)
+
+	executed := false
+	handler := func(_ *coreconfig.Configuration, _ interface{}, _ *dynamic.DynamicAgentConfiguration,
+		_ metrics.MetricEmitter, _ *metaserver.MetaServer,
+	) {
+		executed = true
+	}
+
+	// a disabled handler isn't scheduled, and its stale unhealthy state is overwritten
+	disabledName := "test_register_async_handler_disabled"
+	general.RegisterHeartbeatCheck(disabledName, time.Second, general.HealthzCheckStateNotReady, 0)
+	as.NoError(general.UpdateHealthzState(disabledName, general.HealthzCheckStateNotReady, "stale"))
+	as.NoError(dynamicPolicy.registerAsyncHandler(disabledName, false, handler, time.Second))
+	as.False(periodicalhandler.IsPeriodicalHandlerRegistered(qrm.QRMCPUPluginPeriodicalHandlerGroupName, disabledName))
+	as.False(executed)
+
+	result, ok := general.GetReadinessCheckResult(disabledName)
+	as.True(ok)
+	as.True(result.Ready)
+	as.Equal(general.HealthzCheckReasonHealthy, result.Reason)
+
+	// the heartbeat of the disabled handler never times out
+	fakeClock.SetTime(fakeClock.Now().Add(time.Hour))
+	result, _ = general.GetReadinessCheckResult(disabledName)
+	as.True(result.Ready)
+
+	enabledName := "test_register_async_handler_enabled"
+	as.NoError(dynamicPolicy.registerAsyncHandler(enabledName, true, handler, time.Second))
+	as.True(periodicalhandler.IsPeriodicalHandlerRegistered(qrm.QRMCPUPluginPeriodicalHandlerGroupName, enabledName))
+	result, ok = general.GetReadinessCheckResult(enabledName)
+	as.True(ok)
+	as.False(result.Ready)
+}
+
+func TestCheckReclaimPoolDrift(t *testing.T) {
+	t.Parallel()
+
+	as := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "checkpoint_TestCheckReclaimPoolDrift")
+	as.Nil(err)
+	defer os.RemoveAll(tmpDir)
+
+	cpuTopology, err := machine.GenerateDummyCPUTopology(16, 2, 4)
+	as.Nil(err)
+
+	dynamicPolicy, err := getTestDynamicPolicyWithInitialization(cpuTopology, tmpDir)
+	as.Nil(err)
+
+	reclaimPool := dynamicPolicy.state.GetAllocationInfo(state.PoolNameReclaim, state.FakedContainerName)
+	as.NotNil(reclaimPool)
+
+	testName := "test"
+	podUID := uuid.NewUUID()
+	dynamicPolicy.state.SetAllocationInfo(string(podUID), testName, &state.AllocationInfo{
+		PodUid:                   string(podUID),
+		PodNamespace:             testName,
+		PodName:                  testName,
+		ContainerName:            testName,
+		ContainerType:            pluginapi.ContainerType_MAIN.String(),
+		OwnerPoolName:            state.PoolNameReclaim,
+		AllocationResult:         reclaimPool.AllocationResult.Clone(),
+		OriginalAllocationResult: reclaimPool.AllocationResult.Clone(),
+		QoSLevel:                 consts.PodAnnotationQoSLevelReclaimedCores,
+	})
+	dynamicPolicy.metaServer = &metaserver.MetaServer{
+		MetaAgent: &agent.MetaAgent{
+			PodFetcher: &pod.PodFetcherStub{
+				PodList: []*v1.Pod{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      testName,
+							Namespace: testName,
+							UID:       podUID,
+						},
+						Status: v1.PodStatus{
+							ContainerStatuses: []v1.ContainerStatus{
+								{Name: testName, ContainerID: "containerd://test-container"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	dynamicPolicy.reclaimRelativeRootCgroupPath = "kubepods/besteffort"
+	dynamicPolicy.reclaimPoolDriftTolerance = 1
+	containerCPUSet := reclaimPool.AllocationResult.Clone()
+	dynamicPolicy.getContainerCPUSet = func(uid, containerId string) (*cgroupcm.CPUSetStats, error) {
+		as.Equal(string(podUID), uid)
+		as.Equal("test-container", containerId)
+		return &cgroupcm.CPUSetStats{CPUs: containerCPUSet.String()}, nil
+	}
+	reclaimCPUStats := &cgroupcm.CPUStats{CpuPeriod: 100000, CpuQuota: -1}
+	dynamicPolicy.getCPUWithRelativePath = func(relCgroupPath string) (*cgroupcm.CPUStats, error) {
+		as.Equal("kubepods/besteffort", relCgroupPath)
+		return reclaimCPUStats, nil
+	}
+	general.RegisterHeartbeatCheck(cpuconsts.CheckReclaimPoolDrift, time.Minute, general.HealthzCheckStateNotReady, 0)
+
+	dynamicPolicy.checkReclaimPoolDrift(nil, nil, nil, nil, nil)
+	result, ok := general.GetReadinessCheckResult(cpuconsts.CheckReclaimPoolDrift)
+	as.True(ok)
+	as.True(result.Ready)
+
+	// drift within tolerance is ignored
+	otherCPU := cpuTopology.CPUDetails.CPUs().Difference(reclaimPool.AllocationResult).ToSliceInt()[0]
+	containerCPUSet = reclaimPool.AllocationResult.Union(machine.NewCPUSet(otherCPU))
+	dynamicPolicy.checkReclaimPoolDrift(nil, nil, nil, nil, nil)
+	result, _ = general.GetReadinessCheckResult(cpuconsts.CheckReclaimPoolDrift)
+	as.True(result.Ready)
+
+	// the cpuset of container isn't updated since writing it failed silently,
+	// and it's detected even if the size keeps the same
+	reclaimCPUs := reclaimPool.AllocationResult.ToSliceInt()
+	otherCPUs := cpuTopology.CPUDetails.CPUs().Difference(reclaimPool.AllocationResult).ToSliceInt()
+	as.GreaterOrEqual(len(reclaimCPUs), 3)
+	as.GreaterOrEqual(len(otherCPUs), 2)
+	containerCPUSet = reclaimPool.AllocationResult.Difference(machine.NewCPUSet(reclaimCPUs[0], reclaimCPUs[1])).
+		Union(machine.NewCPUSet(otherCPUs[0], otherCPUs[1]))
+	as.Equal(reclaimPool.AllocationResult.Size(), containerCPUSet.Size())
+	dynamicPolicy.checkReclaimPoolDrift(nil, nil, nil, nil, nil)
+	result, _ = general.GetReadinessCheckResult(cpuconsts.CheckReclaimPoolDrift)
+	as.False(result.Ready)
+	as.Contains(result.Message, "while state is")
+
+	// the cpuset is recovered, but reclaim cgroup is still limited by a stale cpu quota
+	containerCPUSet = reclaimPool.AllocationResult.Clone()
+	reclaimCPUStats = &cgroupcm.CPUStats{CpuPeriod: 100000, CpuQuota: 100000}
+	dynamicPolicy.checkReclaimPoolDrift(nil, nil, nil, nil, nil)
+	result, _ = general.GetReadinessCheckResult(cpuconsts.CheckReclaimPoolDrift)
+	as.False(result.Ready)
+	as.Contains(result.Message, "by quota")
+
+	// quota covering the whole reclaim pool is fine
+	reclaimCPUStats = &cgroupcm.CPUStats{CpuPeriod: 100000, CpuQuota: int64(reclaimPool.AllocationResult.Size()) * 100000}
+	dynamicPolicy.checkReclaimPoolDrift(nil, nil, nil, nil, nil)
+	result, _ = general.GetReadinessCheckResult(cpuconsts.CheckReclaimPoolDrift)
+	as.True(result.Ready)
+}
+
 func TestCheckCPUSet(t *testing.T) {
 	t.Parallel()
 
@@ -4278,6 +4492,105 @@ func TestSchedIdle(t *testing.T) {
 	}
 }
 
+func TestReconcileNUMABindingReclaimCgroupPaths(t *testing.T) {
+	t.Parallel()
+
+	as := require.New(t)
+
+	cgroupRootPath, err := ioutil.TempDir("", "TestReconcileNUMABindingReclaimCgroupPaths")
+	as.Nil(err)
+	defer os.RemoveAll(cgroupRootPath)
+
+	numaBindingPaths := cgroupcm.GetNUMABindingReclaimRelativeRootCgroupPaths("kubepods/besteffort", []int{0, 1, 2, 3})
+
+	// numa 1 is stale since no numa-binding pod runs on it, and numa 0 is expected but missing
+	as.Nil(os.MkdirAll(filepath.Join(cgroupRootPath, numaBindingPaths[1]), 0o755))
+	as.Nil(os.MkdirAll(filepath.Join(cgroupRootPath, numaBindingPaths[2]), 0o755))
+
+	expectedPaths, orphanedPaths, err := reconcileNUMABindingReclaimCgroupPaths(cgroupRootPath,
+		numaBindingPaths, sets.NewInt(0, 2))
+	as.Nil(err)
+	as.Equal([]string{"kubepods/besteffort-0", "kubepods/besteffort-2"}, expectedPaths)
+	as.Equal([]string{"kubepods/besteffort-1"}, orphanedPaths)
+	as.DirExists(filepath.Join(cgroupRootPath, numaBindingPaths[0]))
+	as.NoDirExists(filepath.Join(cgroupRootPath, numaBindingPaths[3]))
+}
+
+func TestSyncCPUIdleWithMissingNUMABindingReclaimCgroup(t *testing.T) {
+	t.Parallel()
+
+	if !cgroupcm.IsCPUIdleSupported() {
+		t.Skip("cpu idle isn't supported")
+	}
+
+	as := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "checkpoint-TestSyncCPUIdleWithMissingNUMABindingReclaimCgroup")
+	as.Nil(err)
+	defer os.RemoveAll(tmpDir)
+
+	cpuTopology, err := machine.GenerateDummyCPUTopology(16, 2, 4)
+	as.Nil(err)
+
+	dynamicPolicy, err := getTestDynamicPolicyWithInitialization(cpuTopology, tmpDir)
+	as.Nil(err)
+
+	dynamicPolicy.enableCPUIdle = true
+	dynamicPolicy.reclaimRelativeRootCgroupPath = "test-sync-cpu-idle"
+	dynamicPolicy.numaBindingReclaimRelativeRootCgroupPaths = cgroupcm.GetNUMABindingReclaimRelativeRootCgroupPaths(
+		dynamicPolicy.reclaimRelativeRootCgroupPath, cpuTopology.CPUDetails.NUMANodes().ToSliceInt())
+
+	cgroupRootPath := cgroupcm.GetCgroupRootPath(cgroupcm.DefaultSelectedSubsys)
+	reclaimAbsPath := filepath.Join(cgroupRootPath, dynamicPolicy.reclaimRelativeRootCgroupPath)
+	numaBindingAbsPath := filepath.Join(cgroupRootPath, dynamicPolicy.numaBindingReclaimRelativeRootCgroupPaths[1])
+	as.Nil(os.MkdirAll(reclaimAbsPath, 0o755))
+	defer func() {
+		_ = os.Remove(numaBindingAbsPath)
+		_ = os.Remove(reclaimAbsPath)
+	}()
+	as.NoDirExists(numaBindingAbsPath)
+
+	// a dedicated_cores pod with numa_binding is newly placed on numa 1
+	podUID := string(uuid.NewUUID())
+	podEntries := state.PodEntries{
+		podUID: state.ContainerEntries{
+			"c1": &state.AllocationInfo{
+				PodUid:                   podUID,
+				PodNamespace:             "test",
+				PodName:                  "test",
+				ContainerName:            "c1",
+				ContainerType:            pluginapi.ContainerType_MAIN.String(),
+				OwnerPoolName:            state.PoolNameDedicated,
+				AllocationResult:         machine.MustParse("2,3,10,11"),
+				OriginalAllocationResult: machine.MustParse("2,3,10,11"),
+				TopologyAwareAssignments: map[int]machine.CPUSet{
+					1: machine.NewCPUSet(2, 3, 10, 11),
+				},
+				OriginalTopologyAwareAssignments: map[int]machine.CPUSet{
+					1: machine.NewCPUSet(2, 3, 10, 11),
+				},
+				Annotations: map[string]string{
+					consts.PodAnnotationQoSLevelKey:                  consts.PodAnnotationQoSLevelDedicatedCores,
+					consts.PodAnnotationMemoryEnhancementNumaBinding: consts.PodAnnotationMemoryEnhancementNumaBindingEnable,
+				},
+				QoSLevel:        consts.PodAnnotationQoSLevelDedicatedCores,
+				RequestQuantity: 4,
+			},
+		},
+	}
+	machineState, err := generateMachineStateFromPodEntries(cpuTopology, podEntries)
+	as.Nil(err)
+	dynamicPolicy.state.SetPodEntries(podEntries)
+	dynamicPolicy.state.SetMachineState(machineState)
+
+	dynamicPolicy.syncCPUIdle(nil, nil, nil, nil, nil)
+
+	as.DirExists(numaBindingAbsPath)
+	contents, err := ioutil.ReadFile(filepath.Join(numaBindingAbsPath, "cpu.idle"))
+	as.Nil(err)
+	as.Equal("1", strings.TrimSpace(string(contents)))
+}
+
 func TestRemoveContainer(t *testing.T) {
 	t.Parallel()
 
@@ -26,4 +26,21 @@ const (
 	ControlKnobKeyBalanceNumaMemory  MemoryControlKnobName = "balance_numa_memory"
 	ControlKnobKeySwapMax            MemoryControlKnobName = "swap_max"
 	ControlKnowKeyMemoryOffloading   MemoryControlKnobName = "memory_offloading"
+
+	// ControlKnobKeyDropCacheIntensity and ControlKnobKeyDropCacheBytes go along with
+	// ControlKnobKeyDropCache to drop only part of the container cache
+	ControlKnobKeyDropCacheIntensity MemoryControlKnobName = "drop_cache_intensity"
+	ControlKnobKeyDropCacheBytes     MemoryControlKnobName = "drop_cache_bytes"
+
+	// ControlKnobKeyEvict advises evicting the container, e.g. when dropping its cache
+	// repeatedly doesn't relieve memory pressure
+	ControlKnobKeyEvict MemoryControlKnobName = "evict"
+)
+
+type DropCacheIntensity string
+
+const (
+	DropCacheIntensityLight  DropCacheIntensity = "light"
+	DropCacheIntensityMedium DropCacheIntensity = "medium"
+	DropCacheIntensityFull   DropCacheIntensity = "full"
 )
@@ -325,12 +325,17 @@ func (p *DynamicPolicy) handleAdvisorDropCache(
 		return fmt.Errorf("get container spec for pod: %s, container: %s failed with error: %v", entryName, subEntryName, err)
 	}
 
+	dropCacheBytes, err := GetDropCacheBytesByAdvice(calculationInfo.CalculationResult.Values, container)
+	if err != nil {
+		return fmt.Errorf("get drop cache bytes for pod: %s, container: %s failed with error: %v", entryName, subEntryName, err)
+	}
+
 	dropCacheWorkName := util.GetContainerAsyncWorkName(entryName, subEntryName, memoryPluginAsyncWorkTopicDropCache)
 	// start a asynchronous work to drop cache for the container whose numaset changed and doesn't require numa_binding
 	err = p.asyncWorkers.AddWork(dropCacheWorkName,
 		&asyncworker.Work{
 			Fn:          cgroupmgr.DropCacheWithTimeoutForContainer,
-			Params:      []interface{}{entryName, containerID, dropCacheTimeoutSeconds, GetFullyDropCacheBytes(container)},
+			Params:      []interface{}{entryName, containerID, dropCacheTimeoutSeconds, dropCacheBytes},
 			DeliveredAt: time.Now(),
 		}, asyncworker.DuplicateWorkPolicyOverride)
 	if err != nil {
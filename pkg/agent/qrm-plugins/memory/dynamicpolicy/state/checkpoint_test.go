@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The Katalyst Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/kubewharf/katalyst-core/pkg/util/machine"
+)
+
+func TestNUMANodeResourcesMapValidate(t *testing.T) {
+	t.Parallel()
+
+	machineInfo := &info.MachineInfo{
+		Topology: []info.Node{
+			{Id: 0, Memory: 100 << 30},
+			{Id: 1, Memory: 100 << 30},
+		},
+	}
+
+	generateMachineState := func(allocated uint64, podAllocated uint64) NUMANodeResourcesMap {
+		return NUMANodeResourcesMap{
+			v1.ResourceMemory: {
+				0: {
+					TotalMemSize: 100 << 30,
+					Allocatable:  100 << 30,
+					Allocated:    10 << 30,
+					PodEntries: PodEntries{
+						"pod1": ContainerEntries{
+							"c1": {TopologyAwareAllocations: map[int]uint64{0: 10 << 30}},
+						},
+					},
+				},
+				1: {
+					TotalMemSize: 100 << 30,
+					Allocatable:  100 << 30,
+					Allocated:    allocated,
+					PodEntries: PodEntries{
+						"pod2": ContainerEntries{
+							"c1": {TopologyAwareAllocations: map[int]uint64{1: podAllocated}},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	require.NoError(t, generateMachineState(100<<30, 100<<30).Validate(machineInfo))
+	require.NoError(t, NUMANodeResourcesMap{}.Validate(machineInfo))
+
+	// over-committed by the recorded allocated memory
+	err := generateMachineState(120<<30, 100<<30).Validate(machineInfo)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "NUMA: 1")
+
+	// over-committed by the sum of pod entries
+	err = generateMachineState(100<<30, 120<<30).Validate(machineInfo)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "NUMA: 1")
+
+	// numa missing in machine info
+	require.Error(t, generateMachineState(0, 0).Validate(&info.MachineInfo{
+		Topology: []info.Node{{Id: 0, Memory: 100 << 30}},
+	}))
+}
+
+func TestRestoreStateWithOverCommittedPodEntries(t *testing.T) {
+	t.Parallel()
+
+	stateDir, err := ioutil.TempDir("", "checkpoint-TestRestoreStateWithOverCommittedPodEntries")
+	require.NoError(t, err)
+	defer os.RemoveAll(stateDir)
+
+	cpuTopology, err := machine.GenerateDummyCPUTopology(16, 1, 2)
+	require.NoError(t, err)
+	machineInfo := &info.MachineInfo{
+		Topology: []info.Node{
+			{Id: 0, Memory: 100 << 30},
+			{Id: 1, Memory: 100 << 30},
+		},
+	}
+
+	st, err := NewCheckpointState(stateDir, "test", "dynamic", cpuTopology, machineInfo, nil, false)
+	require.NoError(t, err)
+
+	generateContainerEntries := func(numaID int, allocated uint64) ContainerEntries {
+		return ContainerEntries{
+			"c1": {
+				AggregatedQuantity:       allocated,
+				NumaAllocationResult:     machine.NewCPUSet(numaID),
+				TopologyAwareAllocations: map[int]uint64{numaID: allocated},
+			},
+		}
+	}
+
+	st.SetPodResourceEntries(PodResourceEntries{
+		v1.ResourceMemory: PodEntries{
+			"pod1": generateContainerEntries(1, 100<<30),
+		},
+	})
+	restored, err := NewCheckpointState(stateDir, "test", "dynamic", cpuTopology, machineInfo, nil, false)
+	require.NoError(t, err)
+	require.Contains(t, restored.GetPodResourceEntries()[v1.ResourceMemory], "pod1")
+
+	// pods over-committing numa 1 are dropped, and the state is rebuilt from the rest
+	st.SetPodResourceEntries(PodResourceEntries{
+		v1.ResourceMemory: PodEntries{
+			"pod1": generateContainerEntries(1, 60<<30),
+			"pod2": generateContainerEntries(1, 60<<30),
+			"pod3": generateContainerEntries(0, 60<<30),
+		},
+	})
+	restored, err = NewCheckpointState(stateDir, "test", "dynamic", cpuTopology, machineInfo, nil, false)
+	require.NoError(t, err)
+
+	podEntries := restored.GetPodResourceEntries()[v1.ResourceMemory]
+	require.Contains(t, podEntries, "pod1")
+	require.NotContains(t, podEntries, "pod2")
+	require.Contains(t, podEntries, "pod3")
+
+	machineState := restored.GetMachineState()[v1.ResourceMemory]
+	require.Equal(t, uint64(60<<30), machineState[0].Allocated)
+	require.Equal(t, uint64(60<<30), machineState[1].Allocated)
+	require.NoError(t, restored.GetMachineState().Validate(machineInfo))
+}
@@ -361,6 +361,51 @@ func (nrm NUMANodeResourcesMap) Clone() NUMANodeResourcesMap {
 	return clone
 }
 
+// Validate checks that memory allocated in each numa, both the recorded one and the sum of
+// its pod entries, doesn't exceed the numa memory capacity in machine info, and returns an
+// error with the offending numa otherwise; resources without capacity in machine info are skipped
+func (nrm NUMANodeResourcesMap) Validate(machineInfo *info.MachineInfo) error {
+	if machineInfo == nil {
+		return fmt.Errorf("nil machineInfo")
+	}
+
+	capacity := make(map[int]uint64, len(machineInfo.Topology))
+	for _, node := range machineInfo.Topology {
+		capacity[node.Id] = node.Memory
+	}
+
+	for numaID, numaNodeState := range nrm[v1.ResourceMemory] {
+		if numaNodeState == nil {
+			continue
+		}
+
+		numaCapacity, ok := capacity[numaID]
+		if !ok {
+			return fmt.Errorf("NUMA: %d not found in machine info", numaID)
+		}
+
+		if numaNodeState.Allocated > numaCapacity {
+			return fmt.Errorf("allocated memory: %d in NUMA: %d exceeds its capacity: %d",
+				numaNodeState.Allocated, numaID, numaCapacity)
+		}
+
+		var podsAllocated uint64 = 0
+		for _, containerEntries := range numaNodeState.PodEntries {
+			for _, allocationInfo := range containerEntries {
+				if allocationInfo != nil {
+					podsAllocated += allocationInfo.TopologyAwareAllocations[numaID]
+				}
+			}
+		}
+		if podsAllocated > numaCapacity {
+			return fmt.Errorf("memory allocated to pods: %d in NUMA: %d exceeds its capacity: %d",
+				podsAllocated, numaID, numaCapacity)
+		}
+	}
+
+	return nil
+}
+
 // reader is used to get information from local states
 type reader interface {
 	GetMachineState() NUMANodeResourcesMap
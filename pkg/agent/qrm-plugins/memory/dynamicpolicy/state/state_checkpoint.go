@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"path"
 	"reflect"
+	"sort"
 	"sync"
 
 	info "github.com/google/cadvisor/info/v1"
@@ -103,15 +104,35 @@ func (sc *stateCheckpoint) restoreState(machineInfo *info.MachineInfo, reservedM
 		return fmt.Errorf("[memory_plugin] configured policy %q differs from state checkpoint policy %q", sc.policyName, checkpoint.PolicyName)
 	}
 
+	// machine state in checkpoint is never restored directly, but an invalid one is
+	// rejected and rebuilt from pod entries below
+	if err = checkpoint.MachineState.Validate(machineInfo); err != nil {
+		klog.Warningf("[memory_plugin] invalid machine state in checkpoint: %v, rebuild it from pod entries", err)
+	}
+
 	generatedResourcesMachineState, err := GenerateMachineStateFromPodEntries(machineInfo, checkpoint.PodResourceEntries, reservedMemory)
 	if err != nil {
 		return fmt.Errorf("GenerateMachineStateFromPodEntries failed with error: %v", err)
 	}
 
+	// pod entries over-committing numa capacity can't be rectified by rebuilding,
+	// so drop them and rebuild machine state from the rest
+	podResourceEntries := checkpoint.PodResourceEntries
+	var droppedPodUIDs []string
+	if err = generatedResourcesMachineState.Validate(machineInfo); err != nil {
+		podResourceEntries, droppedPodUIDs = dropOverCommittedPodEntries(machineInfo, podResourceEntries)
+		klog.Errorf("[memory_plugin] invalid pod entries in checkpoint: %v, drop pods: %v", err, droppedPodUIDs)
+
+		generatedResourcesMachineState, err = GenerateMachineStateFromPodEntries(machineInfo, podResourceEntries, reservedMemory)
+		if err != nil {
+			return fmt.Errorf("GenerateMachineStateFromPodEntries failed with error: %v", err)
+		}
+	}
+
 	sc.cache.SetMachineState(generatedResourcesMachineState)
-	sc.cache.SetPodResourceEntries(checkpoint.PodResourceEntries)
+	sc.cache.SetPodResourceEntries(podResourceEntries)
 
-	if !reflect.DeepEqual(generatedResourcesMachineState, checkpoint.MachineState) {
+	if len(droppedPodUIDs) > 0 || !reflect.DeepEqual(generatedResourcesMachineState, checkpoint.MachineState) {
 		klog.Warningf("[memory_plugin] machine state changed: "+
 			"generatedResourcesMachineState: %s; checkpointMachineState: %s",
 			generatedResourcesMachineState.String(), checkpoint.MachineState.String())
@@ -134,6 +155,67 @@ func (sc *stateCheckpoint) restoreState(machineInfo *info.MachineInfo, reservedM
 	return nil
 }
 
+// dropOverCommittedPodEntries drops pods whose memory allocations don't fit into the capacity
+// of numa nodes any more, pods are kept in the order of their uids to be deterministic
+func dropOverCommittedPodEntries(machineInfo *info.MachineInfo,
+	podResourceEntries PodResourceEntries,
+) (PodResourceEntries, []string) {
+	capacity := make(map[int]uint64, len(machineInfo.Topology))
+	for _, node := range machineInfo.Topology {
+		capacity[node.Id] = node.Memory
+	}
+
+	memoryPodEntries := podResourceEntries[v1.ResourceMemory]
+	podUIDs := make([]string, 0, len(memoryPodEntries))
+	for podUID := range memoryPodEntries {
+		podUIDs = append(podUIDs, podUID)
+	}
+	sort.Strings(podUIDs)
+
+	allocated := make(map[int]uint64, len(capacity))
+	var droppedPodUIDs []string
+	for _, podUID := range podUIDs {
+		podAllocated := make(map[int]uint64)
+		for containerName, allocationInfo := range memoryPodEntries[podUID] {
+			if containerName == "" || allocationInfo == nil {
+				continue
+			}
+			for numaID, quantity := range allocationInfo.TopologyAwareAllocations {
+				podAllocated[numaID] += quantity
+			}
+		}
+
+		fit := true
+		for numaID, quantity := range podAllocated {
+			if allocated[numaID]+quantity > capacity[numaID] {
+				fit = false
+				break
+			}
+		}
+
+		if !fit {
+			droppedPodUIDs = append(droppedPodUIDs, podUID)
+			continue
+		}
+
+		for numaID, quantity := range podAllocated {
+			allocated[numaID] += quantity
+		}
+	}
+
+	if len(droppedPodUIDs) == 0 {
+		return podResourceEntries, nil
+	}
+
+	podResourceEntries = podResourceEntries.Clone()
+	for _, podEntries := range podResourceEntries {
+		for _, podUID := range droppedPodUIDs {
+			delete(podEntries, podUID)
+		}
+	}
+	return podResourceEntries, droppedPodUIDs
+}
+
 func (sc *stateCheckpoint) storeState() error {
 	checkpoint := NewMemoryPluginCheckpoint()
 	checkpoint.PolicyName = sc.policyName
@@ -20,11 +20,13 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"strconv"
 
 	info "github.com/google/cadvisor/info/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 
+	"github.com/kubewharf/katalyst-core/pkg/agent/qrm-plugins/memory/dynamicpolicy/memoryadvisor"
 	"github.com/kubewharf/katalyst-core/pkg/config"
 	"github.com/kubewharf/katalyst-core/pkg/metaserver"
 	"github.com/kubewharf/katalyst-core/pkg/util/general"
@@ -47,6 +49,28 @@ func GetFullyDropCacheBytes(container *v1.Container) int64 {
 	return fullyDropCacheBytes
 }
 
+// GetDropCacheBytesByAdvice returns bytes of cache to drop for the container according to the drop cache
+// intensity in advice; advice without intensity or with full intensity drops cache as much as possible
+func GetDropCacheBytesByAdvice(values map[string]string, container *v1.Container) (int64, error) {
+	fullyDropCacheBytes := GetFullyDropCacheBytes(container)
+
+	intensity := memoryadvisor.DropCacheIntensity(values[string(memoryadvisor.ControlKnobKeyDropCacheIntensity)])
+	if intensity == "" || intensity == memoryadvisor.DropCacheIntensityFull {
+		return fullyDropCacheBytes, nil
+	}
+
+	bytesStr := values[string(memoryadvisor.ControlKnobKeyDropCacheBytes)]
+	dropCacheBytes, err := strconv.ParseInt(bytesStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %s failed with error: %v", memoryadvisor.ControlKnobKeyDropCacheBytes, bytesStr, err)
+	}
+
+	if dropCacheBytes <= 0 || (fullyDropCacheBytes > 0 && dropCacheBytes > fullyDropCacheBytes) {
+		return fullyDropCacheBytes, nil
+	}
+	return dropCacheBytes, nil
+}
+
 // GetReservedMemory is used to spread total reserved memories into per-numa level.
 // this reserve resource calculation logic should be kept in qrm, if advisor wants
 // to get this info, it should depend on the returned checkpoint (through cpu-server)
@@ -21,8 +21,75 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/kubewharf/katalyst-core/pkg/agent/qrm-plugins/memory/dynamicpolicy/memoryadvisor"
 )
 
+func TestGetDropCacheBytesByAdvice(t *testing.T) {
+	t.Parallel()
+
+	container := &v1.Container{
+		Name: "c1",
+		Resources: v1.ResourceRequirements{
+			Limits: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		values  map[string]string
+		want    int64
+		wantErr bool
+	}{
+		{
+			name:   "legacy advice",
+			values: map[string]string{string(memoryadvisor.ControlKnobKeyDropCache): "true"},
+			want:   4 << 30,
+		},
+		{
+			name: "light intensity",
+			values: map[string]string{
+				string(memoryadvisor.ControlKnobKeyDropCache):          "true",
+				string(memoryadvisor.ControlKnobKeyDropCacheIntensity): string(memoryadvisor.DropCacheIntensityLight),
+				string(memoryadvisor.ControlKnobKeyDropCacheBytes):     "1073741824",
+			},
+			want: 1 << 30,
+		},
+		{
+			name: "bytes exceeds limit",
+			values: map[string]string{
+				string(memoryadvisor.ControlKnobKeyDropCache):          "true",
+				string(memoryadvisor.ControlKnobKeyDropCacheIntensity): string(memoryadvisor.DropCacheIntensityMedium),
+				string(memoryadvisor.ControlKnobKeyDropCacheBytes):     "8589934592",
+			},
+			want: 4 << 30,
+		},
+		{
+			name: "invalid bytes",
+			values: map[string]string{
+				string(memoryadvisor.ControlKnobKeyDropCache):          "true",
+				string(memoryadvisor.ControlKnobKeyDropCacheIntensity): string(memoryadvisor.DropCacheIntensityMedium),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := GetDropCacheBytesByAdvice(tt.values, container)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetDropCacheBytesByAdvice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetDropCacheBytesByAdvice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestGetFullyDropCacheBytes(t *testing.T) {
 	t.Parallel()
 
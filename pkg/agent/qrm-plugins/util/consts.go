@@ -28,11 +28,13 @@ const (
 	MetricNameAdvisorUnhealthy        = "advisor_unhealthy"
 
 	// metrics for cpu plugin
-	MetricNamePoolSize         = "pool_size"
-	MetricNameRealStateInvalid = "real_state_invalid"
-	MetricNameCPUSetInvalid    = "cpuset_invalid"
-	MetricNameCPUSetOverlap    = "cpuset_overlap"
-	MetricNameOrphanContainer  = "orphan_container"
+	MetricNamePoolSize            = "pool_size"
+	MetricNameRealStateInvalid    = "real_state_invalid"
+	MetricNameCPUSetInvalid       = "cpuset_invalid"
+	MetricNameCPUSetOverlap       = "cpuset_overlap"
+	MetricNameOrphanContainer     = "orphan_container"
+	MetricNameOrphanReclaimCgroup = "orphan_reclaim_cgroup"
+	MetricNameReclaimPoolDrift    = "reclaim_pool_drift"
 
 	// metrics for memory plugin
 	MetricNameMemSetInvalid                           = "memset_invalid"
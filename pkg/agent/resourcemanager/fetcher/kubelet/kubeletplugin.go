@@ -38,6 +38,7 @@ import (
 	"github.com/kubewharf/katalyst-core/pkg/metaserver"
 	"github.com/kubewharf/katalyst-core/pkg/metrics"
 	"github.com/kubewharf/katalyst-core/pkg/util"
+	"github.com/kubewharf/katalyst-core/pkg/util/general"
 	"github.com/kubewharf/katalyst-core/pkg/util/kubelet/podresources"
 	"github.com/kubewharf/katalyst-core/pkg/util/process"
 )
@@ -45,6 +46,11 @@ import (
 const (
 	// PluginName is name of kubelet reporter plugin
 	PluginName = "kubelet-reporter-plugin"
+
+	metricsNameTopologyReportSkipped = "topology_report_skipped"
+
+	// reportContentHashLength is long enough to tell changes of report content apart
+	reportContentHashLength = 64
 )
 
 // kubeletPlugin implements the endpoint interface, and it's an in-tree reporter plugin
@@ -70,6 +76,11 @@ type kubeletPlugin struct {
 
 	latestReportContentResponse atomic.Value
 
+	// lastReportedHash and lastReportedTime record the latest report triggered by topology
+	// changes, which are used to skip the report if the content is unchanged
+	lastReportedHash string
+	lastReportedTime time.Time
+
 	*process.StopControl
 	emitter    metrics.MetricEmitter
 	metaServer *metaserver.MetaServer
@@ -91,10 +102,12 @@ func NewKubeletReporterPlugin(emitter metrics.MetricEmitter, metaServer *metaser
 		StopControl: process.NewStopControl(time.Time{}),
 	}
 
-	topologyStatusAdapter, err := topology.NewPodResourcesServerTopologyAdapter(metaServer, conf.QoSConfiguration,
+	topologyStatusAdapter, err := topology.NewPodResourcesServerTopologyAdapter(emitter, metaServer, conf.QoSConfiguration,
 		conf.PodResourcesServerEndpoints, conf.KubeletResourcePluginPaths, conf.ResourceNameToZoneTypeMap,
 		nil, p.getNumaInfo, topology.GenericPodResourcesFilter(conf.QoSConfiguration), podresources.GetV1Client,
-		conf.NeedValidationResources)
+		conf.NeedValidationResources, conf.NUMAAllocationImbalanceThreshold, conf.TopologyReportHealthzTimeout,
+		conf.PodResourcesCallTimeout, conf.EnableReportContainerLevelTopology, conf.EnableReportTopologylessDevices,
+		conf.TopologylessDeviceNumaID)
 	if err != nil {
 		return nil, err
 	}
@@ -130,7 +143,7 @@ func (p *kubeletPlugin) Run(success chan<- bool) {
 				continue
 			}
 
-			p.ListAndWatchReportContentCallback(PluginName, resp)
+			p.reportTopologyStatus(resp)
 		case <-p.ctx.Done():
 			klog.Infof("plugin %s has been stopped", PluginName)
 			return
@@ -181,6 +194,35 @@ func (p *kubeletPlugin) topologyStatusChangeHandler() {
 	}
 }
 
+// reportTopologyStatus reports the content to manager, and it's skipped if report only on change
+// is enabled and the content is unchanged since last report within the force report period
+func (p *kubeletPlugin) reportTopologyStatus(resp *v1alpha1.GetReportContentResponse) {
+	if !p.conf.EnableReportTopologyOnlyOnChange {
+		p.ListAndWatchReportContentCallback(PluginName, resp)
+		return
+	}
+
+	value, err := json.Marshal(resp)
+	if err != nil {
+		klog.Errorf("plugin %s failed to marshal report content: %v, report it anyway", PluginName, err)
+		p.ListAndWatchReportContentCallback(PluginName, resp)
+		return
+	}
+
+	now := time.Now()
+	hash := general.GenerateHash(value, reportContentHashLength)
+	forceReport := p.conf.TopologyForceReportPeriod > 0 && now.Sub(p.lastReportedTime) >= p.conf.TopologyForceReportPeriod
+	if hash == p.lastReportedHash && !forceReport {
+		klog.Infof("plugin %s report content is unchanged since %v, skip it", PluginName, p.lastReportedTime)
+		_ = p.emitter.StoreInt64(metricsNameTopologyReportSkipped, 1, metrics.MetricTypeNameCount)
+		return
+	}
+
+	p.ListAndWatchReportContentCallback(PluginName, resp)
+	p.lastReportedHash = hash
+	p.lastReportedTime = now
+}
+
 func (p *kubeletPlugin) setCache(resp *v1alpha1.GetReportContentResponse) {
 	p.latestReportContentResponse.Store(resp)
 }
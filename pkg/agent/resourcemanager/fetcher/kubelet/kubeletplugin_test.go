@@ -341,3 +341,61 @@ func TestGetTopologyStatusContent(t *testing.T) {
 	_, err = kubePlugin.getReportContent(context.TODO())
 	assert.NoError(t, err)
 }
+
+func TestReportTopologyStatusOnlyOnChange(t *testing.T) {
+	t.Parallel()
+
+	dir, err := tmpSocketDir()
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := generateTestConfiguration(t, dir)
+	conf.EnableReportTopologyOnlyOnChange = true
+	conf.TopologyForceReportPeriod = time.Hour
+
+	reported := 0
+	callback := func(name string, resp *v1alpha1.GetReportContentResponse) {
+		reported++
+	}
+
+	plugin, err := NewKubeletReporterPlugin(metrics.DummyMetrics{}, generateTestMetaServer(), conf, callback)
+	assert.NoError(t, err)
+	kubePlugin := plugin.(*kubeletPlugin)
+
+	generateResp := func(value string) *v1alpha1.GetReportContentResponse {
+		return &v1alpha1.GetReportContentResponse{
+			Content: []*v1alpha1.ReportContent{
+				{
+					Field: []*v1alpha1.ReportField{
+						{
+							FieldType: v1alpha1.FieldType_Status,
+							FieldName: "TopologyZone",
+							Value:     []byte(value),
+						},
+					},
+				},
+			},
+		}
+	}
+
+	// the second identical cycle is skipped
+	kubePlugin.reportTopologyStatus(generateResp("a"))
+	assert.Equal(t, 1, reported)
+	kubePlugin.reportTopologyStatus(generateResp("a"))
+	assert.Equal(t, 1, reported)
+
+	// changed content is reported
+	kubePlugin.reportTopologyStatus(generateResp("b"))
+	assert.Equal(t, 2, reported)
+	assert.Equal(t, generateResp("b"), kubePlugin.GetCache())
+
+	// unchanged content is reported anyway after force report period
+	kubePlugin.lastReportedTime = time.Now().Add(-2 * time.Hour)
+	kubePlugin.reportTopologyStatus(generateResp("b"))
+	assert.Equal(t, 3, reported)
+
+	// every cycle is reported if report only on change is disabled
+	conf.EnableReportTopologyOnlyOnChange = false
+	kubePlugin.reportTopologyStatus(generateResp("b"))
+	assert.Equal(t, 4, reported)
+}
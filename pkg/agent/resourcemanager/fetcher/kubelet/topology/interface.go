@@ -31,6 +31,9 @@ type Adapter interface {
 	// GetTopologyPolicy return newest topology policy status
 	GetTopologyPolicy(ctx context.Context) (nodev1alpha1.TopologyPolicy, error)
 
+	// RefreshNumaTopology forces to rebuild the numa topology from numa info
+	RefreshNumaTopology(ctx context.Context) error
+
 	// Run is to start the topology adapter to watch the topology change
 	Run(ctx context.Context, handler func()) error
 }
@@ -51,6 +54,11 @@ func (d DummyAdapter) GetTopologyPolicy(_ context.Context) (nodev1alpha1.Topolog
 	return dummyTopologyPolicy, nil
 }
 
+// RefreshNumaTopology is to refresh dummy numa topology
+func (d DummyAdapter) RefreshNumaTopology(_ context.Context) error {
+	return nil
+}
+
 // Run is to start the dummy topology adapter
 func (d DummyAdapter) Run(_ context.Context, _ func()) error {
 	return nil
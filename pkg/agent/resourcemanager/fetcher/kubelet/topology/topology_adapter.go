@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"strconv"
 	"sync"
 	"time"
@@ -45,6 +46,7 @@ import (
 	"github.com/kubewharf/katalyst-core/pkg/metaserver"
 	metaserverpod "github.com/kubewharf/katalyst-core/pkg/metaserver/agent/pod"
 	"github.com/kubewharf/katalyst-core/pkg/metaserver/spd"
+	"github.com/kubewharf/katalyst-core/pkg/metrics"
 	"github.com/kubewharf/katalyst-core/pkg/util"
 	"github.com/kubewharf/katalyst-core/pkg/util/general"
 	"github.com/kubewharf/katalyst-core/pkg/util/kubelet/podresources"
@@ -55,6 +57,19 @@ const (
 	podResourcesClientTimeout    = 10 * time.Second
 	getTopologyZonesTimeout      = 10 * time.Second
 	podResourcesClientMaxMsgSize = 1024 * 1024 * 16
+
+	metricsNameNUMAAllocationImbalance = "numa_allocation_imbalance"
+	metricsNamePodResourcesCallTimeout = "pod_resources_call_timeout"
+	metricsNameNumaSocketChanged       = "numa_socket_changed"
+	metricsNameNumaResourceReservedGap = "numa_resource_reserved_gap"
+
+	healthzNameNUMAAllocationImbalance              = "numa_allocation_imbalance"
+	healthzNUMAAllocationImbalanceAutoRecoverPeriod = 10 * time.Minute
+
+	healthzNameTopologyReport = "topology_adapter_report"
+	// podResourcesServerMaxFailedTimes is the max consecutive failed times of calling
+	// pod resources server before reporting not ready
+	podResourcesServerMaxFailedTimes = 3
 )
 
 // NumaInfoGetter is to get numa info
@@ -79,6 +94,9 @@ type topologyAdapterImpl struct {
 	// numaSocketZoneNodeMap map numa zone node => socket zone node
 	numaSocketZoneNodeMap map[util.ZoneNode]util.ZoneNode
 
+	// numaInfoGetter is used to rebuild numaSocketZoneNodeMap on demand
+	numaInfoGetter NumaInfoGetter
+
 	// skipDeviceNames name of devices which will be skipped in getting numa allocatable and allocation
 	skipDeviceNames sets.String
 
@@ -96,13 +114,37 @@ type topologyAdapterImpl struct {
 
 	// needValidationResources is the resources needed to be validated
 	needValidationResources []string
+
+	// numaAllocationImbalanceThreshold is the threshold of numa allocation imbalance ratio,
+	// above which a healthz warning will be reported; zero means disabled
+	numaAllocationImbalanceThreshold float64
+
+	// podResourcesServerFailedTimes is the consecutive failed times of calling pod resources server
+	podResourcesServerFailedTimes int
+
+	// podResourcesCallTimeout is the timeout of each call to pod resources server; zero means no timeout
+	// other than the one of GetTopologyZones
+	podResourcesCallTimeout time.Duration
+
+	// enableReportContainerLevelTopology indicates whether to report allocations per container,
+	// whose consumer is namespace/name/uid/container, instead of per pod
+	enableReportContainerLevelTopology bool
+
+	// enableReportTopologylessDevices indicates whether to count devices without topology
+	// into the synthetic numa zone with topologylessDeviceNumaID instead of dropping them
+	enableReportTopologylessDevices bool
+	topologylessDeviceNumaID        int
+
+	emitter metrics.MetricEmitter
 }
 
 // NewPodResourcesServerTopologyAdapter creates a topology adapter which uses pod resources server
-func NewPodResourcesServerTopologyAdapter(metaServer *metaserver.MetaServer, qosConf *generic.QoSConfiguration,
+func NewPodResourcesServerTopologyAdapter(emitter metrics.MetricEmitter, metaServer *metaserver.MetaServer, qosConf *generic.QoSConfiguration,
 	endpoints []string, kubeletResourcePluginPaths []string, resourceNameToZoneTypeMap map[string]string,
 	skipDeviceNames sets.String, numaInfoGetter NumaInfoGetter, podResourcesFilter PodResourcesFilter,
-	getClientFunc podresources.GetClientFunc, needValidationResources []string,
+	getClientFunc podresources.GetClientFunc, needValidationResources []string, numaAllocationImbalanceThreshold float64,
+	reportHealthzTimeout time.Duration, podResourcesCallTimeout time.Duration, enableReportContainerLevelTopology bool,
+	enableReportTopologylessDevices bool, topologylessDeviceNumaID int,
 ) (Adapter, error) {
 	numaInfo, err := numaInfoGetter()
 	if err != nil {
@@ -118,6 +160,11 @@ func NewPodResourcesServerTopologyAdapter(metaServer *metaserver.MetaServer, qos
 		}
 	}
 
+	general.RegisterHeartbeatCheck(healthzNameTopologyReport, reportHealthzTimeout, general.HealthzCheckStateNotReady, 0)
+	if numaAllocationImbalanceThreshold > 0 {
+		general.RegisterReportCheck(healthzNameNUMAAllocationImbalance, healthzNUMAAllocationImbalanceAutoRecoverPeriod)
+	}
+
 	numaSocketZoneNodeMap := util.GenerateNumaSocketZone(numaInfo)
 	return &topologyAdapterImpl{
 		endpoints:                  endpoints,
@@ -125,11 +172,20 @@ func NewPodResourcesServerTopologyAdapter(metaServer *metaserver.MetaServer, qos
 		qosConf:                    qosConf,
 		metaServer:                 metaServer,
 		numaSocketZoneNodeMap:      numaSocketZoneNodeMap,
+		numaInfoGetter:             numaInfoGetter,
 		skipDeviceNames:            skipDeviceNames,
 		getClientFunc:              getClientFunc,
 		podResourcesFilter:         podResourcesFilter,
 		resourceNameToZoneTypeMap:  resourceNameToZoneTypeMap,
 		needValidationResources:    needValidationResources,
+
+		numaAllocationImbalanceThreshold: numaAllocationImbalanceThreshold,
+		podResourcesCallTimeout:          podResourcesCallTimeout,
+		emitter:                          emitter,
+
+		enableReportContainerLevelTopology: enableReportContainerLevelTopology,
+		enableReportTopologylessDevices:    enableReportTopologylessDevices,
+		topologylessDeviceNumaID:           topologylessDeviceNumaID,
 	}, nil
 }
 
@@ -137,6 +193,14 @@ func (p *topologyAdapterImpl) GetTopologyZones(parentCtx context.Context) ([]*no
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
+	// refresh numa topology in each round to detect numa socket changes, and
+	// keep reporting with the previous one if it fails to be refreshed
+	if p.numaInfoGetter != nil {
+		if err := p.refreshNumaTopology(); err != nil {
+			klog.Warningf("refresh numa topology failed: %v", err)
+		}
+	}
+
 	// always force getting pod list instead of cache
 	ctx := context.WithValue(parentCtx, metaserverpod.BypassCacheKey, metaserverpod.BypassCacheTrue)
 
@@ -147,14 +211,18 @@ func (p *topologyAdapterImpl) GetTopologyZones(parentCtx context.Context) ([]*no
 		return nil, errors.Wrap(err, "get pod list from metaServer failed")
 	}
 
-	listPodResourcesResponse, err := p.client.List(ctx, &podresv1.ListPodResourcesRequest{})
+	listPodResourcesResponse, err := p.listPodResources(ctx)
 	if err != nil {
-		return nil, errors.Wrap(err, "list pod from pod resource server failed")
+		err = errors.Wrap(err, "list pod from pod resource server failed")
+		p.updateReportHealthz(err)
+		return nil, err
 	}
 
-	allocatableResources, err := p.client.GetAllocatableResources(ctx, &podresv1.AllocatableResourcesRequest{})
+	allocatableResources, err := p.getAllocatableResources(ctx)
 	if err != nil {
-		return nil, errors.Wrap(err, "get allocatable Resources from pod resource server failed")
+		err = errors.Wrap(err, "get allocatable Resources from pod resource server failed")
+		p.updateReportHealthz(err)
+		return nil, err
 	}
 
 	if klog.V(5).Enabled() {
@@ -183,6 +251,9 @@ func (p *topologyAdapterImpl) GetTopologyZones(parentCtx context.Context) ([]*no
 		return nil, errors.Wrap(err, "get zone allocations failed")
 	}
 
+	// check numa allocation balance, which is only for observation
+	p.checkNUMAAllocationBalance(zoneAllocations)
+
 	// get zone resources by allocatable resources
 	zoneResources, err := p.getZoneResources(allocatableResources)
 	if err != nil {
@@ -218,7 +289,14 @@ func (p *topologyAdapterImpl) GetTopologyZones(parentCtx context.Context) ([]*no
 		return nil, errors.Wrap(err, "get device zone topology failed")
 	}
 
-	return topologyZoneGenerator.GenerateTopologyZoneStatus(zoneAllocations, zoneResources, zoneAttributes, zoneSiblings), nil
+	err = p.addTopologylessDeviceZoneNode(topologyZoneGenerator)
+	if err != nil {
+		return nil, errors.Wrap(err, "get topology-less device zone topology failed")
+	}
+
+	topologyZones := topologyZoneGenerator.GenerateTopologyZoneStatus(zoneAllocations, zoneResources, zoneAttributes, zoneSiblings)
+	p.updateReportHealthz(nil)
+	return topologyZones, nil
 }
 
 // GetTopologyPolicy return newest topology policy status
@@ -234,6 +312,51 @@ func (p *topologyAdapterImpl) GetTopologyPolicy(ctx context.Context) (nodev1alph
 	return utils.GenerateTopologyPolicy(klConfig.TopologyManagerPolicy, klConfig.TopologyManagerScope), nil
 }
 
+// RefreshNumaTopology forces to rebuild numaSocketZoneNodeMap from numa info, e.g. for debugging
+func (p *topologyAdapterImpl) RefreshNumaTopology(_ context.Context) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.refreshNumaTopology()
+}
+
+// refreshNumaTopology rebuilds numaSocketZoneNodeMap from numa info, and the numa zones
+// whose socket changed are logged; it must be called with the mutex held
+func (p *topologyAdapterImpl) refreshNumaTopology() error {
+	if p.numaInfoGetter == nil {
+		return fmt.Errorf("numa info getter is nil")
+	}
+
+	numaInfo, err := p.numaInfoGetter()
+	if err != nil {
+		return fmt.Errorf("failed to get numa info: %s", err)
+	}
+	numaSocketZoneNodeMap := util.GenerateNumaSocketZone(numaInfo)
+
+	for numaZoneNode, socketZoneNode := range numaSocketZoneNodeMap {
+		if oldSocketZoneNode, ok := p.numaSocketZoneNodeMap[numaZoneNode]; !ok {
+			klog.Infof("numa zone %s is added with socket zone %s", numaZoneNode.Meta.Name, socketZoneNode.Meta.Name)
+		} else if oldSocketZoneNode != socketZoneNode {
+			klog.Warningf("socket zone of numa zone %s is changed from %s to %s", numaZoneNode.Meta.Name,
+				oldSocketZoneNode.Meta.Name, socketZoneNode.Meta.Name)
+			if p.emitter != nil {
+				_ = p.emitter.StoreInt64(metricsNameNumaSocketChanged, 1, metrics.MetricTypeNameCount,
+					metrics.MetricTag{Key: "numa", Val: numaZoneNode.Meta.Name},
+					metrics.MetricTag{Key: "old_socket", Val: oldSocketZoneNode.Meta.Name},
+					metrics.MetricTag{Key: "new_socket", Val: socketZoneNode.Meta.Name})
+			}
+		}
+	}
+	for numaZoneNode := range p.numaSocketZoneNodeMap {
+		if _, ok := numaSocketZoneNodeMap[numaZoneNode]; !ok {
+			klog.Infof("numa zone %s is removed", numaZoneNode.Meta.Name)
+		}
+	}
+
+	p.numaSocketZoneNodeMap = numaSocketZoneNodeMap
+	return nil
+}
+
 func (p *topologyAdapterImpl) Run(ctx context.Context, handler func()) error {
 	var (
 		err  error
@@ -291,6 +414,62 @@ func (p *topologyAdapterImpl) Run(ctx context.Context, handler func()) error {
 	return nil
 }
 
+// listPodResources lists pod resources from pod resources server with timeout
+func (p *topologyAdapterImpl) listPodResources(ctx context.Context) (*podresv1.ListPodResourcesResponse, error) {
+	callCtx, cancel := p.withPodResourcesCallTimeout(ctx)
+	defer cancel()
+
+	resp, err := p.client.List(callCtx, &podresv1.ListPodResourcesRequest{})
+	p.checkPodResourcesCallTimeout(callCtx, "List", err)
+	return resp, err
+}
+
+// getAllocatableResources gets allocatable resources from pod resources server with timeout
+func (p *topologyAdapterImpl) getAllocatableResources(ctx context.Context) (*podresv1.AllocatableResourcesResponse, error) {
+	callCtx, cancel := p.withPodResourcesCallTimeout(ctx)
+	defer cancel()
+
+	resp, err := p.client.GetAllocatableResources(callCtx, &podresv1.AllocatableResourcesRequest{})
+	p.checkPodResourcesCallTimeout(callCtx, "GetAllocatableResources", err)
+	return resp, err
+}
+
+func (p *topologyAdapterImpl) withPodResourcesCallTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.podResourcesCallTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.podResourcesCallTimeout)
+}
+
+// checkPodResourcesCallTimeout emits metric if the call to pod resources server failed because of timeout
+func (p *topologyAdapterImpl) checkPodResourcesCallTimeout(callCtx context.Context, method string, err error) {
+	if err == nil || callCtx.Err() != context.DeadlineExceeded {
+		return
+	}
+
+	klog.Errorf("call %s of pod resources server timeout: %v", method, err)
+	if p.emitter != nil {
+		_ = p.emitter.StoreInt64(metricsNamePodResourcesCallTimeout, 1, metrics.MetricTypeNameCount,
+			metrics.MetricTag{Key: "method", Val: method})
+	}
+}
+
+// updateReportHealthz updates the heartbeat of topology report healthz check, it will be
+// ready once a report cycle succeeds, and not ready if pod resources server fails repeatedly
+func (p *topologyAdapterImpl) updateReportHealthz(err error) {
+	if err == nil {
+		p.podResourcesServerFailedTimes = 0
+		_ = general.UpdateHealthzState(healthzNameTopologyReport, general.HealthzCheckStateReady, "")
+		return
+	}
+
+	p.podResourcesServerFailedTimes++
+	if p.podResourcesServerFailedTimes >= podResourcesServerMaxFailedTimes {
+		_ = general.UpdateHealthzState(healthzNameTopologyReport, general.HealthzCheckStateNotReady,
+			fmt.Sprintf("pod resources server failed %d times in a row, last error: %v", p.podResourcesServerFailedTimes, err))
+	}
+}
+
 // validatePodResourcesServerResponse validate pod resources server response, if the resource is empty,
 // maybe the kubelet or qrm plugin is restarting
 func (p *topologyAdapterImpl) validatePodResourcesServerResponse(allocatableResourcesResponse *podresv1.
@@ -368,7 +547,7 @@ func (p *topologyAdapterImpl) addDeviceZoneNodes(generator *util.TopologyZoneGen
 		if targetZoneType, ok := p.resourceNameToZoneTypeMap[device.ResourceName]; ok {
 			for _, deviceId := range device.DeviceIds {
 				deviceNode := util.GenerateDeviceZoneNode(deviceId, targetZoneType)
-				for _, numaNode := range device.Topology.Nodes {
+				for _, numaNode := range device.GetTopology().GetNodes() {
 					numaZoneNode := util.GenerateNumaZoneNode(int(numaNode.ID))
 					err := generator.AddNode(&numaZoneNode, deviceNode)
 					if err != nil {
@@ -386,6 +565,21 @@ func (p *topologyAdapterImpl) addDeviceZoneNodes(generator *util.TopologyZoneGen
 	return nil
 }
 
+// addTopologylessDeviceZoneNode add the synthetic numa zone node which devices without topology are counted into
+// to the generator, it is added to the root if it is not one of the numa zone nodes of the machine
+func (p *topologyAdapterImpl) addTopologylessDeviceZoneNode(generator *util.TopologyZoneGenerator) error {
+	if !p.enableReportTopologylessDevices {
+		return nil
+	}
+
+	numaZoneNode := util.GenerateNumaZoneNode(p.topologylessDeviceNumaID)
+	if _, ok := p.numaSocketZoneNodeMap[numaZoneNode]; ok {
+		return nil
+	}
+
+	return generator.AddNode(nil, numaZoneNode)
+}
+
 // getZoneResources gets a map of zone node to zone Resources. The zone node Resources is combined by allocatable
 // device and allocatable resources from pod resources server
 func (p *topologyAdapterImpl) getZoneResources(allocatableResources *podresv1.AllocatableResourcesResponse) (map[util.ZoneNode]nodev1alpha1.Resources, error) {
@@ -460,9 +654,35 @@ func (p *topologyAdapterImpl) getZoneResources(allocatableResources *podresv1.Al
 		}
 	}
 
+	p.emitNumaResourceReservedGap(resources)
 	return resources, nil
 }
 
+// emitNumaResourceReservedGap emits the gap between capacity and allocatable of each resource
+// in each numa, which indicates how much resource is reserved by system in the numa
+func (p *topologyAdapterImpl) emitNumaResourceReservedGap(resources map[util.ZoneNode]nodev1alpha1.Resources) {
+	if p.emitter == nil {
+		return
+	}
+
+	for zone, zoneResources := range resources {
+		if zone.Meta.Type != nodev1alpha1.TopologyTypeNuma || zoneResources.Capacity == nil || zoneResources.Allocatable == nil {
+			continue
+		}
+
+		for resourceName, capacity := range *zoneResources.Capacity {
+			gap := capacity.DeepCopy()
+			if allocatable, ok := (*zoneResources.Allocatable)[resourceName]; ok {
+				gap.Sub(allocatable)
+			}
+
+			_ = p.emitter.StoreFloat64(metricsNameNumaResourceReservedGap, gap.AsApproximateFloat64(), metrics.MetricTypeNameRaw,
+				metrics.MetricTag{Key: "numa", Val: zone.Meta.Name},
+				metrics.MetricTag{Key: "resource", Val: string(resourceName)})
+		}
+	}
+}
+
 // getZoneAllocations gets a map of zone nodes to zone allocations computed from a list of pod resources that aggregates per-container allocations using
 // aggregateContainerAllocated. The podResourcesFilter is used to filter out some pods that do not need to be reported to cnr
 func (p *topologyAdapterImpl) getZoneAllocations(podList []*v1.Pod, podResourcesList []*podresv1.PodResources) (map[util.ZoneNode]util.ZoneAllocations, error) {
@@ -503,6 +723,39 @@ func (p *topologyAdapterImpl) getZoneAllocations(podList []*v1.Pod, podResources
 			}
 		}
 
+		if p.enableReportContainerLevelTopology {
+			containersAllocated, err := p.getContainersAllocated(pod.ObjectMeta, podResources.Containers)
+			if err != nil {
+				errList = append(errList, fmt.Errorf("pod %s get containers allocated failed, %s", podKey, err))
+				continue
+			}
+
+			// iterate containers in the order of pod resources to keep allocations stable
+			for _, containerResources := range podResources.Containers {
+				if containerResources == nil {
+					continue
+				}
+
+				containerName := containerResources.Name
+				containerAllocated, ok := containersAllocated[containerName]
+				if !ok {
+					continue
+				}
+
+				// revise container allocated according qos level
+				err = p.reviseContainerAllocated(pod, containerName, containerAllocated)
+				if err != nil {
+					errList = append(errList, fmt.Errorf("pod %s container %s revise container allocated failed, %s",
+						podKey, containerName, err))
+					continue
+				}
+
+				addZoneAllocations(zoneAllocationsMap, containerAllocated,
+					generateContainerConsumer(pod, containerName))
+			}
+			continue
+		}
+
 		// aggregates resources in each zone used by all containers of the pod
 		podAllocated, err := p.aggregateContainerAllocated(pod.ObjectMeta, podResources.Containers)
 		if err != nil {
@@ -517,17 +770,7 @@ func (p *topologyAdapterImpl) getZoneAllocations(podList []*v1.Pod, podResources
 			continue
 		}
 
-		for zoneNode, resourceList := range podAllocated {
-			_, ok := zoneAllocationsMap[zoneNode]
-			if !ok {
-				zoneAllocationsMap[zoneNode] = util.ZoneAllocations{}
-			}
-
-			zoneAllocationsMap[zoneNode] = append(zoneAllocationsMap[zoneNode], &nodev1alpha1.Allocation{
-				Consumer: native.GenerateUniqObjectUIDKey(pod),
-				Requests: resourceList,
-			})
-		}
+		addZoneAllocations(zoneAllocationsMap, podAllocated, native.GenerateUniqObjectUIDKey(pod))
 	}
 
 	if len(errList) > 0 {
@@ -537,6 +780,66 @@ func (p *topologyAdapterImpl) getZoneAllocations(podList []*v1.Pod, podResources
 	return zoneAllocationsMap, nil
 }
 
+// addZoneAllocations appends the allocated resources in each zone to the zone allocations map with the given consumer
+func addZoneAllocations(zoneAllocationsMap map[util.ZoneNode]util.ZoneAllocations,
+	allocated map[util.ZoneNode]*v1.ResourceList, consumer string,
+) {
+	for zoneNode, resourceList := range allocated {
+		zoneAllocationsMap[zoneNode] = append(zoneAllocationsMap[zoneNode], &nodev1alpha1.Allocation{
+			Consumer: consumer,
+			Requests: resourceList,
+		})
+	}
+}
+
+// generateContainerConsumer generates the consumer of container level allocation, which is namespace/name/uid/container
+func generateContainerConsumer(pod *v1.Pod, containerName string) string {
+	return fmt.Sprintf("%s/%s", native.GenerateUniqObjectUIDKey(pod), containerName)
+}
+
+// checkNUMAAllocationBalance calculates the imbalance ratio of cpu allocated across numas,
+// which is (max - min) / max, emits it to metric and reports healthz warning if it exceeds
+// the threshold. a high imbalance ratio usually indicates misconfiguration of topology manager.
+func (p *topologyAdapterImpl) checkNUMAAllocationBalance(zoneAllocations map[util.ZoneNode]util.ZoneAllocations) float64 {
+	if len(p.numaSocketZoneNodeMap) == 0 {
+		return 0
+	}
+
+	var maxAllocated, minAllocated int64 = 0, math.MaxInt64
+	for numaZoneNode := range p.numaSocketZoneNodeMap {
+		var allocated int64 = 0
+		for _, allocation := range zoneAllocations[numaZoneNode] {
+			if allocation == nil || allocation.Requests == nil {
+				continue
+			}
+			allocated += allocation.Requests.Cpu().MilliValue()
+		}
+		maxAllocated = general.MaxInt64(maxAllocated, allocated)
+		minAllocated = general.MinInt64(minAllocated, allocated)
+	}
+
+	imbalance := 0.0
+	if maxAllocated > 0 {
+		imbalance = float64(maxAllocated-minAllocated) / float64(maxAllocated)
+	}
+
+	if p.emitter != nil {
+		_ = p.emitter.StoreFloat64(metricsNameNUMAAllocationImbalance, imbalance, metrics.MetricTypeNameRaw)
+	}
+
+	if p.numaAllocationImbalanceThreshold > 0 {
+		if imbalance > p.numaAllocationImbalanceThreshold {
+			klog.Warningf("numa allocation imbalance %.2f exceeds threshold %.2f", imbalance, p.numaAllocationImbalanceThreshold)
+			_ = general.UpdateHealthzState(healthzNameNUMAAllocationImbalance, general.HealthzCheckStateNotReady,
+				fmt.Sprintf("numa allocation imbalance %.2f exceeds threshold %.2f", imbalance, p.numaAllocationImbalanceThreshold))
+		} else {
+			_ = general.UpdateHealthzState(healthzNameNUMAAllocationImbalance, general.HealthzCheckStateReady, "")
+		}
+	}
+
+	return imbalance
+}
+
 // revisePodAllocated is to revise pod allocated according to its qos level
 func (p *topologyAdapterImpl) revisePodAllocated(pod *v1.Pod, podAllocated map[util.ZoneNode]*v1.ResourceList) error {
 	qosLevel, err := p.qosConf.GetQoSLevel(pod, map[string]string{})
@@ -547,20 +850,48 @@ func (p *topologyAdapterImpl) revisePodAllocated(pod *v1.Pod, podAllocated map[u
 	switch qosLevel {
 	case apiconsts.PodAnnotationQoSLevelSharedCores:
 		// revise shared_cores pod allocated according to its numa binding
-		return p.reviseSharedCoresPodAllocated(pod, podAllocated)
+		requests, _ := resourceutil.PodRequestsAndLimits(pod)
+		return p.reviseSharedCoresAllocated(pod, podAllocated, requests)
 	default:
 		return nil
 	}
 }
 
-// reviseSharedCoresPodAllocated is to revise shared_cores pod allocated according to its numa binding
-func (p *topologyAdapterImpl) reviseSharedCoresPodAllocated(pod *v1.Pod, podAllocated map[util.ZoneNode]*v1.ResourceList) error {
-	ok, err := util.ValidateSharedCoresWithNumaBindingPod(p.qosConf, pod, podAllocated)
+// reviseContainerAllocated revises container allocated according to the qos level of its pod,
+// which is the same as revisePodAllocated except using the requests of the container
+func (p *topologyAdapterImpl) reviseContainerAllocated(pod *v1.Pod, containerName string,
+	containerAllocated map[util.ZoneNode]*v1.ResourceList,
+) error {
+	qosLevel, err := p.qosConf.GetQoSLevel(pod, map[string]string{})
+	if err != nil {
+		return err
+	}
+
+	switch qosLevel {
+	case apiconsts.PodAnnotationQoSLevelSharedCores:
+		var requests v1.ResourceList
+		for i := range pod.Spec.Containers {
+			if pod.Spec.Containers[i].Name == containerName {
+				requests = pod.Spec.Containers[i].Resources.Requests
+				break
+			}
+		}
+		return p.reviseSharedCoresAllocated(pod, containerAllocated, requests)
+	default:
+		return nil
+	}
+}
+
+// reviseSharedCoresAllocated is to revise shared_cores pod or container allocated according to its numa binding
+func (p *topologyAdapterImpl) reviseSharedCoresAllocated(pod *v1.Pod, allocated map[util.ZoneNode]*v1.ResourceList,
+	requests v1.ResourceList,
+) error {
+	ok, err := util.ValidateSharedCoresWithNumaBindingPod(p.qosConf, pod, allocated)
 	if !ok || err != nil {
 		return err
 	}
 
-	for zoneNode, resourceList := range podAllocated {
+	for zoneNode, resourceList := range allocated {
 		if zoneNode.Meta.Type != nodev1alpha1.TopologyTypeNuma {
 			continue
 		}
@@ -569,7 +900,6 @@ func (p *topologyAdapterImpl) reviseSharedCoresPodAllocated(pod *v1.Pod, podAllo
 			(!resourceList.Cpu().IsZero() || !resourceList.Memory().IsZero()) {
 
 			// revise the allocated resources to the binding numa node
-			requests, _ := resourceutil.PodRequestsAndLimits(pod)
 			if requests != nil {
 				(*resourceList)[v1.ResourceCPU] = requests.Cpu().DeepCopy()
 				(*resourceList)[v1.ResourceMemory] = requests.Memory().DeepCopy()
@@ -636,9 +966,35 @@ func (p *topologyAdapterImpl) getZoneAttributes(allocatableResources *podresv1.A
 // aggregateContainerAllocated aggregates resources in each zone used by all containers of a pod and returns a map of zone node to
 // container allocated resources.
 func (p *topologyAdapterImpl) aggregateContainerAllocated(podMeta metav1.ObjectMeta, containers []*podresv1.ContainerResources) (map[util.ZoneNode]*v1.ResourceList, error) {
-	var errList []error
+	containersAllocated, err := p.getContainersAllocated(podMeta, containers)
+	if err != nil {
+		return nil, err
+	}
 
 	podAllocated := make(map[util.ZoneNode]*v1.ResourceList)
+	for _, containerAllocated := range containersAllocated {
+		for zoneNode, resourceList := range containerAllocated {
+			if resourceList == nil {
+				continue
+			}
+
+			for resourceName, quantity := range *resourceList {
+				podAllocated = addZoneQuantity(podAllocated, zoneNode, resourceName, quantity)
+			}
+		}
+	}
+
+	return podAllocated, nil
+}
+
+// getContainersAllocated gets resources in each zone used by each container of a pod and returns a map of
+// container name to its allocated resources map.
+func (p *topologyAdapterImpl) getContainersAllocated(podMeta metav1.ObjectMeta,
+	containers []*podresv1.ContainerResources,
+) (map[string]map[util.ZoneNode]*v1.ResourceList, error) {
+	var errList []error
+
+	containersAllocated := make(map[string]map[util.ZoneNode]*v1.ResourceList)
 	for _, containerResources := range containers {
 		if containerResources == nil {
 			continue
@@ -668,22 +1024,14 @@ func (p *topologyAdapterImpl) aggregateContainerAllocated(podMeta metav1.ObjectM
 			continue
 		}
 
-		for zoneNode, resourceList := range containerAllocated {
-			if resourceList == nil {
-				continue
-			}
-
-			for resourceName, quantity := range *resourceList {
-				podAllocated = addZoneQuantity(podAllocated, zoneNode, resourceName, quantity)
-			}
-		}
+		containersAllocated[containerResources.Name] = containerAllocated
 	}
 
 	if len(errList) > 0 {
 		return nil, utilerrors.NewAggregate(errList)
 	}
 
-	return podAllocated, nil
+	return containersAllocated, nil
 }
 
 // addContainerDevices add all numa zone device into the zone resources map, and the skipDeviceNames is used
@@ -699,7 +1047,7 @@ func (p *topologyAdapterImpl) addContainerDevices(zoneResources map[util.ZoneNod
 	}
 
 	for _, device := range containerDevices {
-		if device == nil || device.Topology == nil {
+		if device == nil {
 			continue
 		}
 
@@ -708,6 +1056,15 @@ func (p *topologyAdapterImpl) addContainerDevices(zoneResources map[util.ZoneNod
 		}
 
 		resourceName := v1.ResourceName(device.ResourceName)
+		if device.Topology == nil {
+			// devices without topology are dropped unless they are counted into the synthetic numa zone
+			if p.enableReportTopologylessDevices {
+				zoneNode := util.GenerateNumaZoneNode(p.topologylessDeviceNumaID)
+				zoneResources = addZoneQuantity(zoneResources, zoneNode, resourceName, oneQuantity)
+			}
+			continue
+		}
+
 		for _, node := range device.Topology.Nodes {
 			if node == nil {
 				continue
@@ -790,10 +1147,16 @@ func (p *topologyAdapterImpl) addTopologyAwareQuantity(zoneResourceList map[util
 			continue
 		}
 
-		resourceValue, err := resource.ParseQuantity(fmt.Sprintf("%.2f", quantity.ResourceValue))
-		if err != nil {
-			errList = append(errList, fmt.Errorf("parse resource: %s for zone %s failed: %s", resourceName, zoneNode, err))
-			continue
+		var resourceValue resource.Quantity
+		if resourceName == v1.ResourceCPU {
+			// keep milli-cpu precision for fractional cpu, e.g. cpu of shared_cores pods
+			resourceValue = *resource.NewMilliQuantity(int64(math.Round(quantity.ResourceValue*1000)), resource.DecimalSI)
+		} else {
+			resourceValue, err = resource.ParseQuantity(fmt.Sprintf("%.2f", quantity.ResourceValue))
+			if err != nil {
+				errList = append(errList, fmt.Errorf("parse resource: %s for zone %s failed: %s", resourceName, zoneNode, err))
+				continue
+			}
 		}
 
 		zoneResourceList = addZoneQuantity(zoneResourceList, zoneNode, resourceName, resourceValue)
@@ -18,9 +18,11 @@ package topology
 
 import (
 	"context"
+	"errors"
 	"net"
 	"os"
 	"path"
+	"sync"
 	"testing"
 	"time"
 
@@ -38,6 +40,8 @@ import (
 	"k8s.io/kubernetes/pkg/kubelet/apis/config"
 	"k8s.io/kubernetes/pkg/kubelet/checkpointmanager"
 	testutil "k8s.io/kubernetes/pkg/kubelet/cm/cpumanager/state/testing"
+	"k8s.io/utils/clock"
+	testingclock "k8s.io/utils/clock/testing"
 
 	nodev1alpha1 "github.com/kubewharf/katalyst-api/pkg/apis/node/v1alpha1"
 	"github.com/kubewharf/katalyst-api/pkg/consts"
@@ -48,7 +52,9 @@ import (
 	"github.com/kubewharf/katalyst-core/pkg/metaserver/agent/kubeletconfig"
 	"github.com/kubewharf/katalyst-core/pkg/metaserver/agent/pod"
 	"github.com/kubewharf/katalyst-core/pkg/metaserver/spd"
+	"github.com/kubewharf/katalyst-core/pkg/metrics"
 	"github.com/kubewharf/katalyst-core/pkg/util"
+	"github.com/kubewharf/katalyst-core/pkg/util/general"
 	"github.com/kubewharf/katalyst-core/pkg/util/kubelet/podresources"
 	"github.com/kubewharf/katalyst-core/pkg/util/machine"
 )
@@ -1268,6 +1274,171 @@ func Test_getZoneAllocationsByPodResources(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "pod with fractional cpu",
+			args: args{
+				podList: []*v1.Pod{
+					generateTestPod("default", "pod-1", "pod-1-uid", consts.PodAnnotationQoSLevelDedicatedCores, true, map[string]v1.ResourceRequirements{
+						"container-1": {},
+					}),
+				},
+				numaSocketZoneNodeMap: map[util.ZoneNode]util.ZoneNode{
+					util.GenerateNumaZoneNode(0): util.GenerateSocketZoneNode(0),
+					util.GenerateNumaZoneNode(1): util.GenerateSocketZoneNode(1),
+				},
+				podResourcesList: []*podresv1.PodResources{
+					{
+						Namespace: "default",
+						Name:      "pod-1",
+						Containers: []*podresv1.ContainerResources{
+							{
+								Name: "container-1",
+								Resources: []*podresv1.TopologyAwareResource{
+									{
+										ResourceName: "cpu",
+										OriginalTopologyAwareQuantityList: []*podresv1.TopologyAwareQuantity{
+											{
+												ResourceValue: 2.125,
+												Node:          0,
+											},
+											{
+												ResourceValue: 0.5,
+												Node:          1,
+											},
+										},
+									},
+									{
+										ResourceName: "memory",
+										OriginalTopologyAwareQuantityList: []*podresv1.TopologyAwareQuantity{
+											{
+												ResourceValue: generateFloat64ResourceValue("4G"),
+												Node:          0,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: map[util.ZoneNode]util.ZoneAllocations{
+				util.GenerateNumaZoneNode(0): {
+					{
+						Consumer: "default/pod-1/pod-1-uid",
+						Requests: &v1.ResourceList{
+							"cpu":    resource.MustParse("2125m"),
+							"memory": resource.MustParse("4G"),
+						},
+					},
+				},
+				util.GenerateNumaZoneNode(1): {
+					{
+						Consumer: "default/pod-1/pod-1-uid",
+						Requests: &v1.ResourceList{
+							"cpu": resource.MustParse("500m"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "pod with sidecar container",
+			args: args{
+				podList: []*v1.Pod{
+					generateTestPod("default", "pod-1", "pod-1-uid", consts.PodAnnotationQoSLevelDedicatedCores, true, map[string]v1.ResourceRequirements{
+						"main":    {},
+						"sidecar": {},
+					}),
+				},
+				numaSocketZoneNodeMap: map[util.ZoneNode]util.ZoneNode{
+					util.GenerateNumaZoneNode(0): util.GenerateSocketZoneNode(0),
+					util.GenerateNumaZoneNode(1): util.GenerateSocketZoneNode(1),
+				},
+				podResourcesList: []*podresv1.PodResources{
+					{
+						Namespace: "default",
+						Name:      "pod-1",
+						Containers: []*podresv1.ContainerResources{
+							{
+								Name: "main",
+								Resources: []*podresv1.TopologyAwareResource{
+									{
+										ResourceName: "cpu",
+										OriginalTopologyAwareQuantityList: []*podresv1.TopologyAwareQuantity{
+											{
+												ResourceValue: 8,
+												Node:          0,
+											},
+											{
+												ResourceValue: 8,
+												Node:          1,
+											},
+										},
+									},
+									{
+										ResourceName: "memory",
+										OriginalTopologyAwareQuantityList: []*podresv1.TopologyAwareQuantity{
+											{
+												ResourceValue: generateFloat64ResourceValue("16G"),
+												Node:          0,
+											},
+										},
+									},
+								},
+							},
+							{
+								Name: "sidecar",
+								Resources: []*podresv1.TopologyAwareResource{
+									{
+										ResourceName: "cpu",
+										OriginalTopologyAwareQuantityList: []*podresv1.TopologyAwareQuantity{
+											{
+												ResourceValue: 2,
+												Node:          0,
+											},
+										},
+									},
+									{
+										ResourceName: "memory",
+										OriginalTopologyAwareQuantityList: []*podresv1.TopologyAwareQuantity{
+											{
+												ResourceValue: generateFloat64ResourceValue("4G"),
+												Node:          0,
+											},
+											{
+												ResourceValue: generateFloat64ResourceValue("4G"),
+												Node:          1,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: map[util.ZoneNode]util.ZoneAllocations{
+				util.GenerateNumaZoneNode(0): {
+					{
+						Consumer: "default/pod-1/pod-1-uid",
+						Requests: &v1.ResourceList{
+							"cpu":    resource.MustParse("10"),
+							"memory": resource.MustParse("20G"),
+						},
+					},
+				},
+				util.GenerateNumaZoneNode(1): {
+					{
+						Consumer: "default/pod-1/pod-1-uid",
+						Requests: &v1.ResourceList{
+							"cpu":    resource.MustParse("8"),
+							"memory": resource.MustParse("4G"),
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -1306,7 +1477,9 @@ func Test_getZoneResourcesByAllocatableResources(t *testing.T) {
 		name              string
 		args              args
 		wantZoneResources map[util.ZoneNode]nodev1alpha1.Resources
-		wantErr           bool
+		// wantReservedGap is keyed by numa/resource
+		wantReservedGap map[string]float64
+		wantErr         bool
 	}{
 		{
 			name: "test-1",
@@ -1604,6 +1777,13 @@ func Test_getZoneResourcesByAllocatableResources(t *testing.T) {
 					},
 				},
 			},
+			wantReservedGap: map[string]float64{
+				"0/gpu":    0,
+				"0/cpu":    2,
+				"0/memory": 2e9,
+				"1/cpu":    2,
+				"1/memory": 2e9,
+			},
 		},
 		{
 			name: "test for numa memory bandwidth",
@@ -1823,9 +2003,11 @@ func Test_getZoneResourcesByAllocatableResources(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
+			emitter := &fakeTaggedFloat64MetricsEmitter{values: make(map[string]float64)}
 			p := &topologyAdapterImpl{
 				metaServer:            tt.args.metaServer,
 				numaSocketZoneNodeMap: tt.args.numaSocketZoneNodeMap,
+				emitter:               emitter,
 			}
 			zoneResourcesMap, err := p.getZoneResources(tt.args.allocatableResources)
 			if (err != nil) != tt.wantErr {
@@ -1836,6 +2018,9 @@ func Test_getZoneResourcesByAllocatableResources(t *testing.T) {
 				t.Errorf("getZoneResources() got zoneResources = %v, wantZoneResources = %v",
 					zoneResourcesMap, tt.wantZoneResources)
 			}
+			if tt.wantReservedGap != nil {
+				assert.Equal(t, tt.wantReservedGap, emitter.values)
+			}
 		})
 	}
 }
@@ -2930,6 +3115,244 @@ func Test_podResourcesServerTopologyAdapterImpl_GetTopologyPolicy(t *testing.T)
 	assert.Equal(t, nodev1alpha1.TopologyPolicySingleNUMANodeContainerLevel, got)
 }
 
+type fakeFloat64MetricsEmitter struct {
+	metrics.DummyMetrics
+	values map[string]float64
+}
+
+func (f *fakeFloat64MetricsEmitter) StoreFloat64(key string, val float64, _ metrics.MetricTypeName, _ ...metrics.MetricTag) error {
+	f.values[key] = val
+	return nil
+}
+
+// fakeTaggedFloat64MetricsEmitter records values of numa_resource_reserved_gap keyed by numa/resource
+type fakeTaggedFloat64MetricsEmitter struct {
+	metrics.DummyMetrics
+	values map[string]float64
+}
+
+func (f *fakeTaggedFloat64MetricsEmitter) StoreFloat64(key string, val float64, _ metrics.MetricTypeName, tags ...metrics.MetricTag) error {
+	if key != metricsNameNumaResourceReservedGap {
+		return nil
+	}
+
+	var numa, resourceName string
+	for _, tag := range tags {
+		switch tag.Key {
+		case "numa":
+			numa = tag.Val
+		case "resource":
+			resourceName = tag.Val
+		}
+	}
+	f.values[numa+"/"+resourceName] = val
+	return nil
+}
+
+func Test_podResourcesServerTopologyAdapterImpl_checkNUMAAllocationBalance(t *testing.T) {
+	t.Parallel()
+
+	emitter := &fakeFloat64MetricsEmitter{values: make(map[string]float64)}
+	p := &topologyAdapterImpl{
+		numaSocketZoneNodeMap: map[util.ZoneNode]util.ZoneNode{
+			util.GenerateNumaZoneNode(0): util.GenerateSocketZoneNode(0),
+			util.GenerateNumaZoneNode(1): util.GenerateSocketZoneNode(1),
+		},
+		numaAllocationImbalanceThreshold: 0.5,
+		emitter:                          emitter,
+	}
+
+	// all cpu requests are allocated on numa 0
+	zoneAllocations := map[util.ZoneNode]util.ZoneAllocations{
+		util.GenerateNumaZoneNode(0): {
+			{
+				Consumer: "default/pod-1/pod-1-uid",
+				Requests: &v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("24"),
+					v1.ResourceMemory: resource.MustParse("24G"),
+				},
+			},
+			{
+				Consumer: "default/pod-2/pod-2-uid",
+				Requests: &v1.ResourceList{
+					v1.ResourceCPU: resource.MustParse("8"),
+				},
+			},
+		},
+		util.GenerateNumaZoneNode(1): {
+			{
+				Consumer: "default/pod-3/pod-3-uid",
+				Requests: &v1.ResourceList{
+					v1.ResourceMemory: resource.MustParse("8G"),
+				},
+			},
+		},
+	}
+
+	imbalance := p.checkNUMAAllocationBalance(zoneAllocations)
+	assert.Equal(t, 1.0, imbalance)
+	assert.Equal(t, 1.0, emitter.values[metricsNameNUMAAllocationImbalance])
+
+	// numa 1 gets a quarter of the cpu requests on numa 0
+	zoneAllocations[util.GenerateNumaZoneNode(1)] = append(zoneAllocations[util.GenerateNumaZoneNode(1)],
+		&nodev1alpha1.Allocation{
+			Consumer: "default/pod-4/pod-4-uid",
+			Requests: &v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse("8"),
+			},
+		})
+	imbalance = p.checkNUMAAllocationBalance(zoneAllocations)
+	assert.Equal(t, 0.75, imbalance)
+	assert.Equal(t, 0.75, emitter.values[metricsNameNUMAAllocationImbalance])
+}
+
+func Test_podResourcesServerTopologyAdapterImpl_NUMAAllocationImbalanceHealthz(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	general.SetHealthzClockForTest(fakeClock)
+	defer general.SetHealthzClockForTest(clock.RealClock{})
+
+	dir, err := tmpSocketDir()
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	getNumaInfo := func() ([]info.Node, error) {
+		return []info.Node{
+			{Id: 0, Cores: []info.Core{{SocketID: 0}}},
+			{Id: 1, Cores: []info.Core{{SocketID: 0}}},
+		}, nil
+	}
+	adapter, err := NewPodResourcesServerTopologyAdapter(metrics.DummyMetrics{}, generateTestMetaServer(), generic.NewQoSConfiguration(),
+		[]string{path.Join(dir, "podresources.sock")}, []string{path.Join(dir, "resource-plugins/")}, nil,
+		nil, getNumaInfo, nil, podresources.GetV1Client, []string{"cpu", "memory"}, 0.5, time.Minute, 0, false, false, -1)
+	assert.NoError(t, err)
+	p := adapter.(*topologyAdapterImpl)
+
+	imbalanced := map[util.ZoneNode]util.ZoneAllocations{
+		util.GenerateNumaZoneNode(0): {
+			{
+				Consumer: "default/pod-1/pod-1-uid",
+				Requests: &v1.ResourceList{v1.ResourceCPU: resource.MustParse("8")},
+			},
+		},
+	}
+	balanced := map[util.ZoneNode]util.ZoneAllocations{
+		util.GenerateNumaZoneNode(0): imbalanced[util.GenerateNumaZoneNode(0)],
+		util.GenerateNumaZoneNode(1): {
+			{
+				Consumer: "default/pod-2/pod-2-uid",
+				Requests: &v1.ResourceList{v1.ResourceCPU: resource.MustParse("8")},
+			},
+		},
+	}
+	ready := func() bool {
+		return general.GetRegisterReadinessCheckResult()[healthzNameNUMAAllocationImbalance].Ready
+	}
+
+	p.checkNUMAAllocationBalance(balanced)
+	assert.True(t, ready())
+
+	// sustained imbalance keeps the check not ready beyond the auto recover period
+	p.checkNUMAAllocationBalance(imbalanced)
+	assert.False(t, ready())
+	for i := 0; i < 3; i++ {
+		fakeClock.Step(healthzNUMAAllocationImbalanceAutoRecoverPeriod / 2)
+		p.checkNUMAAllocationBalance(imbalanced)
+		assert.False(t, ready())
+	}
+
+	// the check recovers once numas are balanced for the auto recover period
+	p.checkNUMAAllocationBalance(balanced)
+	fakeClock.Step(healthzNUMAAllocationImbalanceAutoRecoverPeriod / 2)
+	p.checkNUMAAllocationBalance(balanced)
+	assert.False(t, ready())
+	fakeClock.Step(healthzNUMAAllocationImbalanceAutoRecoverPeriod)
+	p.checkNUMAAllocationBalance(balanced)
+	assert.True(t, ready())
+}
+
+func Test_podResourcesServerTopologyAdapterImpl_RefreshNumaTopology(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mutex    sync.Mutex
+		numaInfo = []info.Node{
+			{Id: 0, Cores: []info.Core{{SocketID: 0}}},
+			{Id: 1, Cores: []info.Core{{SocketID: 0}}},
+		}
+		numaErr error
+	)
+	p := &topologyAdapterImpl{
+		numaSocketZoneNodeMap: util.GenerateNumaSocketZone(numaInfo),
+		numaInfoGetter: func() ([]info.Node, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return numaInfo, numaErr
+		},
+	}
+
+	// numa 1 moves to socket 1 and numa 2 is added
+	mutex.Lock()
+	numaInfo = []info.Node{
+		{Id: 0, Cores: []info.Core{{SocketID: 0}}},
+		{Id: 1, Cores: []info.Core{{SocketID: 1}}},
+		{Id: 2, Cores: []info.Core{{SocketID: 1}}},
+	}
+	mutex.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, p.RefreshNumaTopology(context.TODO()))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, map[util.ZoneNode]util.ZoneNode{
+		util.GenerateNumaZoneNode(0): util.GenerateSocketZoneNode(0),
+		util.GenerateNumaZoneNode(1): util.GenerateSocketZoneNode(1),
+		util.GenerateNumaZoneNode(2): util.GenerateSocketZoneNode(1),
+	}, p.numaSocketZoneNodeMap)
+
+	// the map is kept if numa info fails to be fetched
+	mutex.Lock()
+	numaErr = errors.New("failed to get numa info")
+	mutex.Unlock()
+	assert.Error(t, p.RefreshNumaTopology(context.TODO()))
+	assert.Equal(t, 3, len(p.numaSocketZoneNodeMap))
+
+	// numa topology is refreshed in each round of getting topology zones
+	mutex.Lock()
+	numaErr = nil
+	numaInfo = []info.Node{
+		{Id: 0, Cores: []info.Core{{SocketID: 0}}},
+		{Id: 1, Cores: []info.Core{{SocketID: 0}}},
+	}
+	mutex.Unlock()
+	p.metaServer = generateTestMetaServer()
+	p.qosConf = generic.NewQoSConfiguration()
+	p.client = &fakePodResourcesListerClient{
+		ListPodResourcesResponse: &podresv1.ListPodResourcesResponse{
+			PodResources: []*podresv1.PodResources{
+				{Namespace: "default", Name: "pod-1"},
+			},
+		},
+		AllocatableResourcesResponse: &podresv1.AllocatableResourcesResponse{},
+	}
+	zones, err := p.GetTopologyZones(context.TODO())
+	assert.NoError(t, err)
+	assert.True(t, apiequality.Semantic.DeepEqual([]*nodev1alpha1.TopologyZone{
+		{
+			Type: nodev1alpha1.TopologyTypeSocket,
+			Name: "0",
+			Children: []*nodev1alpha1.TopologyZone{
+				{Type: nodev1alpha1.TopologyTypeNuma, Name: "0"},
+				{Type: nodev1alpha1.TopologyTypeNuma, Name: "1"},
+			},
+		},
+	}, zones), "got %v", zones)
+}
+
 func Test_podResourcesServerTopologyAdapterImpl_Run(t *testing.T) {
 	t.Parallel()
 
@@ -2968,9 +3391,9 @@ func Test_podResourcesServerTopologyAdapterImpl_Run(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.TODO())
 	notifier := make(chan struct{}, 1)
-	p, _ := NewPodResourcesServerTopologyAdapter(testMetaServer, generic.NewQoSConfiguration(),
+	p, _ := NewPodResourcesServerTopologyAdapter(metrics.DummyMetrics{}, testMetaServer, generic.NewQoSConfiguration(),
 		endpoints, kubeletResourcePluginPath, nil,
-		nil, getNumaInfo, nil, podresources.GetV1Client, []string{"cpu", "memory"})
+		nil, getNumaInfo, nil, podresources.GetV1Client, []string{"cpu", "memory"}, 0, 0, 0, false, false, -1)
 	err = p.Run(ctx, func() {})
 	assert.NoError(t, err)
 
@@ -2986,3 +3409,422 @@ func Test_podResourcesServerTopologyAdapterImpl_Run(t *testing.T) {
 	close(notifier)
 	time.Sleep(10 * time.Millisecond)
 }
+
+// Test_podResourcesServerTopologyAdapterImpl_ReportHealthz is not run in parallel,
+// because healthz checks are registered globally and shared with other tests
+func Test_podResourcesServerTopologyAdapterImpl_ReportHealthz(t *testing.T) {
+	dir, err := tmpSocketDir()
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	endpoints := []string{
+		path.Join(dir, "podresources.sock"),
+	}
+
+	kubeletResourcePluginPath := []string{
+		path.Join(dir, "resource-plugins/"),
+	}
+
+	listener, err := net.Listen("unix", endpoints[0])
+	if err != nil {
+		t.Fatalf("failed to create listener: %v", err)
+	}
+
+	server := newFakePodResourcesServer(
+		&podresv1.ListPodResourcesResponse{},
+		&podresv1.AllocatableResourcesResponse{},
+	)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	getNumaInfo := func() ([]info.Node, error) {
+		return []info.Node{}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	adapter, err := NewPodResourcesServerTopologyAdapter(metrics.DummyMetrics{}, generateTestMetaServer(), generic.NewQoSConfiguration(),
+		endpoints, kubeletResourcePluginPath, nil,
+		nil, getNumaInfo, nil, podresources.GetV1Client, []string{"cpu", "memory"}, 0, time.Minute, 0, false, false, -1)
+	assert.NoError(t, err)
+	err = adapter.Run(ctx, func() {})
+	assert.NoError(t, err)
+
+	// not ready before the first successful report
+	assert.False(t, general.GetRegisterReadinessCheckResult()[healthzNameTopologyReport].Ready)
+
+	p := adapter.(*topologyAdapterImpl)
+	p.updateReportHealthz(nil)
+	assert.True(t, general.GetRegisterReadinessCheckResult()[healthzNameTopologyReport].Ready)
+
+	// starve the pod resources server
+	server.Stop()
+
+	_, err = adapter.GetTopologyZones(ctx)
+	assert.Error(t, err)
+	assert.True(t, general.GetRegisterReadinessCheckResult()[healthzNameTopologyReport].Ready)
+
+	for i := 1; i < podResourcesServerMaxFailedTimes; i++ {
+		_, err = adapter.GetTopologyZones(ctx)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, podResourcesServerMaxFailedTimes, p.podResourcesServerFailedTimes)
+	assert.False(t, general.GetRegisterReadinessCheckResult()[healthzNameTopologyReport].Ready)
+}
+
+type slowPodResourcesListerClient struct {
+	delay time.Duration
+}
+
+func (f *slowPodResourcesListerClient) List(ctx context.Context, _ *podresv1.ListPodResourcesRequest, _ ...grpc.CallOption) (*podresv1.ListPodResourcesResponse, error) {
+	select {
+	case <-time.After(f.delay):
+		return &podresv1.ListPodResourcesResponse{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *slowPodResourcesListerClient) GetAllocatableResources(ctx context.Context, _ *podresv1.AllocatableResourcesRequest, _ ...grpc.CallOption) (*podresv1.AllocatableResourcesResponse, error) {
+	select {
+	case <-time.After(f.delay):
+		return &podresv1.AllocatableResourcesResponse{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type fakeInt64MetricsEmitter struct {
+	metrics.DummyMetrics
+	mutex  sync.Mutex
+	values map[string]int64
+}
+
+func (f *fakeInt64MetricsEmitter) StoreInt64(key string, val int64, _ metrics.MetricTypeName, _ ...metrics.MetricTag) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.values[key] += val
+	return nil
+}
+
+func Test_podResourcesServerTopologyAdapterImpl_PodResourcesCallTimeout(t *testing.T) {
+	t.Parallel()
+
+	emitter := &fakeInt64MetricsEmitter{values: make(map[string]int64)}
+	p := &topologyAdapterImpl{
+		client:                  &slowPodResourcesListerClient{delay: time.Minute},
+		metaServer:              generateTestMetaServer(),
+		qosConf:                 generic.NewQoSConfiguration(),
+		podResourcesCallTimeout: 100 * time.Millisecond,
+		emitter:                 emitter,
+	}
+
+	start := time.Now()
+	_, err := p.GetTopologyZones(context.TODO())
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, time.Since(start), getTopologyZonesTimeout)
+	assert.Equal(t, int64(1), emitter.values[metricsNamePodResourcesCallTimeout])
+
+	_, err = p.getAllocatableResources(context.TODO())
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, int64(2), emitter.values[metricsNamePodResourcesCallTimeout])
+}
+
+func Test_getZoneAllocations_ContainerLevel(t *testing.T) {
+	t.Parallel()
+
+	podList := []*v1.Pod{
+		generateTestPod("default", "pod-1", "pod-1-uid", consts.PodAnnotationQoSLevelDedicatedCores, true, map[string]v1.ResourceRequirements{
+			"container-1": {},
+			"container-2": {},
+		}),
+	}
+	podResourcesList := []*podresv1.PodResources{
+		{
+			Namespace: "default",
+			Name:      "pod-1",
+			Containers: []*podresv1.ContainerResources{
+				{
+					Name: "container-1",
+					Resources: []*podresv1.TopologyAwareResource{
+						{
+							ResourceName: "cpu",
+							OriginalTopologyAwareQuantityList: []*podresv1.TopologyAwareQuantity{
+								{ResourceValue: 4, Node: 0},
+							},
+						},
+						{
+							ResourceName: "memory",
+							OriginalTopologyAwareQuantityList: []*podresv1.TopologyAwareQuantity{
+								{ResourceValue: generateFloat64ResourceValue("8G"), Node: 0},
+							},
+						},
+					},
+				},
+				{
+					Name: "container-2",
+					Resources: []*podresv1.TopologyAwareResource{
+						{
+							ResourceName: "cpu",
+							OriginalTopologyAwareQuantityList: []*podresv1.TopologyAwareQuantity{
+								{ResourceValue: 2, Node: 0},
+								{ResourceValue: 6, Node: 1},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	qosConf := generic.NewQoSConfiguration()
+	newAdapter := func(enableReportContainerLevelTopology bool) *topologyAdapterImpl {
+		return &topologyAdapterImpl{
+			numaSocketZoneNodeMap: map[util.ZoneNode]util.ZoneNode{
+				util.GenerateNumaZoneNode(0): util.GenerateSocketZoneNode(0),
+				util.GenerateNumaZoneNode(1): util.GenerateSocketZoneNode(1),
+			},
+			qosConf:                            qosConf,
+			podResourcesFilter:                 GenericPodResourcesFilter(qosConf),
+			metaServer:                         generateTestMetaServer(podList...),
+			enableReportContainerLevelTopology: enableReportContainerLevelTopology,
+		}
+	}
+
+	// pod level by default
+	got, err := newAdapter(false).getZoneAllocations(podList, podResourcesList)
+	assert.NoError(t, err)
+	assert.True(t, apiequality.Semantic.DeepEqual(map[util.ZoneNode]util.ZoneAllocations{
+		util.GenerateNumaZoneNode(0): {
+			{
+				Consumer: "default/pod-1/pod-1-uid",
+				Requests: &v1.ResourceList{
+					"cpu":    resource.MustParse("6"),
+					"memory": resource.MustParse("8G"),
+				},
+			},
+		},
+		util.GenerateNumaZoneNode(1): {
+			{
+				Consumer: "default/pod-1/pod-1-uid",
+				Requests: &v1.ResourceList{
+					"cpu": resource.MustParse("6"),
+				},
+			},
+		},
+	}, got), "got %v", got)
+
+	// container level
+	got, err = newAdapter(true).getZoneAllocations(podList, podResourcesList)
+	assert.NoError(t, err)
+	assert.True(t, apiequality.Semantic.DeepEqual(map[util.ZoneNode]util.ZoneAllocations{
+		util.GenerateNumaZoneNode(0): {
+			{
+				Consumer: "default/pod-1/pod-1-uid/container-1",
+				Requests: &v1.ResourceList{
+					"cpu":    resource.MustParse("4"),
+					"memory": resource.MustParse("8G"),
+				},
+			},
+			{
+				Consumer: "default/pod-1/pod-1-uid/container-2",
+				Requests: &v1.ResourceList{
+					"cpu": resource.MustParse("2"),
+				},
+			},
+		},
+		util.GenerateNumaZoneNode(1): {
+			{
+				Consumer: "default/pod-1/pod-1-uid/container-2",
+				Requests: &v1.ResourceList{
+					"cpu": resource.MustParse("6"),
+				},
+			},
+		},
+	}, got), "got %v", got)
+}
+
+func Test_addContainerDevices_TopologylessDevices(t *testing.T) {
+	t.Parallel()
+
+	devices := []*podresv1.ContainerDevices{
+		{
+			ResourceName: "gpu",
+			DeviceIds:    []string{"0"},
+			Topology: &podresv1.TopologyInfo{
+				Nodes: []*podresv1.NUMANode{
+					{ID: 0},
+				},
+			},
+		},
+		{
+			ResourceName: "disk",
+			DeviceIds:    []string{"sda"},
+		},
+		{
+			ResourceName: "disk",
+			DeviceIds:    []string{"sdb"},
+		},
+	}
+
+	// devices without topology are dropped by default
+	p := &topologyAdapterImpl{}
+	got, err := p.addContainerDevices(nil, devices)
+	assert.NoError(t, err)
+	assert.True(t, apiequality.Semantic.DeepEqual(map[util.ZoneNode]*v1.ResourceList{
+		util.GenerateNumaZoneNode(0): {
+			"gpu": resource.MustParse("1"),
+		},
+	}, got), "got %v", got)
+
+	// devices without topology are counted into the synthetic numa zone
+	p = &topologyAdapterImpl{
+		enableReportTopologylessDevices: true,
+		topologylessDeviceNumaID:        -1,
+	}
+	got, err = p.addContainerDevices(nil, devices)
+	assert.NoError(t, err)
+	assert.True(t, apiequality.Semantic.DeepEqual(map[util.ZoneNode]*v1.ResourceList{
+		util.GenerateNumaZoneNode(0): {
+			"gpu": resource.MustParse("1"),
+		},
+		util.GenerateNumaZoneNode(-1): {
+			"disk": resource.MustParse("2"),
+		},
+	}, got), "got %v", got)
+}
+
+func Test_podResourcesServerTopologyAdapterImpl_GetTopologyZones_TopologylessDevices(t *testing.T) {
+	t.Parallel()
+
+	podList := []*v1.Pod{
+		generateTestPod("default", "pod-1", "pod-1-uid", consts.PodAnnotationQoSLevelDedicatedCores, true, map[string]v1.ResourceRequirements{
+			"container-1": {},
+		}),
+	}
+	listPodResources := &podresv1.ListPodResourcesResponse{
+		PodResources: []*podresv1.PodResources{
+			{
+				Namespace: "default",
+				Name:      "pod-1",
+				Containers: []*podresv1.ContainerResources{
+					{
+						Name: "container-1",
+						Devices: []*podresv1.ContainerDevices{
+							{
+								ResourceName: "disk",
+								DeviceIds:    []string{"sda"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	allocatableResources := &podresv1.AllocatableResourcesResponse{
+		Devices: []*podresv1.ContainerDevices{
+			{
+				ResourceName: "disk",
+				DeviceIds:    []string{"sda"},
+			},
+			{
+				ResourceName: "disk",
+				DeviceIds:    []string{"sdb"},
+			},
+		},
+		Resources: []*podresv1.AllocatableTopologyAwareResource{
+			{
+				ResourceName: "cpu",
+				TopologyAwareCapacityQuantityList: []*podresv1.TopologyAwareQuantity{
+					{ResourceValue: 24, Node: 0},
+				},
+				TopologyAwareAllocatableQuantityList: []*podresv1.TopologyAwareQuantity{
+					{ResourceValue: 24, Node: 0},
+				},
+			},
+		},
+	}
+
+	numaZone := &nodev1alpha1.TopologyZone{
+		Type: nodev1alpha1.TopologyTypeNuma,
+		Name: "0",
+		Resources: nodev1alpha1.Resources{
+			Capacity: &v1.ResourceList{
+				"cpu": resource.MustParse("24"),
+			},
+			Allocatable: &v1.ResourceList{
+				"cpu": resource.MustParse("24"),
+			},
+		},
+	}
+	socketZone := &nodev1alpha1.TopologyZone{
+		Type:     nodev1alpha1.TopologyTypeSocket,
+		Name:     "0",
+		Children: []*nodev1alpha1.TopologyZone{numaZone},
+	}
+
+	tests := []struct {
+		name                            string
+		enableReportTopologylessDevices bool
+		want                            []*nodev1alpha1.TopologyZone
+	}{
+		{
+			name: "topology-less devices are dropped by default",
+			want: []*nodev1alpha1.TopologyZone{socketZone},
+		},
+		{
+			name:                            "topology-less devices are reported in the synthetic numa zone",
+			enableReportTopologylessDevices: true,
+			want: []*nodev1alpha1.TopologyZone{
+				{
+					Type: nodev1alpha1.TopologyTypeNuma,
+					Name: "-1",
+					Resources: nodev1alpha1.Resources{
+						Capacity: &v1.ResourceList{
+							"disk": resource.MustParse("2"),
+						},
+						Allocatable: &v1.ResourceList{
+							"disk": resource.MustParse("2"),
+						},
+					},
+					Allocations: []*nodev1alpha1.Allocation{
+						{
+							Consumer: "default/pod-1/pod-1-uid",
+							Requests: &v1.ResourceList{
+								"disk": resource.MustParse("1"),
+							},
+						},
+					},
+				},
+				socketZone,
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := topologyAdapterImpl{
+				client: &fakePodResourcesListerClient{
+					ListPodResourcesResponse:     listPodResources,
+					AllocatableResourcesResponse: allocatableResources,
+				},
+				metaServer: generateTestMetaServer(podList...),
+				qosConf:    generic.NewQoSConfiguration(),
+				numaSocketZoneNodeMap: map[util.ZoneNode]util.ZoneNode{
+					util.GenerateNumaZoneNode(0): util.GenerateSocketZoneNode(0),
+				},
+				enableReportTopologylessDevices: tt.enableReportTopologylessDevices,
+				topologylessDeviceNumaID:        -1,
+			}
+			got, err := p.GetTopologyZones(context.TODO())
+			assert.NoError(t, err)
+			assert.True(t, apiequality.Semantic.DeepEqual(tt.want, got), "got %v", got)
+		})
+	}
+}
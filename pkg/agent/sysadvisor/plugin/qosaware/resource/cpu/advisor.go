@@ -20,10 +20,12 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
 
+	"go.uber.org/atomic"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/errors"
@@ -39,8 +41,10 @@ import (
 	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/plugin/qosaware/resource/cpu/region"
 	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/plugin/qosaware/resource/cpu/region/headroompolicy"
 	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/plugin/qosaware/resource/cpu/region/provisionpolicy"
+	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/plugin/qosaware/resource/helper"
 	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/types"
 	"github.com/kubewharf/katalyst-core/pkg/config"
+	pkgconsts "github.com/kubewharf/katalyst-core/pkg/consts"
 	"github.com/kubewharf/katalyst-core/pkg/metaserver"
 	"github.com/kubewharf/katalyst-core/pkg/metrics"
 	"github.com/kubewharf/katalyst-core/pkg/util/general"
@@ -59,9 +63,20 @@ const (
 	metricRegionIndicatorTargetPrefix  = "region_indicator_target_"
 	metricRegionIndicatorCurrentPrefix = "region_indicator_current_"
 	metricRegionIndicatorErrorPrefix   = "region_indicator_error_"
-
-	cpuAdvisorHealthCheckName     = "cpu_advisor_update"
-	healthCheckTolerationDuration = 30 * time.Second
+	metricCPUAdvisorMetricsDropped     = "cpu_advisor_metrics_dropped"
+	metricCPUAdvisorReservedForReclaim = "cpu_advisor_reserved_for_reclaim"
+	metricCPUAdvisorNumaAvailable      = "cpu_advisor_numa_available"
+	metricCPUAdvisorRegionNameConflict = "cpu_advisor_region_name_conflict"
+	metricCPUAdvisorIsolationDisabled  = "cpu_advisor_isolation_disabled"
+	metricCPUAdvisorIsolationOverflow  = "cpu_advisor_isolation_overflow"
+	metricCPUAdvisorMetricsStale       = "cpu_advisor_metrics_stale"
+
+	cpuAdvisorHealthCheckName          = "cpu_advisor_update"
+	cpuAdvisorIsolationHealthCheckName = "cpu_advisor_isolation"
+	cpuAdvisorHeadroomHealthCheckName  = "cpu_advisor_headroom"
+	cpuAdvisorMetricsHealthCheckName   = "cpu_advisor_metrics"
+	cpuAdvisorPausedHealthCheckName    = "cpu_advisor_paused"
+	healthCheckTolerationDuration      = 30 * time.Second
 )
 
 var errIsolationSafetyCheckFailed = fmt.Errorf("isolation safety check failed")
@@ -81,6 +96,10 @@ func init() {
 	headroomassembler.RegisterInitializer(types.CPUHeadroomAssemblerDedicated, headroomassembler.NewHeadroomAssemblerDedicated)
 }
 
+// RegionAssigner returns the region list for the given container, and it may construct
+// region structures if they don't exist
+type RegionAssigner func(ci *types.ContainerInfo) ([]region.QoSRegion, error)
+
 // cpuResourceAdvisor is the entrance of updating cpu resource provision advice for
 // all qos regions, and merging them into cpu provision result to notify cpu server.
 // Smart algorithms and calculators could be adopted to give accurate realtime resource
@@ -95,29 +114,74 @@ type cpuResourceAdvisor struct {
 	startTime      time.Time
 	advisorUpdated bool
 
-	regionMap          map[string]region.QoSRegion // map[regionName]region
-	reservedForReclaim map[int]int                 // map[numaID]reservedForReclaim
-	numaAvailable      map[int]int                 // map[numaID]availableResource
-	numRegionsPerNuma  map[int]int                 // map[numaID]regionQuantity
-	nonBindingNumas    machine.CPUSet              // numas without numa binding pods
+	regionMap                map[string]region.QoSRegion // map[regionName]region
+	reservedForReclaim       map[int]int                 // map[numaID]reservedForReclaim
+	staticReservedForReclaim map[int]int                 // map[numaID]reservedForReclaim by dynamic configuration
+	numaAvailable            map[int]int                 // map[numaID]availableResource
+	numRegionsPerNuma        map[int]int                 // map[numaID]regionQuantity
+	nonBindingNumas          machine.CPUSet              // numas without numa binding pods
 
 	provisionAssembler provisionassembler.ProvisionAssembler
 	headroomAssembler  headroomassembler.HeadroomAssembler
 
+	// regionAssigners routes containers to regions by qos level, and
+	// containers of qos levels without assigner don't belong to any region
+	regionAssigners map[string]RegionAssigner
+
 	isolator        isolation.Isolator
 	isolationSafety bool
+	// isolationDisabledCycles is the number of consecutive cycles in which
+	// isolation is disabled due to safety check failure
+	isolationDisabledCycles int
+	// isolationSkipUntil is the time before which updating with isolation is skipped
+	// since isolation keeps failing safety check
+	isolationSkipUntil time.Time
+	// isolationOverflowContainers are isolation candidates kept in share pool in current
+	// cycle since isolation regions on their numa have reached the limit
+	isolationOverflowContainers sets.String
+
+	// metricsStale is true if pool sizes are frozen since driving metrics are stale
+	metricsStale bool
 
 	mutex      sync.RWMutex
 	metaCache  metacache.MetaCache
 	metaServer *metaserver.MetaServer
 	emitter    metrics.MetricEmitter
 	doOnce     sync.Once
+
+	// metricSamplesCh buffers metric samples computed in update, and they are emitted
+	// by a separate goroutine to keep slow metrics backend off the critical path
+	metricSamplesCh       chan []metricSample
+	droppedMetricsBatches *atomic.Int64
+
+	// paused is set during node maintenance or manual debugging to freeze pool sizes,
+	// and lastCalculationResult is re-sent to cpu server instead while paused
+	paused                *atomic.Bool
+	lastCalculationResult *types.InternalCPUCalculationResult
+
+	// servingDefaultHeadroom is set if default headroom is served since advisor is not updated,
+	// and it's used to update headroom healthz only when the state changes
+	servingDefaultHeadroom *atomic.Bool
+}
+
+// metricSample is a snapshot of a metric item to be emitted asynchronously
+type metricSample struct {
+	name       string
+	value      float64
+	isInt      bool
+	metricType metrics.MetricTypeName
+	tags       []metrics.MetricTag
 }
 
+var _ types.ResourceHeadroomProvider = &cpuResourceAdvisor{}
+
 // NewCPUResourceAdvisor returns a cpuResourceAdvisor instance
 func NewCPUResourceAdvisor(conf *config.Configuration, extraConf interface{}, metaCache metacache.MetaCache,
 	metaServer *metaserver.MetaServer, emitter metrics.MetricEmitter,
 ) *cpuResourceAdvisor {
+	// metrics of advisor, regions and assemblers are all tagged with cgroup version
+	emitter = helper.WithCgroupVersionTag(emitter)
+
 	cra := &cpuResourceAdvisor{
 		conf:      conf,
 		extraConf: extraConf,
@@ -133,16 +197,35 @@ func NewCPUResourceAdvisor(conf *config.Configuration, extraConf interface{}, me
 		numaAvailable:      make(map[int]int),
 		numRegionsPerNuma:  make(map[int]int),
 		nonBindingNumas:    machine.NewCPUSet(),
+		regionAssigners:    make(map[string]RegionAssigner),
+
+		isolationOverflowContainers: sets.NewString(),
 
 		isolator: isolation.NewLoadIsolator(conf, extraConf, emitter, metaCache, metaServer),
 
 		metaCache:  metaCache,
 		metaServer: metaServer,
 		emitter:    emitter,
+
+		metricSamplesCh:        make(chan []metricSample, general.Max(conf.CPUAdvisorConfiguration.MetricsBufferSize, 1)),
+		droppedMetricsBatches:  atomic.NewInt64(0),
+		paused:                 atomic.NewBool(false),
+		servingDefaultHeadroom: atomic.NewBool(false),
+	}
+
+	// paused and headroom checks are registered ahead, since advisor may be paused
+	// or asked for headroom before the first update
+	general.RegisterHeartbeatCheck(cpuAdvisorPausedHealthCheckName, 0, general.HealthzCheckStateReady, 0)
+	if conf.CPUAdvisorConfiguration.ServeDefaultHeadroomBeforeUpdated {
+		general.RegisterHeartbeatCheck(cpuAdvisorHeadroomHealthCheckName, 0, general.HealthzCheckStateReady, 0)
 	}
 
+	cra.RegisterRegionAssigner(consts.PodAnnotationQoSLevelSharedCores, cra.assignShareContainerToRegions)
+	cra.RegisterRegionAssigner(consts.PodAnnotationQoSLevelDedicatedCores, cra.assignDedicatedContainerToRegions)
+
 	coreNumReservedForReclaim := conf.DynamicAgentConfiguration.GetDynamicConfiguration().MinReclaimedResourceForAllocate[v1.ResourceCPU]
-	cra.reservedForReclaim = machine.GetCoreNumReservedForReclaim(int(coreNumReservedForReclaim.Value()), metaServer.KatalystMachineInfo.NumNUMANodes)
+	cra.staticReservedForReclaim = machine.GetCoreNumReservedForReclaim(int(coreNumReservedForReclaim.Value()), metaServer.KatalystMachineInfo.NumNUMANodes)
+	cra.reservedForReclaim = cra.staticReservedForReclaim
 
 	if err := cra.initializeProvisionAssembler(); err != nil {
 		klog.Errorf("[qosaware-cpu] initialize provision assembler failed: %v", err)
@@ -155,12 +238,12 @@ func NewCPUResourceAdvisor(conf *config.Configuration, extraConf interface{}, me
 }
 
 func (cra *cpuResourceAdvisor) Run(ctx context.Context) {
+	go cra.runMetricsEmitter(ctx)
+
 	for {
 		select {
 		case v := <-cra.recvCh:
-			cra.doOnce.Do(func() {
-				general.RegisterReportCheck(cpuAdvisorHealthCheckName, healthCheckTolerationDuration)
-			})
+			cra.doOnce.Do(cra.registerHealthzChecks)
 
 			lag := time.Since(v.TimeStamp)
 			klog.Infof("[qosaware-cpu] receive update trigger, checkpoint at %v", v.TimeStamp)
@@ -183,6 +266,14 @@ func (cra *cpuResourceAdvisor) Run(ctx context.Context) {
 	}
 }
 
+func (cra *cpuResourceAdvisor) registerHealthzChecks() {
+	general.RegisterReportCheck(cpuAdvisorHealthCheckName, healthCheckTolerationDuration)
+	general.RegisterHeartbeatCheck(cpuAdvisorIsolationHealthCheckName, 0, general.HealthzCheckStateReady, 0)
+	if cra.conf.CPUAdvisorConfiguration.FreezeOnStaleMetricsThreshold > 0 {
+		general.RegisterHeartbeatCheck(cpuAdvisorMetricsHealthCheckName, 0, general.HealthzCheckStateReady, 0)
+	}
+}
+
 func (cra *cpuResourceAdvisor) GetChannels() (interface{}, interface{}) {
 	return cra.recvCh, cra.sendCh
 }
@@ -193,10 +284,24 @@ func (cra *cpuResourceAdvisor) GetHeadroom() (resource.Quantity, error) {
 	cra.mutex.RLock()
 	defer cra.mutex.RUnlock()
 
+	serveDefault := cra.conf.CPUAdvisorConfiguration.ServeDefaultHeadroomBeforeUpdated
 	if !cra.advisorUpdated {
+		if serveDefault {
+			headroom := *resource.NewMilliQuantity(int64(cra.conf.CPUAdvisorConfiguration.DefaultHeadroomCores*1000), resource.DecimalSI)
+			klog.Infof("[qosaware-cpu] serve default headroom %v: advisor not updated", headroom)
+			if !cra.servingDefaultHeadroom.Swap(true) {
+				_ = general.UpdateHealthzState(cpuAdvisorHeadroomHealthCheckName, general.HealthzCheckStateNotReady,
+					"serve default headroom: advisor not updated")
+			}
+			return headroom, nil
+		}
+
 		klog.Infof("[qosaware-cpu] skip getting headroom: advisor not updated")
 		return resource.Quantity{}, fmt.Errorf("advisor not updated")
 	}
+	if serveDefault && cra.servingDefaultHeadroom.Swap(false) {
+		_ = general.UpdateHealthzState(cpuAdvisorHeadroomHealthCheckName, general.HealthzCheckStateReady, "")
+	}
 
 	if cra.headroomAssembler == nil {
 		klog.Errorf("[qosaware-cpu] get headroom failed: no legal assembler")
@@ -206,11 +311,103 @@ func (cra *cpuResourceAdvisor) GetHeadroom() (resource.Quantity, error) {
 	headroom, err := cra.headroomAssembler.GetHeadroom()
 	if err != nil {
 		klog.Errorf("[qosaware-cpu] get headroom failed: %v", err)
-	} else {
-		klog.Infof("[qosaware-cpu] get headroom: %v", headroom)
+		return headroom, err
+	}
+
+	headroom = cra.capHeadroom(headroom, cra.metaServer.CPUTopology.NumCPUs, "node")
+	klog.Infof("[qosaware-cpu] get headroom: %v", headroom)
+
+	return headroom, nil
+}
+
+// GetNUMAHeadroom distributes total headroom to each numa in proportion to the size of reclaim pool on it
+func (cra *cpuResourceAdvisor) GetNUMAHeadroom() (map[int]resource.Quantity, error) {
+	headroom, err := cra.GetHeadroom()
+	if err != nil {
+		return nil, err
+	}
+
+	reclaimPoolInfo, ok := cra.metaCache.GetPoolInfo(state.PoolNameReclaim)
+	if !ok || reclaimPoolInfo == nil {
+		return nil, fmt.Errorf("failed to get reclaim pool info")
 	}
+	reclaimPoolSize := reclaimPoolInfo.TopologyAwareAssignments.MergeCPUSet().Size()
+
+	numaHeadroom := make(map[int]resource.Quantity)
+	breakdown := cra.getCPUSetBreakdown(cra.metaServer.CPUDetails.CPUs())
+	for numaID, numaCPUs := range breakdown.numaCPUs {
+		var milliValue int64 = 0
+		if reclaimPoolSize > 0 {
+			milliValue = headroom.MilliValue() * int64(reclaimPoolInfo.TopologyAwareAssignments[numaID].Size()) / int64(reclaimPoolSize)
+		}
+		numaHeadroom[numaID] = cra.capHeadroom(*resource.NewMilliQuantity(milliValue, resource.DecimalSI),
+			numaCPUs.Size(), fmt.Sprintf("numa%d", numaID))
+	}
+	klog.Infof("[qosaware-cpu] get numa headroom: %v", numaHeadroom)
+
+	return numaHeadroom, nil
+}
+
+// GetRegionsByNUMA returns descriptors of regions bound to the given numa sorted by region name,
+// so that consumers like debugging tools don't need to access the internal region map
+func (cra *cpuResourceAdvisor) GetRegionsByNUMA(numaID int) []types.RegionDescriptor {
+	cra.mutex.RLock()
+	defer cra.mutex.RUnlock()
+
+	descriptors := make([]types.RegionDescriptor, 0)
+	for _, r := range cra.regionMap {
+		if !r.GetBindingNumas().Contains(numaID) {
+			continue
+		}
+		descriptors = append(descriptors, types.RegionDescriptor{
+			Name:          r.Name(),
+			Type:          r.Type(),
+			OwnerPoolName: r.OwnerPoolName(),
+			Throttled:     r.IsThrottled(),
+		})
+	}
+	sort.Slice(descriptors, func(i, j int) bool {
+		return descriptors[i].Name < descriptors[j].Name
+	})
+	return descriptors
+}
 
-	return headroom, err
+// Pause freezes cpu advisor, the last calculation result will be re-sent to cpu server until resumed,
+// and healthz is kept not ready since pool sizes are not adjusted any more
+func (cra *cpuResourceAdvisor) Pause() {
+	cra.paused.Store(true)
+	klog.Infof("[qosaware-cpu] cpu advisor paused")
+	_ = general.UpdateHealthzState(cpuAdvisorPausedHealthCheckName, general.HealthzCheckStateNotReady, "paused")
+}
+
+// Resume unfreezes cpu advisor paused before
+func (cra *cpuResourceAdvisor) Resume() {
+	cra.paused.Store(false)
+	klog.Infof("[qosaware-cpu] cpu advisor resumed")
+	_ = general.UpdateHealthzState(cpuAdvisorPausedHealthCheckName, general.HealthzCheckStateReady, "")
+}
+
+// GetUpdateStatus returns whether cpu advisor has been updated successfully
+func (cra *cpuResourceAdvisor) GetUpdateStatus() types.PolicyUpdateStatus {
+	cra.mutex.RLock()
+	defer cra.mutex.RUnlock()
+
+	if cra.advisorUpdated {
+		return types.PolicyUpdateSucceeded
+	}
+	return types.PolicyUpdateFailed
+}
+
+// GetLastCalculationResult returns a copy of the last calculation result sent to cpu server,
+// and false is returned if no result has been calculated yet
+func (cra *cpuResourceAdvisor) GetLastCalculationResult() (types.InternalCPUCalculationResult, bool) {
+	cra.mutex.RLock()
+	defer cra.mutex.RUnlock()
+
+	if cra.lastCalculationResult == nil {
+		return types.InternalCPUCalculationResult{}, false
+	}
+	return *cra.lastCalculationResult.Clone(), true
 }
 
 // update works in a monolithic way to maintain lifecycle and triggers update actions for all regions;
@@ -218,16 +415,65 @@ func (cra *cpuResourceAdvisor) GetHeadroom() (resource.Quantity, error) {
 func (cra *cpuResourceAdvisor) update() (err error) {
 	cra.mutex.Lock()
 	defer cra.mutex.Unlock()
+
+	if cra.paused.Load() {
+		klog.Infof("[qosaware-cpu] skip updating: advisor is paused")
+		// nothing can be re-sent if advisor is paused before any result is calculated
+		if cra.lastCalculationResult == nil {
+			return fmt.Errorf("advisor is paused without any calculation result")
+		}
+		return cra.notifyCPUServer(*cra.lastCalculationResult)
+	}
+
+	// freeze pool sizes instead of making decisions on stale metrics
+	if cra.checkMetricsStale() {
+		if cra.lastCalculationResult == nil {
+			return nil
+		}
+		return cra.notifyCPUServer(*cra.lastCalculationResult)
+	}
+
+	if cra.conf.IsolationSafetyFailuresToSkip > 0 && time.Now().Before(cra.isolationSkipUntil) {
+		klog.Infof("[qosaware-cpu] skip updateWithIsolationGuardian(true) until %v", cra.isolationSkipUntil)
+		cra.updateIsolationDisabledStatus(true)
+		return cra.updateWithIsolationGuardian(false)
+	}
+
 	if err = cra.updateWithIsolationGuardian(true); err != nil {
 		if err == errIsolationSafetyCheckFailed {
 			klog.Warningf("[qosaware-cpu] failed to updateWithIsolationGuardian(true): %q", err)
+			cra.updateIsolationDisabledStatus(true)
+			if cra.conf.IsolationSafetyFailuresToSkip > 0 && cra.isolationDisabledCycles >= cra.conf.IsolationSafetyFailuresToSkip {
+				cra.isolationSkipUntil = time.Now().Add(cra.conf.IsolationSafetySkipCooldown)
+				klog.Warningf("[qosaware-cpu] isolation failed safety check for %v cycles, skip it until %v",
+					cra.isolationDisabledCycles, cra.isolationSkipUntil)
+			}
 			return cra.updateWithIsolationGuardian(false)
 		}
 		return err
 	}
+	cra.updateIsolationDisabledStatus(false)
 	return nil
 }
 
+// updateIsolationDisabledStatus records whether isolation is disabled in current cycle due to safety
+// check failure, and keeps healthz degraded until isolation works again
+func (cra *cpuResourceAdvisor) updateIsolationDisabledStatus(disabled bool) {
+	if !disabled {
+		if cra.isolationDisabledCycles > 0 {
+			klog.Infof("[qosaware-cpu] isolation recovered after disabled for %v cycles", cra.isolationDisabledCycles)
+			cra.isolationDisabledCycles = 0
+			_ = general.UpdateHealthzState(cpuAdvisorIsolationHealthCheckName, general.HealthzCheckStateReady, "")
+		}
+		return
+	}
+
+	cra.isolationDisabledCycles++
+	_ = cra.emitter.StoreInt64(metricCPUAdvisorIsolationDisabled, 1, metrics.MetricTypeNameCount)
+	_ = general.UpdateHealthzState(cpuAdvisorIsolationHealthCheckName, general.HealthzCheckStateNotReady,
+		fmt.Sprintf("isolation disabled due to safety check failure for %v cycles", cra.isolationDisabledCycles))
+}
+
 // updateWithIsolationGuardian returns true if the process works as expected,
 // otherwise, we should retry with the isolation disabled
 // todo: we should re-design the mechanism of isolation instead of disabling this functionality
@@ -252,6 +498,7 @@ func (cra *cpuResourceAdvisor) updateWithIsolationGuardian(tryIsolation bool) er
 		return nil
 	}
 
+	cra.updateReservedForReclaim()
 	cra.updateNumasAvailableResource()
 	isolationExists := cra.setIsolatedContainers(tryIsolation)
 
@@ -295,8 +542,13 @@ func (cra *cpuResourceAdvisor) updateWithIsolationGuardian(tryIsolation bool) er
 	}
 	cra.updateRegionStatus()
 	cra.emitMetrics(calculationResult)
+	cra.lastCalculationResult = &calculationResult
 
-	// notify cpu server
+	return cra.notifyCPUServer(calculationResult)
+}
+
+// notifyCPUServer sends calculation result to cpu server without blocking
+func (cra *cpuResourceAdvisor) notifyCPUServer(calculationResult types.InternalCPUCalculationResult) error {
 	select {
 	case cra.sendCh <- calculationResult:
 		klog.Infof("[qosaware-cpu] notify cpu server: %+v", calculationResult)
@@ -307,11 +559,12 @@ func (cra *cpuResourceAdvisor) updateWithIsolationGuardian(tryIsolation bool) er
 	}
 }
 
-// setIsolatedContainers get isolation status from isolator and update into containers
+// setIsolatedContainers get isolation status from isolator and update into containers,
+// pods opting in isolation by annotation are always isolated along with those from isolator
 func (cra *cpuResourceAdvisor) setIsolatedContainers(enableIsolated bool) bool {
 	isolatedPods := sets.NewString()
 	if enableIsolated {
-		isolatedPods = sets.NewString(cra.isolator.GetIsolatedPods()...)
+		isolatedPods = sets.NewString(cra.isolator.GetIsolatedPods()...).Union(cra.getIsolationOptInPods())
 	}
 	if len(isolatedPods) > 0 {
 		klog.Infof("[qosaware-cpu] current isolated pod: %v", isolatedPods.List())
@@ -327,11 +580,34 @@ func (cra *cpuResourceAdvisor) setIsolatedContainers(enableIsolated bool) bool {
 	return len(isolatedPods) > 0
 }
 
+// getIsolationOptInPods returns pods carrying the isolation opt-in annotation
+func (cra *cpuResourceAdvisor) getIsolationOptInPods() sets.String {
+	checkedPods := sets.NewString()
+	optInPods := sets.NewString()
+	cra.metaCache.RangeContainer(func(podUID string, _ string, _ *types.ContainerInfo) bool {
+		if checkedPods.Has(podUID) {
+			return true
+		}
+		checkedPods.Insert(podUID)
+
+		pod, err := cra.metaServer.GetPod(context.Background(), podUID)
+		if err != nil {
+			klog.Warningf("[qosaware-cpu] get pod %v failed: %v", podUID, err)
+			return true
+		}
+		if pod.Annotations[pkgconsts.PodAnnotationCPUIsolationOptInKey] == pkgconsts.PodAnnotationCPUIsolationOptInEnabled {
+			optInPods.Insert(podUID)
+		}
+		return true
+	})
+	return optInPods
+}
+
 // checkIsolationSafety returns true iff the isolated-limit-sum and share-pool-size exceed total capacity
 // todo: this logic contains a lot of assumptions and should be refined in the future
 func (cra *cpuResourceAdvisor) checkIsolationSafety() bool {
 	shareAndIsolationPoolSize := 0
-	nonBindingNumas := cra.metaServer.CPUDetails.NUMANodes()
+	nonBindingNumas := cra.metaServer.CPUDetails.NUMANodes().Difference(machine.NewCPUSet(cra.conf.DrainedNUMAs...))
 	for _, r := range cra.regionMap {
 		if r.Type() == types.QoSRegionTypeShare {
 			controlKnob, err := r.GetProvision()
@@ -353,7 +629,11 @@ func (cra *cpuResourceAdvisor) checkIsolationSafety() bool {
 		}
 	}
 
-	nonBindingSize := cra.metaServer.CPUsPerNuma() * nonBindingNumas.Size()
+	nonBindingSize := 0
+	breakdown := cra.getCPUSetBreakdown(cra.metaServer.CPUDetails.CPUs())
+	for _, numaID := range nonBindingNumas.ToSliceInt() {
+		nonBindingSize += breakdown.numaCPUs[numaID].Size()
+	}
 	klog.Infof("[qosaware-cpu] shareAndIsolationPoolSize %v, nonBindingSize %v", shareAndIsolationPoolSize, nonBindingSize)
 	if shareAndIsolationPoolSize > nonBindingSize {
 		return false
@@ -370,6 +650,7 @@ func (cra *cpuResourceAdvisor) assignContainersToRegions() error {
 	for _, r := range cra.regionMap {
 		r.Clear()
 	}
+	cra.isolationOverflowContainers = sets.NewString()
 
 	// sync containers
 	f := func(podUID string, containerName string, ci *types.ContainerInfo) bool {
@@ -382,14 +663,21 @@ func (cra *cpuResourceAdvisor) assignContainersToRegions() error {
 		}
 
 		// update region pod set and region map
+		validRegions := make([]region.QoSRegion, 0, len(regions))
 		for _, r := range regions {
+			// keep the former region if regions of different types share the same name
+			if cra.isRegionNameConflicted(r) {
+				continue
+			}
 			if err := r.AddContainer(ci); err != nil {
 				errList = append(errList, err)
 				return true
 			}
 			// region may be set in regionMap for multiple times, and it is reentrant
 			cra.regionMap[r.Name()] = r
+			validRegions = append(validRegions, r)
 		}
+		regions = validRegions
 
 		// update container info
 		cra.setContainerRegions(ci, regions)
@@ -398,7 +686,7 @@ func (cra *cpuResourceAdvisor) assignContainersToRegions() error {
 		if ci.OwnerPoolName == state.PoolNameDedicated {
 			// dedicated pool should not exist in metaCache.poolEntries
 			return true
-		} else if ci.Isolated || cra.conf.IsolationForceEnablePools.Has(ci.OriginOwnerPoolName) {
+		} else if cra.isIsolationCandidate(ci) {
 			// isolated pool should not exist in metaCache.poolEntries
 			return true
 		} else {
@@ -424,14 +712,20 @@ func (cra *cpuResourceAdvisor) assignToRegions(ci *types.ContainerInfo) ([]regio
 		return nil, fmt.Errorf("container info is nil")
 	}
 
-	switch ci.QoSLevel {
-	case consts.PodAnnotationQoSLevelSharedCores:
-		return cra.assignShareContainerToRegions(ci)
-	case consts.PodAnnotationQoSLevelDedicatedCores:
-		return cra.assignDedicatedContainerToRegions(ci)
-	default:
+	assigner, ok := cra.regionAssigners[ci.QoSLevel]
+	if !ok {
 		return nil, nil
 	}
+	return assigner(ci)
+}
+
+// RegisterRegionAssigner registers the assigner of containers with the qos level, so that new qos levels can
+// plug in their own region construction; the assigner registered before for the qos level is replaced
+func (cra *cpuResourceAdvisor) RegisterRegionAssigner(qosLevel string, assigner RegionAssigner) {
+	cra.mutex.Lock()
+	defer cra.mutex.Unlock()
+
+	cra.regionAssigners[qosLevel] = assigner
 }
 
 func (cra *cpuResourceAdvisor) assignShareContainerToRegions(ci *types.ContainerInfo) ([]region.QoSRegion, error) {
@@ -441,7 +735,12 @@ func (cra *cpuResourceAdvisor) assignShareContainerToRegions(ci *types.Container
 			return nil, fmt.Errorf("empty owner pool name, %v/%v", ci.PodUID, ci.ContainerName)
 		}
 
-		if len(ci.TopologyAwareAssignments) != 1 {
+		// topology aware assignments may be momentarily empty right after allocation,
+		// skip the container until it's ready instead of failing the whole assignment
+		if len(ci.TopologyAwareAssignments) == 0 {
+			klog.Warningf("empty topology aware assignments of numa binding container: %s/%s, skip it", ci.PodUID, ci.ContainerName)
+			return nil, nil
+		} else if len(ci.TopologyAwareAssignments) != 1 {
 			return nil, fmt.Errorf("invalid topology aware assignments of container: %s/%s", ci.PodUID, ci.ContainerName)
 		}
 
@@ -466,7 +765,7 @@ func (cra *cpuResourceAdvisor) assignShareContainerToRegions(ci *types.Container
 	}
 
 	// assign isolated container
-	if ci.Isolated || cra.conf.IsolationForceEnablePools.Has(ci.OriginOwnerPoolName) {
+	if cra.isIsolationCandidate(ci) {
 		regionName := ""
 		if cra.conf.IsolationNonExclusivePools.Has(ci.OriginOwnerPoolName) {
 			// use origin owner pool name as region name, because all the container in this pool
@@ -494,8 +793,13 @@ func (cra *cpuResourceAdvisor) assignShareContainerToRegions(ci *types.Container
 			}
 		}
 
+		if cra.isIsolationRegionsExceeded(numaID) {
+			cra.keepIsolationCandidateInShare(ci, numaID)
+			return cra.assignShareContainerToRegions(ci)
+		}
+
 		r := region.NewQoSRegionIsolation(ci, regionName, cra.conf, cra.extraConf, numaID, cra.metaCache, cra.metaServer, cra.emitter)
-		klog.Infof("create a new isolation region (%s/%s) for container %s/%s", r.OwnerPoolName(), r.Name(), ci.PodUID, ci.ContainerName)
+		general.InfoS("create a new isolation region", regionLogKeysAndValues(r, ci)...)
 		return []region.QoSRegion{r}, nil
 	}
 
@@ -514,7 +818,7 @@ func (cra *cpuResourceAdvisor) assignShareContainerToRegions(ci *types.Container
 
 	// create one region by owner pool name
 	r := region.NewQoSRegionShare(ci, cra.conf, cra.extraConf, numaID, cra.metaCache, cra.metaServer, cra.emitter)
-	klog.Infof("create a new share region (%s/%s) for container %s/%s", r.OwnerPoolName(), r.Name(), ci.PodUID, ci.ContainerName)
+	general.InfoS("create a new share region", regionLogKeysAndValues(r, ci)...)
 	return []region.QoSRegion{r}, nil
 }
 
@@ -530,6 +834,7 @@ func (cra *cpuResourceAdvisor) assignDedicatedContainerToRegions(ci *types.Conta
 	// create regions by numa node
 	for numaID := range ci.TopologyAwareAssignments {
 		r := region.NewQoSRegionDedicatedNumaExclusive(ci, cra.conf, numaID, cra.extraConf, cra.metaCache, cra.metaServer, cra.emitter)
+		general.InfoS("create a new dedicated region", regionLogKeysAndValues(r, ci)...)
 		regions = append(regions, r)
 	}
 	return regions, nil
@@ -540,7 +845,7 @@ func (cra *cpuResourceAdvisor) gcRegionMap() {
 	for regionName, r := range cra.regionMap {
 		if r.IsEmpty() {
 			delete(cra.regionMap, regionName)
-			klog.Infof("[qosaware-cpu] delete region %v", regionName)
+			general.InfoS("delete empty region", regionLogKeysAndValues(r, nil)...)
 		}
 	}
 }
@@ -550,13 +855,18 @@ func (cra *cpuResourceAdvisor) gcRegionMap() {
 // 2. binding numas of non numa binding regions
 // 3. region quantity of each numa
 func (cra *cpuResourceAdvisor) updateAdvisorEssentials() {
-	cra.nonBindingNumas = cra.metaServer.CPUDetails.NUMANodes()
+	// drained numas are never treated as non-binding ones
+	drainedNumas := machine.NewCPUSet(cra.conf.DrainedNUMAs...)
+	cra.nonBindingNumas = cra.metaServer.CPUDetails.NUMANodes().Difference(drainedNumas)
 
 	// update non-binding numas
 	for _, r := range cra.regionMap {
 		if !r.IsNumaBinding() {
 			continue
 		}
+		if drained := r.GetBindingNumas().Intersection(drainedNumas); !drained.IsEmpty() {
+			klog.Warningf("[qosaware-cpu] region %v of type %v is left on drained numas %v", r.Name(), r.Type(), drained)
+		}
 		// ignore isolation region
 		if r.Type() == types.QoSRegionTypeDedicatedNumaExclusive || r.Type() == types.QoSRegionTypeShare {
 			cra.nonBindingNumas = cra.nonBindingNumas.Difference(r.GetBindingNumas())
@@ -594,28 +904,86 @@ func (cra *cpuResourceAdvisor) assembleProvision() (types.InternalCPUCalculation
 	return calculationResult, err
 }
 
+// emitMetrics collects metric samples and hands them over to metrics emitter goroutine without blocking;
+// if the buffer is full, the oldest pending batch will be dropped
 func (cra *cpuResourceAdvisor) emitMetrics(calculationResult types.InternalCPUCalculationResult) {
-	// emit region indicator related metrics
+	samples := cra.collectMetricSamples(calculationResult)
+
+	for {
+		select {
+		case cra.metricSamplesCh <- samples:
+			return
+		default:
+		}
+
+		select {
+		case <-cra.metricSamplesCh:
+			cra.droppedMetricsBatches.Inc()
+			klog.Warningf("[qosaware-cpu] metrics buffer is full, drop the oldest batch")
+		default:
+		}
+	}
+}
+
+// runMetricsEmitter emits buffered metric samples until context is done
+func (cra *cpuResourceAdvisor) runMetricsEmitter(ctx context.Context) {
+	for {
+		select {
+		case samples := <-cra.metricSamplesCh:
+			for _, sample := range samples {
+				if sample.isInt {
+					_ = cra.emitter.StoreInt64(sample.name, int64(sample.value), sample.metricType, sample.tags...)
+				} else {
+					_ = cra.emitter.StoreFloat64(sample.name, sample.value, sample.metricType, sample.tags...)
+				}
+			}
+			_ = cra.emitter.StoreInt64(metricCPUAdvisorMetricsDropped, cra.droppedMetricsBatches.Load(), metrics.MetricTypeNameRaw)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (cra *cpuResourceAdvisor) collectMetricSamples(calculationResult types.InternalCPUCalculationResult) []metricSample {
+	var samples []metricSample
+
+	// collect region indicator related metrics
 	for _, r := range cra.regionMap {
 		tags := region.GetRegionBasicMetricTags(r)
 
-		_ = cra.emitter.StoreInt64(metricRegionStatus, int64(cra.period.Seconds()), metrics.MetricTypeNameCount, tags...)
+		statusTags := append([]metrics.MetricTag{{Key: "throttle_reason", Val: string(r.GetThrottleReason())}}, tags...)
+		samples = append(samples, metricSample{name: metricRegionStatus, value: cra.period.Seconds(), isInt: true, metricType: metrics.MetricTypeNameCount, tags: statusTags})
 
 		indicators := r.GetControlEssentials().Indicators
 		for indicatorName, indicator := range indicators {
-			_ = cra.emitter.StoreFloat64(metricRegionIndicatorTargetPrefix+indicatorName, indicator.Target, metrics.MetricTypeNameRaw, tags...)
-			_ = cra.emitter.StoreFloat64(metricRegionIndicatorCurrentPrefix+indicatorName, indicator.Current, metrics.MetricTypeNameRaw, tags...)
-			_ = cra.emitter.StoreFloat64(metricRegionIndicatorErrorPrefix+indicatorName, indicator.Current-indicator.Target, metrics.MetricTypeNameRaw, tags...)
+			samples = append(samples,
+				metricSample{name: metricRegionIndicatorTargetPrefix + indicatorName, value: indicator.Target, metricType: metrics.MetricTypeNameRaw, tags: tags},
+				metricSample{name: metricRegionIndicatorCurrentPrefix + indicatorName, value: indicator.Current, metricType: metrics.MetricTypeNameRaw, tags: tags},
+				metricSample{name: metricRegionIndicatorErrorPrefix + indicatorName, value: indicator.Current - indicator.Target, metricType: metrics.MetricTypeNameRaw, tags: tags})
 		}
 	}
 
-	// emit calculated pool sizes
+	// collect calculated pool sizes
 	for poolName, poolEntry := range calculationResult.PoolEntries {
 		for numaID, size := range poolEntry {
-			_ = cra.emitter.StoreInt64(metricCPUAdvisorPoolSize, int64(size), metrics.MetricTypeNameRaw,
-				metrics.MetricTag{Key: "name", Val: poolName},
-				metrics.MetricTag{Key: "numa_id", Val: strconv.Itoa(numaID)},
-				metrics.MetricTag{Key: "pool_type", Val: state.GetPoolType(poolName)})
+			samples = append(samples, metricSample{name: metricCPUAdvisorPoolSize, value: float64(size), isInt: true, metricType: metrics.MetricTypeNameRaw,
+				tags: []metrics.MetricTag{
+					{Key: "name", Val: poolName},
+					{Key: "numa_id", Val: strconv.Itoa(numaID)},
+					{Key: "pool_type", Val: state.GetPoolType(poolName)},
+				}})
 		}
 	}
+
+	// collect reserved for reclaim and available resource of each numa, which bound the reclaim pool sizes
+	for numaID, reserved := range cra.reservedForReclaim {
+		samples = append(samples, metricSample{name: metricCPUAdvisorReservedForReclaim, value: float64(reserved), isInt: true, metricType: metrics.MetricTypeNameRaw,
+			tags: []metrics.MetricTag{{Key: "numa_id", Val: strconv.Itoa(numaID)}}})
+	}
+	for numaID, available := range cra.numaAvailable {
+		samples = append(samples, metricSample{name: metricCPUAdvisorNumaAvailable, value: float64(available), isInt: true, metricType: metrics.MetricTypeNameRaw,
+			tags: []metrics.MetricTag{{Key: "numa_id", Val: strconv.Itoa(numaID)}}})
+	}
+
+	return samples
 }
@@ -18,8 +18,12 @@ package cpu
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 	"k8s.io/kubelet/pkg/apis/resourceplugin/v1alpha1"
@@ -29,6 +33,9 @@ import (
 	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/plugin/qosaware/resource/cpu/assembler/provisionassembler"
 	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/plugin/qosaware/resource/cpu/region"
 	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/types"
+	pkgconsts "github.com/kubewharf/katalyst-core/pkg/consts"
+	metrictypes "github.com/kubewharf/katalyst-core/pkg/metaserver/agent/metric/types"
+	"github.com/kubewharf/katalyst-core/pkg/metrics"
 	"github.com/kubewharf/katalyst-core/pkg/util/general"
 	"github.com/kubewharf/katalyst-core/pkg/util/machine"
 )
@@ -89,6 +96,110 @@ func (cra *cpuResourceAdvisor) setContainerRegions(ci *types.ContainerInfo, regi
 	}
 }
 
+// isRegionNameConflicted returns true if the region name has been taken by another region of different type
+func (cra *cpuResourceAdvisor) isRegionNameConflicted(r region.QoSRegion) bool {
+	existing, ok := cra.regionMap[r.Name()]
+	if !ok || existing.Type() == r.Type() {
+		return false
+	}
+
+	klog.Errorf("[qosaware-cpu] region name %v of type %v conflicts with existing region of type %v, ignore it",
+		r.Name(), r.Type(), existing.Type())
+	_ = cra.emitter.StoreInt64(metricCPUAdvisorRegionNameConflict, 1, metrics.MetricTypeNameRaw,
+		metrics.MetricTag{Key: "region_name", Val: r.Name()},
+		metrics.MetricTag{Key: "region_type", Val: string(r.Type())},
+		metrics.MetricTag{Key: "existing_region_type", Val: string(existing.Type())})
+	return true
+}
+
+// isIsolationCandidate returns true if the container should be assigned to isolation region,
+// unless it's kept in share pool since isolation regions on its numa have reached the limit
+func (cra *cpuResourceAdvisor) isIsolationCandidate(ci *types.ContainerInfo) bool {
+	if !ci.Isolated && !cra.conf.IsolationForceEnablePools.Has(ci.OriginOwnerPoolName) {
+		return false
+	}
+	return !cra.isolationOverflowContainers.Has(ci.PodUID + "/" + ci.ContainerName)
+}
+
+// isIsolationRegionsExceeded returns true if no more isolation region can be created on the numa;
+// regions not gc-ed yet are counted as well, so that existing isolation regions keep their slots
+func (cra *cpuResourceAdvisor) isIsolationRegionsExceeded(numaID int) bool {
+	if cra.conf.IsolationMaxRegionsPerNuma <= 0 {
+		return false
+	}
+
+	count := 0
+	for _, r := range cra.regionMap {
+		if r.Type() != types.QoSRegionTypeIsolation {
+			continue
+		}
+		if numaID == state.FakedNUMAID {
+			if !r.IsNumaBinding() {
+				count++
+			}
+		} else if r.IsNumaBinding() && r.GetBindingNumas().Contains(numaID) {
+			count++
+		}
+	}
+	return count >= cra.conf.IsolationMaxRegionsPerNuma
+}
+
+// keepIsolationCandidateInShare marks the isolation candidate as overflowed in current cycle
+func (cra *cpuResourceAdvisor) keepIsolationCandidateInShare(ci *types.ContainerInfo, numaID int) {
+	cra.isolationOverflowContainers.Insert(ci.PodUID + "/" + ci.ContainerName)
+
+	klog.Warningf("[qosaware-cpu] isolation regions on numa %v reach the limit %v, keep container %s/%s in share pool",
+		numaID, cra.conf.IsolationMaxRegionsPerNuma, ci.PodUID, ci.ContainerName)
+	_ = cra.emitter.StoreInt64(metricCPUAdvisorIsolationOverflow, 1, metrics.MetricTypeNameCount,
+		metrics.MetricTag{Key: "numa_id", Val: strconv.Itoa(numaID)},
+		metrics.MetricTag{Key: "pool_name", Val: ci.OriginOwnerPoolName})
+}
+
+// cpuSetBreakdown is the breakdown of a cpuset by socket and numa, and sockets or
+// numas without any cpu in the cpuset are not included
+type cpuSetBreakdown struct {
+	socketCPUs map[int]machine.CPUSet
+	numaCPUs   map[int]machine.CPUSet
+}
+
+// getCPUSetBreakdown returns the breakdown of cpus by socket and numa according to
+// cpu topology, and cpus not found in the topology are ignored
+func (cra *cpuResourceAdvisor) getCPUSetBreakdown(cpus machine.CPUSet) cpuSetBreakdown {
+	breakdown := cpuSetBreakdown{
+		socketCPUs: make(map[int]machine.CPUSet),
+		numaCPUs:   make(map[int]machine.CPUSet),
+	}
+
+	details := cra.metaServer.CPUDetails.KeepOnly(cpus)
+	for _, socketID := range details.Sockets().ToSliceInt() {
+		breakdown.socketCPUs[socketID] = details.CPUsInSockets(socketID)
+	}
+	for _, numaID := range details.NUMANodes().ToSliceInt() {
+		breakdown.numaCPUs[numaID] = details.CPUsInNUMANodes(numaID)
+	}
+	return breakdown
+}
+
+// regionLogKeysAndValues returns the key/value pairs of region context for structured logging,
+// along with the container context if container info is given
+func regionLogKeysAndValues(r region.QoSRegion, ci *types.ContainerInfo) []interface{} {
+	keysAndValues := []interface{}{
+		"regionName", r.Name(),
+		"regionType", r.Type(),
+		"ownerPoolName", r.OwnerPoolName(),
+		"bindingNumas", r.GetBindingNumas().String(),
+	}
+	if ci != nil {
+		keysAndValues = append(keysAndValues,
+			"podUID", ci.PodUID,
+			"podNamespace", ci.PodNamespace,
+			"podName", ci.PodName,
+			"containerName", ci.ContainerName,
+			"qosLevel", ci.QoSLevel)
+	}
+	return keysAndValues
+}
+
 func (cra *cpuResourceAdvisor) getPoolRegions(poolName string) []region.QoSRegion {
 	pool, ok := cra.metaCache.GetPoolInfo(poolName)
 	if !ok || pool == nil {
@@ -146,6 +257,43 @@ func (cra *cpuResourceAdvisor) initializeHeadroomAssembler() error {
 	return nil
 }
 
+// updateReservedForReclaim updates reserved for reclaim of each numa; in adaptive mode, it scales
+// with idle cpus of the numa, and falls back to the static value if cpu metrics are missing
+func (cra *cpuResourceAdvisor) updateReservedForReclaim() {
+	if !cra.conf.EnableAdaptiveReservedForReclaim {
+		cra.reservedForReclaim = cra.staticReservedForReclaim
+		return
+	}
+
+	reservedForReclaim := make(map[int]int)
+	breakdown := cra.getCPUSetBreakdown(cra.metaServer.CPUDetails.CPUs())
+	for id := 0; id < cra.metaServer.NumNUMANodes; id++ {
+		cpus := breakdown.numaCPUs[id].ToSliceInt()
+
+		usageRatioSum, validCPUs := 0., 0
+		for _, cpu := range cpus {
+			data, err := cra.metaServer.GetCPUMetric(cpu, pkgconsts.MetricCPUUsageRatio)
+			if err != nil {
+				continue
+			}
+			usageRatioSum += data.Value
+			validCPUs++
+		}
+		if validCPUs == 0 {
+			klog.Warningf("[qosaware-cpu] no cpu usage of numa %v, use static reserved for reclaim", id)
+			reservedForReclaim[id] = cra.staticReservedForReclaim[id]
+			continue
+		}
+
+		idleCPUs := int((1 - usageRatioSum/float64(validCPUs)) * float64(len(cpus)))
+		reservedForReclaim[id] = general.Max(cra.conf.AdaptiveReservedForReclaimMin,
+			general.Min(idleCPUs, cra.conf.AdaptiveReservedForReclaimMax))
+	}
+	klog.Infof("[qosaware-cpu] adaptive reserved for reclaim: %v", reservedForReclaim)
+
+	cra.reservedForReclaim = reservedForReclaim
+}
+
 // updateNumasAvailableResource updates available resource of all numa nodes.
 // available = total - reserved pool - reserved for reclaim
 func (cra *cpuResourceAdvisor) updateNumasAvailableResource() {
@@ -166,9 +314,21 @@ func (cra *cpuResourceAdvisor) updateNumasAvailableResource() {
 	}
 }
 
+// getNumasReservedForAllocate sums up reserved for allocate of the given numas, numas without
+// overrides share the node-wide value evenly
 func (cra *cpuResourceAdvisor) getNumasReservedForAllocate(numas machine.CPUSet) float64 {
 	reserved := cra.conf.GetDynamicConfiguration().ReservedResourceForAllocate[v1.ResourceCPU]
-	return float64(reserved.Value()*int64(numas.Size())) / float64(cra.metaServer.NumNUMANodes)
+	reservedPerNuma := float64(reserved.Value()) / float64(cra.metaServer.NumNUMANodes)
+
+	res := 0.0
+	for _, numaID := range numas.ToSliceInt() {
+		if v, ok := cra.conf.NUMAReservedForAllocate[numaID]; ok {
+			res += v
+			continue
+		}
+		res += reservedPerNuma
+	}
+	return res
 }
 
 func (cra *cpuResourceAdvisor) getRegionMaxRequirement(r region.QoSRegion) float64 {
@@ -287,3 +447,64 @@ func (cra *cpuResourceAdvisor) updateRegionStatus() {
 		_ = cra.metaCache.SetRegionInfo(regionName, regionInfo)
 	}
 }
+
+// capHeadroom limits headroom of the given cpus by MaxHeadroomCores and MaxHeadroomRatio,
+// MaxHeadroomCores is scaled by the proportion of the given cpus to node cpus
+func (cra *cpuResourceAdvisor) capHeadroom(headroom resource.Quantity, cpus int, scope string) resource.Quantity {
+	numCPUs := cra.metaServer.CPUTopology.NumCPUs
+	if numCPUs <= 0 {
+		return headroom
+	}
+
+	limit := math.MaxFloat64
+	if cra.conf.MaxHeadroomCores > 0 {
+		limit = math.Min(limit, cra.conf.MaxHeadroomCores*float64(cpus)/float64(numCPUs))
+	}
+	if cra.conf.MaxHeadroomRatio > 0 {
+		limit = math.Min(limit, cra.conf.MaxHeadroomRatio*float64(cpus))
+	}
+
+	if limit == math.MaxFloat64 {
+		return headroom
+	}
+
+	limitMilliValue := int64(limit * 1000)
+	if headroom.MilliValue() <= limitMilliValue {
+		return headroom
+	}
+
+	klog.Infof("[qosaware-cpu] cap %v headroom from %v to %vm", scope, headroom.String(), limitMilliValue)
+	return *resource.NewMilliQuantity(limitMilliValue, resource.DecimalSI)
+}
+
+// checkMetricsStale returns true if numa or container metrics are stale beyond FreezeOnStaleMetricsThreshold,
+// and keeps healthz degraded until they are fresh again
+func (cra *cpuResourceAdvisor) checkMetricsStale() bool {
+	threshold := cra.conf.FreezeOnStaleMetricsThreshold
+	if threshold <= 0 {
+		return false
+	}
+
+	for _, scope := range []metrictypes.MetricsScope{metrictypes.MetricsScopeNuma, metrictypes.MetricsScopeContainer} {
+		age := cra.metaServer.NewestSampleAge(scope)
+		// a scope without any sample can't be stale, e.g. container metrics on a node without pods
+		if age <= threshold || age == time.Duration(math.MaxInt64) {
+			continue
+		}
+
+		klog.Warningf("[qosaware-cpu] freeze pool sizes: %v metrics are stale for %v", scope, age)
+		cra.metricsStale = true
+		_ = cra.emitter.StoreInt64(metricCPUAdvisorMetricsStale, 1, metrics.MetricTypeNameCount,
+			metrics.MetricTag{Key: "scope", Val: string(scope)})
+		_ = general.UpdateHealthzState(cpuAdvisorMetricsHealthCheckName, general.HealthzCheckStateNotReady,
+			fmt.Sprintf("%v metrics are stale for more than %v", scope, threshold))
+		return true
+	}
+
+	if cra.metricsStale {
+		klog.Infof("[qosaware-cpu] metrics are fresh again, resume updating pool sizes")
+		cra.metricsStale = false
+		_ = general.UpdateHealthzState(cpuAdvisorMetricsHealthCheckName, general.HealthzCheckStateReady, "")
+	}
+	return false
+}
@@ -18,6 +18,7 @@ package cpu
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"reflect"
@@ -28,6 +29,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -42,6 +44,7 @@ import (
 	"github.com/kubewharf/katalyst-core/pkg/agent/qrm-plugins/cpu/dynamicpolicy/state"
 	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/metacache"
 	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/plugin/qosaware/resource/cpu/region"
+	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/plugin/qosaware/resource/helper"
 	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/types"
 	"github.com/kubewharf/katalyst-core/pkg/config"
 	metric_consts "github.com/kubewharf/katalyst-core/pkg/consts"
@@ -53,6 +56,8 @@ import (
 	"github.com/kubewharf/katalyst-core/pkg/metaserver/spd"
 	"github.com/kubewharf/katalyst-core/pkg/metrics"
 	metricspool "github.com/kubewharf/katalyst-core/pkg/metrics/metrics-pool"
+	"github.com/kubewharf/katalyst-core/pkg/util/cgroup/common"
+	"github.com/kubewharf/katalyst-core/pkg/util/general"
 	"github.com/kubewharf/katalyst-core/pkg/util/machine"
 	utilmetric "github.com/kubewharf/katalyst-core/pkg/util/metric"
 )
@@ -1235,6 +1240,16 @@ func TestAdvisorUpdate(t *testing.T) {
 					if !reflect.DeepEqual(tt.wantInternalCalculationResult.PoolEntries, resp) {
 						t.Errorf("cpu provision\nexpected: %+v,\nactual: %+v", tt.wantInternalCalculationResult, advisorResp)
 					}
+
+					// the last result is cached, and modifying the returned copy doesn't affect the cache
+					lastResult, ok := advisor.GetLastCalculationResult()
+					assert.True(t, ok)
+					assert.Equal(t, advisorResp, lastResult)
+					for pool := range lastResult.PoolEntries {
+						delete(lastResult.PoolEntries, pool)
+					}
+					lastResult, _ = advisor.GetLastCalculationResult()
+					assert.Equal(t, advisorResp, lastResult)
 				}
 			}
 
@@ -1311,3 +1326,1021 @@ func TestGetIsolatedContainerRegions(t *testing.T) {
 	assert.ElementsMatch(t, []string{}, f(c3_1))
 	assert.ElementsMatch(t, []string{}, f(c3_2))
 }
+
+type slowMetricEmitter struct {
+	metrics.DummyMetrics
+	delay   time.Duration
+	dropped *atomic.Int64
+}
+
+func (s *slowMetricEmitter) StoreInt64(key string, val int64, _ metrics.MetricTypeName, _ ...metrics.MetricTag) error {
+	time.Sleep(s.delay)
+	if key == metricCPUAdvisorMetricsDropped {
+		s.dropped.Store(val)
+	}
+	return nil
+}
+
+func (s *slowMetricEmitter) StoreFloat64(_ string, _ float64, _ metrics.MetricTypeName, _ ...metrics.MetricTag) error {
+	time.Sleep(s.delay)
+	return nil
+}
+
+type fakeIsolator struct {
+	isolatedPods []string
+	calls        int
+}
+
+func (f *fakeIsolator) GetIsolatedPods() []string {
+	f.calls++
+	return f.isolatedPods
+}
+
+type isolationDisabledMetricEmitter struct {
+	metrics.DummyMetrics
+	count *atomic.Int64
+}
+
+func (e *isolationDisabledMetricEmitter) StoreInt64(key string, val int64, _ metrics.MetricTypeName, _ ...metrics.MetricTag) error {
+	if key == metricCPUAdvisorIsolationDisabled {
+		e.count.Add(val)
+	}
+	return nil
+}
+
+func drainCalculationResult(advisor *cpuResourceAdvisor) {
+	select {
+	case <-advisor.sendCh:
+	default:
+	}
+}
+
+// setIsolationTestPoolsAndContainer sets reserve and share pools, and a shared_cores container
+// whose limit exceeds node capacity, so isolation safety check always fails for it
+func setIsolationTestPoolsAndContainer(t *testing.T, metaCache metacache.MetaCache) {
+	require.NoError(t, metaCache.SetPoolInfo(state.PoolNameReserve, &types.PoolInfo{
+		PoolName: state.PoolNameReserve,
+		TopologyAwareAssignments: map[int]machine.CPUSet{
+			0: machine.MustParse("0"),
+			1: machine.MustParse("24"),
+		},
+	}))
+	require.NoError(t, metaCache.SetPoolInfo(state.PoolNameShare, &types.PoolInfo{
+		PoolName: state.PoolNameShare,
+		TopologyAwareAssignments: map[int]machine.CPUSet{
+			0: machine.MustParse("1-23,48-71"),
+			1: machine.MustParse("25-47,72-95"),
+		},
+	}))
+	ci := makeContainerInfo("uid1", "default", "pod1", "c1", consts.PodAnnotationQoSLevelSharedCores, state.PoolNameShare, nil,
+		map[int]machine.CPUSet{
+			0: machine.MustParse("1-23,48-71"),
+			1: machine.MustParse("25-47,72-95"),
+		}, 4, 200)
+	require.NoError(t, metaCache.SetContainerInfo(ci.PodUID, ci.ContainerName, ci))
+}
+
+// TestUpdateWithIsolationDisabled doesn't run in parallel since it relies on the global healthz registry
+func TestUpdateWithIsolationDisabled(t *testing.T) {
+	general.ResetHealthzForTest()
+	defer general.ResetHealthzForTest()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestUpdateWithIsolationDisabled")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, metaCache := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+	advisor.startTime = time.Now().Add(-types.StartUpPeriod)
+	advisor.registerHealthzChecks()
+
+	emitter := &isolationDisabledMetricEmitter{count: atomic.NewInt64(0)}
+	advisor.emitter = emitter
+	isolator := &fakeIsolator{isolatedPods: []string{"uid1"}}
+	advisor.isolator = isolator
+
+	setIsolationTestPoolsAndContainer(t, metaCache)
+
+	for i := 1; i <= 2; i++ {
+		_ = advisor.update()
+		drainCalculationResult(advisor)
+		require.Equal(t, i, advisor.isolationDisabledCycles)
+		require.Equal(t, int64(i), emitter.count.Load())
+
+		result, ok := general.GetReadinessCheckResult(cpuAdvisorIsolationHealthCheckName)
+		require.True(t, ok)
+		require.False(t, result.Ready)
+	}
+
+	// isolation works again
+	isolator.isolatedPods = nil
+	_ = advisor.update()
+	drainCalculationResult(advisor)
+	require.Equal(t, 0, advisor.isolationDisabledCycles)
+	require.Equal(t, int64(2), emitter.count.Load())
+}
+
+// TestUpdateSkipIsolationDuringCooldown doesn't run in parallel since it relies on the global healthz registry
+func TestUpdateSkipIsolationDuringCooldown(t *testing.T) {
+	general.ResetHealthzForTest()
+	defer general.ResetHealthzForTest()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestUpdateSkipIsolationDuringCooldown")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	conf.CPUIsolationConfiguration.IsolationSafetyFailuresToSkip = 2
+	conf.CPUIsolationConfiguration.IsolationSafetySkipCooldown = time.Hour
+
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, metaCache := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+	advisor.startTime = time.Now().Add(-types.StartUpPeriod)
+	advisor.registerHealthzChecks()
+
+	isolator := &fakeIsolator{isolatedPods: []string{"uid1"}}
+	advisor.isolator = isolator
+
+	setIsolationTestPoolsAndContainer(t, metaCache)
+
+	for i := 1; i <= 2; i++ {
+		_ = advisor.update()
+		drainCalculationResult(advisor)
+		require.Equal(t, i, isolator.calls)
+	}
+	require.True(t, advisor.isolationSkipUntil.After(time.Now()))
+
+	// updating with isolation is skipped during cooldown
+	for i := 0; i < 3; i++ {
+		_ = advisor.update()
+		drainCalculationResult(advisor)
+		require.Equal(t, 2, isolator.calls)
+		require.Equal(t, 3+i, advisor.isolationDisabledCycles)
+	}
+
+	// isolation is probed again after cooldown, and works this time
+	advisor.isolationSkipUntil = time.Now().Add(-time.Second)
+	isolator.isolatedPods = nil
+	_ = advisor.update()
+	drainCalculationResult(advisor)
+	require.Equal(t, 3, isolator.calls)
+	require.Equal(t, 0, advisor.isolationDisabledCycles)
+}
+
+func TestSetIsolatedContainersWithOptInAnnotation(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestSetIsolatedContainersWithOptInAnnotation")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	pods := []*v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "pod1",
+				UID:  "uid1",
+				Annotations: map[string]string{
+					pkgconsts.PodAnnotationCPUIsolationOptInKey: pkgconsts.PodAnnotationCPUIsolationOptInEnabled,
+				},
+			},
+		},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod2", UID: "uid2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod3", UID: "uid3"}},
+	}
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, metaCache := newTestCPUResourceAdvisor(t, pods, conf, mf, nil)
+	advisor.startTime = time.Now().Add(-types.StartUpPeriod)
+	advisor.registerHealthzChecks()
+
+	// isolator only selects pod2, while pod1 opts in isolation by annotation
+	advisor.isolator = &fakeIsolator{isolatedPods: []string{"uid2"}}
+
+	require.NoError(t, metaCache.SetPoolInfo(state.PoolNameReserve, &types.PoolInfo{
+		PoolName: state.PoolNameReserve,
+		TopologyAwareAssignments: map[int]machine.CPUSet{
+			0: machine.MustParse("0"),
+			1: machine.MustParse("24"),
+		},
+	}))
+	require.NoError(t, metaCache.SetPoolInfo(state.PoolNameShare, &types.PoolInfo{
+		PoolName: state.PoolNameShare,
+		TopologyAwareAssignments: map[int]machine.CPUSet{
+			0: machine.MustParse("1-23,48-71"),
+			1: machine.MustParse("25-47,72-95"),
+		},
+	}))
+	for _, pod := range pods {
+		ci := makeContainerInfo(string(pod.UID), "default", pod.Name, "c1", consts.PodAnnotationQoSLevelSharedCores, state.PoolNameShare, nil,
+			map[int]machine.CPUSet{
+				0: machine.MustParse("1-23,48-71"),
+				1: machine.MustParse("25-47,72-95"),
+			}, 4, 4)
+		require.NoError(t, metaCache.SetContainerInfo(ci.PodUID, ci.ContainerName, ci))
+	}
+
+	isolated := func() []string {
+		var podUIDs []string
+		metaCache.RangeContainer(func(podUID string, _ string, ci *types.ContainerInfo) bool {
+			if ci.Isolated {
+				podUIDs = append(podUIDs, podUID)
+			}
+			return true
+		})
+		return podUIDs
+	}
+
+	require.True(t, advisor.setIsolatedContainers(true))
+	require.ElementsMatch(t, []string{"uid1", "uid2"}, isolated())
+
+	// opt-in pods are not isolated either when isolation is disabled
+	require.False(t, advisor.setIsolatedContainers(false))
+	require.Empty(t, isolated())
+
+	// isolation safety accounting takes opt-in pods into account: limit of pod1 exceeds node capacity
+	advisor.isolator = &fakeIsolator{}
+	ci, ok := metaCache.GetContainerInfo("uid1", "c1")
+	require.True(t, ok)
+	ci.CPULimit = 200
+	require.NoError(t, metaCache.SetContainerInfo(ci.PodUID, ci.ContainerName, ci))
+
+	_ = advisor.update()
+	drainCalculationResult(advisor)
+	require.Equal(t, 1, advisor.isolationDisabledCycles)
+}
+
+func TestEmitMetricsAsync(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestEmitMetricsAsync")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	conf.CPUAdvisorConfiguration.MetricsBufferSize = 2
+
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, _ := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+
+	emitter := &slowMetricEmitter{delay: 100 * time.Millisecond, dropped: atomic.NewInt64(0)}
+	advisor.emitter = emitter
+
+	calculationResult := types.InternalCPUCalculationResult{
+		PoolEntries: map[string]map[int]int{
+			state.PoolNameShare:   {-1: 20},
+			state.PoolNameReclaim: {0: 10, 1: 10},
+			state.PoolNameReserve: {-1: 2},
+		},
+	}
+
+	// emitting metrics should not be blocked by slow emitter
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		advisor.emitMetrics(calculationResult)
+	}
+	require.Less(t, time.Since(start), 100*time.Millisecond)
+
+	// only the latest two batches are kept in buffer
+	require.Equal(t, int64(3), advisor.droppedMetricsBatches.Load())
+	require.Equal(t, 2, len(advisor.metricSamplesCh))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go advisor.runMetricsEmitter(ctx)
+
+	require.Eventually(t, func() bool {
+		return emitter.dropped.Load() == 3
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+type poolSizeMetricEmitter struct {
+	metrics.DummyMetrics
+	mutex sync.Mutex
+	tags  [][]metrics.MetricTag
+}
+
+func (e *poolSizeMetricEmitter) StoreInt64(key string, _ int64, _ metrics.MetricTypeName, tags ...metrics.MetricTag) error {
+	if key == metricCPUAdvisorPoolSize {
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		e.tags = append(e.tags, tags)
+	}
+	return nil
+}
+
+func (e *poolSizeMetricEmitter) getTags() [][]metrics.MetricTag {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.tags
+}
+
+func TestEmitMetricsWithCgroupVersionTag(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestEmitMetricsWithCgroupVersionTag")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	testAdvisor, metaCache := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+
+	emitter := &poolSizeMetricEmitter{}
+	advisor := NewCPUResourceAdvisor(conf, struct{}{}, metaCache, testAdvisor.metaServer, emitter)
+
+	advisor.emitMetrics(types.InternalCPUCalculationResult{
+		PoolEntries: map[string]map[int]int{
+			state.PoolNameShare:   {-1: 20},
+			state.PoolNameReclaim: {0: 10, 1: 10},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go advisor.runMetricsEmitter(ctx)
+
+	require.Eventually(t, func() bool {
+		return len(emitter.getTags()) == 3
+	}, 5*time.Second, 50*time.Millisecond)
+
+	cgroupVersion := "v1"
+	if common.CheckCgroup2UnifiedMode() {
+		cgroupVersion = "v2"
+	}
+	for _, tags := range emitter.getTags() {
+		require.Contains(t, tags, metrics.MetricTag{Key: helper.MetricTagKeyCgroupVersion, Val: cgroupVersion})
+	}
+}
+
+func TestUpdateAdvisorEssentialsWithDrainedNUMAs(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestUpdateAdvisorEssentialsWithDrainedNUMAs")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	conf.DrainedNUMAs = []int{1}
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, _ := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+
+	share := region.NewQoSRegionBase("share", state.PoolNameShare, types.QoSRegionTypeShare,
+		conf, struct{}{}, false, nil, nil, metrics.DummyMetrics{})
+	advisor.regionMap[share.Name()] = share
+
+	// share and reclaim pools of non binding numas are only sized on numa 0
+	advisor.updateAdvisorEssentials()
+	require.Equal(t, machine.NewCPUSet(0), advisor.nonBindingNumas)
+	require.Equal(t, machine.NewCPUSet(0), share.GetBindingNumas())
+	require.Equal(t, 1, advisor.numRegionsPerNuma[0])
+	require.Equal(t, 0, advisor.numRegionsPerNuma[1])
+}
+
+func TestUpdateReservedForReclaim(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestUpdateReservedForReclaim")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, _ := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+
+	// static mode by default
+	staticReservedForReclaim := advisor.reservedForReclaim
+	advisor.updateReservedForReclaim()
+	require.Equal(t, staticReservedForReclaim, advisor.reservedForReclaim)
+
+	conf.EnableAdaptiveReservedForReclaim = true
+	conf.AdaptiveReservedForReclaimMin = 4
+	conf.AdaptiveReservedForReclaimMax = 16
+
+	// numa0 is mostly idle and numa1 is busy
+	now := time.Now()
+	for _, cpu := range advisor.metaServer.CPUDetails.CPUsInNUMANodes(0).ToSliceInt() {
+		mf.SetCPUMetric(cpu, pkgconsts.MetricCPUUsageRatio, utilmetric.MetricData{Value: 0.8, Time: &now})
+	}
+	for _, cpu := range advisor.metaServer.CPUDetails.CPUsInNUMANodes(1).ToSliceInt() {
+		mf.SetCPUMetric(cpu, pkgconsts.MetricCPUUsageRatio, utilmetric.MetricData{Value: 0.95, Time: &now})
+	}
+	advisor.updateReservedForReclaim()
+	require.Equal(t, map[int]int{0: 9, 1: 4}, advisor.reservedForReclaim)
+
+	for _, cpu := range advisor.metaServer.CPUDetails.CPUsInNUMANodes(1).ToSliceInt() {
+		mf.SetCPUMetric(cpu, pkgconsts.MetricCPUUsageRatio, utilmetric.MetricData{Value: 0.1, Time: &now})
+	}
+	advisor.updateReservedForReclaim()
+	require.Equal(t, map[int]int{0: 9, 1: 16}, advisor.reservedForReclaim)
+}
+
+func TestPauseAndResume(t *testing.T) {
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestPauseAndResume")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, _ := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+
+	// nothing is sent while paused before any result is calculated, which fails the update
+	_, ok := advisor.GetLastCalculationResult()
+	require.False(t, ok)
+	require.True(t, general.GetRegisterReadinessCheckResult()[cpuAdvisorPausedHealthCheckName].Ready)
+	advisor.Pause()
+	require.False(t, general.GetRegisterReadinessCheckResult()[cpuAdvisorPausedHealthCheckName].Ready)
+	require.Error(t, advisor.update())
+	require.Equal(t, 0, len(advisor.sendCh))
+
+	// the last calculation result is re-sent while paused
+	lastResult := types.InternalCPUCalculationResult{
+		PoolEntries: map[string]map[int]int{
+			state.PoolNameShare:   {-1: 20},
+			state.PoolNameReclaim: {-1: 10},
+		},
+	}
+	advisor.lastCalculationResult = &lastResult
+	require.NoError(t, advisor.update())
+	require.Equal(t, lastResult, <-advisor.sendCh)
+	require.False(t, advisor.advisorUpdated)
+	require.False(t, general.GetRegisterReadinessCheckResult()[cpuAdvisorPausedHealthCheckName].Ready)
+
+	// advisor goes through the normal update after resumed, which is skipped during startup
+	advisor.Resume()
+	require.True(t, general.GetRegisterReadinessCheckResult()[cpuAdvisorPausedHealthCheckName].Ready)
+	require.NoError(t, advisor.update())
+	require.Equal(t, 0, len(advisor.sendCh))
+}
+
+func TestUpdateWithStaleMetrics(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestUpdateWithStaleMetrics")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	conf.FreezeOnStaleMetricsThreshold = time.Minute
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, _ := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+
+	lastResult := types.InternalCPUCalculationResult{
+		PoolEntries: map[string]map[int]int{
+			state.PoolNameShare:   {-1: 20},
+			state.PoolNameReclaim: {-1: 10},
+		},
+	}
+	advisor.lastCalculationResult = &lastResult
+
+	// numa metrics are fresh but container metrics are stale
+	now := time.Now()
+	staleTime := now.Add(-time.Hour)
+	mf.SetNumaMetric(0, pkgconsts.MetricMemUsedNuma, utilmetric.MetricData{Value: 10, Time: &now})
+	mf.SetContainerMetric("uid1", "c1", pkgconsts.MetricCPUUsageContainer, utilmetric.MetricData{Value: 2, Time: &staleTime})
+
+	require.NoError(t, advisor.update())
+	require.Equal(t, lastResult, <-advisor.sendCh)
+	require.True(t, advisor.metricsStale)
+
+	// advisor goes through the normal update after metrics are fresh, which is skipped during startup
+	mf.SetContainerMetric("uid1", "c1", pkgconsts.MetricCPUUsageContainer, utilmetric.MetricData{Value: 2, Time: &now})
+	require.NoError(t, advisor.update())
+	require.False(t, advisor.metricsStale)
+	require.Equal(t, 0, len(advisor.sendCh))
+}
+
+func TestUpdateWithoutContainerMetrics(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestUpdateWithoutContainerMetrics")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	conf.FreezeOnStaleMetricsThreshold = time.Minute
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, _ := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+
+	advisor.lastCalculationResult = &types.InternalCPUCalculationResult{
+		PoolEntries: map[string]map[int]int{
+			state.PoolNameShare:   {-1: 20},
+			state.PoolNameReclaim: {-1: 10},
+		},
+	}
+
+	// node without pods has no container metrics at all, which shouldn't freeze pool sizes
+	now := time.Now()
+	mf.SetNumaMetric(0, pkgconsts.MetricMemUsedNuma, utilmetric.MetricData{Value: 10, Time: &now})
+
+	require.NoError(t, advisor.update())
+	require.False(t, advisor.metricsStale)
+	require.Equal(t, 0, len(advisor.sendCh))
+}
+
+func TestRegisterRegionAssigner(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestRegisterRegionAssigner")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, _ := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+
+	ci := &types.ContainerInfo{PodUID: "uid1", ContainerName: "c1", QoSLevel: "custom_cores"}
+
+	// containers of unknown qos levels don't belong to any region
+	regions, err := advisor.assignToRegions(ci)
+	require.NoError(t, err)
+	require.Nil(t, regions)
+
+	custom := region.NewQoSRegionBase("custom", "custom", types.QoSRegionTypeShare,
+		conf, struct{}{}, false, nil, nil, metrics.DummyMetrics{})
+	var assigned *types.ContainerInfo
+	advisor.RegisterRegionAssigner("custom_cores", func(ci *types.ContainerInfo) ([]region.QoSRegion, error) {
+		assigned = ci
+		return []region.QoSRegion{custom}, nil
+	})
+
+	regions, err = advisor.assignToRegions(ci)
+	require.NoError(t, err)
+	require.Equal(t, []region.QoSRegion{custom}, regions)
+	require.Equal(t, ci, assigned)
+}
+
+func TestRegionLogKeysAndValues(t *testing.T) {
+	t.Parallel()
+
+	conf, _ := options.NewOptions().Config()
+	r := region.NewQoSRegionBase("share-1", state.PoolNameShare, types.QoSRegionTypeShare,
+		conf, struct{}{}, false, nil, nil, metrics.DummyMetrics{})
+	ci := makeContainerInfo("uid1", "default", "pod1", "c1", consts.PodAnnotationQoSLevelSharedCores,
+		state.PoolNameShare, nil, nil, 4)
+
+	toMap := func(keysAndValues []interface{}) map[string]interface{} {
+		require.Equal(t, 0, len(keysAndValues)%2)
+		res := make(map[string]interface{})
+		for i := 0; i < len(keysAndValues); i += 2 {
+			key, ok := keysAndValues[i].(string)
+			require.True(t, ok)
+			res[key] = keysAndValues[i+1]
+		}
+		return res
+	}
+
+	regionOnly := toMap(regionLogKeysAndValues(r, nil))
+	require.Equal(t, map[string]interface{}{
+		"regionName":    "share-1",
+		"regionType":    types.QoSRegionTypeShare,
+		"ownerPoolName": state.PoolNameShare,
+		"bindingNumas":  "",
+	}, regionOnly)
+
+	withContainer := toMap(regionLogKeysAndValues(r, ci))
+	require.Equal(t, "uid1", withContainer["podUID"])
+	require.Equal(t, "default", withContainer["podNamespace"])
+	require.Equal(t, "pod1", withContainer["podName"])
+	require.Equal(t, "c1", withContainer["containerName"])
+	require.Equal(t, consts.PodAnnotationQoSLevelSharedCores, withContainer["qosLevel"])
+	require.Equal(t, "share-1", withContainer["regionName"])
+}
+
+func TestGetRegionReservedForAllocateWithNUMAOverrides(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestGetRegionReservedForAllocateWithNUMAOverrides")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	conf.NUMAReservedForAllocate = map[int]float64{1: 8}
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, _ := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+	advisor.numRegionsPerNuma = map[int]int{0: 1, 1: 1}
+
+	reserved := conf.GetDynamicConfiguration().ReservedResourceForAllocate[v1.ResourceCPU]
+	defaultReservedPerNuma := float64(reserved.Value()) / float64(advisor.metaServer.NumNUMANodes)
+
+	dedicated0 := region.NewQoSRegionBase("dedicated-0", "", types.QoSRegionTypeDedicatedNumaExclusive,
+		conf, struct{}{}, true, nil, nil, metrics.DummyMetrics{})
+	dedicated0.SetBindingNumas(machine.NewCPUSet(0))
+	dedicated1 := region.NewQoSRegionBase("dedicated-1", "", types.QoSRegionTypeDedicatedNumaExclusive,
+		conf, struct{}{}, true, nil, nil, metrics.DummyMetrics{})
+	dedicated1.SetBindingNumas(machine.NewCPUSet(1))
+
+	// region on the override numa gets the overridden value, others keep the default share
+	require.Equal(t, defaultReservedPerNuma, advisor.getRegionReservedForAllocate(dedicated0))
+	require.Equal(t, 8.0, advisor.getRegionReservedForAllocate(dedicated1))
+	require.Equal(t, defaultReservedPerNuma+8, advisor.getNumasReservedForAllocate(machine.NewCPUSet(0, 1)))
+}
+
+func TestGetCPUSetBreakdown(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestGetCPUSetBreakdown")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, _ := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+
+	// cpus spanning socket0/numa0 and socket1/numa1, cpu 200 doesn't exist
+	breakdown := advisor.getCPUSetBreakdown(machine.MustParse("20-27,70,200"))
+	require.Equal(t, 2, len(breakdown.socketCPUs))
+	require.Equal(t, 5, breakdown.socketCPUs[0].Size())
+	require.Equal(t, 4, breakdown.socketCPUs[1].Size())
+	require.Equal(t, 2, len(breakdown.numaCPUs))
+	require.True(t, breakdown.numaCPUs[0].Equals(machine.MustParse("20-23,70")))
+	require.True(t, breakdown.numaCPUs[1].Equals(machine.MustParse("24-27")))
+
+	breakdown = advisor.getCPUSetBreakdown(machine.NewCPUSet())
+	require.Equal(t, 0, len(breakdown.socketCPUs))
+	require.Equal(t, 0, len(breakdown.numaCPUs))
+}
+
+func TestIsRegionNameConflicted(t *testing.T) {
+	t.Parallel()
+
+	conf, _ := options.NewOptions().Config()
+
+	share := region.NewQoSRegionBase("batch", "batch", types.QoSRegionTypeShare,
+		conf, struct{}{}, false, nil, nil, metrics.DummyMetrics{})
+	advisor := &cpuResourceAdvisor{
+		regionMap: map[string]region.QoSRegion{share.Name(): share},
+		emitter:   metrics.DummyMetrics{},
+	}
+
+	isolation := region.NewQoSRegionBase("batch", "batch", types.QoSRegionTypeIsolation,
+		conf, struct{}{}, false, nil, nil, metrics.DummyMetrics{})
+	require.True(t, advisor.isRegionNameConflicted(isolation))
+	require.Equal(t, types.QoSRegionTypeShare, advisor.regionMap["batch"].Type())
+
+	require.False(t, advisor.isRegionNameConflicted(share))
+
+	other := region.NewQoSRegionBase("flink", "flink", types.QoSRegionTypeIsolation,
+		conf, struct{}{}, false, nil, nil, metrics.DummyMetrics{})
+	require.False(t, advisor.isRegionNameConflicted(other))
+}
+
+func TestGetRegionsByNUMA(t *testing.T) {
+	t.Parallel()
+
+	conf, _ := options.NewOptions().Config()
+
+	share := region.NewQoSRegionBase("share", "share", types.QoSRegionTypeShare,
+		conf, struct{}{}, false, nil, nil, metrics.DummyMetrics{})
+	share.SetBindingNumas(machine.NewCPUSet(0, 1))
+	share.SetThrottled(true)
+	isolation := region.NewQoSRegionBase("isolation-pod1", "share", types.QoSRegionTypeIsolation,
+		conf, struct{}{}, false, nil, nil, metrics.DummyMetrics{})
+	isolation.SetBindingNumas(machine.NewCPUSet(0, 1))
+	dedicated := region.NewQoSRegionBase("dedicated-numa2", "dedicated", types.QoSRegionTypeDedicatedNumaExclusive,
+		conf, struct{}{}, true, nil, nil, metrics.DummyMetrics{})
+	dedicated.SetBindingNumas(machine.NewCPUSet(2))
+
+	advisor := &cpuResourceAdvisor{
+		regionMap: map[string]region.QoSRegion{
+			share.Name():     share,
+			isolation.Name(): isolation,
+			dedicated.Name(): dedicated,
+		},
+	}
+
+	shared := []types.RegionDescriptor{
+		{Name: "isolation-pod1", Type: types.QoSRegionTypeIsolation, OwnerPoolName: "share"},
+		{Name: "share", Type: types.QoSRegionTypeShare, OwnerPoolName: "share", Throttled: true},
+	}
+	require.Equal(t, shared, advisor.GetRegionsByNUMA(0))
+	require.Equal(t, shared, advisor.GetRegionsByNUMA(1))
+	require.Equal(t, []types.RegionDescriptor{
+		{Name: "dedicated-numa2", Type: types.QoSRegionTypeDedicatedNumaExclusive, OwnerPoolName: "dedicated"},
+	}, advisor.GetRegionsByNUMA(2))
+	require.Equal(t, []types.RegionDescriptor{}, advisor.GetRegionsByNUMA(3))
+}
+
+func TestCollectNumaMetricSamples(t *testing.T) {
+	t.Parallel()
+
+	advisor := &cpuResourceAdvisor{
+		reservedForReclaim: map[int]int{0: 2, 1: 4},
+		numaAvailable:      map[int]int{0: 22, 1: 20},
+	}
+
+	reserved := make(map[string]float64)
+	available := make(map[string]float64)
+	for _, sample := range advisor.collectMetricSamples(types.InternalCPUCalculationResult{}) {
+		require.Equal(t, 1, len(sample.tags))
+		require.Equal(t, "numa_id", sample.tags[0].Key)
+		switch sample.name {
+		case metricCPUAdvisorReservedForReclaim:
+			reserved[sample.tags[0].Val] = sample.value
+		case metricCPUAdvisorNumaAvailable:
+			available[sample.tags[0].Val] = sample.value
+		}
+	}
+	require.Equal(t, map[string]float64{"0": 2, "1": 4}, reserved)
+	require.Equal(t, map[string]float64{"0": 22, "1": 20}, available)
+}
+
+type fakeHeadroomAssembler struct {
+	headroom resource.Quantity
+}
+
+func (fa *fakeHeadroomAssembler) GetHeadroom() (resource.Quantity, error) {
+	return fa.headroom, nil
+}
+
+func milliValues(quantities map[int]resource.Quantity) map[int]int64 {
+	values := make(map[int]int64, len(quantities))
+	for key, quantity := range quantities {
+		values[key] = quantity.MilliValue()
+	}
+	return values
+}
+
+func TestGetHeadroomBeforeUpdated(t *testing.T) {
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestGetHeadroomBeforeUpdated")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, _ := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+	advisor.headroomAssembler = &fakeHeadroomAssembler{headroom: *resource.NewQuantity(80, resource.DecimalSI)}
+
+	// return error before updated by default
+	_, err = advisor.GetHeadroom()
+	require.Error(t, err)
+
+	// serve default headroom before updated, with headroom healthz registered in advance
+	conf.ServeDefaultHeadroomBeforeUpdated = true
+	conf.DefaultHeadroomCores = 2.5
+	advisor, _ = newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+	advisor.headroomAssembler = &fakeHeadroomAssembler{headroom: *resource.NewQuantity(80, resource.DecimalSI)}
+	headroomReady := func() bool {
+		result, ok := general.GetReadinessCheckResult(cpuAdvisorHeadroomHealthCheckName)
+		require.True(t, ok)
+		return result.Ready
+	}
+	require.True(t, headroomReady())
+
+	for i := 0; i < 2; i++ {
+		headroom, err := advisor.GetHeadroom()
+		require.NoError(t, err)
+		require.Equal(t, int64(2500), headroom.MilliValue())
+		require.False(t, headroomReady())
+	}
+
+	// serve the real headroom after updated
+	advisor.advisorUpdated = true
+	for i := 0; i < 2; i++ {
+		headroom, err := advisor.GetHeadroom()
+		require.NoError(t, err)
+		require.Equal(t, int64(80000), headroom.MilliValue())
+		require.True(t, headroomReady())
+	}
+}
+
+func TestGetHeadroomCapped(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestGetHeadroomCapped")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, metaCache := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+	advisor.advisorUpdated = true
+	advisor.headroomAssembler = &fakeHeadroomAssembler{headroom: *resource.NewQuantity(80, resource.DecimalSI)}
+
+	// reclaim pool has 30 cpus on numa0 and 10 cpus on numa1
+	err = metaCache.SetPoolInfo(state.PoolNameReclaim, &types.PoolInfo{
+		PoolName: state.PoolNameReclaim,
+		TopologyAwareAssignments: map[int]machine.CPUSet{
+			0: machine.MustParse("0-23,48-53"),
+			1: machine.MustParse("24-33"),
+		},
+	})
+	require.NoError(t, err)
+
+	// uncapped by default
+	headroom, err := advisor.GetHeadroom()
+	require.NoError(t, err)
+	require.Equal(t, int64(80000), headroom.MilliValue())
+
+	// node headroom is capped to 96*0.25=24 and distributed to numas as 18 and 6,
+	// then numa0 is capped to 48*0.25=12
+	conf.MaxHeadroomRatio = 0.25
+	headroom, err = advisor.GetHeadroom()
+	require.NoError(t, err)
+	require.Equal(t, int64(24000), headroom.MilliValue())
+	numaHeadroom, err := advisor.GetNUMAHeadroom()
+	require.NoError(t, err)
+	require.Equal(t, map[int]int64{0: 12000, 1: 6000}, milliValues(numaHeadroom))
+
+	// absolute cores takes effect if it's tighter, numas are capped to 10 each
+	conf.MaxHeadroomCores = 20
+	headroom, err = advisor.GetHeadroom()
+	require.NoError(t, err)
+	require.Equal(t, int64(20000), headroom.MilliValue())
+	numaHeadroom, err = advisor.GetNUMAHeadroom()
+	require.NoError(t, err)
+	require.Equal(t, map[int]int64{0: 10000, 1: 5000}, milliValues(numaHeadroom))
+}
+
+func TestAssignShareContainerToRegionsWithEmptyAssignments(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestAssignShareContainerToRegionsWithEmptyAssignments")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	conf.GenericSysAdvisorConfiguration.EnableShareCoresNumaBinding = true
+
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, metaCache := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+
+	numaBinding := map[string]string{consts.PodAnnotationMemoryEnhancementNumaBinding: consts.PodAnnotationMemoryEnhancementNumaBindingEnable}
+	notReady := makeContainerInfo("uid1", "default", "pod1", "c1", consts.PodAnnotationQoSLevelSharedCores,
+		state.PoolNameShare+"-NUMA1", numaBinding, map[int]machine.CPUSet{}, 4)
+	ready := makeContainerInfo("uid2", "default", "pod2", "c1", consts.PodAnnotationQoSLevelSharedCores,
+		state.PoolNameShare+"-NUMA1", numaBinding, map[int]machine.CPUSet{1: machine.MustParse("24-27")}, 4)
+
+	// container with empty assignments is skipped without error
+	regions, err := advisor.assignShareContainerToRegions(notReady)
+	require.NoError(t, err)
+	require.Nil(t, regions)
+
+	// and it doesn't block other containers from being assigned
+	require.NoError(t, metaCache.SetContainerInfo(notReady.PodUID, notReady.ContainerName, notReady))
+	require.NoError(t, metaCache.SetContainerInfo(ready.PodUID, ready.ContainerName, ready))
+	require.NoError(t, advisor.assignContainersToRegions())
+
+	ci, ok := metaCache.GetContainerInfo(ready.PodUID, ready.ContainerName)
+	require.True(t, ok)
+	require.Equal(t, 1, len(ci.RegionNames))
+	ci, ok = metaCache.GetContainerInfo(notReady.PodUID, notReady.ContainerName)
+	require.True(t, ok)
+	require.Equal(t, 0, len(ci.RegionNames))
+}
+
+func TestAssignShareContainerToRegionsWithIsolationLimit(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestAssignShareContainerToRegionsWithIsolationLimit")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	conf.IsolationMaxRegionsPerNuma = 1
+
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, metaCache := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+
+	for i := 0; i < 3; i++ {
+		ci := makeContainerInfo(fmt.Sprintf("uid%d", i), "default", fmt.Sprintf("pod%d", i), "c1",
+			consts.PodAnnotationQoSLevelSharedCores, state.PoolNameShare, nil, nil, 4)
+		ci.Isolated = true
+		require.NoError(t, metaCache.SetContainerInfo(ci.PodUID, ci.ContainerName, ci))
+	}
+
+	getIsolatedPods := func() []string {
+		var isolated []string
+		metaCache.RangeContainer(func(podUID string, _ string, ci *types.ContainerInfo) bool {
+			require.Equal(t, 1, len(ci.RegionNames))
+			if advisor.regionMap[ci.RegionNames.List()[0]].Type() == types.QoSRegionTypeIsolation {
+				isolated = append(isolated, podUID)
+			}
+			return true
+		})
+		return isolated
+	}
+
+	// only one isolation region is created, and the overflow stays in share
+	require.NoError(t, advisor.assignContainersToRegions())
+	isolated := getIsolatedPods()
+	require.Equal(t, 1, len(isolated))
+	require.Equal(t, 2, advisor.isolationOverflowContainers.Len())
+
+	// the existing isolation region keeps its slot in the following cycles
+	require.NoError(t, advisor.assignContainersToRegions())
+	require.Equal(t, isolated, getIsolatedPods())
+	require.Equal(t, 2, advisor.isolationOverflowContainers.Len())
+}
+
+func TestAssignShareContainerToRegionsWithPoolProvisionPolicy(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestAssignShareContainerToRegionsWithPoolProvisionPolicy")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(ckDir) }()
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(sfDir) }()
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	conf.CPUAdvisorConfiguration.ProvisionPolicies = map[types.QoSRegionType][]types.CPUProvisionPolicyName{
+		types.QoSRegionTypeShare: {types.CPUProvisionPolicyRama},
+	}
+	conf.CPUAdvisorConfiguration.PoolProvisionPolicies = map[string]types.CPUProvisionPolicyName{
+		"batch": types.CPUProvisionPolicyCanonical,
+		"flink": "unknown",
+	}
+
+	mf := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*metric.FakeMetricsFetcher)
+	advisor, _ := newTestCPUResourceAdvisor(t, nil, conf, mf, nil)
+
+	tests := []struct {
+		poolName   string
+		wantPolicy types.CPUProvisionPolicyName
+	}{
+		{poolName: state.PoolNameShare, wantPolicy: types.CPUProvisionPolicyRama},
+		{poolName: "batch", wantPolicy: types.CPUProvisionPolicyCanonical},
+		{poolName: "flink", wantPolicy: types.CPUProvisionPolicyRama},
+	}
+	for _, tt := range tests {
+		ci := makeContainerInfo("uid-"+tt.poolName, "default", "pod-"+tt.poolName, "c1",
+			consts.PodAnnotationQoSLevelSharedCores, tt.poolName, nil, nil, 4)
+		regions, err := advisor.assignShareContainerToRegions(ci)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(regions))
+
+		policyTopPriority, _ := regions[0].GetProvisionPolicy()
+		require.Equal(t, tt.wantPolicy, policyTopPriority, "pool %v", tt.poolName)
+	}
+}
@@ -35,6 +35,15 @@ import (
 	"github.com/kubewharf/katalyst-core/pkg/util/machine"
 )
 
+const (
+	metricProvisionReclaimPoolSizeNegative = "provision_reclaim_pool_size_negative"
+	metricProvisionZeroNumaAvailable       = "provision_zero_numa_available"
+)
+
+// ProvisionPostProcessor adjusts pool entries of the assembled provision result in place,
+// e.g. to leave some cores unallocated on a numa for node-specific agents
+type ProvisionPostProcessor func(result *types.InternalCPUCalculationResult) error
+
 type ProvisionAssemblerCommon struct {
 	conf               *config.Configuration
 	regionMap          *map[string]region.QoSRegion
@@ -46,6 +55,18 @@ type ProvisionAssemblerCommon struct {
 	metaServer   *metaserver.MetaServer
 	emitter      metrics.MetricEmitter
 	regionHelper *RegionMapHelper
+
+	// provisionCache memorizes control knob of each region keyed by region name,
+	// so that provision of a region is only got once in an AssembleProvision call
+	provisionCache map[string]types.ControlKnob
+
+	// postProcessors are invoked in order on the assembled provision result before it's returned
+	postProcessors []ProvisionPostProcessor
+
+	// lastNumaAvailable and lastNumaPoolEntries record available resource and pool entries of each
+	// numa in the last successful AssembleProvision call, to be kept for numas with zero available
+	lastNumaAvailable   map[int]int
+	lastNumaPoolEntries map[int]map[string]int
 }
 
 func NewProvisionAssemblerCommon(conf *config.Configuration, _ interface{}, regionMap *map[string]region.QoSRegion,
@@ -66,8 +87,36 @@ func NewProvisionAssemblerCommon(conf *config.Configuration, _ interface{}, regi
 	}
 }
 
+// ProvisionSnapshot records inputs of ProvisionAssemblerCommon shared by cpu advisor,
+// so that a bad provision result can be reproduced offline by replaying it
+type ProvisionSnapshot struct {
+	RegionMap          map[string]region.QoSRegion
+	ReservedForReclaim map[int]int
+	NumaAvailable      map[int]int
+	NonBindingNumas    machine.CPUSet
+}
+
+// NewProvisionAssemblerCommonFromSnapshot creates a ProvisionAssemblerCommon from a copy of the
+// snapshot rather than pointers to live advisor data, metaReader and metaServer can be stubbed
+// to make AssembleProvision deterministic
+func NewProvisionAssemblerCommonFromSnapshot(conf *config.Configuration, snapshot ProvisionSnapshot,
+	metaReader metacache.MetaReader, metaServer *metaserver.MetaServer, emitter metrics.MetricEmitter,
+) ProvisionAssembler {
+	regionMap := make(map[string]region.QoSRegion, len(snapshot.RegionMap))
+	for regionName, r := range snapshot.RegionMap {
+		regionMap[regionName] = r
+	}
+	reservedForReclaim := general.DeepCopyIntToIntMap(snapshot.ReservedForReclaim)
+	numaAvailable := general.DeepCopyIntToIntMap(snapshot.NumaAvailable)
+	nonBindingNumas := snapshot.NonBindingNumas.Clone()
+
+	return NewProvisionAssemblerCommon(conf, nil, &regionMap, &reservedForReclaim, &numaAvailable, &nonBindingNumas,
+		metaReader, metaServer, emitter)
+}
+
 func (pa *ProvisionAssemblerCommon) AssembleProvision() (types.InternalCPUCalculationResult, error) {
 	nodeEnableReclaim := pa.conf.GetDynamicConfiguration().EnableReclaim
+	pa.provisionCache = make(map[string]types.ControlKnob)
 
 	calculationResult := types.InternalCPUCalculationResult{
 		PoolEntries: make(map[string]map[int]int),
@@ -78,6 +127,17 @@ func (pa *ProvisionAssemblerCommon) AssembleProvision() (types.InternalCPUCalcul
 	reservePoolSize, _ := pa.metaReader.GetPoolSize(state.PoolNameReserve)
 	calculationResult.SetPoolEntry(state.PoolNameReserve, state.FakedNUMAID, reservePoolSize)
 
+	// available resource excludes extra reserved pools besides the reserve pool
+	numaAvailable := pa.getNumaAvailable()
+
+	keptNumas, err := pa.handleZeroAvailableNumas(numaAvailable)
+	if err != nil {
+		return types.InternalCPUCalculationResult{}, err
+	}
+
+	// iterate regions in order of name, so that the result and error are reproducible
+	regions := sortedRegions(*pa.regionMap)
+
 	shares := 0
 	isolationUppers := 0
 
@@ -85,8 +145,13 @@ func (pa *ProvisionAssemblerCommon) AssembleProvision() (types.InternalCPUCalcul
 	isolationUpperSizes := make(map[string]int)
 	isolationLowerSizes := make(map[string]int)
 
-	for _, r := range *pa.regionMap {
-		controlKnob, err := r.GetProvision()
+	for _, r := range regions {
+		// pool entries of numas with zero available are kept from the last result
+		if r.IsNumaBinding() && !r.GetBindingNumas().Intersection(keptNumas).IsEmpty() {
+			continue
+		}
+
+		controlKnob, err := pa.getRegionProvision(r)
 		if err != nil {
 			return types.InternalCPUCalculationResult{}, err
 		}
@@ -99,7 +164,7 @@ func (pa *ProvisionAssemblerCommon) AssembleProvision() (types.InternalCPUCalcul
 
 				nonReclaimRequirement := int(controlKnob[types.ControlKnobNonReclaimedCPUSize].Value)
 				// available = NUMA Size - Reserved - ReservedForReclaimed
-				available := getNumasAvailableResource(*pa.numaAvailable, r.GetBindingNumas())
+				available := getNumasAvailableResource(numaAvailable, r.GetBindingNumas())
 
 				// calc isolation pool size
 				isolationPoolSizeSum := 0
@@ -110,12 +175,15 @@ func (pa *ProvisionAssemblerCommon) AssembleProvision() (types.InternalCPUCalcul
 				if len(isolationRegions) > 0 {
 					isolationUpperSum := 0
 					for _, isolationRegion := range isolationRegions {
-						isolationControlKnob, err := isolationRegion.GetProvision()
+						if err := checkBindingNumasAvailable(numaAvailable, isolationRegion); err != nil {
+							return types.InternalCPUCalculationResult{}, err
+						}
+						isolationControlKnob, err := pa.getRegionProvision(isolationRegion)
 						if err != nil {
 							return types.InternalCPUCalculationResult{}, err
 						}
 						isolationRegionControlKnobs[isolationRegion.Name()] = isolationControlKnob
-						isolationUpperSum += int(isolationControlKnob[types.ControlKnobNonReclaimedCPUSizeUpper].Value)
+						isolationUpperSum += pa.getIsolationCPUSize(isolationControlKnob, types.ControlKnobNonReclaimedCPUSizeUpper)
 					}
 
 					if nonReclaimRequirement+isolationUpperSum > available {
@@ -125,10 +193,13 @@ func (pa *ProvisionAssemblerCommon) AssembleProvision() (types.InternalCPUCalcul
 
 				numaPoolSize := map[string]int{r.OwnerPoolName(): nonReclaimRequirement}
 				for isolationRegionName, isolationRegionControlKnob := range isolationRegionControlKnobs {
-					numaPoolSize[isolationRegionName] = int(isolationRegionControlKnob[isolationRegionControlKnobKey].Value)
+					numaPoolSize[isolationRegionName] = pa.getIsolationCPUSize(isolationRegionControlKnob, isolationRegionControlKnobKey)
 				}
-				poolThrottled := regulatePoolSizes(numaPoolSize, available, nodeEnableReclaim)
+				isolationRequirement := general.SumUpMapValues(numaPoolSize) - nonReclaimRequirement
+				numaEnableReclaim := pa.numasEnableReclaim(nodeEnableReclaim, r.GetBindingNumas())
+				poolThrottled := regulatePoolSizes(numaPoolSize, available, numaEnableReclaim)
 				r.SetThrottled(poolThrottled)
+				r.SetThrottleReason(getThrottleReason(poolThrottled, nonReclaimRequirement, isolationRequirement, available, reservedForReclaim))
 
 				nonReclaimRequirement = numaPoolSize[r.OwnerPoolName()]
 				for isolationRegionName := range isolationRegionControlKnobs {
@@ -140,7 +211,7 @@ func (pa *ProvisionAssemblerCommon) AssembleProvision() (types.InternalCPUCalcul
 				// calc share and reclaimed pool size
 				sharePoolSize := 0
 				reclaimed := 0
-				if nodeEnableReclaim {
+				if numaEnableReclaim {
 					reclaimed = available - nonReclaimRequirement - isolationPoolSizeSum + reservedForReclaim
 					sharePoolSize = nonReclaimRequirement
 				} else {
@@ -157,15 +228,18 @@ func (pa *ProvisionAssemblerCommon) AssembleProvision() (types.InternalCPUCalcul
 			}
 		case types.QoSRegionTypeIsolation:
 			if r.IsNumaBinding() {
+				if err := checkBindingNumasAvailable(numaAvailable, r); err != nil {
+					return types.InternalCPUCalculationResult{}, err
+				}
 				regionNuma := r.GetBindingNumas().ToSliceInt()[0] // always one binding numa for this type of region
 				// If there is a SNB pool with the same NUMA ID, it will be calculated while processing the SNB pool.
 				if shareRegions := pa.regionHelper.GetRegions(regionNuma, types.QoSRegionTypeShare); len(shareRegions) == 0 {
-					calculationResult.SetPoolEntry(r.Name(), regionNuma, int(controlKnob[types.ControlKnobNonReclaimedCPUSizeUpper].Value))
+					calculationResult.SetPoolEntry(r.Name(), regionNuma, pa.getIsolationCPUSize(controlKnob, types.ControlKnobNonReclaimedCPUSizeUpper))
 				}
 			} else {
 				// save limits and requests for isolated region
-				isolationUpperSizes[r.Name()] = int(controlKnob[types.ControlKnobNonReclaimedCPUSizeUpper].Value)
-				isolationLowerSizes[r.Name()] = int(controlKnob[types.ControlKnobNonReclaimedCPUSizeLower].Value)
+				isolationUpperSizes[r.Name()] = pa.getIsolationCPUSize(controlKnob, types.ControlKnobNonReclaimedCPUSizeUpper)
+				isolationLowerSizes[r.Name()] = pa.getIsolationCPUSize(controlKnob, types.ControlKnobNonReclaimedCPUSizeLower)
 
 				isolationUppers += isolationUpperSizes[r.Name()]
 			}
@@ -179,7 +253,8 @@ func (pa *ProvisionAssemblerCommon) AssembleProvision() (types.InternalCPUCalcul
 			}
 			podUID, _, _ := podSet.PopAny()
 
-			enableReclaim, err := helper.PodEnableReclaim(context.Background(), pa.metaServer, podUID, nodeEnableReclaim)
+			enableReclaim, err := helper.PodEnableReclaim(context.Background(), pa.metaServer, podUID,
+				pa.numasEnableReclaim(nodeEnableReclaim, r.GetBindingNumas()))
 			if err != nil {
 				return types.InternalCPUCalculationResult{}, err
 			}
@@ -190,7 +265,7 @@ func (pa *ProvisionAssemblerCommon) AssembleProvision() (types.InternalCPUCalcul
 					calculationResult.SetPoolEntry(state.PoolNameReclaim, regionNuma, reservedForReclaim)
 				}
 			} else {
-				available := getNumasAvailableResource(*pa.numaAvailable, r.GetBindingNumas())
+				available := getNumasAvailableResource(numaAvailable, r.GetBindingNumas())
 				nonReclaimRequirement := int(controlKnob[types.ControlKnobNonReclaimedCPUSize].Value)
 				reclaimed := available - nonReclaimRequirement + reservedForReclaim
 
@@ -202,15 +277,26 @@ func (pa *ProvisionAssemblerCommon) AssembleProvision() (types.InternalCPUCalcul
 		}
 	}
 
-	shareAndIsolatedPoolAvailable := getNumasAvailableResource(*pa.numaAvailable, *pa.nonBindingNumas)
+	for _, numaID := range keptNumas.ToSliceInt() {
+		for poolName, poolSize := range pa.lastNumaPoolEntries[numaID] {
+			calculationResult.SetPoolEntry(poolName, numaID, poolSize)
+		}
+	}
+
+	shareAndIsolatedPoolAvailable := getNumasAvailableResource(numaAvailable, *pa.nonBindingNumas)
 	shareAndIsolatePoolSizes := general.MergeMapInt(sharePoolSizes, isolationUpperSizes)
 	if shares+isolationUppers > shareAndIsolatedPoolAvailable {
 		shareAndIsolatePoolSizes = general.MergeMapInt(sharePoolSizes, isolationLowerSizes)
 	}
-	poolThrottled := regulatePoolSizes(shareAndIsolatePoolSizes, shareAndIsolatedPoolAvailable, nodeEnableReclaim)
-	for _, r := range *pa.regionMap {
+	isolationRequirement := general.SumUpMapValues(shareAndIsolatePoolSizes) - shares
+	nonBindingEnableReclaim := pa.numasEnableReclaim(nodeEnableReclaim, *pa.nonBindingNumas)
+	poolThrottled := regulatePoolSizesWithShareFloor(shareAndIsolatePoolSizes, sharePoolSizes, isolationLowerSizes,
+		shareAndIsolatedPoolAvailable, nonBindingEnableReclaim, pa.conf.CPUAdvisorConfiguration.MinSharePoolCoreNum)
+	throttleReason := getThrottleReason(poolThrottled, shares, isolationRequirement, shareAndIsolatedPoolAvailable, pa.getNumasReservedForReclaim(*pa.nonBindingNumas))
+	for _, r := range regions {
 		if r.Type() == types.QoSRegionTypeShare && !r.IsNumaBinding() {
 			r.SetThrottled(poolThrottled)
+			r.SetThrottleReason(throttleReason)
 		}
 	}
 
@@ -227,18 +313,244 @@ func (pa *ProvisionAssemblerCommon) AssembleProvision() (types.InternalCPUCalcul
 	var reclaimPoolSizeOfNonBindingNumas int
 
 	// fill in reclaim pool entries of non binding numas
-	if nodeEnableReclaim {
+	if nonBindingEnableReclaim {
 		// generate based on share pool requirement on non binding numas
 		reclaimPoolSizeOfNonBindingNumas = shareAndIsolatedPoolAvailable - general.SumUpMapValues(shareAndIsolatePoolSizes) + pa.getNumasReservedForReclaim(*pa.nonBindingNumas)
+
+		// pool sizes may exceed available resource after regulation, and reclaim pool
+		// should keep at least the reserved part in that case
+		if reclaimPoolSizeOfNonBindingNumas < 0 {
+			klog.Warningf("reclaim pool size of non binding numas %v is negative, shareAndIsolatePoolSizes %v exceed available %v",
+				reclaimPoolSizeOfNonBindingNumas, shareAndIsolatePoolSizes, shareAndIsolatedPoolAvailable)
+			_ = pa.emitter.StoreInt64(metricProvisionReclaimPoolSizeNegative, 1, metrics.MetricTypeNameCount)
+		}
+		reclaimPoolSizeOfNonBindingNumas = general.Max(reclaimPoolSizeOfNonBindingNumas,
+			general.Max(pa.getNumasReservedForReclaim(*pa.nonBindingNumas), 0))
 	} else {
 		// generate by reserved value on non binding numas
 		reclaimPoolSizeOfNonBindingNumas = pa.getNumasReservedForReclaim(*pa.nonBindingNumas)
 	}
 	calculationResult.SetPoolEntry(state.PoolNameReclaim, state.FakedNUMAID, reclaimPoolSizeOfNonBindingNumas)
 
+	// cap total reclaim pool size across all numas
+	if maxReclaimCoreNum := pa.conf.CPUAdvisorConfiguration.MaxReclaimCoreNum; maxReclaimCoreNum > 0 {
+		reclaimPoolSizes := calculationResult.PoolEntries[state.PoolNameReclaim]
+		reserved := make(map[int]int)
+		for numaID := range reclaimPoolSizes {
+			if numaID == state.FakedNUMAID {
+				reserved[numaID] = pa.getNumasReservedForReclaim(*pa.nonBindingNumas)
+			} else {
+				reserved[numaID] = pa.getNumasReservedForReclaim(machine.NewCPUSet(numaID))
+			}
+		}
+		capReclaimPoolSizes(reclaimPoolSizes, reserved, maxReclaimCoreNum)
+		klog.InfoS("cap reclaim pool sizes", "maxReclaimCoreNum", maxReclaimCoreNum, "reclaimPoolSizes", reclaimPoolSizes)
+	}
+
+	if err := pa.postProcess(&calculationResult, numaAvailable); err != nil {
+		return types.InternalCPUCalculationResult{}, err
+	}
+
+	pa.recordNumaProvision(calculationResult, numaAvailable)
 	return calculationResult, nil
 }
 
+// numasEnableReclaim returns whether reclaim is enabled on all the numas, and per-numa overrides
+// take precedence over the node-wide one. since reclaim pool of non binding numas is sized as a
+// whole, it's only enabled if none of these numas has reclaim disabled.
+func (pa *ProvisionAssemblerCommon) numasEnableReclaim(nodeEnableReclaim bool, numas machine.CPUSet) bool {
+	if numas.IsEmpty() {
+		return nodeEnableReclaim
+	}
+
+	for _, numaID := range numas.ToSliceInt() {
+		enableReclaim, ok := pa.conf.CPUAdvisorConfiguration.NUMAEnableReclaim[numaID]
+		if !ok {
+			enableReclaim = nodeEnableReclaim
+		}
+		if !enableReclaim {
+			return false
+		}
+	}
+	return true
+}
+
+// handleZeroAvailableNumas checks numas with numa binding regions but zero available resource while
+// other numas have, and handles them by the configured policy. it returns numas whose available
+// resource and pool entries should be kept from the last result, and available resource of them
+// in numaAvailable is replaced by the last one in place.
+func (pa *ProvisionAssemblerCommon) handleZeroAvailableNumas(numaAvailable map[int]int) (machine.CPUSet, error) {
+	keptNumas := machine.NewCPUSet()
+
+	policy := pa.conf.CPUAdvisorConfiguration.ZeroNumaAvailablePolicy
+	if policy == "" || policy == types.CPUZeroNumaAvailablePolicyNone {
+		return keptNumas, nil
+	}
+	totalAvailable := 0
+	for _, available := range numaAvailable {
+		totalAvailable += available
+	}
+	if totalAvailable == 0 {
+		return keptNumas, nil
+	}
+
+	bindingNumas := machine.NewCPUSet()
+	for _, r := range *pa.regionMap {
+		if r.IsNumaBinding() {
+			bindingNumas = bindingNumas.Union(r.GetBindingNumas())
+		}
+	}
+
+	for _, numaID := range bindingNumas.ToSliceInt() {
+		if available, ok := numaAvailable[numaID]; !ok || available > 0 {
+			continue
+		}
+
+		klog.Warningf("numa %v has zero available resource while other numas have, handle it by policy %v", numaID, policy)
+		_ = pa.emitter.StoreInt64(metricProvisionZeroNumaAvailable, 1, metrics.MetricTypeNameCount,
+			metrics.MetricTag{Key: "numa_id", Val: fmt.Sprintf("%d", numaID)},
+			metrics.MetricTag{Key: "policy", Val: string(policy)})
+
+		switch policy {
+		case types.CPUZeroNumaAvailablePolicyKeepLast:
+			if _, ok := pa.lastNumaPoolEntries[numaID]; !ok {
+				return keptNumas, fmt.Errorf("numa %v has zero available resource and no last provision to keep", numaID)
+			}
+			numaAvailable[numaID] = pa.lastNumaAvailable[numaID]
+			keptNumas.Add(numaID)
+		case types.CPUZeroNumaAvailablePolicyError:
+			return keptNumas, fmt.Errorf("numa %v has zero available resource while other numas have", numaID)
+		default:
+			return keptNumas, fmt.Errorf("unsupported zero numa available policy %v", policy)
+		}
+	}
+	return keptNumas, nil
+}
+
+// recordNumaProvision records available resource and pool entries of numas with non-zero available
+func (pa *ProvisionAssemblerCommon) recordNumaProvision(calculationResult types.InternalCPUCalculationResult, numaAvailable map[int]int) {
+	lastNumaAvailable := make(map[int]int)
+	lastNumaPoolEntries := make(map[int]map[string]int)
+	for numaID, available := range numaAvailable {
+		if available <= 0 {
+			continue
+		}
+		lastNumaAvailable[numaID] = available
+		lastNumaPoolEntries[numaID] = make(map[string]int)
+	}
+
+	for poolName, entries := range calculationResult.PoolEntries {
+		for numaID, poolSize := range entries {
+			if poolEntries, ok := lastNumaPoolEntries[numaID]; ok {
+				poolEntries[poolName] = poolSize
+			}
+		}
+	}
+
+	pa.lastNumaAvailable = lastNumaAvailable
+	pa.lastNumaPoolEntries = lastNumaPoolEntries
+}
+
+// RegisterPostProcessor appends a post-processor to the chain invoked on the assembled provision result
+func (pa *ProvisionAssemblerCommon) RegisterPostProcessor(postProcessor ProvisionPostProcessor) {
+	pa.postProcessors = append(pa.postProcessors, postProcessor)
+}
+
+// postProcess runs the post-processor chain on the result, and validates the result again
+// since pool entries may be adjusted by post-processors
+func (pa *ProvisionAssemblerCommon) postProcess(calculationResult *types.InternalCPUCalculationResult, numaAvailable map[int]int) error {
+	if len(pa.postProcessors) == 0 {
+		return nil
+	}
+
+	for _, postProcessor := range pa.postProcessors {
+		if err := postProcessor(calculationResult); err != nil {
+			return fmt.Errorf("post-process provision failed: %v", err)
+		}
+	}
+
+	if err := pa.validateCalculationResult(*calculationResult, numaAvailable); err != nil {
+		return fmt.Errorf("invalid provision after post-process: %v", err)
+	}
+	klog.InfoS("post-processed provision", "poolEntries", calculationResult.PoolEntries)
+	return nil
+}
+
+// validateCalculationResult checks that pool sizes are non-negative, and pool sizes on each numa
+// don't exceed its available resource plus the resource reserved for reclaim
+func (pa *ProvisionAssemblerCommon) validateCalculationResult(calculationResult types.InternalCPUCalculationResult, numaAvailable map[int]int) error {
+	numaSum := make(map[int]int)
+	for poolName, entries := range calculationResult.PoolEntries {
+		if poolName == state.PoolNameReserve {
+			continue
+		}
+
+		for numaID, size := range entries {
+			if size < 0 {
+				return fmt.Errorf("pool %v has negative size %v on numa %v", poolName, size, numaID)
+			}
+			numaSum[numaID] += size
+		}
+	}
+
+	for numaID, sum := range numaSum {
+		numas := machine.NewCPUSet(numaID)
+		if numaID == state.FakedNUMAID {
+			numas = *pa.nonBindingNumas
+		}
+
+		if limit := getNumasAvailableResource(numaAvailable, numas) + pa.getNumasReservedForReclaim(numas); sum > limit {
+			return fmt.Errorf("pool sizes %v on numa %v exceed limit %v", sum, numaID, limit)
+		}
+	}
+	return nil
+}
+
+// getNumaAvailable returns available resource of each numa, with sizes of extra reserved pools excluded
+func (pa *ProvisionAssemblerCommon) getNumaAvailable() map[int]int {
+	numaAvailable := general.DeepCopyIntToIntMap(*pa.numaAvailable)
+	for _, poolName := range pa.conf.CPUAdvisorConfiguration.ExtraReservedPoolNames {
+		pool, ok := pa.metaReader.GetPoolInfo(poolName)
+		if !ok || pool == nil {
+			continue
+		}
+
+		for numaID, cpuset := range pool.TopologyAwareAssignments {
+			if available, ok := numaAvailable[numaID]; ok {
+				numaAvailable[numaID] = general.Max(available-cpuset.Size(), 0)
+			}
+		}
+	}
+	return numaAvailable
+}
+
+// getRegionProvision returns control knob of the region, and it's only got from region once in an AssembleProvision call
+func (pa *ProvisionAssemblerCommon) getRegionProvision(r region.QoSRegion) (types.ControlKnob, error) {
+	if controlKnob, ok := pa.provisionCache[r.Name()]; ok {
+		return controlKnob, nil
+	}
+
+	controlKnob, err := r.GetProvision()
+	if err != nil {
+		return nil, err
+	}
+	if pa.conf.CPUAdvisorConfiguration.ClampProvisionByEssentials {
+		controlKnob = clampControlKnobByEssentials(r, controlKnob)
+	}
+	pa.provisionCache[r.Name()] = controlKnob
+	return controlKnob, nil
+}
+
+// getIsolationCPUSize returns the cpu size of isolation region for the given control knob,
+// which is capped by the max isolation core num to avoid starving share pools
+func (pa *ProvisionAssemblerCommon) getIsolationCPUSize(controlKnob types.ControlKnob, key types.ControlKnobName) int {
+	size := int(controlKnob[key].Value)
+	if maxIsolationCoreNum := pa.conf.CPUAdvisorConfiguration.MaxIsolationCoreNum; maxIsolationCoreNum > 0 {
+		size = general.Min(size, maxIsolationCoreNum)
+	}
+	return size
+}
+
 func (pa *ProvisionAssemblerCommon) getNumasReservedForReclaim(numas machine.CPUSet) int {
 	res := 0
 	for _, id := range numas.ToSliceInt() {
@@ -273,7 +585,7 @@ func (rm *RegionMapHelper) GetRegions(numaID int, regionType types.QoSRegionType
 }
 
 func (rm *RegionMapHelper) preProcessRegions(regions map[string]region.QoSRegion) {
-	for _, r := range regions {
+	for _, r := range sortedRegions(regions) {
 		for _, numaID := range r.GetBindingNumas().ToSliceInt() {
 			numaRecords, ok := rm.regions[numaID]
 			if !ok {
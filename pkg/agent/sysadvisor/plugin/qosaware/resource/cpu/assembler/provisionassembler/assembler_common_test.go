@@ -17,6 +17,7 @@ limitations under the License.
 package provisionassembler
 
 import (
+	"fmt"
 	"os"
 	"testing"
 
@@ -49,12 +50,15 @@ type FakeRegion struct {
 	controlKnob                types.ControlKnob
 	headroom                   float64
 	throttled                  bool
+	throttleReason             types.ThrottleReason
 	provisionCurrentPolicyName types.CPUProvisionPolicyName
 	provisionPolicyTopPriority types.CPUProvisionPolicyName
 	headroomCurrentPolicyName  types.CPUHeadroomPolicyName
 	headroomPolicyTopPriority  types.CPUHeadroomPolicyName
 	controlEssentials          types.ControlEssentials
 	essentials                 types.ResourceEssentials
+	getProvisionTimes          int
+	provisionErr               error
 }
 
 func NewFakeRegion(name string, regionType types.QoSRegionType, ownerPoolName string) *FakeRegion {
@@ -101,6 +105,10 @@ func (fake *FakeRegion) SetEssentials(essentials types.ResourceEssentials) {
 	fake.essentials = essentials
 }
 
+func (fake *FakeRegion) GetEssentials() types.ResourceEssentials {
+	return fake.essentials
+}
+
 func (fake *FakeRegion) SetIsNumaBinding(isNumaBinding bool) {
 	fake.isNumaBinding = isNumaBinding
 }
@@ -118,6 +126,10 @@ func (fake *FakeRegion) SetProvision(controlKnob types.ControlKnob) {
 }
 
 func (fake *FakeRegion) GetProvision() (types.ControlKnob, error) {
+	fake.getProvisionTimes++
+	if fake.provisionErr != nil {
+		return nil, fake.provisionErr
+	}
 	return fake.controlKnob, nil
 }
 
@@ -138,6 +150,14 @@ func (fake *FakeRegion) SetProvisionPolicy(policyTopPriority, currentPolicyName
 	fake.provisionCurrentPolicyName = currentPolicyName
 }
 
+func (fake *FakeRegion) SetThrottleReason(reason types.ThrottleReason) {
+	fake.throttleReason = reason
+}
+
+func (fake *FakeRegion) GetThrottleReason() types.ThrottleReason {
+	return fake.throttleReason
+}
+
 func (fake *FakeRegion) GetProvisionPolicy() (types.CPUProvisionPolicyName, types.CPUProvisionPolicyName) {
 	return fake.provisionPolicyTopPriority, fake.provisionCurrentPolicyName
 }
@@ -247,10 +267,14 @@ func TestAssembleProvision(t *testing.T) {
 		types.ControlKnobNonReclaimedCPUSize: {Value: 8},
 	})
 	tests := []struct {
-		name            string
-		enableReclaimed bool
-		poolInfos       []testCasePoolConfig
-		expect          map[string]map[int]int
+		name                string
+		enableReclaimed     bool
+		maxReclaimCoreNum   int
+		maxIsolationCoreNum int
+		poolInfos           []testCasePoolConfig
+		expect              map[string]map[int]int
+		// expectThrottleReasons is checked only if not empty
+		expectThrottleReasons map[string]types.ThrottleReason
 	}{
 		{
 			name:            "test1",
@@ -733,6 +757,181 @@ func TestAssembleProvision(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:              "reclaim capped by max reclaim core num",
+			enableReclaimed:   true,
+			maxReclaimCoreNum: 20,
+			poolInfos: []testCasePoolConfig{
+				{
+					poolName:      "share",
+					poolType:      types.QoSRegionTypeShare,
+					numa:          machine.NewCPUSet(0),
+					isNumaBinding: false,
+					provision: types.ControlKnob{
+						types.ControlKnobNonReclaimedCPUSize: {Value: 6},
+					},
+				},
+				{
+					poolName:      "share-NUMA1",
+					poolType:      types.QoSRegionTypeShare,
+					numa:          machine.NewCPUSet(1),
+					isNumaBinding: true,
+					provision: types.ControlKnob{
+						types.ControlKnobNonReclaimedCPUSize: {Value: 8},
+					},
+				},
+			},
+			expect: map[string]map[int]int{
+				"share": {
+					-1: 6,
+				},
+				"share-NUMA1": {
+					1: 8,
+				},
+				"reserve": {
+					-1: 0,
+				},
+				"reclaim": {
+					-1: 10,
+					1:  10,
+				},
+			},
+		},
+		{
+			name:            "throttle reasons",
+			enableReclaimed: true,
+			poolInfos: []testCasePoolConfig{
+				{
+					poolName:      "share",
+					poolType:      types.QoSRegionTypeShare,
+					numa:          machine.NewCPUSet(0),
+					isNumaBinding: false,
+					provision: types.ControlKnob{
+						types.ControlKnobNonReclaimedCPUSize: {Value: 22},
+					},
+				},
+				{
+					poolName:      "share-NUMA1",
+					poolType:      types.QoSRegionTypeShare,
+					numa:          machine.NewCPUSet(1),
+					isNumaBinding: true,
+					provision: types.ControlKnob{
+						types.ControlKnobNonReclaimedCPUSize: {Value: 14},
+					},
+				},
+				{
+					poolName:      "isolation-NUMA1",
+					poolType:      types.QoSRegionTypeIsolation,
+					numa:          machine.NewCPUSet(1),
+					isNumaBinding: true,
+					provision: types.ControlKnob{
+						types.ControlKnobNonReclaimedCPUSizeUpper: {Value: 8},
+						types.ControlKnobNonReclaimedCPUSizeLower: {Value: 8},
+					},
+				},
+			},
+			expect: map[string]map[int]int{
+				"share": {
+					-1: 20,
+				},
+				"share-NUMA1": {
+					1: 13,
+				},
+				"isolation-NUMA1": {
+					1: 7,
+				},
+				"reserve": {
+					-1: 0,
+				},
+				"reclaim": {
+					-1: 4,
+					1:  4,
+				},
+			},
+			expectThrottleReasons: map[string]types.ThrottleReason{
+				"share":       types.ThrottleReasonReserve,
+				"share-NUMA1": types.ThrottleReasonIsolationPressure,
+			},
+		},
+		{
+			name:                "isolation capped by max isolation core num",
+			enableReclaimed:     true,
+			maxIsolationCoreNum: 6,
+			poolInfos: []testCasePoolConfig{
+				{
+					poolName:      "share",
+					poolType:      types.QoSRegionTypeShare,
+					numa:          machine.NewCPUSet(0),
+					isNumaBinding: false,
+					provision: types.ControlKnob{
+						types.ControlKnobNonReclaimedCPUSize: {Value: 6},
+					},
+				},
+				{
+					poolName:      "isolation-pod3",
+					poolType:      types.QoSRegionTypeIsolation,
+					numa:          machine.NewCPUSet(0),
+					isNumaBinding: false,
+					provision: types.ControlKnob{
+						types.ControlKnobNonReclaimedCPUSizeUpper: {Value: 12},
+						types.ControlKnobNonReclaimedCPUSizeLower: {Value: 4},
+					},
+				},
+				{
+					poolName:      "share-NUMA1",
+					poolType:      types.QoSRegionTypeShare,
+					numa:          machine.NewCPUSet(1),
+					isNumaBinding: true,
+					provision: types.ControlKnob{
+						types.ControlKnobNonReclaimedCPUSize: {Value: 4},
+					},
+				},
+				{
+					poolName:      "isolation-NUMA1",
+					poolType:      types.QoSRegionTypeIsolation,
+					numa:          machine.NewCPUSet(1),
+					isNumaBinding: true,
+					provision: types.ControlKnob{
+						types.ControlKnobNonReclaimedCPUSizeUpper: {Value: 8},
+						types.ControlKnobNonReclaimedCPUSizeLower: {Value: 4},
+					},
+				},
+				{
+					poolName:      "isolation-NUMA1-pod2",
+					poolType:      types.QoSRegionTypeIsolation,
+					numa:          machine.NewCPUSet(1),
+					isNumaBinding: true,
+					provision: types.ControlKnob{
+						types.ControlKnobNonReclaimedCPUSizeUpper: {Value: 8},
+						types.ControlKnobNonReclaimedCPUSizeLower: {Value: 4},
+					},
+				},
+			},
+			expect: map[string]map[int]int{
+				"share": {
+					-1: 6,
+				},
+				"isolation-pod3": {
+					-1: 6,
+				},
+				"share-NUMA1": {
+					1: 4,
+				},
+				"isolation-NUMA1": {
+					1: 6,
+				},
+				"isolation-NUMA1-pod2": {
+					1: 6,
+				},
+				"reserve": {
+					-1: 0,
+				},
+				"reclaim": {
+					-1: 12,
+					1:  8,
+				},
+			},
+		},
 	}
 
 	reservedForReclaim := map[int]int{
@@ -753,6 +952,8 @@ func TestAssembleProvision(t *testing.T) {
 			t.Parallel()
 
 			conf := generateTestConf(t, test.enableReclaimed)
+			conf.CPUAdvisorConfiguration.MaxReclaimCoreNum = test.maxReclaimCoreNum
+			conf.CPUAdvisorConfiguration.MaxIsolationCoreNum = test.maxIsolationCoreNum
 
 			genericCtx, err := katalyst_base.GenerateFakeGenericContext([]runtime.Object{})
 			require.NoError(t, err)
@@ -787,10 +988,524 @@ func TestAssembleProvision(t *testing.T) {
 			require.NotNil(t, result, "invalid assembler result")
 			t.Logf("%v", result)
 			require.Equal(t, test.expect, result.PoolEntries, "unexpected result")
+			for regionName, reason := range test.expectThrottleReasons {
+				require.Equal(t, reason, regionMap[regionName].GetThrottleReason(), "unexpected throttle reason of %v", regionName)
+			}
 		})
 	}
 }
 
+func TestAssembleProvisionGetProvisionOnce(t *testing.T) {
+	t.Parallel()
+
+	conf := generateTestConf(t, true)
+
+	genericCtx, err := katalyst_base.GenerateFakeGenericContext([]runtime.Object{})
+	require.NoError(t, err)
+
+	metaServer, err := metaserver.NewMetaServer(genericCtx.Client, metrics.DummyMetrics{}, conf)
+	require.NoError(t, err)
+	defer func() {
+		os.RemoveAll(conf.GenericSysAdvisorConfiguration.StateFileDirectory)
+		os.RemoveAll(conf.MetaServerConfiguration.CheckpointManagerDir)
+	}()
+
+	metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}))
+	require.NoError(t, err)
+
+	share := NewFakeRegion("share-NUMA1", types.QoSRegionTypeShare, "share-NUMA1")
+	share.SetBindingNumas(machine.NewCPUSet(1))
+	share.SetIsNumaBinding(true)
+	share.SetProvision(types.ControlKnob{types.ControlKnobNonReclaimedCPUSize: {Value: 4}})
+
+	// isolation region is referred both by share region on the same numa and by itself
+	isolation := NewFakeRegion("isolation-NUMA1", types.QoSRegionTypeIsolation, "isolation-NUMA1")
+	isolation.SetBindingNumas(machine.NewCPUSet(1))
+	isolation.SetIsNumaBinding(true)
+	isolation.SetProvision(types.ControlKnob{
+		types.ControlKnobNonReclaimedCPUSizeUpper: {Value: 8},
+		types.ControlKnobNonReclaimedCPUSizeLower: {Value: 4},
+	})
+
+	regionMap := map[string]region.QoSRegion{share.Name(): share, isolation.Name(): isolation}
+	reservedForReclaim := map[int]int{0: 4, 1: 4}
+	numaAvailable := map[int]int{0: 20, 1: 20}
+	nonBindingNumas := machine.NewCPUSet(0)
+
+	common := NewProvisionAssemblerCommon(conf, nil, &regionMap, &reservedForReclaim, &numaAvailable, &nonBindingNumas, metaCache, metaServer, metrics.DummyMetrics{})
+	for i := 1; i <= 2; i++ {
+		_, err = common.AssembleProvision()
+		require.NoError(t, err)
+		require.Equal(t, i, share.getProvisionTimes)
+		require.Equal(t, i, isolation.getProvisionTimes)
+	}
+}
+
+func TestAssembleProvisionWithUnknownIsolationNuma(t *testing.T) {
+	t.Parallel()
+
+	conf := generateTestConf(t, true)
+
+	genericCtx, err := katalyst_base.GenerateFakeGenericContext([]runtime.Object{})
+	require.NoError(t, err)
+
+	metaServer, err := metaserver.NewMetaServer(genericCtx.Client, metrics.DummyMetrics{}, conf)
+	require.NoError(t, err)
+	defer func() {
+		os.RemoveAll(conf.GenericSysAdvisorConfiguration.StateFileDirectory)
+		os.RemoveAll(conf.MetaServerConfiguration.CheckpointManagerDir)
+	}()
+
+	metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}))
+	require.NoError(t, err)
+
+	share := NewFakeRegion("share", types.QoSRegionTypeShare, "share")
+	share.SetBindingNumas(machine.NewCPUSet(0))
+	share.SetProvision(types.ControlKnob{types.ControlKnobNonReclaimedCPUSize: {Value: 4}})
+
+	isolation := NewFakeRegion("isolation-NUMA3", types.QoSRegionTypeIsolation, "isolation-NUMA3")
+	isolation.SetBindingNumas(machine.NewCPUSet(3))
+	isolation.SetIsNumaBinding(true)
+	isolation.SetProvision(types.ControlKnob{
+		types.ControlKnobNonReclaimedCPUSizeUpper: {Value: 8},
+		types.ControlKnobNonReclaimedCPUSizeLower: {Value: 4},
+	})
+
+	regionMap := map[string]region.QoSRegion{share.Name(): share, isolation.Name(): isolation}
+	reservedForReclaim := map[int]int{0: 4}
+	numaAvailable := map[int]int{0: 20}
+	nonBindingNumas := machine.NewCPUSet(0)
+
+	common := NewProvisionAssemblerCommon(conf, nil, &regionMap, &reservedForReclaim, &numaAvailable, &nonBindingNumas, metaCache, metaServer, metrics.DummyMetrics{})
+	_, err = common.AssembleProvision()
+	require.EqualError(t, err, "binding numa 3 of region isolation-NUMA3 not found in available numas")
+}
+
+func TestAssembleProvisionWithPostProcessor(t *testing.T) {
+	t.Parallel()
+
+	conf := generateTestConf(t, true)
+
+	genericCtx, err := katalyst_base.GenerateFakeGenericContext([]runtime.Object{})
+	require.NoError(t, err)
+
+	metaServer, err := metaserver.NewMetaServer(genericCtx.Client, metrics.DummyMetrics{}, conf)
+	require.NoError(t, err)
+	defer func() {
+		os.RemoveAll(conf.GenericSysAdvisorConfiguration.StateFileDirectory)
+		os.RemoveAll(conf.MetaServerConfiguration.CheckpointManagerDir)
+	}()
+
+	metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}))
+	require.NoError(t, err)
+
+	shareNUMA1 := NewFakeRegion("share-NUMA1", types.QoSRegionTypeShare, "share-NUMA1")
+	shareNUMA1.SetBindingNumas(machine.NewCPUSet(1))
+	shareNUMA1.SetIsNumaBinding(true)
+	shareNUMA1.SetProvision(types.ControlKnob{types.ControlKnobNonReclaimedCPUSize: {Value: 4}})
+
+	regionMap := map[string]region.QoSRegion{shareNUMA1.Name(): shareNUMA1}
+	reservedForReclaim := map[int]int{0: 4, 1: 4}
+	numaAvailable := map[int]int{0: 20, 1: 20}
+	nonBindingNumas := machine.NewCPUSet(0)
+
+	assembler := NewProvisionAssemblerCommon(conf, nil, &regionMap, &reservedForReclaim, &numaAvailable, &nonBindingNumas, metaCache, metaServer, metrics.DummyMetrics{})
+	common := assembler.(*ProvisionAssemblerCommon)
+
+	// leave one core of reclaim pool on numa 1 unallocated
+	common.RegisterPostProcessor(func(result *types.InternalCPUCalculationResult) error {
+		size, ok := result.GetPoolEntry("reclaim", 1)
+		if !ok {
+			return fmt.Errorf("reclaim pool on numa 1 not found")
+		}
+		result.SetPoolEntry("reclaim", 1, size-1)
+		return nil
+	})
+	result, err := common.AssembleProvision()
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[int]int{
+		"reserve":     {-1: 0},
+		"share-NUMA1": {1: 4},
+		"reclaim":     {-1: 24, 1: 19},
+	}, result.PoolEntries)
+
+	// result exceeding available resource is rejected by validation
+	common.RegisterPostProcessor(func(result *types.InternalCPUCalculationResult) error {
+		result.SetPoolEntry("share-NUMA1", 1, 8)
+		return nil
+	})
+	_, err = common.AssembleProvision()
+	require.EqualError(t, err, "invalid provision after post-process: pool sizes 27 on numa 1 exceed limit 24")
+}
+
+type negativeReclaimMetricEmitter struct {
+	metrics.DummyMetrics
+	count int64
+}
+
+func (e *negativeReclaimMetricEmitter) StoreInt64(key string, val int64, _ metrics.MetricTypeName, _ ...metrics.MetricTag) error {
+	if key == metricProvisionReclaimPoolSizeNegative {
+		e.count += val
+	}
+	return nil
+}
+
+func TestAssembleProvisionWithNegativeReclaimPoolSize(t *testing.T) {
+	t.Parallel()
+
+	conf := generateTestConf(t, true)
+
+	genericCtx, err := katalyst_base.GenerateFakeGenericContext([]runtime.Object{})
+	require.NoError(t, err)
+
+	metaServer, err := metaserver.NewMetaServer(genericCtx.Client, metrics.DummyMetrics{}, conf)
+	require.NoError(t, err)
+	defer func() {
+		os.RemoveAll(conf.GenericSysAdvisorConfiguration.StateFileDirectory)
+		os.RemoveAll(conf.MetaServerConfiguration.CheckpointManagerDir)
+	}()
+
+	metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}))
+	require.NoError(t, err)
+
+	// normalization fails since available resource can't be split among share pools,
+	// so that each share pool falls back to the whole available resource
+	regionMap := map[string]region.QoSRegion{}
+	for _, name := range []string{"share-a", "share-b", "share-c"} {
+		r := NewFakeRegion(name, types.QoSRegionTypeShare, name)
+		r.SetBindingNumas(machine.NewCPUSet(0))
+		r.SetProvision(types.ControlKnob{types.ControlKnobNonReclaimedCPUSize: {Value: 4}})
+		regionMap[name] = r
+	}
+	reservedForReclaim := map[int]int{0: 1}
+	numaAvailable := map[int]int{0: 1}
+	nonBindingNumas := machine.NewCPUSet(0)
+
+	emitter := &negativeReclaimMetricEmitter{}
+	common := NewProvisionAssemblerCommon(conf, nil, &regionMap, &reservedForReclaim, &numaAvailable, &nonBindingNumas, metaCache, metaServer, emitter)
+	result, err := common.AssembleProvision()
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[int]int{
+		"reserve": {-1: 0},
+		"share-a": {-1: 1},
+		"share-b": {-1: 1},
+		"share-c": {-1: 1},
+		"reclaim": {-1: 1},
+	}, result.PoolEntries)
+	require.Equal(t, int64(1), emitter.count)
+}
+
+func TestAssembleProvisionClampedByEssentials(t *testing.T) {
+	t.Parallel()
+
+	conf := generateTestConf(t, true)
+	conf.CPUAdvisorConfiguration.ClampProvisionByEssentials = true
+
+	genericCtx, err := katalyst_base.GenerateFakeGenericContext([]runtime.Object{})
+	require.NoError(t, err)
+
+	metaServer, err := metaserver.NewMetaServer(genericCtx.Client, metrics.DummyMetrics{}, conf)
+	require.NoError(t, err)
+	defer func() {
+		os.RemoveAll(conf.GenericSysAdvisorConfiguration.StateFileDirectory)
+		os.RemoveAll(conf.MetaServerConfiguration.CheckpointManagerDir)
+	}()
+
+	metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}))
+	require.NoError(t, err)
+
+	// policy of share region emits a requirement beyond its upper bound
+	share := NewFakeRegion("share", types.QoSRegionTypeShare, "share")
+	share.SetBindingNumas(machine.NewCPUSet(0))
+	share.SetEssentials(types.ResourceEssentials{ResourceUpperBound: 16, ResourceLowerBound: 2})
+	share.SetProvision(types.ControlKnob{types.ControlKnobNonReclaimedCPUSize: {Value: 30}})
+
+	regionMap := map[string]region.QoSRegion{share.Name(): share}
+	reservedForReclaim := map[int]int{0: 4}
+	numaAvailable := map[int]int{0: 20}
+	nonBindingNumas := machine.NewCPUSet(0)
+
+	common := NewProvisionAssemblerCommon(conf, nil, &regionMap, &reservedForReclaim, &numaAvailable, &nonBindingNumas, metaCache, metaServer, metrics.DummyMetrics{})
+	result, err := common.AssembleProvision()
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[int]int{
+		"reserve": {-1: 0},
+		"share":   {-1: 16},
+		"reclaim": {-1: 8},
+	}, result.PoolEntries)
+
+	// control knob of the region itself is left untouched
+	controlKnob, err := share.GetProvision()
+	require.NoError(t, err)
+	require.Equal(t, float64(30), controlKnob[types.ControlKnobNonReclaimedCPUSize].Value)
+}
+
+func TestAssembleProvisionDeterministicError(t *testing.T) {
+	t.Parallel()
+
+	conf := generateTestConf(t, true)
+
+	genericCtx, err := katalyst_base.GenerateFakeGenericContext([]runtime.Object{})
+	require.NoError(t, err)
+
+	metaServer, err := metaserver.NewMetaServer(genericCtx.Client, metrics.DummyMetrics{}, conf)
+	require.NoError(t, err)
+	defer func() {
+		os.RemoveAll(conf.GenericSysAdvisorConfiguration.StateFileDirectory)
+		os.RemoveAll(conf.MetaServerConfiguration.CheckpointManagerDir)
+	}()
+
+	metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}))
+	require.NoError(t, err)
+
+	regionMap := map[string]region.QoSRegion{}
+	for _, name := range []string{"share-a", "share-b", "share-c", "share-d", "share-e"} {
+		r := NewFakeRegion(name, types.QoSRegionTypeShare, name)
+		r.SetBindingNumas(machine.NewCPUSet(0))
+		r.SetProvision(types.ControlKnob{types.ControlKnobNonReclaimedCPUSize: {Value: 2}})
+		if name != "share-a" {
+			r.provisionErr = fmt.Errorf("failed to get provision of %v", name)
+		}
+		regionMap[name] = r
+	}
+	reservedForReclaim := map[int]int{0: 4}
+	numaAvailable := map[int]int{0: 20}
+	nonBindingNumas := machine.NewCPUSet(0)
+
+	common := NewProvisionAssemblerCommon(conf, nil, &regionMap, &reservedForReclaim, &numaAvailable, &nonBindingNumas, metaCache, metaServer, metrics.DummyMetrics{})
+	for i := 0; i < 10; i++ {
+		_, err = common.AssembleProvision()
+		require.EqualError(t, err, "failed to get provision of share-b")
+	}
+}
+
+func TestAssembleProvisionFromSnapshot(t *testing.T) {
+	t.Parallel()
+
+	conf := generateTestConf(t, true)
+
+	genericCtx, err := katalyst_base.GenerateFakeGenericContext([]runtime.Object{})
+	require.NoError(t, err)
+
+	metaServer, err := metaserver.NewMetaServer(genericCtx.Client, metrics.DummyMetrics{}, conf)
+	require.NoError(t, err)
+	defer func() {
+		os.RemoveAll(conf.GenericSysAdvisorConfiguration.StateFileDirectory)
+		os.RemoveAll(conf.MetaServerConfiguration.CheckpointManagerDir)
+	}()
+
+	metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}))
+	require.NoError(t, err)
+
+	share := NewFakeRegion("share", types.QoSRegionTypeShare, "share")
+	share.SetBindingNumas(machine.NewCPUSet(0))
+	share.SetProvision(types.ControlKnob{types.ControlKnobNonReclaimedCPUSize: {Value: 6}})
+
+	shareNUMA1 := NewFakeRegion("share-NUMA1", types.QoSRegionTypeShare, "share-NUMA1")
+	shareNUMA1.SetBindingNumas(machine.NewCPUSet(1))
+	shareNUMA1.SetIsNumaBinding(true)
+	shareNUMA1.SetProvision(types.ControlKnob{types.ControlKnobNonReclaimedCPUSize: {Value: 4}})
+
+	snapshot := ProvisionSnapshot{
+		RegionMap:          map[string]region.QoSRegion{share.Name(): share, shareNUMA1.Name(): shareNUMA1},
+		ReservedForReclaim: map[int]int{0: 4, 1: 4},
+		NumaAvailable:      map[int]int{0: 20, 1: 20},
+		NonBindingNumas:    machine.NewCPUSet(0),
+	}
+	replay := NewProvisionAssemblerCommonFromSnapshot(conf, snapshot, metaCache, metaServer, metrics.DummyMetrics{})
+
+	expect := map[string]map[int]int{
+		"reserve":     {-1: 0},
+		"share":       {-1: 6},
+		"share-NUMA1": {1: 4},
+		"reclaim":     {-1: 18, 1: 20},
+	}
+	result, err := replay.AssembleProvision()
+	require.NoError(t, err)
+	require.Equal(t, expect, result.PoolEntries)
+
+	// replay is not affected by changes of the recorded inputs
+	snapshot.NumaAvailable[0] = 10
+	delete(snapshot.RegionMap, share.Name())
+	result, err = replay.AssembleProvision()
+	require.NoError(t, err)
+	require.Equal(t, expect, result.PoolEntries)
+}
+
+func TestAssembleProvisionWithExtraReservedPools(t *testing.T) {
+	t.Parallel()
+
+	conf := generateTestConf(t, true)
+	conf.CPUAdvisorConfiguration.ExtraReservedPoolNames = []string{"operator-reserve", "not-exist"}
+
+	genericCtx, err := katalyst_base.GenerateFakeGenericContext([]runtime.Object{})
+	require.NoError(t, err)
+
+	metaServer, err := metaserver.NewMetaServer(genericCtx.Client, metrics.DummyMetrics{}, conf)
+	require.NoError(t, err)
+	defer func() {
+		os.RemoveAll(conf.GenericSysAdvisorConfiguration.StateFileDirectory)
+		os.RemoveAll(conf.MetaServerConfiguration.CheckpointManagerDir)
+	}()
+
+	metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}))
+	require.NoError(t, err)
+	require.NoError(t, metaCache.SetPoolInfo("operator-reserve", &types.PoolInfo{
+		PoolName: "operator-reserve",
+		TopologyAwareAssignments: map[int]machine.CPUSet{
+			0: machine.MustParse("0-3"),
+			1: machine.MustParse("24-25"),
+		},
+	}))
+
+	share := NewFakeRegion("share", types.QoSRegionTypeShare, "share")
+	share.SetBindingNumas(machine.NewCPUSet(0))
+	share.SetProvision(types.ControlKnob{types.ControlKnobNonReclaimedCPUSize: {Value: 6}})
+
+	shareNUMA1 := NewFakeRegion("share-NUMA1", types.QoSRegionTypeShare, "share-NUMA1")
+	shareNUMA1.SetBindingNumas(machine.NewCPUSet(1))
+	shareNUMA1.SetIsNumaBinding(true)
+	shareNUMA1.SetProvision(types.ControlKnob{types.ControlKnobNonReclaimedCPUSize: {Value: 4}})
+
+	snapshot := ProvisionSnapshot{
+		RegionMap:          map[string]region.QoSRegion{share.Name(): share, shareNUMA1.Name(): shareNUMA1},
+		ReservedForReclaim: map[int]int{0: 4, 1: 4},
+		NumaAvailable:      map[int]int{0: 20, 1: 20},
+		NonBindingNumas:    machine.NewCPUSet(0),
+	}
+	pa := NewProvisionAssemblerCommonFromSnapshot(conf, snapshot, metaCache, metaServer, metrics.DummyMetrics{})
+
+	// available of numa0 and numa1 shrinks by 4 and 2 respectively,
+	// and reclaim pools don't expand over the extra reserved pool
+	result, err := pa.AssembleProvision()
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[int]int{
+		"reserve":     {-1: 0},
+		"share":       {-1: 6},
+		"share-NUMA1": {1: 4},
+		"reclaim":     {-1: 14, 1: 18},
+	}, result.PoolEntries)
+}
+
+func TestAssembleProvisionWithZeroNumaAvailable(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []types.CPUZeroNumaAvailablePolicy{
+		types.CPUZeroNumaAvailablePolicyKeepLast,
+		types.CPUZeroNumaAvailablePolicyError,
+	} {
+		conf := generateTestConf(t, true)
+		conf.CPUAdvisorConfiguration.ZeroNumaAvailablePolicy = policy
+
+		genericCtx, err := katalyst_base.GenerateFakeGenericContext([]runtime.Object{})
+		require.NoError(t, err)
+
+		metaServer, err := metaserver.NewMetaServer(genericCtx.Client, metrics.DummyMetrics{}, conf)
+		require.NoError(t, err)
+
+		metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}))
+		require.NoError(t, err)
+
+		share := NewFakeRegion("share", types.QoSRegionTypeShare, "share")
+		share.SetBindingNumas(machine.NewCPUSet(0))
+		share.SetProvision(types.ControlKnob{types.ControlKnobNonReclaimedCPUSize: {Value: 6}})
+
+		shareNUMA1 := NewFakeRegion("share-NUMA1", types.QoSRegionTypeShare, "share-NUMA1")
+		shareNUMA1.SetBindingNumas(machine.NewCPUSet(1))
+		shareNUMA1.SetIsNumaBinding(true)
+		shareNUMA1.SetProvision(types.ControlKnob{types.ControlKnobNonReclaimedCPUSize: {Value: 4}})
+
+		regionMap := map[string]region.QoSRegion{share.Name(): share, shareNUMA1.Name(): shareNUMA1}
+		reservedForReclaim := map[int]int{0: 4, 1: 4}
+		numaAvailable := map[int]int{0: 20, 1: 0}
+		nonBindingNumas := machine.NewCPUSet(0)
+
+		common := NewProvisionAssemblerCommon(conf, nil, &regionMap, &reservedForReclaim, &numaAvailable, &nonBindingNumas, metaCache, metaServer, metrics.DummyMetrics{})
+
+		// no last provision to keep for the numa with zero available
+		_, err = common.AssembleProvision()
+		require.Error(t, err, "policy %v", policy)
+
+		numaAvailable[1] = 20
+		result, err := common.AssembleProvision()
+		require.NoError(t, err, "policy %v", policy)
+		expected := map[string]map[int]int{
+			"reserve":     {-1: 0},
+			"share":       {-1: 6},
+			"share-NUMA1": {1: 4},
+			"reclaim":     {-1: 18, 1: 20},
+		}
+		require.Equal(t, expected, result.PoolEntries, "policy %v", policy)
+
+		// numa1 is not populated in this cycle, while its region provision changes
+		numaAvailable[1] = 0
+		shareNUMA1.SetProvision(types.ControlKnob{types.ControlKnobNonReclaimedCPUSize: {Value: 8}})
+		result, err = common.AssembleProvision()
+		switch policy {
+		case types.CPUZeroNumaAvailablePolicyKeepLast:
+			require.NoError(t, err)
+			require.Equal(t, expected, result.PoolEntries)
+		case types.CPUZeroNumaAvailablePolicyError:
+			require.EqualError(t, err, "numa 1 has zero available resource while other numas have")
+		}
+
+		os.RemoveAll(conf.GenericSysAdvisorConfiguration.StateFileDirectory)
+		os.RemoveAll(conf.MetaServerConfiguration.CheckpointManagerDir)
+	}
+}
+
+func TestAssembleProvisionWithNUMAEnableReclaim(t *testing.T) {
+	t.Parallel()
+
+	conf := generateTestConf(t, true)
+	conf.CPUAdvisorConfiguration.NUMAEnableReclaim = map[int]bool{1: false}
+
+	genericCtx, err := katalyst_base.GenerateFakeGenericContext([]runtime.Object{})
+	require.NoError(t, err)
+
+	metaServer, err := metaserver.NewMetaServer(genericCtx.Client, metrics.DummyMetrics{}, conf)
+	require.NoError(t, err)
+	defer func() {
+		os.RemoveAll(conf.GenericSysAdvisorConfiguration.StateFileDirectory)
+		os.RemoveAll(conf.MetaServerConfiguration.CheckpointManagerDir)
+	}()
+
+	metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}))
+	require.NoError(t, err)
+
+	regionMap := map[string]region.QoSRegion{}
+	for _, numaID := range []int{0, 1} {
+		r := NewFakeRegion(fmt.Sprintf("share-NUMA%d", numaID), types.QoSRegionTypeShare, fmt.Sprintf("share-NUMA%d", numaID))
+		r.SetBindingNumas(machine.NewCPUSet(numaID))
+		r.SetIsNumaBinding(true)
+		r.SetProvision(types.ControlKnob{types.ControlKnobNonReclaimedCPUSize: {Value: 4}})
+		regionMap[r.Name()] = r
+	}
+	reservedForReclaim := map[int]int{0: 4, 1: 4}
+	numaAvailable := map[int]int{0: 20, 1: 20}
+	nonBindingNumas := machine.NewCPUSet()
+
+	common := NewProvisionAssemblerCommon(conf, nil, &regionMap, &reservedForReclaim, &numaAvailable, &nonBindingNumas, metaCache, metaServer, metrics.DummyMetrics{})
+	result, err := common.AssembleProvision()
+	require.NoError(t, err)
+
+	// reclaim is enabled on numa0 by the node-wide switch
+	require.Equal(t, 4, result.PoolEntries["share-NUMA0"][0])
+	require.Equal(t, 20, result.PoolEntries["reclaim"][0])
+
+	// reclaim is disabled on numa1 by override, so only reserved for reclaim is kept
+	require.Equal(t, 20, result.PoolEntries["share-NUMA1"][1])
+	require.Equal(t, 4, result.PoolEntries["reclaim"][1])
+
+	// override also enables reclaim on numas when it's disabled node-wide
+	conf.GetDynamicConfiguration().EnableReclaim = false
+	conf.CPUAdvisorConfiguration.NUMAEnableReclaim = map[int]bool{0: true}
+	result, err = common.AssembleProvision()
+	require.NoError(t, err)
+	require.Equal(t, 20, result.PoolEntries["reclaim"][0])
+	require.Equal(t, 4, result.PoolEntries["reclaim"][1])
+}
+
 func generateTestConf(t *testing.T, enableReclaim bool) *config.Configuration {
 	conf, err := options.NewOptions().Config()
 	require.NoError(t, err)
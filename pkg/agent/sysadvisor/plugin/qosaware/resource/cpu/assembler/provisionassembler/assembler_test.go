@@ -20,6 +20,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/kubewharf/katalyst-core/pkg/util/general"
 )
 
 func TestRegulatePoolSizes(t *testing.T) {
@@ -92,3 +94,98 @@ func TestRegulatePoolSizes(t *testing.T) {
 		})
 	}
 }
+
+func TestRegulatePoolSizesWithShareFloor(t *testing.T) {
+	t.Parallel()
+
+	sharePoolSizes := map[string]int{"share": 12, "batch": 4}
+	isolationLowerSizes := map[string]int{"isolation": 8}
+
+	tests := []struct {
+		name              string
+		available         int
+		minSharePoolSize  int
+		expectedPoolSizes map[string]int
+	}{
+		{
+			name:              "no floor",
+			available:         20,
+			minSharePoolSize:  0,
+			expectedPoolSizes: map[string]int{"share": 10, "batch": 3, "isolation": 7},
+		},
+		{
+			name:              "floor not reached",
+			available:         20,
+			minSharePoolSize:  3,
+			expectedPoolSizes: map[string]int{"share": 10, "batch": 3, "isolation": 7},
+		},
+		{
+			name:              "floor held with isolation at lower size",
+			available:         20,
+			minSharePoolSize:  4,
+			expectedPoolSizes: map[string]int{"share": 8, "batch": 4, "isolation": 8},
+		},
+		{
+			name:              "floor violated when capacity exhausted",
+			available:         14,
+			minSharePoolSize:  4,
+			expectedPoolSizes: map[string]int{"share": 7, "batch": 2, "isolation": 5},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			poolSizes := general.MergeMapInt(sharePoolSizes, isolationLowerSizes)
+			throttled := regulatePoolSizesWithShareFloor(poolSizes, sharePoolSizes, isolationLowerSizes,
+				tt.available, true, tt.minSharePoolSize)
+			assert.True(t, throttled)
+			assert.Equal(t, tt.expectedPoolSizes, poolSizes)
+		})
+	}
+}
+
+func TestCapReclaimPoolSizes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		reclaimPoolSizes  map[int]int
+		reserved          map[int]int
+		maxReclaimSize    int
+		expectedPoolSizes map[int]int
+	}{
+		{
+			name:              "not exceeding cap",
+			reclaimPoolSizes:  map[int]int{-1: 10, 1: 8},
+			reserved:          map[int]int{-1: 4, 1: 4},
+			maxReclaimSize:    20,
+			expectedPoolSizes: map[int]int{-1: 10, 1: 8},
+		},
+		{
+			name:              "shrink proportionally",
+			reclaimPoolSizes:  map[int]int{0: 24, 1: 14},
+			reserved:          map[int]int{0: 4, 1: 4},
+			maxReclaimSize:    23,
+			expectedPoolSizes: map[int]int{0: 14, 1: 9},
+		},
+		{
+			name:              "reserved honored",
+			reclaimPoolSizes:  map[int]int{0: 24, 1: 14},
+			reserved:          map[int]int{0: 4, 1: 6},
+			maxReclaimSize:    8,
+			expectedPoolSizes: map[int]int{0: 4, 1: 6},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			capReclaimPoolSizes(tt.reclaimPoolSizes, tt.reserved, tt.maxReclaimSize)
+			assert.Equal(t, tt.expectedPoolSizes, tt.reclaimPoolSizes)
+		})
+	}
+}
@@ -19,11 +19,64 @@ package provisionassembler
 import (
 	"fmt"
 	"math"
+	"sort"
 
+	"k8s.io/klog/v2"
+
+	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/plugin/qosaware/resource/cpu/region"
+	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/types"
 	"github.com/kubewharf/katalyst-core/pkg/util/general"
 	"github.com/kubewharf/katalyst-core/pkg/util/machine"
 )
 
+// sortedRegions returns regions sorted by name, to make iteration over regions deterministic
+func sortedRegions(regionMap map[string]region.QoSRegion) []region.QoSRegion {
+	regions := make([]region.QoSRegion, 0, len(regionMap))
+	for _, r := range regionMap {
+		regions = append(regions, r)
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].Name() < regions[j].Name()
+	})
+	return regions
+}
+
+// checkBindingNumasAvailable makes sure all binding numas of the region are known in numaAvailable,
+// otherwise pool sizes of the region would be calculated with zero available resource silently
+func checkBindingNumasAvailable(numaAvailable map[int]int, r region.QoSRegion) error {
+	for _, numaID := range r.GetBindingNumas().ToSliceInt() {
+		if _, ok := numaAvailable[numaID]; !ok {
+			return fmt.Errorf("binding numa %v of region %v not found in available numas", numaID, r.Name())
+		}
+	}
+	return nil
+}
+
+// clampControlKnobByEssentials returns a copy of control knob with non-reclaimed cpu size clamped into
+// resource bounds of the region essentials, bounds are ignored if upper bound is not set
+func clampControlKnobByEssentials(r region.QoSRegion, controlKnob types.ControlKnob) types.ControlKnob {
+	essentials := r.GetEssentials()
+	knob, ok := controlKnob[types.ControlKnobNonReclaimedCPUSize]
+	if !ok || essentials.ResourceUpperBound <= 0 {
+		return controlKnob
+	}
+
+	clamped := general.Clamp(knob.Value, essentials.ResourceLowerBound, essentials.ResourceUpperBound)
+	if clamped == knob.Value {
+		return controlKnob
+	}
+	klog.Warningf("clamp %v of region %v from %v to %v, bounds [%v, %v]", types.ControlKnobNonReclaimedCPUSize,
+		r.Name(), knob.Value, clamped, essentials.ResourceLowerBound, essentials.ResourceUpperBound)
+
+	res := make(types.ControlKnob, len(controlKnob))
+	for name, value := range controlKnob {
+		res[name] = value
+	}
+	knob.Value = clamped
+	res[types.ControlKnobNonReclaimedCPUSize] = knob
+	return res
+}
+
 func getNumasAvailableResource(numaAvailable map[int]int, numas machine.CPUSet) int {
 	res := 0
 	for _, numaID := range numas.ToSliceInt() {
@@ -61,6 +114,76 @@ func regulatePoolSizes(poolSizes map[string]int, available int, enableReclaim bo
 	return throttled
 }
 
+// regulatePoolSizesWithShareFloor regulates pool sizes as regulatePoolSizes does, while keeping each
+// share pool no smaller than min(its requirement, minSharePoolSize) by shrinking isolation pools to their
+// lower sizes first. the floor is only violated if share floors and isolation lower sizes can't fit in
+// available resource.
+func regulatePoolSizesWithShareFloor(poolSizes, sharePoolSizes, isolationLowerSizes map[string]int,
+	available int, enableReclaim bool, minSharePoolSize int,
+) bool {
+	requirements := general.MergeMapInt(poolSizes, nil)
+	throttled := regulatePoolSizes(poolSizes, available, enableReclaim)
+	if minSharePoolSize <= 0 {
+		return throttled
+	}
+
+	// base sizes are share floors and isolation lower sizes, and the excess parts of requirements
+	// beyond them are regulated by the rest available resource
+	baseSizes := make(map[string]int)
+	excessSizes := make(map[string]int)
+	violated := false
+	for poolName, requirement := range requirements {
+		base := 0
+		if _, ok := sharePoolSizes[poolName]; ok {
+			base = general.Min(requirement, minSharePoolSize)
+			violated = violated || poolSizes[poolName] < base
+		} else if lower, ok := isolationLowerSizes[poolName]; ok {
+			base = general.Min(requirement, lower)
+		}
+		baseSizes[poolName] = base
+		excessSizes[poolName] = requirement - base
+	}
+	if !violated {
+		return throttled
+	}
+
+	rest := available - general.SumUpMapValues(baseSizes)
+	if rest < 0 {
+		klog.Warningf("share pool floor %v is violated: pool requirements %v exceed available %v",
+			minSharePoolSize, requirements, available)
+		return throttled
+	}
+
+	excessSum := general.SumUpMapValues(excessSizes)
+	if excessSum > 0 && (excessSum > rest || !enableReclaim) {
+		if err := normalizePoolSizes(excessSizes, rest); err != nil {
+			for poolName := range excessSizes {
+				excessSizes[poolName] = 0
+			}
+		}
+	}
+	for poolName := range poolSizes {
+		poolSizes[poolName] = baseSizes[poolName] + excessSizes[poolName]
+	}
+	return throttled
+}
+
+// getThrottleReason derives the reason why share pool is throttled, based on its requirement,
+// requirement of isolation pools sharing the same resource, and the available resource which
+// has excluded the part reserved for reclaim.
+func getThrottleReason(throttled bool, shareRequirement, isolationRequirement, available, reservedForReclaim int) types.ThrottleReason {
+	if !throttled {
+		return types.ThrottleReasonNone
+	}
+
+	if isolationRequirement > 0 && shareRequirement < available {
+		return types.ThrottleReasonIsolationPressure
+	} else if shareRequirement < available+reservedForReclaim {
+		return types.ThrottleReasonReserve
+	}
+	return types.ThrottleReasonCapacity
+}
+
 func normalizePoolSizes(poolSizes map[string]int, targetSum int) error {
 	sum := general.SumUpMapValues(poolSizes)
 	if sum == targetSum {
@@ -126,3 +249,50 @@ func selectPoolHelper(poolSizesOriginal, poolSizesNormalized map[string]int) str
 	}
 	return selected
 }
+
+// capReclaimPoolSizes shrinks reclaim pool sizes (keyed by numa id) proportionally to the
+// part exceeding reserved values, to make sure their sum doesn't exceed maxReclaimSize.
+// reserved values are always honored even if their sum exceeds maxReclaimSize.
+func capReclaimPoolSizes(reclaimPoolSizes map[int]int, reserved map[int]int, maxReclaimSize int) {
+	sum, shrinkableSum := 0, 0
+	shrinkable := make(map[int]int)
+	for numaID, size := range reclaimPoolSizes {
+		sum += size
+		shrinkable[numaID] = general.Max(size-reserved[numaID], 0)
+		shrinkableSum += shrinkable[numaID]
+	}
+
+	excess := sum - maxReclaimSize
+	if excess <= 0 {
+		return
+	}
+
+	if excess >= shrinkableSum {
+		for numaID := range reclaimPoolSizes {
+			reclaimPoolSizes[numaID] -= shrinkable[numaID]
+		}
+		return
+	}
+
+	// shrink by the largest remainder method to keep the sum exactly
+	numaIDs := make([]int, 0, len(shrinkable))
+	remainders := make(map[int]float64)
+	reduced := 0
+	for numaID, size := range shrinkable {
+		value := float64(excess*size) / float64(shrinkableSum)
+		reduction := int(math.Floor(value))
+		reclaimPoolSizes[numaID] -= reduction
+		reduced += reduction
+		remainders[numaID] = value - float64(reduction)
+		numaIDs = append(numaIDs, numaID)
+	}
+	sort.Slice(numaIDs, func(i, j int) bool {
+		if remainders[numaIDs[i]] != remainders[numaIDs[j]] {
+			return remainders[numaIDs[i]] > remainders[numaIDs[j]]
+		}
+		return numaIDs[i] < numaIDs[j]
+	})
+	for i := 0; i < excess-reduced && i < len(numaIDs); i++ {
+		reclaimPoolSizes[numaIDs[i]] -= 1
+	}
+}
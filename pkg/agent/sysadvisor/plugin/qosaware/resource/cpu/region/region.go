@@ -47,9 +47,13 @@ type QoSRegion interface {
 	SetBindingNumas(machine.CPUSet)
 	// SetEssentials updates essential region values for policy update
 	SetEssentials(essentials types.ResourceEssentials)
+	// GetEssentials returns essential region values set by advisor
+	GetEssentials() types.ResourceEssentials
 
 	IsNumaBinding() bool
 	SetThrottled(throttled bool)
+	// SetThrottleReason updates the reason why this region is throttled
+	SetThrottleReason(reason types.ThrottleReason)
 
 	// AddContainer stores a container keyed by pod uid and container name to region
 	AddContainer(ci *types.ContainerInfo) error
@@ -67,6 +71,8 @@ type QoSRegion interface {
 	GetHeadroom() (float64, error)
 
 	IsThrottled() bool
+	// GetThrottleReason returns the reason why this region is throttled
+	GetThrottleReason() types.ThrottleReason
 
 	// GetProvisionPolicy returns provision policy for this region,
 	// the first is policy with top priority, while the second is the policy that is in-use currently
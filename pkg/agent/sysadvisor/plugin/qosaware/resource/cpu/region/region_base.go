@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"math"
 	"sync"
+	"time"
 
 	"go.uber.org/atomic"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -44,6 +45,7 @@ import (
 const (
 	metricCPUGetHeadroomFailed             = "get_cpu_headroom_failed"
 	metricCPUGetProvisionFailed            = "get_cpu_provision_failed"
+	metricCPUProvisionFallback             = "cpu_provision_fallback"
 	metricRegionHeadroom                   = "region_headroom"
 	metricCPUProvisionControlKnobRaw       = "cpu_provision_control_knob_raw"
 	metricCPUProvisionControlKnobRegulated = "cpu_provision_control_knob_regulated"
@@ -172,6 +174,11 @@ type QoSRegionBase struct {
 	provisionPolicyNameInUse types.CPUProvisionPolicyName
 	provisionPolicyResults   map[types.CPUProvisionPolicyName]*provisionPolicyResult
 
+	// provisionFailedTimes records consecutive failures of GetProvision; once it reaches
+	// the fallback threshold, region falls back to none policy until provisionFallbackUntil
+	provisionFailedTimes   int
+	provisionFallbackUntil time.Time
+
 	// headroomPolicies for comparing and merging different headroom policy results,
 	// the former has higher priority; headroomPolicyNameInUse indicates the headroom
 	// policy in-use currently
@@ -192,6 +199,8 @@ type QoSRegionBase struct {
 
 	// throttled: true if unable to reach the ResourceUpperBound due to competition for resources with other regions.
 	throttled atomic.Bool
+	// throttleReason: reason why the region is throttled, which is derived by provision assembler
+	throttleReason atomic.String
 
 	// idle: true if containers in the region is not running as usual, maybe there is no incoming business traffic
 	idle atomic.Bool
@@ -227,6 +236,7 @@ func NewQoSRegionBase(name string, ownerPoolName string, regionType types.QoSReg
 
 		enableBorweinModel: conf.PolicyRama.EnableBorwein,
 		throttled:          *atomic.NewBool(false),
+		throttleReason:     *atomic.NewString(string(types.ThrottleReasonNone)),
 		idle:               *atomic.NewBool(false),
 
 		isNumaBinding: isNumaBinding,
@@ -307,10 +317,21 @@ func (r *QoSRegionBase) SetEssentials(essentials types.ResourceEssentials) {
 	r.ResourceEssentials = essentials
 }
 
+func (r *QoSRegionBase) GetEssentials() types.ResourceEssentials {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.ResourceEssentials
+}
+
 func (r *QoSRegionBase) SetThrottled(throttled bool) {
 	r.throttled.Store(throttled)
 }
 
+func (r *QoSRegionBase) SetThrottleReason(reason types.ThrottleReason) {
+	r.throttleReason.Store(string(reason))
+}
+
 func (r *QoSRegionBase) IsNumaBinding() bool {
 	return r.isNumaBinding
 }
@@ -366,6 +387,11 @@ func (r *QoSRegionBase) GetProvision() (types.ControlKnob, error) {
 	oldProvisionPolicyNameInUse := r.provisionPolicyNameInUse
 	r.provisionPolicyNameInUse = types.CPUProvisionPolicyNone
 
+	now := time.Now()
+	if now.Before(r.provisionFallbackUntil) {
+		return r.getFallbackControlKnob(), nil
+	}
+
 	for _, internal := range r.provisionPolicies {
 		if internal.updateStatus != types.PolicyUpdateSucceeded {
 			_ = r.emitter.StoreInt64(metricCPUGetProvisionFailed, 1, metrics.MetricTypeNameRaw,
@@ -388,12 +414,46 @@ func (r *QoSRegionBase) GetProvision() (types.ControlKnob, error) {
 			}
 		}
 
+		r.provisionFailedTimes = 0
 		return result.getControlKnob(), nil
 	}
 
+	// fall back to none policy if configured provision policies keep failing, and retry them after cooldown;
+	// failed times is not reset when cooldown expires, so that a single failure afterward falls back again
+	r.provisionFailedTimes++
+	threshold := r.conf.ProvisionFallbackFailedThreshold
+	if threshold > 0 && r.provisionFailedTimes >= threshold {
+		r.provisionFallbackUntil = now.Add(r.conf.ProvisionFallbackCooldown)
+		klog.Warningf("[qosaware-cpu] region: %v failed to get provision for %v times, fall back to %v policy until %v",
+			r.Name(), r.provisionFailedTimes, types.CPUProvisionPolicyNone, r.provisionFallbackUntil)
+		return r.getFallbackControlKnob(), nil
+	}
+
 	return types.ControlKnob{}, fmt.Errorf("failed to get legal provision")
 }
 
+// getFallbackControlKnob returns control knob which keeps all resources of the region from being reclaimed,
+// it is used when the region falls back to none policy
+func (r *QoSRegionBase) getFallbackControlKnob() types.ControlKnob {
+	_ = r.emitter.StoreInt64(metricCPUProvisionFallback, 1, metrics.MetricTypeNameRaw,
+		metrics.MetricTag{Key: metricTagKeyRegionType, Val: string(r.regionType)},
+		metrics.MetricTag{Key: metricTagKeyRegionName, Val: r.name})
+
+	value := types.ControlKnobValue{
+		Value:  r.ResourceUpperBound,
+		Action: types.ControlKnobActionNone,
+	}
+	if r.regionType == types.QoSRegionTypeIsolation {
+		return types.ControlKnob{
+			types.ControlKnobNonReclaimedCPUSizeUpper: value,
+			types.ControlKnobNonReclaimedCPUSizeLower: value,
+		}
+	}
+	return types.ControlKnob{
+		types.ControlKnobNonReclaimedCPUSize: value,
+	}
+}
+
 func (r *QoSRegionBase) GetHeadroom() (float64, error) {
 	r.Lock()
 	defer r.Unlock()
@@ -522,6 +582,18 @@ func (r *QoSRegionBase) initProvisionPolicy(conf *config.Configuration, extraCon
 	// try new policies
 	// todo move to separate functions
 	initializers := provisionpolicy.GetRegisteredInitializers()
+
+	// share pool may override the default provision policy of its region type
+	if r.regionType == types.QoSRegionTypeShare {
+		if policyName, ok := conf.CPUAdvisorConfiguration.PoolProvisionPolicies[r.ownerPoolName]; ok {
+			if _, ok := initializers[policyName]; ok {
+				configuredProvisionPolicy = []types.CPUProvisionPolicyName{policyName}
+			} else {
+				klog.Warningf("[qosaware-cpu] unknown provision policy %v for pool %v, fall back to default policies %v",
+					policyName, r.ownerPoolName, configuredProvisionPolicy)
+			}
+		}
+	}
 	for _, policyName := range configuredProvisionPolicy {
 		if initializer, ok := initializers[policyName]; ok {
 			policy := initializer(r.name, r.regionType, r.ownerPoolName, conf, extraConf, metaReader, metaServer, emitter)
@@ -809,6 +881,10 @@ func (r *QoSRegionBase) IsThrottled() bool {
 	return r.throttled.Load()
 }
 
+func (r *QoSRegionBase) GetThrottleReason() types.ThrottleReason {
+	return types.ThrottleReason(r.throttleReason.Load())
+}
+
 func (r *QoSRegionBase) IsIdle() bool {
 	return r.idle.Load()
 }
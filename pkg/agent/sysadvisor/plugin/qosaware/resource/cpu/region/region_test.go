@@ -19,6 +19,7 @@ package region
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -30,6 +31,8 @@ import (
 	"github.com/kubewharf/katalyst-core/cmd/katalyst-agent/app/options"
 	"github.com/kubewharf/katalyst-core/pkg/agent/qrm-plugins/cpu/dynamicpolicy/state"
 	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/metacache"
+	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/plugin/qosaware/resource/cpu/region/provisionpolicy"
+	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/plugin/qosaware/resource/cpu/region/regulator"
 	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/types"
 	"github.com/kubewharf/katalyst-core/pkg/metaserver"
 	"github.com/kubewharf/katalyst-core/pkg/metaserver/agent/metric"
@@ -198,3 +201,54 @@ func TestIsNumaBinding(t *testing.T) {
 	isolation2 := NewQoSRegionIsolation(&ci4, "isolation-1", conf, nil, state.FakedNUMAID, metaCache, metaServer, metrics.DummyMetrics{})
 	require.False(t, isolation2.IsNumaBinding(), "test IsNumaBinding failed")
 }
+
+func TestGetProvisionFallback(t *testing.T) {
+	t.Parallel()
+
+	conf, err := options.NewOptions().Config()
+	require.NoError(t, err)
+	conf.GenericSysAdvisorConfiguration.StateFileDirectory = t.TempDir()
+	conf.ProvisionFallbackFailedThreshold = 3
+	conf.ProvisionFallbackCooldown = time.Minute
+
+	metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metric.NewFakeMetricsFetcher(metrics.DummyMetrics{}))
+	require.NoError(t, err)
+
+	r := NewQoSRegionBase("share", "share", types.QoSRegionTypeShare, conf, nil, false, metaCache, nil, metrics.DummyMetrics{})
+	r.SetEssentials(types.ResourceEssentials{ResourceUpperBound: 40, EnableReclaim: true})
+
+	// no provision policy succeeds, region falls back after consecutive failures
+	for i := 0; i < 2; i++ {
+		_, err = r.GetProvision()
+		require.Error(t, err)
+	}
+	controlKnob, err := r.GetProvision()
+	require.NoError(t, err)
+	require.Equal(t, 40.0, controlKnob[types.ControlKnobNonReclaimedCPUSize].Value)
+	_, policyInUse := r.GetProvisionPolicy()
+	require.Equal(t, types.CPUProvisionPolicyNone, policyInUse)
+
+	// configured policy recovers, but region keeps fallback until cooldown expires
+	reg := regulator.NewDummyRegulator()
+	reg.SetLatestRequirement(20)
+	result := newProvisionPolicyResult(r.ResourceEssentials)
+	result.controlKnobValueRegulators[types.ControlKnobNonReclaimedCPUSize] = reg
+	r.provisionPolicies = append(r.provisionPolicies, &internalProvisionPolicy{
+		name:                types.CPUProvisionPolicyCanonical,
+		policy:              provisionpolicy.NewPolicyNone("", "", "", nil, nil, nil, nil, nil),
+		internalPolicyState: internalPolicyState{updateStatus: types.PolicyUpdateSucceeded},
+	})
+	r.provisionPolicyResults[types.CPUProvisionPolicyCanonical] = result
+
+	controlKnob, err = r.GetProvision()
+	require.NoError(t, err)
+	require.Equal(t, 40.0, controlKnob[types.ControlKnobNonReclaimedCPUSize].Value)
+
+	r.provisionFallbackUntil = time.Now().Add(-time.Second)
+	controlKnob, err = r.GetProvision()
+	require.NoError(t, err)
+	require.Equal(t, 20.0, controlKnob[types.ControlKnobNonReclaimedCPUSize].Value)
+	_, policyInUse = r.GetProvisionPolicy()
+	require.Equal(t, types.CPUProvisionPolicyCanonical, policyInUse)
+	require.Equal(t, 0, r.provisionFailedTimes)
+}
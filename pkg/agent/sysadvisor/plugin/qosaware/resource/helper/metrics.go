@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The Katalyst Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helper
+
+import (
+	"sync"
+
+	"github.com/kubewharf/katalyst-core/pkg/metrics"
+	"github.com/kubewharf/katalyst-core/pkg/util/cgroup/common"
+)
+
+const (
+	MetricTagKeyCgroupVersion = "cgroup_version"
+
+	cgroupVersionV1 = "v1"
+	cgroupVersionV2 = "v2"
+)
+
+var (
+	cgroupVersionOnce sync.Once
+	cgroupVersion     string
+)
+
+// GetCgroupVersion returns the cgroup version of the node, which is only derived once
+func GetCgroupVersion() string {
+	cgroupVersionOnce.Do(func() {
+		cgroupVersion = cgroupVersionV1
+		if common.CheckCgroup2UnifiedMode() {
+			cgroupVersion = cgroupVersionV2
+		}
+	})
+	return cgroupVersion
+}
+
+// cgroupVersionTaggedEmitter attaches the cgroup version tag to all metrics emitted,
+// since advisor behaves differently between cgroup v1 and v2
+type cgroupVersionTaggedEmitter struct {
+	metrics.MetricEmitter
+	tag metrics.MetricTag
+}
+
+// WithCgroupVersionTag wraps the emitter to tag all metrics with the cgroup version of the node
+func WithCgroupVersionTag(emitter metrics.MetricEmitter) metrics.MetricEmitter {
+	if _, ok := emitter.(*cgroupVersionTaggedEmitter); ok {
+		return emitter
+	}
+	return &cgroupVersionTaggedEmitter{
+		MetricEmitter: emitter,
+		tag:           metrics.MetricTag{Key: MetricTagKeyCgroupVersion, Val: GetCgroupVersion()},
+	}
+}
+
+func (e *cgroupVersionTaggedEmitter) StoreInt64(key string, val int64, emitType metrics.MetricTypeName, tags ...metrics.MetricTag) error {
+	return e.MetricEmitter.StoreInt64(key, val, emitType, append(tags, e.tag)...)
+}
+
+func (e *cgroupVersionTaggedEmitter) StoreFloat64(key string, val float64, emitType metrics.MetricTypeName, tags ...metrics.MetricTag) error {
+	return e.MetricEmitter.StoreFloat64(key, val, emitType, append(tags, e.tag)...)
+}
+
+func (e *cgroupVersionTaggedEmitter) WithTags(unit string, commonTags ...metrics.MetricTag) metrics.MetricEmitter {
+	return WithCgroupVersionTag(e.MetricEmitter.WithTags(unit, commonTags...))
+}
@@ -441,6 +441,7 @@ func TestUpdate(t *testing.T) {
 		cgroupMetrics        []cgroupMetric
 		cgroupNUMAMetrics    []cgroupNUMAMetric
 		metricsFetcherSynced *bool
+		excludedAnnotations  map[string]string
 		wantAdviceResult     types.InternalMemoryCalculationResult
 	}{
 		{
@@ -640,6 +641,199 @@ func TestUpdate(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "node pressure drop cache with excluded pod",
+			pools: map[string]*types.PoolInfo{
+				state.PoolNameReserve: {
+					PoolName: state.PoolNameReserve,
+					TopologyAwareAssignments: map[int]machine.CPUSet{
+						0: machine.MustParse("0"),
+						1: machine.MustParse("24"),
+					},
+					OriginalTopologyAwareAssignments: map[int]machine.CPUSet{
+						0: machine.MustParse("0"),
+						1: machine.MustParse("24"),
+					},
+				},
+			},
+			reclaimedEnable: false,
+			needRecvAdvices: true,
+			containers: []*types.ContainerInfo{
+				makeContainerInfo("uid1", "default", "pod1", "c1", consts.PodAnnotationQoSLevelReclaimedCores, nil,
+					map[int]machine.CPUSet{
+						0: machine.MustParse("1"),
+						1: machine.MustParse("25"),
+					}, 200<<30),
+				makeContainerInfo("uid2", "default", "pod2", "c2", consts.PodAnnotationQoSLevelReclaimedCores, nil,
+					map[int]machine.CPUSet{
+						0: machine.MustParse("1"),
+						1: machine.MustParse("25"),
+					}, 200<<30),
+				makeContainerInfo("uid3", "default", "pod3", "c3", consts.PodAnnotationQoSLevelReclaimedCores, nil,
+					map[int]machine.CPUSet{
+						0: machine.MustParse("1"),
+						1: machine.MustParse("25"),
+					}, 200<<30),
+			},
+			pods: []*v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "pod1",
+						Namespace:   "default",
+						UID:         "uid1",
+						Annotations: map[string]string{"cache-reaper-excluded": "true"},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "pod2",
+						Namespace: "default",
+						UID:       "uid2",
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "pod3",
+						Namespace: "default",
+						UID:       "uid3",
+					},
+				},
+			},
+			excludedAnnotations: map[string]string{"cache-reaper-excluded": "true"},
+			wantHeadroom:        *resource.NewQuantity(996<<30, resource.DecimalSI),
+			nodeMetrics:         dropCacheNodeMetrics,
+			numaMetrics:         defaultNumaMetrics,
+			containerMetrics: []containerMetric{
+				{
+					metricName:    coreconsts.MetricMemCacheContainer,
+					metricValue:   metricutil.MetricData{Value: 60 << 30},
+					podUID:        "uid1",
+					containerName: "c1",
+				},
+				{
+					metricName:    coreconsts.MetricMemCacheContainer,
+					metricValue:   metricutil.MetricData{Value: 10 << 30},
+					podUID:        "uid2",
+					containerName: "c2",
+				},
+				{
+					metricName:    coreconsts.MetricMemCacheContainer,
+					metricValue:   metricutil.MetricData{Value: 20 << 30},
+					podUID:        "uid3",
+					containerName: "c3",
+				},
+			},
+			wantAdviceResult: types.InternalMemoryCalculationResult{
+				ContainerEntries: []types.ContainerMemoryAdvices{
+					{
+						PodUID:        "uid2",
+						ContainerName: "c2",
+						Values:        map[string]string{string(memoryadvisor.ControlKnobKeyDropCache): "true"},
+					},
+					{
+						PodUID:        "uid3",
+						ContainerName: "c3",
+						Values:        map[string]string{string(memoryadvisor.ControlKnobKeyDropCache): "true"},
+					},
+				},
+			},
+		},
+		{
+			name: "node pressure drop cache of pods with equal cache",
+			pools: map[string]*types.PoolInfo{
+				state.PoolNameReserve: {
+					PoolName: state.PoolNameReserve,
+					TopologyAwareAssignments: map[int]machine.CPUSet{
+						0: machine.MustParse("0"),
+						1: machine.MustParse("24"),
+					},
+					OriginalTopologyAwareAssignments: map[int]machine.CPUSet{
+						0: machine.MustParse("0"),
+						1: machine.MustParse("24"),
+					},
+				},
+			},
+			reclaimedEnable: false,
+			needRecvAdvices: true,
+			containers: []*types.ContainerInfo{
+				makeContainerInfo("uid1", "default", "pod1", "c1", consts.PodAnnotationQoSLevelReclaimedCores, nil,
+					map[int]machine.CPUSet{
+						0: machine.MustParse("1"),
+						1: machine.MustParse("25"),
+					}, 200<<30),
+				makeContainerInfo("uid2", "default", "pod2", "c2", consts.PodAnnotationQoSLevelReclaimedCores, nil,
+					map[int]machine.CPUSet{
+						0: machine.MustParse("1"),
+						1: machine.MustParse("25"),
+					}, 200<<30),
+				makeContainerInfo("uid3", "default", "pod3", "c3", consts.PodAnnotationQoSLevelReclaimedCores, nil,
+					map[int]machine.CPUSet{
+						0: machine.MustParse("1"),
+						1: machine.MustParse("25"),
+					}, 200<<30),
+			},
+			pods: []*v1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "pod1",
+						Namespace: "default",
+						UID:       "uid1",
+					},
+					Spec: v1.PodSpec{
+						Priority: pointer.Int32(100),
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "pod2",
+						Namespace: "default",
+						UID:       "uid2",
+					},
+					Spec: v1.PodSpec{
+						Priority: pointer.Int32(10),
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "pod3",
+						Namespace: "default",
+						UID:       "uid3",
+					},
+				},
+			},
+			wantHeadroom: *resource.NewQuantity(996<<30, resource.DecimalSI),
+			nodeMetrics:  dropCacheNodeMetrics,
+			numaMetrics:  defaultNumaMetrics,
+			containerMetrics: []containerMetric{
+				{
+					metricName:    coreconsts.MetricMemCacheContainer,
+					metricValue:   metricutil.MetricData{Value: 80 << 30},
+					podUID:        "uid1",
+					containerName: "c1",
+				},
+				{
+					metricName:    coreconsts.MetricMemCacheContainer,
+					metricValue:   metricutil.MetricData{Value: 80 << 30},
+					podUID:        "uid2",
+					containerName: "c2",
+				},
+				{
+					metricName:    coreconsts.MetricMemCacheContainer,
+					metricValue:   metricutil.MetricData{Value: 20 << 30},
+					podUID:        "uid3",
+					containerName: "c3",
+				},
+			},
+			wantAdviceResult: types.InternalMemoryCalculationResult{
+				ContainerEntries: []types.ContainerMemoryAdvices{
+					{
+						PodUID:        "uid2",
+						ContainerName: "c2",
+						Values:        map[string]string{string(memoryadvisor.ControlKnobKeyDropCache): "true"},
+					},
+				},
+			},
+		},
 		{
 			name: "numa0 pressure drop cache",
 			pools: map[string]*types.PoolInfo{
@@ -736,6 +930,78 @@ func TestUpdate(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "numa0 pressure drop cache(fallback to container cache)",
+			pools: map[string]*types.PoolInfo{
+				state.PoolNameReserve: {
+					PoolName: state.PoolNameReserve,
+					TopologyAwareAssignments: map[int]machine.CPUSet{
+						0: machine.MustParse("0"),
+						1: machine.MustParse("24"),
+					},
+					OriginalTopologyAwareAssignments: map[int]machine.CPUSet{
+						0: machine.MustParse("0"),
+						1: machine.MustParse("24"),
+					},
+				},
+			},
+			reclaimedEnable: false,
+			needRecvAdvices: true,
+			containers: []*types.ContainerInfo{
+				makeContainerInfo("uid1", "default", "pod1", "c1", consts.PodAnnotationQoSLevelReclaimedCores, nil,
+					map[int]machine.CPUSet{
+						0: machine.MustParse("1"),
+						1: machine.MustParse("25"),
+					}, 200<<30),
+				makeContainerInfo("uid2", "default", "pod2", "c2", consts.PodAnnotationQoSLevelReclaimedCores, nil,
+					map[int]machine.CPUSet{
+						0: machine.MustParse("1"),
+						1: machine.MustParse("25"),
+					}, 200<<30),
+				makeContainerInfo("uid3", "default", "pod3", "c3", consts.PodAnnotationQoSLevelReclaimedCores, nil,
+					map[int]machine.CPUSet{
+						0: machine.MustParse("1"),
+						1: machine.MustParse("25"),
+					}, 200<<30),
+			},
+			wantHeadroom: *resource.NewQuantity(996<<30, resource.DecimalSI),
+			nodeMetrics:  defaultNodeMetrics,
+			numaMetrics:  dropCacheNUMAMetrics,
+			containerMetrics: []containerMetric{
+				{
+					metricName:    coreconsts.MetricMemCacheContainer,
+					metricValue:   metricutil.MetricData{Value: 20 << 20},
+					podUID:        "uid1",
+					containerName: "c1",
+				},
+				{
+					metricName:    coreconsts.MetricMemCacheContainer,
+					metricValue:   metricutil.MetricData{Value: 18 << 30},
+					podUID:        "uid2",
+					containerName: "c2",
+				},
+				{
+					metricName:    coreconsts.MetricMemCacheContainer,
+					metricValue:   metricutil.MetricData{Value: 4 << 30},
+					podUID:        "uid3",
+					containerName: "c3",
+				},
+			},
+			wantAdviceResult: types.InternalMemoryCalculationResult{
+				ContainerEntries: []types.ContainerMemoryAdvices{
+					{
+						PodUID:        "uid3",
+						ContainerName: "c3",
+						Values:        map[string]string{string(memoryadvisor.ControlKnobKeyDropCache): "true"},
+					},
+					{
+						PodUID:        "uid2",
+						ContainerName: "c2",
+						Values:        map[string]string{string(memoryadvisor.ControlKnobKeyDropCache): "true"},
+					},
+				},
+			},
+		},
 		{
 			name: "set reclaimed group memory limit(succeeded)",
 			pools: map[string]*types.PoolInfo{
@@ -2223,6 +2489,9 @@ func TestUpdate(t *testing.T) {
 
 			advisor, metaCache := newTestMemoryAdvisor(t, tt.pods, ckDir, sfDir, fetcher, tt.plugins)
 			advisor.conf.GetDynamicConfiguration().EnableReclaim = tt.reclaimedEnable
+			if tt.excludedAnnotations != nil {
+				advisor.conf.ExcludedPodAnnotations = tt.excludedAnnotations
+			}
 			transparentMemoryOffloadingConfiguration := tmo.NewTransparentMemoryOffloadingConfiguration()
 			transparentMemoryOffloadingConfiguration.QoSLevelConfigs[consts.QoSLevelReclaimedCores] = tmo.NewTMOConfigDetail(transparentMemoryOffloadingConfiguration.DefaultConfigurations)
 			transparentMemoryOffloadingConfiguration.QoSLevelConfigs[consts.QoSLevelReclaimedCores].EnableTMO = true
@@ -2278,3 +2547,181 @@ func TestUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestGetDropCacheIntensity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		targetReclaimed float64
+		cache           float64
+		wantIntensity   memoryadvisor.DropCacheIntensity
+		wantBytes       int64
+	}{
+		{
+			name:            "small target",
+			targetReclaimed: 1 << 30,
+			cache:           8 << 30,
+			wantIntensity:   memoryadvisor.DropCacheIntensityLight,
+			wantBytes:       2 << 30,
+		},
+		{
+			name:            "medium target",
+			targetReclaimed: 3 << 30,
+			cache:           8 << 30,
+			wantIntensity:   memoryadvisor.DropCacheIntensityMedium,
+			wantBytes:       4 << 30,
+		},
+		{
+			name:            "large target",
+			targetReclaimed: 6 << 30,
+			cache:           8 << 30,
+			wantIntensity:   memoryadvisor.DropCacheIntensityFull,
+			wantBytes:       0,
+		},
+		{
+			name:            "target exceeds cache",
+			targetReclaimed: 16 << 30,
+			cache:           8 << 30,
+			wantIntensity:   memoryadvisor.DropCacheIntensityFull,
+			wantBytes:       0,
+		},
+		{
+			name:            "unknown cache",
+			targetReclaimed: 1 << 30,
+			cache:           0,
+			wantIntensity:   memoryadvisor.DropCacheIntensityFull,
+			wantBytes:       0,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			intensity := memadvisorplugin.GetDropCacheIntensity(tt.targetReclaimed, tt.cache)
+			assert.Equal(t, tt.wantIntensity, intensity)
+			assert.Equal(t, tt.wantBytes, memadvisorplugin.GetDropCacheBytes(intensity, tt.cache))
+		})
+	}
+}
+
+func TestCacheReaperEscalateToEviction(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestCacheReaperEscalateToEviction")
+	require.NoError(t, err)
+	defer os.RemoveAll(ckDir)
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer os.RemoveAll(sfDir)
+
+	fetcher := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{})
+	metricsFetcher := fetcher.(*metric.FakeMetricsFetcher)
+	metricsFetcher.SetNodeMetric(coreconsts.MetricMemTotalSystem, metricutil.MetricData{Value: 500 << 30})
+	metricsFetcher.SetContainerMetric("uid1", "c1", coreconsts.MetricMemCacheContainer, metricutil.MetricData{Value: 10 << 30})
+
+	advisor, metaCache := newTestMemoryAdvisor(t, defaultPodList, ckDir, sfDir, fetcher, nil)
+	advisor.conf.IneffectiveDropCacheTimesToEvict = 2
+	require.NoError(t, metaCache.SetContainerInfo("uid1", "c1", makeContainerInfo("uid1", "default", "pod1", "c1",
+		consts.PodAnnotationQoSLevelReclaimedCores, nil, nil, 20<<30)))
+
+	reaper := memadvisorplugin.NewCacheReaper(advisor.conf, nil, metaCache, advisor.metaServer, metrics.DummyMetrics{})
+	targetReclaimed := resource.MustParse("5Gi")
+	status := &types.MemoryPressureStatus{
+		NodeCondition: &types.MemoryPressureCondition{
+			TargetReclaimed: &targetReclaimed,
+			State:           types.MemoryPressureDropCache,
+		},
+	}
+	dropCacheAdvices := []types.ContainerMemoryAdvices{{
+		PodUID:        "uid1",
+		ContainerName: "c1",
+		Values:        map[string]string{string(memoryadvisor.ControlKnobKeyDropCache): "true"},
+	}}
+	evictAdvices := []types.ContainerMemoryAdvices{{
+		PodUID:        "uid1",
+		ContainerName: "c1",
+		Values: map[string]string{
+			string(memoryadvisor.ControlKnobKeyDropCache): "true",
+			string(memoryadvisor.ControlKnobKeyEvict):     "true",
+		},
+	}}
+	reconcileWithCache := func(cache float64) []types.ContainerMemoryAdvices {
+		metricsFetcher.SetContainerMetric("uid1", "c1", coreconsts.MetricMemCacheContainer, metricutil.MetricData{Value: cache})
+		require.NoError(t, reaper.Reconcile(status))
+		return reaper.GetAdvices().ContainerEntries
+	}
+
+	// being selected repeatedly isn't ineffective by itself, if the cache isn't observed to be dropped
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, dropCacheAdvices, reconcileWithCache(10<<30))
+	}
+
+	// cache keeps refilling after being dropped, so eviction is advised along with
+	// dropping cache after the second ineffective drop
+	assert.Equal(t, dropCacheAdvices, reconcileWithCache(4<<30))
+	assert.Equal(t, dropCacheAdvices, reconcileWithCache(10<<30))
+	assert.Equal(t, dropCacheAdvices, reconcileWithCache(4<<30))
+	assert.Equal(t, evictAdvices, reconcileWithCache(10<<30))
+
+	// the count restarts once the container is no longer selected
+	require.NoError(t, reaper.Reconcile(&types.MemoryPressureStatus{NodeCondition: &types.MemoryPressureCondition{}}))
+	assert.Empty(t, reaper.GetAdvices().ContainerEntries)
+	assert.Equal(t, dropCacheAdvices, reconcileWithCache(10<<30))
+	assert.Equal(t, dropCacheAdvices, reconcileWithCache(4<<30))
+	assert.Equal(t, dropCacheAdvices, reconcileWithCache(10<<30))
+}
+
+func TestCacheReaperSoftAndHardTiers(t *testing.T) {
+	t.Parallel()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestCacheReaperSoftAndHardTiers")
+	require.NoError(t, err)
+	defer os.RemoveAll(ckDir)
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer os.RemoveAll(sfDir)
+
+	fetcher := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{})
+	metricsFetcher := fetcher.(*metric.FakeMetricsFetcher)
+	metricsFetcher.SetNodeMetric(coreconsts.MetricMemTotalSystem, metricutil.MetricData{Value: 500 << 30})
+	metricsFetcher.SetContainerMetric("uid1", "c1", coreconsts.MetricMemCacheContainer, metricutil.MetricData{Value: 10 << 30})
+	metricsFetcher.SetContainerMetric("uid2", "c2", coreconsts.MetricMemCacheContainer, metricutil.MetricData{Value: 8 << 30})
+	metricsFetcher.SetContainerMetric("uid3", "c3", coreconsts.MetricMemCacheContainer, metricutil.MetricData{Value: 6 << 30})
+
+	advisor, metaCache := newTestMemoryAdvisor(t, defaultPodList, ckDir, sfDir, fetcher, nil)
+	advisor.conf.HardDropCacheTarget = 20 << 30
+	advisor.conf.SoftDropCacheMaxContainers = 1
+	for i, pod := range []string{"pod1", "pod2", "pod3"} {
+		podUID, containerName := fmt.Sprintf("uid%d", i+1), fmt.Sprintf("c%d", i+1)
+		require.NoError(t, metaCache.SetContainerInfo(podUID, containerName, makeContainerInfo(podUID, "default", pod, containerName,
+			consts.PodAnnotationQoSLevelReclaimedCores, nil, nil, 20<<30)))
+	}
+
+	reaper := memadvisorplugin.NewCacheReaper(advisor.conf, nil, metaCache, advisor.metaServer, metrics.DummyMetrics{})
+	reconcile := func(targetReclaimed resource.Quantity) []string {
+		require.NoError(t, reaper.Reconcile(&types.MemoryPressureStatus{
+			NodeCondition: &types.MemoryPressureCondition{
+				TargetReclaimed: &targetReclaimed,
+				State:           types.MemoryPressureDropCache,
+			},
+		}))
+		podUIDs := make([]string, 0)
+		for _, entry := range reaper.GetAdvices().ContainerEntries {
+			podUIDs = append(podUIDs, entry.PodUID)
+		}
+		return podUIDs
+	}
+
+	// soft tier only drops cache of the container with the most cache, even if the target isn't covered
+	soft := reconcile(resource.MustParse("15Gi"))
+	assert.Equal(t, []string{"uid1"}, soft)
+
+	// hard tier selects containers until the target is covered
+	hard := reconcile(resource.MustParse("22Gi"))
+	assert.ElementsMatch(t, []string{"uid1", "uid2", "uid3"}, hard)
+	assert.Greater(t, len(hard), len(soft))
+}
@@ -19,6 +19,7 @@ package headroompolicy
 import (
 	"fmt"
 	"math"
+	"strconv"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 
@@ -29,33 +30,61 @@ import (
 	"github.com/kubewharf/katalyst-core/pkg/config"
 	"github.com/kubewharf/katalyst-core/pkg/consts"
 	"github.com/kubewharf/katalyst-core/pkg/metaserver"
+	metrictypes "github.com/kubewharf/katalyst-core/pkg/metaserver/agent/metric/types"
 	"github.com/kubewharf/katalyst-core/pkg/metrics"
 	"github.com/kubewharf/katalyst-core/pkg/util/general"
+	"github.com/kubewharf/katalyst-core/pkg/util/machine"
 	"github.com/kubewharf/katalyst-core/pkg/util/metric"
 )
 
+const (
+	numaAwareHealthCheckName = "memory_headroom_policy_numa_aware"
+
+	metricNameNUMAAwareUncoveredNUMA = "memory_headroom_numa_aware_uncovered_numa"
+)
+
+// ReclaimableMemoryDetails records per-numa reclaimable memory before reserves are subtracted,
+// together with the reserves subtracted from the total, in the last update of numa-aware policy
+type ReclaimableMemoryDetails struct {
+	NUMAReclaimableMemory   map[int]float64
+	SystemWatermarkReserved float64
+	ReservedForAllocate     float64
+}
+
 type PolicyNUMAAware struct {
 	*PolicyBase
 
-	// memoryHeadroom is valid to be used iff updateStatus successes
-	memoryHeadroom float64
-	updateStatus   types.PolicyUpdateStatus
+	// memoryHeadroom and numaMemoryHeadroom are valid to be used iff updateStatus successes
+	memoryHeadroom     float64
+	numaMemoryHeadroom map[int]resource.Quantity
+	updateStatus       types.PolicyUpdateStatus
+
+	// reclaimableMemoryDetails is kept for what-if analysis of how much headroom the reserves cost
+	reclaimableMemoryDetails ReclaimableMemoryDetails
 
-	conf *config.Configuration
+	conf    *config.Configuration
+	emitter metrics.MetricEmitter
 }
 
 func NewPolicyNUMAAware(conf *config.Configuration, _ interface{}, metaReader metacache.MetaReader,
-	metaServer *metaserver.MetaServer, _ metrics.MetricEmitter,
+	metaServer *metaserver.MetaServer, emitter metrics.MetricEmitter,
 ) HeadroomPolicy {
 	p := PolicyNUMAAware{
 		PolicyBase:   NewPolicyBase(metaReader, metaServer),
 		updateStatus: types.PolicyUpdateFailed,
 		conf:         conf,
+		emitter:      emitter,
 	}
 
+	// the check turns not ready if the policy keeps failing or stops being updated for longer than the timeout
+	timeout := conf.NUMAAwareHealthCheckTimeout
+	general.RegisterHeartbeatCheck(numaAwareHealthCheckName, timeout, general.HealthzCheckStateReady, timeout)
+
 	return &p
 }
 
+var _ types.ResourceHeadroomProvider = &PolicyNUMAAware{}
+
 func (p *PolicyNUMAAware) Name() types.MemoryHeadroomPolicyName {
 	return types.MemoryHeadroomPolicyNUMAAware
 }
@@ -71,6 +100,7 @@ func (p *PolicyNUMAAware) Update() (err error) {
 		} else {
 			p.updateStatus = types.PolicyUpdateSucceeded
 		}
+		_ = general.UpdateHealthzStateByError(numaAwareHealthCheckName, err)
 	}()
 
 	var (
@@ -78,6 +108,8 @@ func (p *PolicyNUMAAware) Update() (err error) {
 		availNUMATotal      float64 = 0
 		reservedForAllocate float64 = 0
 		data                metric.MetricData
+
+		numaReclaimableMemory = make(map[int]float64)
 	)
 	dynamicConfig := p.conf.GetDynamicConfiguration()
 
@@ -86,21 +118,62 @@ func (p *PolicyNUMAAware) Update() (err error) {
 		return err
 	}
 
+	// read all metrics from one snapshot, to avoid mixing values collected at different time
+	metricsReader := p.metaServer.MetricsFetcher.Snapshot()
+
+	// only numas with all required metrics populated are taken into account, since
+	// the collector may not have populated metrics for some numas yet
+	populatedNUMAs := machine.NewCPUSet(metricsReader.ListNumasWithMetric(consts.MetricMemFreeNuma)...).
+		Intersection(machine.NewCPUSet(metricsReader.ListNumasWithMetric(consts.MetricMemInactiveFileNuma)...)).
+		Intersection(machine.NewCPUSet(metricsReader.ListNumasWithMetric(consts.MetricMemTotalNuma)...))
+	if !availNUMAs.IsEmpty() && availNUMAs.Intersection(populatedNUMAs).IsEmpty() {
+		return fmt.Errorf("memory metrics of available numas %v are not populated", availNUMAs.String())
+	}
+
+	// excluded numas contribute nothing to both total and per-numa headroom
+	excludedNUMAs := machine.NewCPUSet(p.conf.NUMAAwareExcludedNUMAs...)
+
+	// numas not existing on this machine, which may be caused by topology drift, are dropped
+	// before headroom is calculated, so that per-numa headroom sums up to the total one
+	allNUMAs := p.metaServer.CPUDetails.NUMANodes()
+
+	uncoveredNUMAs := availNUMAs.Difference(populatedNUMAs).Difference(excludedNUMAs)
+	if !uncoveredNUMAs.IsEmpty() && !p.conf.NUMAAwareAllowPartialCoverage {
+		return fmt.Errorf("memory metrics of numas %v are not populated", uncoveredNUMAs.String())
+	}
+
 	for _, numaID := range availNUMAs.ToSliceInt() {
-		data, err = p.metaServer.GetNumaMetric(numaID, consts.MetricMemFreeNuma)
+		if excludedNUMAs.Contains(numaID) {
+			general.Infof("skip numa %v: excluded from headroom", numaID)
+			continue
+		}
+
+		if !allNUMAs.Contains(numaID) {
+			general.Warningf("skip numa %v: not found on machine numas %v", numaID, allNUMAs.String())
+			continue
+		}
+
+		if !populatedNUMAs.Contains(numaID) {
+			general.Warningf("skip numa %v: memory metrics not populated", numaID)
+			_ = p.emitter.StoreInt64(metricNameNUMAAwareUncoveredNUMA, 1, metrics.MetricTypeNameRaw,
+				metrics.MetricTag{Key: "numa", Val: strconv.Itoa(numaID)})
+			continue
+		}
+
+		data, err = metricsReader.GetNumaMetric(numaID, consts.MetricMemFreeNuma)
 		if err != nil {
 			general.Errorf("Can not get numa memory free, numaID: %v", numaID)
 			return err
 		}
 		free := data.Value
 
-		data, err = p.metaServer.GetNumaMetric(numaID, consts.MetricMemInactiveFileNuma)
+		data, err = metricsReader.GetNumaMetric(numaID, consts.MetricMemInactiveFileNuma)
 		if err != nil {
 			return err
 		}
 		inactiveFile := data.Value
 
-		data, err = p.metaServer.GetNumaMetric(numaID, consts.MetricMemTotalNuma)
+		data, err = metricsReader.GetNumaMetric(numaID, consts.MetricMemTotalNuma)
 		if err != nil {
 			general.ErrorS(err, "Can not get numa memory total", "numaID", numaID)
 			return err
@@ -118,13 +191,29 @@ func (p *PolicyNUMAAware) Update() (err error) {
 		)
 
 		reclaimableMemory += numaReclaimable
+		numaReclaimableMemory[numaID] = numaReclaimable
 	}
 
 	for _, container := range reclaimedCoresContainers {
-		reclaimableMemory += container.MemoryRequest
+		if len(container.TopologyAwareAssignments) == 0 {
+			reclaimableMemory += container.MemoryRequest
+			continue
+		}
+
+		for numaID, reclaimableMemoryPerNUMA := range p.splitMemoryRequestByNUMA(metricsReader, container) {
+			if excludedNUMAs.Contains(numaID) {
+				continue
+			} else if !allNUMAs.Contains(numaID) {
+				general.Warningf("skip reclaimable memory of container %v/%v on numa %v: not found on machine numas %v",
+					container.PodUID, container.ContainerName, numaID, allNUMAs.String())
+				continue
+			}
+			reclaimableMemory += reclaimableMemoryPerNUMA
+			numaReclaimableMemory[numaID] += reclaimableMemoryPerNUMA
+		}
 	}
 
-	watermarkScaleFactor, err := p.metaServer.GetNodeMetric(consts.MetricMemScaleFactorSystem)
+	watermarkScaleFactor, err := metricsReader.GetNodeMetric(consts.MetricMemScaleFactorSystem)
 	if err != nil {
 		general.InfoS("Can not get system watermark scale factor")
 		return err
@@ -140,9 +229,53 @@ func (p *PolicyNUMAAware) Update() (err error) {
 		"reservedForAllocate", general.FormatMemoryQuantity(reservedForAllocate))
 	p.memoryHeadroom = math.Max(reclaimableMemory-systemWatermarkReserved-reservedForAllocate, 0)
 
+	// distribute total headroom to each numa in proportion to its reclaimable memory
+	reduceRatio := 0.0
+	if reclaimableMemory > 0 {
+		reduceRatio = p.memoryHeadroom / reclaimableMemory
+	}
+
+	numaMemoryHeadroom := make(map[int]resource.Quantity)
+	for _, numaID := range allNUMAs.ToSliceInt() {
+		numaMemoryHeadroom[numaID] = *resource.NewQuantity(int64(numaReclaimableMemory[numaID]*reduceRatio), resource.BinarySI)
+	}
+	p.numaMemoryHeadroom = numaMemoryHeadroom
+	p.reclaimableMemoryDetails = ReclaimableMemoryDetails{
+		NUMAReclaimableMemory:   numaReclaimableMemory,
+		SystemWatermarkReserved: systemWatermarkReserved,
+		ReservedForAllocate:     reservedForAllocate,
+	}
+
 	return nil
 }
 
+// splitMemoryRequestByNUMA splits memory request of the container across its assigned numas in proportion
+// to its actual per-numa memory usage, and falls back to even split if per-numa usage is not available
+func (p *PolicyNUMAAware) splitMemoryRequestByNUMA(metricsReader metrictypes.MetricsReader, container *types.ContainerInfo) map[int]float64 {
+	numaMemory := make(map[int]float64, len(container.TopologyAwareAssignments))
+	total := 0.
+	for numaID := range container.TopologyAwareAssignments {
+		data, err := metricsReader.GetContainerNumaMetric(container.PodUID, container.ContainerName,
+			strconv.Itoa(numaID), consts.MetricsMemTotalPerNumaContainer)
+		if err != nil {
+			numaMemory = nil
+			break
+		}
+		numaMemory[numaID] = data.Value
+		total += data.Value
+	}
+
+	split := make(map[int]float64, len(container.TopologyAwareAssignments))
+	for numaID := range container.TopologyAwareAssignments {
+		if numaMemory != nil && total > 0 {
+			split[numaID] = container.MemoryRequest * numaMemory[numaID] / total
+		} else {
+			split[numaID] = container.MemoryRequest / float64(len(container.TopologyAwareAssignments))
+		}
+	}
+	return split
+}
+
 func (p *PolicyNUMAAware) GetHeadroom() (resource.Quantity, error) {
 	if p.updateStatus != types.PolicyUpdateSucceeded {
 		return resource.Quantity{}, fmt.Errorf("last update failed")
@@ -150,3 +283,37 @@ func (p *PolicyNUMAAware) GetHeadroom() (resource.Quantity, error) {
 
 	return *resource.NewQuantity(int64(p.memoryHeadroom), resource.BinarySI), nil
 }
+
+func (p *PolicyNUMAAware) GetNUMAHeadroom() (map[int]resource.Quantity, error) {
+	if p.updateStatus != types.PolicyUpdateSucceeded {
+		return nil, fmt.Errorf("last update failed")
+	}
+
+	numaHeadroom := make(map[int]resource.Quantity, len(p.numaMemoryHeadroom))
+	for numaID, quantity := range p.numaMemoryHeadroom {
+		numaHeadroom[numaID] = quantity.DeepCopy()
+	}
+	return numaHeadroom, nil
+}
+
+func (p *PolicyNUMAAware) GetUpdateStatus() types.PolicyUpdateStatus {
+	return p.updateStatus
+}
+
+// GetReclaimableMemoryDetails returns per-numa reclaimable memory computed by the last update before
+// reserves are subtracted, along with the reserves, so that the cost of the reserves can be reasoned about
+func (p *PolicyNUMAAware) GetReclaimableMemoryDetails() (ReclaimableMemoryDetails, error) {
+	if p.updateStatus != types.PolicyUpdateSucceeded {
+		return ReclaimableMemoryDetails{}, fmt.Errorf("last update failed")
+	}
+
+	numaReclaimableMemory := make(map[int]float64, len(p.reclaimableMemoryDetails.NUMAReclaimableMemory))
+	for numaID, reclaimable := range p.reclaimableMemoryDetails.NUMAReclaimableMemory {
+		numaReclaimableMemory[numaID] = reclaimable
+	}
+	return ReclaimableMemoryDetails{
+		NUMAReclaimableMemory:   numaReclaimableMemory,
+		SystemWatermarkReserved: p.reclaimableMemoryDetails.SystemWatermarkReserved,
+		ReservedForAllocate:     p.reclaimableMemoryDetails.ReservedForAllocate,
+	}, nil
+}
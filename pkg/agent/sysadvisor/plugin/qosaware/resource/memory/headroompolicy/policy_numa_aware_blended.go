@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The Katalyst Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package headroompolicy
+
+import (
+	"fmt"
+	"math"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/metacache"
+	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/types"
+	"github.com/kubewharf/katalyst-core/pkg/config"
+	"github.com/kubewharf/katalyst-core/pkg/metaserver"
+	"github.com/kubewharf/katalyst-core/pkg/metrics"
+	"github.com/kubewharf/katalyst-core/pkg/util/general"
+)
+
+// PolicyNUMAAwareBlended runs both numa-aware and canonical policies, and blends their
+// per-numa headroom by weighted average; the canonical headroom is distributed to numas
+// in proportion to numa memory capacity since it's not numa-aware itself
+type PolicyNUMAAwareBlended struct {
+	numaAware *PolicyNUMAAware
+	canonical *PolicyCanonical
+
+	// memoryHeadroom and numaMemoryHeadroom are valid to be used iff updateStatus successes
+	memoryHeadroom     float64
+	numaMemoryHeadroom map[int]resource.Quantity
+	updateStatus       types.PolicyUpdateStatus
+
+	metaServer *metaserver.MetaServer
+	conf       *config.Configuration
+}
+
+func NewPolicyNUMAAwareBlended(conf *config.Configuration, extraConfig interface{}, metaReader metacache.MetaReader,
+	metaServer *metaserver.MetaServer, emitter metrics.MetricEmitter,
+) HeadroomPolicy {
+	p := PolicyNUMAAwareBlended{
+		numaAware:    NewPolicyNUMAAware(conf, extraConfig, metaReader, metaServer, emitter).(*PolicyNUMAAware),
+		canonical:    NewPolicyCanonical(conf, extraConfig, metaReader, metaServer, emitter).(*PolicyCanonical),
+		updateStatus: types.PolicyUpdateFailed,
+		metaServer:   metaServer,
+		conf:         conf,
+	}
+
+	return &p
+}
+
+var _ types.ResourceHeadroomProvider = &PolicyNUMAAwareBlended{}
+
+func (p *PolicyNUMAAwareBlended) Name() types.MemoryHeadroomPolicyName {
+	return types.MemoryHeadroomPolicyNUMAAwareBlended
+}
+
+func (p *PolicyNUMAAwareBlended) SetPodSet(podSet types.PodSet) {
+	p.numaAware.SetPodSet(podSet)
+	p.canonical.SetPodSet(podSet)
+}
+
+func (p *PolicyNUMAAwareBlended) SetEssentials(essentials types.ResourceEssentials) {
+	p.numaAware.SetEssentials(essentials)
+	p.canonical.SetEssentials(essentials)
+}
+
+func (p *PolicyNUMAAwareBlended) Update() (err error) {
+	defer func() {
+		if err != nil {
+			p.updateStatus = types.PolicyUpdateFailed
+		} else {
+			p.updateStatus = types.PolicyUpdateSucceeded
+		}
+	}()
+
+	if err = p.numaAware.Update(); err != nil {
+		return fmt.Errorf("update numa-aware policy failed: %v", err)
+	}
+	if err = p.canonical.Update(); err != nil {
+		return fmt.Errorf("update canonical policy failed: %v", err)
+	}
+
+	numaAwareNUMAHeadroom, err := p.numaAware.GetNUMAHeadroom()
+	if err != nil {
+		return err
+	}
+	canonicalNUMAHeadroom := p.distributeCanonicalHeadroom(p.canonical.memoryHeadroom)
+
+	weight := p.conf.NUMAAwareBlendWeight
+	blendedNUMAHeadroom := make(map[int]float64, len(numaAwareNUMAHeadroom))
+	blended := 0.
+	for numaID, quantity := range numaAwareNUMAHeadroom {
+		blendedNUMAHeadroom[numaID] = weight*float64(quantity.Value()) + (1-weight)*canonicalNUMAHeadroom[numaID]
+
+		// the weighted average never falls below the safer one, so bounding by it
+		// takes the safer result of each numa regardless of the weight
+		if p.conf.NUMAAwareBlendBoundedBySafer {
+			blendedNUMAHeadroom[numaID] = math.Min(blendedNUMAHeadroom[numaID],
+				math.Min(float64(quantity.Value()), canonicalNUMAHeadroom[numaID]))
+		}
+		blended += blendedNUMAHeadroom[numaID]
+	}
+
+	general.InfoS("blended memory headroom", "weight", weight,
+		"numaAwareHeadroom", general.FormatMemoryQuantity(p.numaAware.memoryHeadroom),
+		"canonicalHeadroom", general.FormatMemoryQuantity(p.canonical.memoryHeadroom),
+		"blendedHeadroom", general.FormatMemoryQuantity(blended))
+
+	p.memoryHeadroom = blended
+	p.numaMemoryHeadroom = make(map[int]resource.Quantity, len(blendedNUMAHeadroom))
+	for numaID, headroom := range blendedNUMAHeadroom {
+		p.numaMemoryHeadroom[numaID] = *resource.NewQuantity(int64(headroom), resource.BinarySI)
+	}
+	return nil
+}
+
+// distributeCanonicalHeadroom splits canonical headroom to numas in proportion to their memory
+// capacity, and falls back to even split if capacity is not available
+func (p *PolicyNUMAAwareBlended) distributeCanonicalHeadroom(headroom float64) map[int]float64 {
+	numas := p.metaServer.CPUDetails.NUMANodes().ToSliceInt()
+	if len(numas) == 0 {
+		return nil
+	}
+
+	totalCapacity := uint64(0)
+	for _, numaID := range numas {
+		totalCapacity += p.metaServer.MemoryDetails[numaID]
+	}
+
+	numaHeadroom := make(map[int]float64, len(numas))
+	for _, numaID := range numas {
+		if totalCapacity > 0 {
+			numaHeadroom[numaID] = headroom * float64(p.metaServer.MemoryDetails[numaID]) / float64(totalCapacity)
+		} else {
+			numaHeadroom[numaID] = headroom / float64(len(numas))
+		}
+	}
+	return numaHeadroom
+}
+
+func (p *PolicyNUMAAwareBlended) GetHeadroom() (resource.Quantity, error) {
+	if p.updateStatus != types.PolicyUpdateSucceeded {
+		return resource.Quantity{}, fmt.Errorf("last update failed")
+	}
+
+	return *resource.NewQuantity(int64(p.memoryHeadroom), resource.BinarySI), nil
+}
+
+func (p *PolicyNUMAAwareBlended) GetNUMAHeadroom() (map[int]resource.Quantity, error) {
+	if p.updateStatus != types.PolicyUpdateSucceeded {
+		return nil, fmt.Errorf("last update failed")
+	}
+
+	numaHeadroom := make(map[int]resource.Quantity, len(p.numaMemoryHeadroom))
+	for numaID, quantity := range p.numaMemoryHeadroom {
+		numaHeadroom[numaID] = quantity.DeepCopy()
+	}
+	return numaHeadroom, nil
+}
+
+func (p *PolicyNUMAAwareBlended) GetUpdateStatus() types.PolicyUpdateStatus {
+	return p.updateStatus
+}
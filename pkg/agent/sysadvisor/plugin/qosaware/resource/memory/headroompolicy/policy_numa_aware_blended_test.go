@@ -0,0 +1,149 @@
+/*
+Copyright 2022 The Katalyst Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package headroompolicy
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/metacache"
+	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/types"
+	"github.com/kubewharf/katalyst-core/pkg/config/agent/dynamic/adminqos/reclaimedresource/memoryheadroom"
+	pkgconsts "github.com/kubewharf/katalyst-core/pkg/consts"
+	"github.com/kubewharf/katalyst-core/pkg/metaserver/agent/metric"
+	"github.com/kubewharf/katalyst-core/pkg/metrics"
+	metricspool "github.com/kubewharf/katalyst-core/pkg/metrics/metrics-pool"
+	utilmetric "github.com/kubewharf/katalyst-core/pkg/util/metric"
+)
+
+func TestPolicyNUMAAwareBlended(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		weight           float64
+		boundedBySafer   bool
+		wantNUMAHeadroom string
+		wantHeadroom     string
+	}{
+		{
+			// numa-aware: 110.5Gi per numa, canonical: 396Gi split to 198Gi per numa
+			name:             "weighted average",
+			weight:           0.5,
+			boundedBySafer:   false,
+			wantNUMAHeadroom: "154.25Gi",
+			wantHeadroom:     "308.5Gi",
+		},
+		{
+			name:             "weighted average towards numa-aware",
+			weight:           0.75,
+			boundedBySafer:   false,
+			wantNUMAHeadroom: "132.375Gi",
+			wantHeadroom:     "264.75Gi",
+		},
+		{
+			name:             "bounded by the safer numa-aware result",
+			weight:           0.5,
+			boundedBySafer:   true,
+			wantNUMAHeadroom: "110.5Gi",
+			wantHeadroom:     "221Gi",
+		},
+		{
+			name:             "canonical only",
+			weight:           0,
+			boundedBySafer:   false,
+			wantNUMAHeadroom: "198Gi",
+			wantHeadroom:     "396Gi",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			now := time.Now()
+
+			ckDir, err := ioutil.TempDir("", "checkpoint-TestPolicyNUMAAwareBlended")
+			require.NoError(t, err)
+			defer os.RemoveAll(ckDir)
+
+			sfDir, err := ioutil.TempDir("", "statefile")
+			require.NoError(t, err)
+			defer os.RemoveAll(sfDir)
+
+			conf := generateTestConfiguration(t, ckDir, sfDir)
+			// bounding by safer is left as default unless it's set explicitly
+			require.False(t, conf.NUMAAwareBlendBoundedBySafer)
+			conf.NUMAAwareBlendWeight = tt.weight
+			if tt.boundedBySafer {
+				conf.NUMAAwareBlendBoundedBySafer = true
+			}
+			conf.GetDynamicConfiguration().MemoryHeadroomConfiguration = &memoryheadroom.MemoryHeadroomConfiguration{
+				MemoryUtilBasedConfiguration: &memoryheadroom.MemoryUtilBasedConfiguration{
+					CacheBasedRatio: 0.5,
+				},
+			}
+
+			metricsFetcher := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{})
+			metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metricsFetcher)
+			require.NoError(t, err)
+
+			metaServer := generateTestMetaServer(t, []*v1.Pod{}, metricsFetcher)
+			p := NewPolicyNUMAAwareBlended(conf, nil, metaCache, metaServer, metrics.DummyMetrics{}).(*PolicyNUMAAwareBlended)
+
+			store := metricsFetcher.(*metric.FakeMetricsFetcher)
+			store.SetNodeMetric(pkgconsts.MetricMemScaleFactorSystem, utilmetric.MetricData{Value: 500, Time: &now})
+			for numaID := 0; numaID < 2; numaID++ {
+				store.SetNumaMetric(numaID, pkgconsts.MetricMemTotalNuma, utilmetric.MetricData{Value: 250 << 30, Time: &now})
+				store.SetNumaMetric(numaID, pkgconsts.MetricMemFreeNuma, utilmetric.MetricData{Value: 100 << 30, Time: &now})
+				store.SetNumaMetric(numaID, pkgconsts.MetricMemInactiveFileNuma, utilmetric.MetricData{Value: 50 << 30, Time: &now})
+			}
+
+			p.SetEssentials(types.ResourceEssentials{
+				EnableReclaim:       true,
+				ResourceUpperBound:  400 << 30,
+				ReservedForAllocate: 4 << 30,
+			})
+
+			_, err = p.GetNUMAHeadroom()
+			require.Error(t, err)
+
+			require.NoError(t, p.Update())
+			require.Equal(t, types.PolicyUpdateSucceeded, p.GetUpdateStatus())
+
+			expectedHeadroom := resource.MustParse(tt.wantHeadroom)
+			headroom, err := p.GetHeadroom()
+			require.NoError(t, err)
+			require.InDelta(t, expectedHeadroom.Value(), headroom.Value(), 2)
+
+			expectedNUMAHeadroom := resource.MustParse(tt.wantNUMAHeadroom)
+			numaHeadroom, err := p.GetNUMAHeadroom()
+			require.NoError(t, err)
+			require.Len(t, numaHeadroom, 2)
+			for numaID := 0; numaID < 2; numaID++ {
+				quantity := numaHeadroom[numaID]
+				require.InDelta(t, expectedNUMAHeadroom.Value(), quantity.Value(), 1)
+			}
+		})
+	}
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/kubewharf/katalyst-core/pkg/metaserver/agent/metric"
 	"github.com/kubewharf/katalyst-core/pkg/metrics"
 	metricspool "github.com/kubewharf/katalyst-core/pkg/metrics/metrics-pool"
+	"github.com/kubewharf/katalyst-core/pkg/util/general"
 	"github.com/kubewharf/katalyst-core/pkg/util/machine"
 	utilmetric "github.com/kubewharf/katalyst-core/pkg/util/metric"
 )
@@ -121,6 +122,32 @@ func TestPolicyNUMAAware(t *testing.T) {
 			wantErr: false,
 			want:    resource.MustParse("221Gi"),
 		},
+		{
+			name: "partial numa metrics missing",
+			fields: fields{
+				podList:    []*v1.Pod{},
+				containers: []*types.ContainerInfo{},
+				essentials: types.ResourceEssentials{
+					EnableReclaim:       true,
+					ResourceUpperBound:  400 << 30,
+					ReservedForAllocate: 4 << 30,
+				},
+				memoryHeadroomConfiguration: &memoryheadroom.MemoryHeadroomConfiguration{
+					MemoryUtilBasedConfiguration: &memoryheadroom.MemoryUtilBasedConfiguration{
+						CacheBasedRatio: 0.5,
+					},
+				},
+				setFakeMetric: func(store *metric.FakeMetricsFetcher) {
+					store.SetNodeMetric(pkgconsts.MetricMemScaleFactorSystem, utilmetric.MetricData{Value: 500, Time: &now})
+					store.SetNumaMetric(0, pkgconsts.MetricMemTotalNuma, utilmetric.MetricData{Value: 250 << 30, Time: &now})
+					store.SetNumaMetric(0, pkgconsts.MetricMemFreeNuma, utilmetric.MetricData{Value: 100 << 30, Time: &now})
+					store.SetNumaMetric(0, pkgconsts.MetricMemInactiveFileNuma, utilmetric.MetricData{Value: 50 << 30, Time: &now})
+					store.SetNumaMetric(1, pkgconsts.MetricMemFreeNuma, utilmetric.MetricData{Value: 100 << 30, Time: &now})
+				},
+			},
+			wantErr: false,
+			want:    resource.MustParse("110.5Gi"),
+		},
 		{
 			name: "normal: reclaimed_cores containers only",
 			fields: fields{
@@ -245,3 +272,439 @@ func TestPolicyNUMAAware(t *testing.T) {
 		})
 	}
 }
+
+func TestPolicyNUMAAware_GetNUMAHeadroom(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestPolicyNUMAAware_GetNUMAHeadroom")
+	require.NoError(t, err)
+	defer os.RemoveAll(ckDir)
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer os.RemoveAll(sfDir)
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	conf.GetDynamicConfiguration().MemoryHeadroomConfiguration = &memoryheadroom.MemoryHeadroomConfiguration{
+		MemoryUtilBasedConfiguration: &memoryheadroom.MemoryUtilBasedConfiguration{
+			CacheBasedRatio: 0.5,
+		},
+	}
+
+	metricsFetcher := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{})
+	metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metricsFetcher)
+	require.NoError(t, err)
+
+	// reclaimed_cores container assigned to a numa out of machine range
+	c := makeContainerInfo("pod1", "default", "pod1", "container1",
+		consts.PodAnnotationQoSLevelReclaimedCores, nil,
+		types.TopologyAwareAssignment{
+			3: machine.NewCPUSet(0),
+		}, 20<<30)
+	require.NoError(t, metaCache.SetContainerInfo(c.PodUID, c.ContainerName, c))
+
+	metaServer := generateTestMetaServer(t, []*v1.Pod{}, metricsFetcher)
+	p := NewPolicyNUMAAware(conf, nil, metaCache, metaServer, metrics.DummyMetrics{}).(*PolicyNUMAAware)
+
+	store := metricsFetcher.(*metric.FakeMetricsFetcher)
+	store.SetNodeMetric(pkgconsts.MetricMemScaleFactorSystem, utilmetric.MetricData{Value: 500, Time: &now})
+	for numaID := 0; numaID < 2; numaID++ {
+		store.SetNumaMetric(numaID, pkgconsts.MetricMemTotalNuma, utilmetric.MetricData{Value: 250 << 30, Time: &now})
+		store.SetNumaMetric(numaID, pkgconsts.MetricMemFreeNuma, utilmetric.MetricData{Value: 100 << 30, Time: &now})
+		store.SetNumaMetric(numaID, pkgconsts.MetricMemInactiveFileNuma, utilmetric.MetricData{Value: 50 << 30, Time: &now})
+	}
+
+	p.SetEssentials(types.ResourceEssentials{
+		EnableReclaim:       true,
+		ResourceUpperBound:  400 << 30,
+		ReservedForAllocate: 4 << 30,
+	})
+
+	_, err = p.GetNUMAHeadroom()
+	require.Error(t, err)
+
+	require.NoError(t, p.Update())
+	require.Equal(t, types.PolicyUpdateSucceeded, p.GetUpdateStatus())
+
+	numaHeadroom, err := p.GetNUMAHeadroom()
+	require.NoError(t, err)
+	require.Len(t, numaHeadroom, 2)
+	require.NotContains(t, numaHeadroom, 3)
+	require.Contains(t, numaHeadroom, 0)
+	require.Contains(t, numaHeadroom, 1)
+
+	// memory of the stray numa is excluded from the total headroom as well
+	total, err := p.GetHeadroom()
+	require.NoError(t, err)
+	sum := int64(0)
+	for _, quantity := range numaHeadroom {
+		sum += quantity.Value()
+	}
+	require.InDelta(t, total.Value(), sum, 2)
+	details, err := p.GetReclaimableMemoryDetails()
+	require.NoError(t, err)
+	require.NotContains(t, details.NUMAReclaimableMemory, 3)
+}
+
+// not parallel since healthz checks are shared globally with policies created by other tests
+func TestPolicyNUMAAware_HealthCheck(t *testing.T) {
+	now := time.Now()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestPolicyNUMAAware_HealthCheck")
+	require.NoError(t, err)
+	defer os.RemoveAll(ckDir)
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer os.RemoveAll(sfDir)
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	conf.NUMAAwareHealthCheckTimeout = 100 * time.Millisecond
+	conf.GetDynamicConfiguration().MemoryHeadroomConfiguration = &memoryheadroom.MemoryHeadroomConfiguration{
+		MemoryUtilBasedConfiguration: &memoryheadroom.MemoryUtilBasedConfiguration{
+			CacheBasedRatio: 0.5,
+		},
+	}
+
+	metricsFetcher := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{})
+	metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metricsFetcher)
+	require.NoError(t, err)
+
+	metaServer := generateTestMetaServer(t, []*v1.Pod{}, metricsFetcher)
+	p := NewPolicyNUMAAware(conf, nil, metaCache, metaServer, metrics.DummyMetrics{})
+
+	store := metricsFetcher.(*metric.FakeMetricsFetcher)
+	store.SetNodeMetric(pkgconsts.MetricMemScaleFactorSystem, utilmetric.MetricData{Value: 500, Time: &now})
+	for numaID := 0; numaID < 2; numaID++ {
+		store.SetNumaMetric(numaID, pkgconsts.MetricMemTotalNuma, utilmetric.MetricData{Value: 250 << 30, Time: &now})
+		store.SetNumaMetric(numaID, pkgconsts.MetricMemFreeNuma, utilmetric.MetricData{Value: 100 << 30, Time: &now})
+		store.SetNumaMetric(numaID, pkgconsts.MetricMemInactiveFileNuma, utilmetric.MetricData{Value: 50 << 30, Time: &now})
+	}
+
+	p.SetEssentials(types.ResourceEssentials{
+		EnableReclaim:       true,
+		ResourceUpperBound:  400 << 30,
+		ReservedForAllocate: 4 << 30,
+	})
+
+	require.NoError(t, p.Update())
+	result, ok := general.GetReadinessCheckResult(numaAwareHealthCheckName)
+	require.True(t, ok)
+	require.True(t, result.Ready)
+
+	// the check turns not ready once updates stop for longer than the timeout
+	time.Sleep(200 * time.Millisecond)
+	result, ok = general.GetReadinessCheckResult(numaAwareHealthCheckName)
+	require.True(t, ok)
+	require.False(t, result.Ready)
+
+	require.NoError(t, p.Update())
+	result, ok = general.GetReadinessCheckResult(numaAwareHealthCheckName)
+	require.True(t, ok)
+	require.True(t, result.Ready)
+}
+
+func TestPolicyNUMAAware_ExcludedNUMAs(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestPolicyNUMAAware_ExcludedNUMAs")
+	require.NoError(t, err)
+	defer os.RemoveAll(ckDir)
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer os.RemoveAll(sfDir)
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	conf.NUMAAwareExcludedNUMAs = []int{1}
+	conf.GetDynamicConfiguration().MemoryHeadroomConfiguration = &memoryheadroom.MemoryHeadroomConfiguration{
+		MemoryUtilBasedConfiguration: &memoryheadroom.MemoryUtilBasedConfiguration{
+			CacheBasedRatio: 0.5,
+		},
+	}
+
+	metricsFetcher := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{})
+	metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metricsFetcher)
+	require.NoError(t, err)
+
+	metaServer := generateTestMetaServer(t, []*v1.Pod{}, metricsFetcher)
+	p := NewPolicyNUMAAware(conf, nil, metaCache, metaServer, metrics.DummyMetrics{}).(*PolicyNUMAAware)
+
+	store := metricsFetcher.(*metric.FakeMetricsFetcher)
+	store.SetNodeMetric(pkgconsts.MetricMemScaleFactorSystem, utilmetric.MetricData{Value: 500, Time: &now})
+	for numaID := 0; numaID < 2; numaID++ {
+		store.SetNumaMetric(numaID, pkgconsts.MetricMemTotalNuma, utilmetric.MetricData{Value: 250 << 30, Time: &now})
+		store.SetNumaMetric(numaID, pkgconsts.MetricMemFreeNuma, utilmetric.MetricData{Value: 100 << 30, Time: &now})
+		store.SetNumaMetric(numaID, pkgconsts.MetricMemInactiveFileNuma, utilmetric.MetricData{Value: 50 << 30, Time: &now})
+	}
+
+	p.SetEssentials(types.ResourceEssentials{
+		EnableReclaim:       true,
+		ResourceUpperBound:  400 << 30,
+		ReservedForAllocate: 4 << 30,
+	})
+
+	require.NoError(t, p.Update())
+
+	// numa 0: 100Gi free + 50Gi inactive file * 0.5 - 12.5Gi watermark reserved - 2Gi reserved for allocate
+	expected := resource.MustParse("110.5Gi")
+	headroom, err := p.GetHeadroom()
+	require.NoError(t, err)
+	require.Equal(t, expected.Value(), headroom.Value())
+
+	numaHeadroom, err := p.GetNUMAHeadroom()
+	require.NoError(t, err)
+	require.Len(t, numaHeadroom, 2)
+	numa0Headroom, numa1Headroom := numaHeadroom[0], numaHeadroom[1]
+	require.InDelta(t, expected.Value(), numa0Headroom.Value(), 1)
+	require.Equal(t, int64(0), numa1Headroom.Value())
+}
+
+func TestPolicyNUMAAware_GetReclaimableMemoryDetails(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestPolicyNUMAAware_GetReclaimableMemoryDetails")
+	require.NoError(t, err)
+	defer os.RemoveAll(ckDir)
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer os.RemoveAll(sfDir)
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	conf.GetDynamicConfiguration().MemoryHeadroomConfiguration = &memoryheadroom.MemoryHeadroomConfiguration{
+		MemoryUtilBasedConfiguration: &memoryheadroom.MemoryUtilBasedConfiguration{
+			CacheBasedRatio: 0.5,
+		},
+	}
+
+	metricsFetcher := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{})
+	metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metricsFetcher)
+	require.NoError(t, err)
+
+	metaServer := generateTestMetaServer(t, []*v1.Pod{}, metricsFetcher)
+	p := NewPolicyNUMAAware(conf, nil, metaCache, metaServer, metrics.DummyMetrics{}).(*PolicyNUMAAware)
+
+	store := metricsFetcher.(*metric.FakeMetricsFetcher)
+	store.SetNodeMetric(pkgconsts.MetricMemScaleFactorSystem, utilmetric.MetricData{Value: 500, Time: &now})
+	for numaID := 0; numaID < 2; numaID++ {
+		store.SetNumaMetric(numaID, pkgconsts.MetricMemTotalNuma, utilmetric.MetricData{Value: 250 << 30, Time: &now})
+		store.SetNumaMetric(numaID, pkgconsts.MetricMemFreeNuma, utilmetric.MetricData{Value: 100 << 30, Time: &now})
+		store.SetNumaMetric(numaID, pkgconsts.MetricMemInactiveFileNuma, utilmetric.MetricData{Value: 50 << 30, Time: &now})
+	}
+
+	p.SetEssentials(types.ResourceEssentials{
+		EnableReclaim:       true,
+		ResourceUpperBound:  400 << 30,
+		ReservedForAllocate: 4 << 30,
+	})
+
+	_, err = p.GetReclaimableMemoryDetails()
+	require.Error(t, err)
+
+	require.NoError(t, p.Update())
+
+	details, err := p.GetReclaimableMemoryDetails()
+	require.NoError(t, err)
+	// each numa: 100Gi free + 50Gi inactive file * 0.5
+	require.Len(t, details.NUMAReclaimableMemory, 2)
+	require.Equal(t, float64(125<<30), details.NUMAReclaimableMemory[0])
+	require.Equal(t, float64(125<<30), details.NUMAReclaimableMemory[1])
+	// 500Gi total * 500 / 10000
+	require.Equal(t, float64(25<<30), details.SystemWatermarkReserved)
+	require.Equal(t, float64(4<<30), details.ReservedForAllocate)
+
+	// final headroom equals reclaimable memory minus the reserves, distributed in proportion to reclaimable memory
+	reclaimable := details.NUMAReclaimableMemory[0] + details.NUMAReclaimableMemory[1]
+	headroom, err := p.GetHeadroom()
+	require.NoError(t, err)
+	require.InDelta(t, reclaimable-details.SystemWatermarkReserved-details.ReservedForAllocate, float64(headroom.Value()), 1)
+
+	numaHeadroom, err := p.GetNUMAHeadroom()
+	require.NoError(t, err)
+	for numaID, reclaimableOnNUMA := range details.NUMAReclaimableMemory {
+		quantity := numaHeadroom[numaID]
+		require.InDelta(t, reclaimableOnNUMA*float64(headroom.Value())/reclaimable, float64(quantity.Value()), 1)
+	}
+
+	// returned details are copies and never affect the policy
+	details.NUMAReclaimableMemory[0] = 0
+	details, err = p.GetReclaimableMemoryDetails()
+	require.NoError(t, err)
+	require.Equal(t, float64(125<<30), details.NUMAReclaimableMemory[0])
+}
+
+type uncoveredNUMAMetricEmitter struct {
+	metrics.DummyMetrics
+	numas []string
+}
+
+func (e *uncoveredNUMAMetricEmitter) StoreInt64(key string, _ int64, _ metrics.MetricTypeName, tags ...metrics.MetricTag) error {
+	if key == metricNameNUMAAwareUncoveredNUMA {
+		for _, tag := range tags {
+			if tag.Key == "numa" {
+				e.numas = append(e.numas, tag.Val)
+			}
+		}
+	}
+	return nil
+}
+
+func TestPolicyNUMAAware_PartialCoverage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                 string
+		allowPartialCoverage bool
+		wantErr              bool
+	}{
+		{
+			name:                 "lenient mode",
+			allowPartialCoverage: true,
+		},
+		{
+			name:                 "strict mode",
+			allowPartialCoverage: false,
+			wantErr:              true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			now := time.Now()
+
+			ckDir, err := ioutil.TempDir("", "checkpoint-TestPolicyNUMAAware_PartialCoverage")
+			require.NoError(t, err)
+			defer os.RemoveAll(ckDir)
+
+			sfDir, err := ioutil.TempDir("", "statefile")
+			require.NoError(t, err)
+			defer os.RemoveAll(sfDir)
+
+			conf := generateTestConfiguration(t, ckDir, sfDir)
+			conf.NUMAAwareAllowPartialCoverage = tt.allowPartialCoverage
+			conf.GetDynamicConfiguration().MemoryHeadroomConfiguration = &memoryheadroom.MemoryHeadroomConfiguration{
+				MemoryUtilBasedConfiguration: &memoryheadroom.MemoryUtilBasedConfiguration{
+					CacheBasedRatio: 0.5,
+				},
+			}
+
+			metricsFetcher := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{})
+			metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metricsFetcher)
+			require.NoError(t, err)
+
+			emitter := &uncoveredNUMAMetricEmitter{}
+			metaServer := generateTestMetaServer(t, []*v1.Pod{}, metricsFetcher)
+			p := NewPolicyNUMAAware(conf, nil, metaCache, metaServer, emitter).(*PolicyNUMAAware)
+
+			// numa 1 misses memory total metric
+			store := metricsFetcher.(*metric.FakeMetricsFetcher)
+			store.SetNodeMetric(pkgconsts.MetricMemScaleFactorSystem, utilmetric.MetricData{Value: 500, Time: &now})
+			for numaID := 0; numaID < 2; numaID++ {
+				store.SetNumaMetric(numaID, pkgconsts.MetricMemFreeNuma, utilmetric.MetricData{Value: 100 << 30, Time: &now})
+				store.SetNumaMetric(numaID, pkgconsts.MetricMemInactiveFileNuma, utilmetric.MetricData{Value: 50 << 30, Time: &now})
+			}
+			store.SetNumaMetric(0, pkgconsts.MetricMemTotalNuma, utilmetric.MetricData{Value: 250 << 30, Time: &now})
+
+			p.SetEssentials(types.ResourceEssentials{
+				EnableReclaim:       true,
+				ResourceUpperBound:  400 << 30,
+				ReservedForAllocate: 4 << 30,
+			})
+
+			err = p.Update()
+			if tt.wantErr {
+				require.Error(t, err)
+				_, err = p.GetHeadroom()
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			// numa 0: 100Gi free + 50Gi inactive file * 0.5 - 12.5Gi watermark reserved - 2Gi reserved for allocate
+			expected := resource.MustParse("110.5Gi")
+			headroom, err := p.GetHeadroom()
+			require.NoError(t, err)
+			require.Equal(t, expected.Value(), headroom.Value())
+
+			numaHeadroom, err := p.GetNUMAHeadroom()
+			require.NoError(t, err)
+			numa0Headroom, numa1Headroom := numaHeadroom[0], numaHeadroom[1]
+			require.InDelta(t, expected.Value(), numa0Headroom.Value(), 1)
+			require.Equal(t, int64(0), numa1Headroom.Value())
+			require.Equal(t, []string{"1"}, emitter.numas)
+		})
+	}
+}
+
+func TestPolicyNUMAAware_SkewedReclaimedContainer(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	ckDir, err := ioutil.TempDir("", "checkpoint-TestPolicyNUMAAware_SkewedReclaimedContainer")
+	require.NoError(t, err)
+	defer os.RemoveAll(ckDir)
+
+	sfDir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer os.RemoveAll(sfDir)
+
+	conf := generateTestConfiguration(t, ckDir, sfDir)
+	conf.GetDynamicConfiguration().MemoryHeadroomConfiguration = &memoryheadroom.MemoryHeadroomConfiguration{
+		MemoryUtilBasedConfiguration: &memoryheadroom.MemoryUtilBasedConfiguration{
+			CacheBasedRatio: 0,
+		},
+	}
+
+	metricsFetcher := metric.NewFakeMetricsFetcher(metrics.DummyMetrics{})
+	metaCache, err := metacache.NewMetaCacheImp(conf, metricspool.DummyMetricsEmitterPool{}, metricsFetcher)
+	require.NoError(t, err)
+
+	// reclaimed_cores container bound to both numas, while most of its memory is on numa 0
+	c := makeContainerInfo("pod1", "default", "pod1", "container1",
+		consts.PodAnnotationQoSLevelReclaimedCores, nil,
+		types.TopologyAwareAssignment{
+			0: machine.NewCPUSet(0),
+			1: machine.NewCPUSet(24),
+		}, 40<<30)
+	require.NoError(t, metaCache.SetContainerInfo(c.PodUID, c.ContainerName, c))
+
+	metaServer := generateTestMetaServer(t, []*v1.Pod{}, metricsFetcher)
+	p := NewPolicyNUMAAware(conf, nil, metaCache, metaServer, metrics.DummyMetrics{}).(*PolicyNUMAAware)
+
+	store := metricsFetcher.(*metric.FakeMetricsFetcher)
+	store.SetNodeMetric(pkgconsts.MetricMemScaleFactorSystem, utilmetric.MetricData{Value: 0, Time: &now})
+	for numaID := 0; numaID < 2; numaID++ {
+		store.SetNumaMetric(numaID, pkgconsts.MetricMemTotalNuma, utilmetric.MetricData{Value: 250 << 30, Time: &now})
+		store.SetNumaMetric(numaID, pkgconsts.MetricMemFreeNuma, utilmetric.MetricData{Value: 100 << 30, Time: &now})
+		store.SetNumaMetric(numaID, pkgconsts.MetricMemInactiveFileNuma, utilmetric.MetricData{Value: 50 << 30, Time: &now})
+	}
+	store.SetContainerNumaMetric(c.PodUID, c.ContainerName, "0", pkgconsts.MetricsMemTotalPerNumaContainer,
+		utilmetric.MetricData{Value: 30 << 30, Time: &now})
+	store.SetContainerNumaMetric(c.PodUID, c.ContainerName, "1", pkgconsts.MetricsMemTotalPerNumaContainer,
+		utilmetric.MetricData{Value: 10 << 30, Time: &now})
+
+	p.SetEssentials(types.ResourceEssentials{
+		EnableReclaim:       true,
+		ResourceUpperBound:  400 << 30,
+		ReservedForAllocate: 0,
+	})
+
+	require.NoError(t, p.Update())
+
+	// the request of 40Gi is split as 30Gi and 10Gi following the actual memory usage
+	numaHeadroom, err := p.GetNUMAHeadroom()
+	require.NoError(t, err)
+	numa0Headroom, numa1Headroom := numaHeadroom[0], numaHeadroom[1]
+	require.Equal(t, int64(130<<30), numa0Headroom.Value())
+	require.Equal(t, int64(110<<30), numa1Headroom.Value())
+}
@@ -17,10 +17,14 @@ limitations under the License.
 package plugin
 
 import (
+	"context"
+	"fmt"
+	"math"
 	"strconv"
 	"sync"
 
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/kubelet/pkg/apis/resourceplugin/v1alpha1"
 
 	apiconsts "github.com/kubewharf/katalyst-api/pkg/consts"
@@ -40,15 +44,84 @@ import (
 
 const (
 	CacheReaper = "cache-reaper"
+
+	// the ratio of cache expected to be reclaimed to container cache, below which
+	// light or medium intensity is enough; and the ratio of cache dropped by them
+	dropCacheLightRatio  = 0.25
+	dropCacheMediumRatio = 0.5
+
+	// dropCacheRefilledRatio is the ratio to container cache before dropping, below which the cache
+	// is considered dropped, and back to which the dropped cache is considered refilled
+	dropCacheRefilledRatio = 0.9
 )
 
+var dropCacheIntensityOrder = map[memoryadvisor.DropCacheIntensity]int{
+	memoryadvisor.DropCacheIntensityLight:  1,
+	memoryadvisor.DropCacheIntensityMedium: 2,
+	memoryadvisor.DropCacheIntensityFull:   3,
+}
+
+// reapCacheTarget records the container and how much of its cache is to be dropped
+type reapCacheTarget struct {
+	ci        *types.ContainerInfo
+	intensity memoryadvisor.DropCacheIntensity
+	bytes     int64
+	// evict is set if dropping cache of the container has been ineffective for too many times
+	evict bool
+}
+
+// dropCacheRecord records container cache measured since the container is selected to drop cache,
+// to tell whether the cache refilled after being dropped
+type dropCacheRecord struct {
+	cacheBeforeDrop   float64
+	minCacheSinceDrop float64
+	ineffectiveTimes  int
+}
+
+// GetDropCacheIntensity maps the cache expected to be reclaimed from a container to an intensity
+// level by its ratio to the container cache, full is returned if container cache is unknown
+func GetDropCacheIntensity(targetReclaimed, cache float64) memoryadvisor.DropCacheIntensity {
+	switch {
+	case cache <= 0:
+		return memoryadvisor.DropCacheIntensityFull
+	case targetReclaimed <= cache*dropCacheLightRatio:
+		return memoryadvisor.DropCacheIntensityLight
+	case targetReclaimed <= cache*dropCacheMediumRatio:
+		return memoryadvisor.DropCacheIntensityMedium
+	default:
+		return memoryadvisor.DropCacheIntensityFull
+	}
+}
+
+// GetDropCacheBytes returns bytes of container cache to drop for the intensity level,
+// and 0 is returned for full intensity, which means dropping cache as much as possible
+func GetDropCacheBytes(intensity memoryadvisor.DropCacheIntensity, cache float64) int64 {
+	switch intensity {
+	case memoryadvisor.DropCacheIntensityLight:
+		return int64(cache * dropCacheLightRatio)
+	case memoryadvisor.DropCacheIntensityMedium:
+		return int64(cache * dropCacheMediumRatio)
+	default:
+		return 0
+	}
+}
+
 type cacheReaper struct {
 	conf                  *config.Configuration
 	mutex                 sync.RWMutex
 	metaReader            metacache.MetaReader
 	metaServer            *metaserver.MetaServer
 	emitter               metrics.MetricEmitter
-	containersToReapCache map[consts.PodContainerName]*types.ContainerInfo
+	containersToReapCache map[consts.PodContainerName]*reapCacheTarget
+
+	// numaCacheFallbackContainers records containers whose per-numa cache is estimated by
+	// container level cache, to make sure the fallback is logged only once for each container;
+	// it's only accessed in Reconcile, so no lock is needed
+	numaCacheFallbackContainers sets.String
+
+	// dropCacheRecords records cache of containers selected to drop cache in consecutive reconciles,
+	// it's only accessed in Reconcile, so no lock is needed
+	dropCacheRecords map[consts.PodContainerName]*dropCacheRecord
 }
 
 func NewCacheReaper(conf *config.Configuration, extraConfig interface{}, metaReader metacache.MetaReader, metaServer *metaserver.MetaServer, emitter metrics.MetricEmitter) MemoryAdvisorPlugin {
@@ -56,47 +129,143 @@ func NewCacheReaper(conf *config.Configuration, extraConfig interface{}, metaRea
 		conf:                  conf,
 		metaReader:            metaReader,
 		metaServer:            metaServer,
-		containersToReapCache: make(map[consts.PodContainerName]*types.ContainerInfo),
+		containersToReapCache: make(map[consts.PodContainerName]*reapCacheTarget),
 		emitter:               emitter,
+
+		numaCacheFallbackContainers: sets.NewString(),
+		dropCacheRecords:            make(map[consts.PodContainerName]*dropCacheRecord),
 	}
 }
 
-func (cp *cacheReaper) selectContainers(containers []*types.ContainerInfo, cacheToReap resource.Quantity, numaID int, metricName string) []*types.ContainerInfo {
+// getContainerMetric gets metric of the container; if per-numa file cache is missing, which may
+// happen on kernels or collectors not supporting it, it falls back to distributing container level
+// cache evenly across the numas the container is assigned to.
+func (cp *cacheReaper) getContainerMetric(ci *types.ContainerInfo, metricName string, numaID int) (float64, error) {
+	value, err := helper.GetContainerMetric(cp.metaServer.MetricsFetcher, cp.emitter, ci.PodUID, ci.ContainerName, metricName, numaID)
+	if err == nil || metricName != consts.MetricsMemFilePerNumaContainer {
+		return value, err
+	}
+
+	if _, ok := ci.TopologyAwareAssignments[numaID]; !ok || len(ci.TopologyAwareAssignments) == 0 {
+		return 0, fmt.Errorf("container is not assigned to numa %v: %v", numaID, err)
+	}
+
+	cache, fallbackErr := helper.GetContainerMetric(cp.metaServer.MetricsFetcher, cp.emitter, ci.PodUID, ci.ContainerName,
+		consts.MetricMemCacheContainer, -1)
+	if fallbackErr != nil {
+		return 0, fmt.Errorf("failed to get %v: %v, and fallback to %v failed: %v", metricName, err,
+			consts.MetricMemCacheContainer, fallbackErr)
+	}
+
+	containerKey := string(native.GeneratePodContainerName(ci.PodName, ci.ContainerName))
+	if !cp.numaCacheFallbackContainers.Has(containerKey) {
+		cp.numaCacheFallbackContainers.Insert(containerKey)
+		general.InfoS("per-numa cache is missing, fall back to container cache",
+			"podName", ci.PodName, "containerName", ci.ContainerName, "numaID", numaID)
+	}
+
+	return cache / float64(len(ci.TopologyAwareAssignments)), nil
+}
+
+// getMaxSelectedContainers returns the max number of containers to select for the target to reclaim;
+// in soft tier, i.e. the target is below the hard one, only the containers with the most cache are
+// selected, and 0 is returned in hard tier, which means selecting until the target is covered
+func (cp *cacheReaper) getMaxSelectedContainers(cacheToReap resource.Quantity) int {
+	if cp.conf.HardDropCacheTarget <= 0 || cacheToReap.Value() >= cp.conf.HardDropCacheTarget {
+		return 0
+	}
+	return general.Max(cp.conf.SoftDropCacheMaxContainers, 1)
+}
+
+func (cp *cacheReaper) selectContainers(containers []*types.ContainerInfo, cacheToReap resource.Quantity, numaID int, metricName string) []*reapCacheTarget {
 	general.NewMultiSorter(func(s1, s2 interface{}) int {
 		c1, c2 := s1.(*types.ContainerInfo), s2.(*types.ContainerInfo)
-		c1Metric, c1Err := helper.GetContainerMetric(cp.metaServer.MetricsFetcher, cp.emitter, c1.PodUID, c1.ContainerName, metricName, numaID)
-		c2Metric, c2Err := helper.GetContainerMetric(cp.metaServer.MetricsFetcher, cp.emitter, c2.PodUID, c2.ContainerName, metricName, numaID)
+		c1Metric, c1Err := cp.getContainerMetric(c1, metricName, numaID)
+		c2Metric, c2Err := cp.getContainerMetric(c2, metricName, numaID)
 		if c1Err != nil || c2Err != nil {
 			return general.CmpError(c1Err, c2Err)
 		}
 
 		// prioritize evicting the pod whose metric value is greater
 		return general.CmpFloat64(c1Metric, c2Metric)
+	}, func(s1, s2 interface{}) int {
+		c1, c2 := s1.(*types.ContainerInfo), s2.(*types.ContainerInfo)
+
+		// prioritize evicting the pod whose priority is lower if metric values are equal
+		return general.CmpInt32(cp.getPodPriority(c2), cp.getPodPriority(c1))
 	}).Sort(types.NewContainerSourceImpList(containers))
 
-	selected := make([]*types.ContainerInfo, 0)
+	selected := make([]*reapCacheTarget, 0)
 	sum := resource.NewQuantity(0, resource.BinarySI)
+	maxSelected := cp.getMaxSelectedContainers(cacheToReap)
 
 	for _, ci := range containers {
-		metric, err := helper.GetContainerMetric(cp.metaServer.MetricsFetcher, cp.emitter, ci.PodUID, ci.ContainerName, metricName, numaID)
+		metric, err := cp.getContainerMetric(ci, metricName, numaID)
 		if err != nil {
 			general.Errorf("failed to get metric %v for pod %v/%v container %v on numa %v err %v", metricName, ci.PodNamespace, ci.PodName, ci.ContainerName, numaID, err)
 			continue
 		}
-		selected = append(selected, ci)
+
+		target := &reapCacheTarget{ci: ci, intensity: memoryadvisor.DropCacheIntensityFull}
+		if cp.conf.EnableGraduatedDropCache {
+			// only the cache not covered by the containers selected before is expected from this one
+			target.intensity = GetDropCacheIntensity(float64(cacheToReap.Value()-sum.Value()), metric)
+			target.bytes = GetDropCacheBytes(target.intensity, metric)
+		}
+		selected = append(selected, target)
 		sum.Add(*resource.NewQuantity(int64(metric), resource.BinarySI))
-		if sum.Cmp(cacheToReap) > 0 {
+		if sum.Cmp(cacheToReap) > 0 || (maxSelected > 0 && len(selected) >= maxSelected) {
 			break
 		}
 	}
 	return selected
 }
 
+// getPodPriority returns priority in pod spec, and 0 is returned if it's not set or pod is not found
+func (cp *cacheReaper) getPodPriority(ci *types.ContainerInfo) int32 {
+	pod, err := cp.metaServer.GetPod(context.Background(), ci.PodUID)
+	if err != nil || pod.Spec.Priority == nil {
+		return 0
+	}
+	return *pod.Spec.Priority
+}
+
+// isExcludedPod checks whether the pod is protected from cache reaping by the configured
+// labels or annotations; if pod info can't be fetched, the pod is treated as excluded to be safe.
+func (cp *cacheReaper) isExcludedPod(ci *types.ContainerInfo) bool {
+	if len(cp.conf.ExcludedPodLabels) == 0 && len(cp.conf.ExcludedPodAnnotations) == 0 {
+		return false
+	}
+
+	pod, err := cp.metaServer.GetPod(context.Background(), ci.PodUID)
+	if err != nil {
+		general.ErrorS(err, "failed to get pod", "podName", ci.PodName, "podUID", ci.PodUID)
+		return true
+	}
+
+	for key, value := range cp.conf.ExcludedPodLabels {
+		if v, ok := pod.Labels[key]; ok && v == value {
+			return true
+		}
+	}
+	for key, value := range cp.conf.ExcludedPodAnnotations {
+		if v, ok := pod.Annotations[key]; ok && v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func (cp *cacheReaper) reclaimedContainersFilter(ci *types.ContainerInfo, numaID int, minCacheUtilizationThreshold float64) bool {
 	if ci == nil || ci.QoSLevel != apiconsts.PodAnnotationQoSLevelReclaimedCores || ci.ContainerType != v1alpha1.ContainerType_MAIN {
 		return false
 	}
 
+	if cp.isExcludedPod(ci) {
+		general.InfoS("skip reclaiming it because pod is excluded", "podName", ci.PodName, "containerName", ci.ContainerName)
+		return false
+	}
+
 	var (
 		total metric.MetricData
 		cache metric.MetricData
@@ -120,7 +289,7 @@ func (cp *cacheReaper) reclaimedContainersFilter(ci *types.ContainerInfo, numaID
 			general.ErrorS(err, "failed to get MetricMemTotalNuma")
 			return true
 		}
-		cache, err = cp.metaServer.GetContainerNumaMetric(ci.PodUID, ci.ContainerName, strconv.Itoa(numaID), consts.MetricsMemFilePerNumaContainer)
+		cache.Value, err = cp.getContainerMetric(ci, consts.MetricsMemFilePerNumaContainer, numaID)
 		if err != nil {
 			general.ErrorS(err, "failed to get MetricsMemFilePerNumaContainer", "podName", ci.PodName, "containerName", ci.ContainerName, "numaID", numaID)
 			return true
@@ -137,22 +306,48 @@ func (cp *cacheReaper) reclaimedContainersFilter(ci *types.ContainerInfo, numaID
 	return true
 }
 
+// addReapCacheTarget adds the target into targets, and if the container is selected for
+// multiple times (e.g. by both node and numa pressure), the higher intensity is kept
+func addReapCacheTarget(targets map[consts.PodContainerName]*reapCacheTarget, target *reapCacheTarget) {
+	key := native.GeneratePodContainerName(target.ci.PodName, target.ci.ContainerName)
+	existing, ok := targets[key]
+	if !ok {
+		targets[key] = target
+		return
+	}
+
+	if dropCacheIntensityOrder[target.intensity] > dropCacheIntensityOrder[existing.intensity] {
+		existing.intensity = target.intensity
+	}
+	if existing.intensity == memoryadvisor.DropCacheIntensityFull {
+		existing.bytes = 0
+	} else {
+		existing.bytes = general.MaxInt64(existing.bytes, target.bytes)
+	}
+}
+
 func (cp *cacheReaper) Reconcile(status *types.MemoryPressureStatus) error {
-	containersToReapCache := make(map[consts.PodContainerName]*types.ContainerInfo)
+	containersToReapCache := make(map[consts.PodContainerName]*reapCacheTarget)
 	minCacheUtilizationThreshold := cp.conf.MinCacheUtilizationThreshold
 
+	// forget the containers which no longer exist
+	existingContainers := sets.NewString()
 	containers := make([]*types.ContainerInfo, 0)
 	cp.metaReader.RangeContainer(func(podUID string, containerName string, containerInfo *types.ContainerInfo) bool {
+		if containerInfo != nil {
+			existingContainers.Insert(string(native.GeneratePodContainerName(containerInfo.PodName, containerName)))
+		}
 		if cp.reclaimedContainersFilter(containerInfo, state.FakedNUMAID, minCacheUtilizationThreshold) {
 			containers = append(containers, containerInfo)
 		}
 		return true
 	})
+	cp.numaCacheFallbackContainers = cp.numaCacheFallbackContainers.Intersection(existingContainers)
 
 	if status.NodeCondition.State == types.MemoryPressureDropCache && status.NodeCondition.TargetReclaimed != nil {
 		selected := cp.selectContainers(containers, *status.NodeCondition.TargetReclaimed, -1, consts.MetricMemCacheContainer)
-		for _, ci := range selected {
-			containersToReapCache[native.GeneratePodContainerName(ci.PodName, ci.ContainerName)] = ci
+		for _, target := range selected {
+			addReapCacheTarget(containersToReapCache, target)
 		}
 	}
 
@@ -166,30 +361,86 @@ func (cp *cacheReaper) Reconcile(status *types.MemoryPressureStatus) error {
 				return true
 			})
 			selected := cp.selectContainers(containers, *condition.TargetReclaimed, numaID, consts.MetricsMemFilePerNumaContainer)
-			for _, ci := range selected {
-				containersToReapCache[native.GeneratePodContainerName(ci.PodName, ci.ContainerName)] = ci
+			for _, target := range selected {
+				addReapCacheTarget(containersToReapCache, target)
 			}
 		}
 	}
 
+	cp.escalateIneffectiveDropCache(containersToReapCache)
+
 	cp.mutex.Lock()
 	defer cp.mutex.Unlock()
 	cp.containersToReapCache = containersToReapCache
 	return nil
 }
 
+// escalateIneffectiveDropCache measures cache of containers selected to drop cache in consecutive reconciles,
+// a drop is ineffective if the cache is observed to be dropped and then refilled, and the targets are marked
+// to be evicted if dropping their cache has been ineffective for too many times
+func (cp *cacheReaper) escalateIneffectiveDropCache(targets map[consts.PodContainerName]*reapCacheTarget) {
+	dropCacheRecords := make(map[consts.PodContainerName]*dropCacheRecord, len(targets))
+	for key, target := range targets {
+		record := cp.dropCacheRecords[key]
+
+		cache, err := cp.metaServer.GetContainerMetric(target.ci.PodUID, target.ci.ContainerName, consts.MetricMemCacheContainer)
+		if err != nil {
+			general.ErrorS(err, "failed to get MetricMemCacheContainer, skip measuring drop cache",
+				"podName", target.ci.PodName, "containerName", target.ci.ContainerName)
+		} else if record == nil {
+			record = &dropCacheRecord{cacheBeforeDrop: cache.Value, minCacheSinceDrop: cache.Value}
+		} else {
+			record.minCacheSinceDrop = math.Min(record.minCacheSinceDrop, cache.Value)
+			refilledThreshold := record.cacheBeforeDrop * dropCacheRefilledRatio
+			if record.minCacheSinceDrop <= refilledThreshold && cache.Value >= refilledThreshold {
+				record.ineffectiveTimes++
+				general.InfoS("dropping cache is ineffective since the cache refilled",
+					"podName", target.ci.PodName, "containerName", target.ci.ContainerName,
+					"cacheBeforeDrop", general.FormatMemoryQuantity(record.cacheBeforeDrop),
+					"minCacheSinceDrop", general.FormatMemoryQuantity(record.minCacheSinceDrop),
+					"cache", general.FormatMemoryQuantity(cache.Value), "ineffectiveTimes", record.ineffectiveTimes)
+
+				// the drop advised in this reconcile starts a new round of measurement
+				record.cacheBeforeDrop, record.minCacheSinceDrop = cache.Value, cache.Value
+			}
+		}
+
+		if record == nil {
+			continue
+		}
+		dropCacheRecords[key] = record
+
+		if cp.conf.IneffectiveDropCacheTimesToEvict > 0 && record.ineffectiveTimes >= cp.conf.IneffectiveDropCacheTimesToEvict {
+			general.InfoS("escalate to evict container since dropping its cache is ineffective",
+				"podName", target.ci.PodName, "containerName", target.ci.ContainerName, "ineffectiveTimes", record.ineffectiveTimes)
+			target.evict = true
+		}
+	}
+	cp.dropCacheRecords = dropCacheRecords
+}
+
 func (cp *cacheReaper) GetAdvices() types.InternalMemoryCalculationResult {
 	result := types.InternalMemoryCalculationResult{
 		ContainerEntries: make([]types.ContainerMemoryAdvices, 0),
 	}
 	cp.mutex.RLock()
 	defer cp.mutex.RUnlock()
-	for _, ci := range cp.containersToReapCache {
+	for _, target := range cp.containersToReapCache {
 		entry := types.ContainerMemoryAdvices{
-			PodUID:        ci.PodUID,
-			ContainerName: ci.ContainerName,
+			PodUID:        target.ci.PodUID,
+			ContainerName: target.ci.ContainerName,
 			Values:        map[string]string{string(memoryadvisor.ControlKnobKeyDropCache): "true"},
 		}
+		// full intensity keeps the legacy advice, which drops cache as much as possible
+		if target.intensity != memoryadvisor.DropCacheIntensityFull {
+			entry.Values[string(memoryadvisor.ControlKnobKeyDropCacheIntensity)] = string(target.intensity)
+			entry.Values[string(memoryadvisor.ControlKnobKeyDropCacheBytes)] = strconv.FormatInt(target.bytes, 10)
+		}
+		// dropping cache is still advised along with eviction, since the latter is only a signal
+		// which may not be handled by the consumers of the advices
+		if target.evict {
+			entry.Values[string(memoryadvisor.ControlKnobKeyEvict)] = "true"
+		}
 		result.ContainerEntries = append(result.ContainerEntries, entry)
 	}
 
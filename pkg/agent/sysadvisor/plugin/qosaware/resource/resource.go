@@ -19,6 +19,7 @@ package resource
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -43,6 +44,10 @@ type ResourceAdvisor interface {
 
 	// GetHeadroom returns the corresponding headroom quantity according to resource name
 	GetHeadroom(resourceName v1.ResourceName) (resource.Quantity, error)
+
+	// RegisterHeadroomProvider registers headroom provider for resources other than cpu and memory,
+	// so that their headroom can be reported the same way as cpu and memory
+	RegisterHeadroomProvider(resourceName v1.ResourceName, provider types.ResourceHeadroomProvider)
 }
 
 // SubResourceAdvisor updates resource provision of a certain dimension based on the latest
@@ -62,6 +67,9 @@ type SubResourceAdvisor interface {
 
 type resourceAdvisorWrapper struct {
 	subAdvisorsToRun map[types.QoSResourceName]SubResourceAdvisor
+
+	mutex             sync.RWMutex
+	headroomProviders map[v1.ResourceName]types.ResourceHeadroomProvider
 }
 
 // NewResourceAdvisor returns a resource advisor wrapper instance, initializing all required
@@ -70,7 +78,8 @@ func NewResourceAdvisor(conf *config.Configuration, extraConf interface{}, metaC
 	metaServer *metaserver.MetaServer, emitter metrics.MetricEmitter,
 ) (ResourceAdvisor, error) {
 	resourceAdvisor := resourceAdvisorWrapper{
-		subAdvisorsToRun: make(map[types.QoSResourceName]SubResourceAdvisor),
+		subAdvisorsToRun:  make(map[types.QoSResourceName]SubResourceAdvisor),
+		headroomProviders: make(map[v1.ResourceName]types.ResourceHeadroomProvider),
 	}
 
 	for _, resourceNameStr := range conf.ResourceAdvisors {
@@ -119,8 +128,29 @@ func (ra *resourceAdvisorWrapper) GetHeadroom(resourceName v1.ResourceName) (res
 	case v1.ResourceMemory:
 		return ra.getSubAdvisorHeadroom(types.QoSResourceMemory)
 	default:
+		return ra.getProviderHeadroom(resourceName)
+	}
+}
+
+func (ra *resourceAdvisorWrapper) RegisterHeadroomProvider(resourceName v1.ResourceName, provider types.ResourceHeadroomProvider) {
+	ra.mutex.Lock()
+	defer ra.mutex.Unlock()
+
+	ra.headroomProviders[resourceName] = provider
+}
+
+func (ra *resourceAdvisorWrapper) getProviderHeadroom(resourceName v1.ResourceName) (resource.Quantity, error) {
+	ra.mutex.RLock()
+	provider, ok := ra.headroomProviders[resourceName]
+	ra.mutex.RUnlock()
+
+	if !ok {
 		return resource.Quantity{}, fmt.Errorf("illegal resource %v", resourceName)
 	}
+	if provider.GetUpdateStatus() != types.PolicyUpdateSucceeded {
+		return resource.Quantity{}, fmt.Errorf("headroom provider for %v not updated", resourceName)
+	}
+	return provider.GetHeadroom()
 }
 
 func (ra *resourceAdvisorWrapper) getSubAdvisorHeadroom(resourceName types.QoSResourceName) (resource.Quantity, error) {
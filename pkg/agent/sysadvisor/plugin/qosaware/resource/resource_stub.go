@@ -30,6 +30,7 @@ import (
 type ResourceAdvisorStub struct {
 	sync.Mutex
 	resources map[v1.ResourceName]resource.Quantity
+	providers map[v1.ResourceName]types.ResourceHeadroomProvider
 }
 
 var _ ResourceAdvisor = NewResourceAdvisorStub()
@@ -37,6 +38,7 @@ var _ ResourceAdvisor = NewResourceAdvisorStub()
 func NewResourceAdvisorStub() *ResourceAdvisorStub {
 	return &ResourceAdvisorStub{
 		resources: make(map[v1.ResourceName]resource.Quantity),
+		providers: make(map[v1.ResourceName]types.ResourceHeadroomProvider),
 	}
 }
 
@@ -54,9 +56,19 @@ func (r *ResourceAdvisorStub) GetHeadroom(resourceName v1.ResourceName) (resourc
 	if quantity, ok := r.resources[resourceName]; ok {
 		return quantity, nil
 	}
+	if provider, ok := r.providers[resourceName]; ok {
+		return provider.GetHeadroom()
+	}
 	return resource.Quantity{}, fmt.Errorf("not exist")
 }
 
+func (r *ResourceAdvisorStub) RegisterHeadroomProvider(resourceName v1.ResourceName, provider types.ResourceHeadroomProvider) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.providers[resourceName] = provider
+}
+
 func (r *ResourceAdvisorStub) SetHeadroom(resourceName v1.ResourceName, quantity resource.Quantity) {
 	r.Lock()
 	defer r.Unlock()
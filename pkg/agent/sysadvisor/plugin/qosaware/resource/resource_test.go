@@ -0,0 +1,83 @@
+/*
+Copyright 2022 The Katalyst Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/types"
+)
+
+type fakeHeadroomProvider struct {
+	headroom     resource.Quantity
+	numaHeadroom map[int]resource.Quantity
+	updateStatus types.PolicyUpdateStatus
+}
+
+func (f *fakeHeadroomProvider) GetHeadroom() (resource.Quantity, error) {
+	return f.headroom, nil
+}
+
+func (f *fakeHeadroomProvider) GetNUMAHeadroom() (map[int]resource.Quantity, error) {
+	return f.numaHeadroom, nil
+}
+
+func (f *fakeHeadroomProvider) GetUpdateStatus() types.PolicyUpdateStatus {
+	return f.updateStatus
+}
+
+func TestResourceAdvisorWrapper_GetHeadroomFromProvider(t *testing.T) {
+	t.Parallel()
+
+	deviceResourceName := v1.ResourceName("example.com/device")
+	ra := &resourceAdvisorWrapper{
+		subAdvisorsToRun:  make(map[types.QoSResourceName]SubResourceAdvisor),
+		headroomProviders: make(map[v1.ResourceName]types.ResourceHeadroomProvider),
+	}
+
+	_, err := ra.GetHeadroom(deviceResourceName)
+	require.Error(t, err)
+
+	provider := &fakeHeadroomProvider{
+		headroom: resource.MustParse("4"),
+		numaHeadroom: map[int]resource.Quantity{
+			0: resource.MustParse("2"),
+			1: resource.MustParse("2"),
+		},
+		updateStatus: types.PolicyUpdateFailed,
+	}
+	ra.RegisterHeadroomProvider(deviceResourceName, provider)
+
+	// provider not updated yet
+	_, err = ra.GetHeadroom(deviceResourceName)
+	require.Error(t, err)
+
+	provider.updateStatus = types.PolicyUpdateSucceeded
+	headroom, err := ra.GetHeadroom(deviceResourceName)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), headroom.Value())
+
+	// cpu and memory are still served by sub advisors
+	ra.subAdvisorsToRun[types.QoSResourceCPU] = NewSubResourceAdvisorStub()
+	headroom, err = ra.GetHeadroom(v1.ResourceCPU)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), headroom.Value())
+}
@@ -55,6 +55,19 @@ const (
 	CPUProvisionAssemblerCommon CPUProvisionAssemblerName = "common"
 )
 
+// CPUZeroNumaAvailablePolicy defines how cpu provision assembler handles a numa with zero
+// available resource while other numas have, which is usually transient during startup
+type CPUZeroNumaAvailablePolicy string
+
+const (
+	// CPUZeroNumaAvailablePolicyNone sizes pools on the numa as if it has no capacity
+	CPUZeroNumaAvailablePolicyNone CPUZeroNumaAvailablePolicy = "none"
+	// CPUZeroNumaAvailablePolicyKeepLast skips sizing pools on the numa and keeps the last result
+	CPUZeroNumaAvailablePolicyKeepLast CPUZeroNumaAvailablePolicy = "keep-last"
+	// CPUZeroNumaAvailablePolicyError fails the provision assembling
+	CPUZeroNumaAvailablePolicyError CPUZeroNumaAvailablePolicy = "error"
+)
+
 // CPUHeadroomAssemblerName defines assemblers for cpu advisor to generate node
 // headroom from region headroom or node level policy
 type CPUHeadroomAssemblerName string
@@ -151,6 +164,20 @@ const (
 	BoundUnknownCode int       = 3
 )
 
+// ThrottleReason declares reasons why a region is throttled
+type ThrottleReason string
+
+const (
+	// ThrottleReasonNone indicates region is not throttled
+	ThrottleReasonNone ThrottleReason = "none"
+	// ThrottleReasonIsolationPressure indicates isolation pools take up the resource that region requires
+	ThrottleReasonIsolationPressure ThrottleReason = "isolation_pressure"
+	// ThrottleReasonReserve indicates the resource reserved for reclaim takes up the resource that region requires
+	ThrottleReasonReserve ThrottleReason = "reserve"
+	// ThrottleReasonCapacity indicates the requirement of region exceeds capacity
+	ThrottleReasonCapacity ThrottleReason = "capacity"
+)
+
 // RegionStatus holds stability accounting info of region
 type RegionStatus struct {
 	OvershootStatus map[string]OvershootType `json:"overshoot_status"` // map[indicatorMetric]overshootType
@@ -188,6 +215,14 @@ type RegionInfo struct {
 	HeadroomPolicyInUse       CPUHeadroomPolicyName `json:"headroom_policy_in_use"`
 }
 
+// RegionDescriptor is a lightweight snapshot of region for consumers out of the advisor
+type RegionDescriptor struct {
+	Name          string        `json:"name"`
+	Type          QoSRegionType `json:"type"`
+	OwnerPoolName string        `json:"owner_pool_name"`
+	Throttled     bool          `json:"throttled"`
+}
+
 // InternalCPUCalculationResult conveys minimal information to cpu server for composing
 // calculation result
 type InternalCPUCalculationResult struct {
@@ -195,6 +230,22 @@ type InternalCPUCalculationResult struct {
 	TimeStamp   time.Time
 }
 
+// PoolEntryDiff describes the change of cpu size of a pool in a numa between two calculation results
+type PoolEntryDiff struct {
+	PoolName string
+	NumaID   int
+	Before   int
+	After    int
+}
+
+// CalculationResultDiff holds pool entries added, removed or changed between two calculation results,
+// each sorted by pool name and numa id
+type CalculationResultDiff struct {
+	Added   []PoolEntryDiff
+	Removed []PoolEntryDiff
+	Changed []PoolEntryDiff
+}
+
 // ControlEssentials defines essential metrics for cpu advisor feedback control
 type ControlEssentials struct {
 	ControlKnobs   ControlKnob
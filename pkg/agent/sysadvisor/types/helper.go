@@ -17,7 +17,9 @@ limitations under the License.
 package types
 
 import (
+	"encoding/json"
 	"reflect"
+	"sort"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 
@@ -272,6 +274,88 @@ func (r *InternalCPUCalculationResult) SetPoolEntry(poolName string, numaID int,
 	r.PoolEntries[poolName][numaID] = poolSize
 }
 
+// Clone returns a deep copy of calculation result
+func (r *InternalCPUCalculationResult) Clone() *InternalCPUCalculationResult {
+	clone := &InternalCPUCalculationResult{
+		TimeStamp: r.TimeStamp,
+	}
+	if r.PoolEntries != nil {
+		clone.PoolEntries = make(map[string]map[int]int, len(r.PoolEntries))
+		for poolName, entries := range r.PoolEntries {
+			clone.PoolEntries[poolName] = make(map[int]int, len(entries))
+			for numaID, size := range entries {
+				clone.PoolEntries[poolName][numaID] = size
+			}
+		}
+	}
+	return clone
+}
+
+// Marshal returns deterministic json of calculation result for checkpointing and diffing,
+// since map keys are always sorted by encoding/json
+func (r *InternalCPUCalculationResult) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// UnmarshalInternalCPUCalculationResult parses calculation result from json generated by Marshal
+func UnmarshalInternalCPUCalculationResult(data []byte) (*InternalCPUCalculationResult, error) {
+	r := &InternalCPUCalculationResult{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// DiffCalculationResult compares pool entries of two calculation results and returns what is
+// added, removed or changed from a to b; nil result is regarded as empty
+func DiffCalculationResult(a, b *InternalCPUCalculationResult) *CalculationResultDiff {
+	var before, after map[string]map[int]int
+	if a != nil {
+		before = a.PoolEntries
+	}
+	if b != nil {
+		after = b.PoolEntries
+	}
+
+	diff := &CalculationResultDiff{}
+	for poolName, entries := range before {
+		for numaID, size := range entries {
+			newSize, ok := after[poolName][numaID]
+			if !ok {
+				diff.Removed = append(diff.Removed, PoolEntryDiff{PoolName: poolName, NumaID: numaID, Before: size})
+			} else if newSize != size {
+				diff.Changed = append(diff.Changed, PoolEntryDiff{PoolName: poolName, NumaID: numaID, Before: size, After: newSize})
+			}
+		}
+	}
+	for poolName, entries := range after {
+		for numaID, size := range entries {
+			if _, ok := before[poolName][numaID]; !ok {
+				diff.Added = append(diff.Added, PoolEntryDiff{PoolName: poolName, NumaID: numaID, After: size})
+			}
+		}
+	}
+
+	for _, entries := range [][]PoolEntryDiff{diff.Added, diff.Removed, diff.Changed} {
+		sortPoolEntryDiffs(entries)
+	}
+	return diff
+}
+
+// IsEmpty returns true if there is no difference between the two calculation results
+func (d *CalculationResultDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func sortPoolEntryDiffs(entries []PoolEntryDiff) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].PoolName != entries[j].PoolName {
+			return entries[i].PoolName < entries[j].PoolName
+		}
+		return entries[i].NumaID < entries[j].NumaID
+	})
+}
+
 func (ck ControlKnob) Clone() ControlKnob {
 	if ck == nil {
 		return nil
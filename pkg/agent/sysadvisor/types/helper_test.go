@@ -19,8 +19,10 @@ package types
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/kubewharf/katalyst-api/pkg/consts"
@@ -63,3 +65,65 @@ func TestClonePodEntries(t *testing.T) {
 
 	assert.True(t, reflect.DeepEqual(copyPodEntries, podEntries))
 }
+
+func TestMarshalInternalCPUCalculationResult(t *testing.T) {
+	t.Parallel()
+
+	r := &InternalCPUCalculationResult{
+		PoolEntries: map[string]map[int]int{
+			"share":   {-1: 20},
+			"reclaim": {1: 4, 0: 6},
+			"reserve": {-1: 2},
+		},
+		TimeStamp: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	data, err := r.Marshal()
+	require.NoError(t, err)
+	require.Equal(t, `{"PoolEntries":{"reclaim":{"0":6,"1":4},"reserve":{"-1":2},"share":{"-1":20}},"TimeStamp":"2023-01-01T00:00:00Z"}`, string(data))
+
+	for i := 0; i < 10; i++ {
+		again, err := r.Marshal()
+		require.NoError(t, err)
+		require.Equal(t, data, again)
+	}
+
+	got, err := UnmarshalInternalCPUCalculationResult(data)
+	require.NoError(t, err)
+	require.Equal(t, r, got)
+
+	_, err = UnmarshalInternalCPUCalculationResult([]byte("invalid"))
+	require.Error(t, err)
+}
+
+func TestDiffCalculationResult(t *testing.T) {
+	t.Parallel()
+
+	before := &InternalCPUCalculationResult{
+		PoolEntries: map[string]map[int]int{
+			"share":   {-1: 20},
+			"reclaim": {0: 6, 1: 4},
+			"reserve": {-1: 2},
+		},
+	}
+
+	after := before.Clone()
+	require.True(t, DiffCalculationResult(before, after).IsEmpty())
+
+	after.PoolEntries["reclaim"][1] = 8
+	diff := DiffCalculationResult(before, after)
+	require.Equal(t, &CalculationResultDiff{
+		Changed: []PoolEntryDiff{{PoolName: "reclaim", NumaID: 1, Before: 4, After: 8}},
+	}, diff)
+
+	after.PoolEntries["isolation"] = map[int]int{-1: 2}
+	delete(after.PoolEntries, "reserve")
+	diff = DiffCalculationResult(before, after)
+	require.Equal(t, []PoolEntryDiff{{PoolName: "isolation", NumaID: -1, After: 2}}, diff.Added)
+	require.Equal(t, []PoolEntryDiff{{PoolName: "reserve", NumaID: -1, Before: 2}}, diff.Removed)
+	require.Len(t, diff.Changed, 1)
+
+	diff = DiffCalculationResult(nil, before)
+	require.Len(t, diff.Added, 4)
+	require.Empty(t, diff.Removed)
+}
@@ -19,6 +19,7 @@ package types
 import (
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/kubelet/pkg/apis/resourceplugin/v1alpha1"
 )
@@ -95,6 +96,17 @@ const (
 	PolicyUpdateFailed    PolicyUpdateStatus = "failed"
 )
 
+// ResourceHeadroomProvider is implemented by anything that can advertise reclaimable
+// capacity of a certain resource, so that it can be reported in a uniform way.
+type ResourceHeadroomProvider interface {
+	// GetHeadroom returns the latest total headroom quantity
+	GetHeadroom() (resource.Quantity, error)
+	// GetNUMAHeadroom returns the latest headroom quantity keyed by numa id
+	GetNUMAHeadroom() (map[int]resource.Quantity, error)
+	// GetUpdateStatus returns whether the latest headroom calculation succeeded
+	GetUpdateStatus() PolicyUpdateStatus
+}
+
 type TriggerInfo struct {
 	TimeStamp time.Time
 }
@@ -47,6 +47,10 @@ type HandlerCtx struct {
 	interval time.Duration
 	ready    bool
 	funcName string
+
+	// jitterFactor spreads the first fire and subsequent intervals of the handler,
+	// to avoid handlers on different nodes running at the same time
+	jitterFactor float64
 }
 
 // PeriodicalHandlerManager works as a general framework to run periodical jobs;
@@ -103,9 +107,19 @@ func (phm *PeriodicalHandlerManager) Run(ctx context.Context) {
 					"interval", handlerCtx.interval)
 
 				handlerCtx.ctx, handlerCtx.cancel = context.WithCancel(context.Background())
-				go wait.Until(func() {
-					handlerCtx.handler(phm.coreConf, phm.extraConf, phm.dynamicConf, phm.emitter, phm.metaServer)
-				}, handlerCtx.interval, handlerCtx.ctx.Done())
+				go func(ctx context.Context) {
+					if delay := firstFireDelay(handlerCtx.interval, handlerCtx.jitterFactor); delay > 0 {
+						select {
+						case <-time.After(delay):
+						case <-ctx.Done():
+							return
+						}
+					}
+
+					wait.JitterUntil(func() {
+						handlerCtx.handler(phm.coreConf, phm.extraConf, phm.dynamicConf, phm.emitter, phm.metaServer)
+					}, handlerCtx.interval, handlerCtx.jitterFactor, true, ctx.Done())
+				}(handlerCtx.ctx)
 			}
 		}
 	}, 5*time.Second, ctx.Done())
@@ -118,20 +132,51 @@ var (
 	handlerMtx  sync.Mutex
 )
 
+// firstFireDelay returns a random delay in [0, interval*jitterFactor) before the first fire of a handler
+func firstFireDelay(interval time.Duration, jitterFactor float64) time.Duration {
+	if jitterFactor <= 0 {
+		return 0
+	}
+	return wait.Jitter(interval, jitterFactor) - interval
+}
+
 func RegisterPeriodicalHandlerWithHealthz(handlerName string, initState general.HealthzCheckState, groupName string,
 	handler Handler, interval time.Duration, tolerationTimes int64,
 ) (err error) {
-	general.RegisterHeartbeatCheck(handlerName, time.Duration(tolerationTimes)*interval, initState, time.Duration(tolerationTimes)*interval)
-	return RegisterPeriodicalHandler(groupName, handlerName, handler, interval)
+	return RegisterJitteredPeriodicalHandlerWithHealthz(handlerName, initState, groupName, handler, interval, tolerationTimes, 0)
+}
+
+// RegisterJitteredPeriodicalHandlerWithHealthz is the same as RegisterPeriodicalHandlerWithHealthz,
+// but the handler fires with jittered intervals
+func RegisterJitteredPeriodicalHandlerWithHealthz(handlerName string, initState general.HealthzCheckState, groupName string,
+	handler Handler, interval time.Duration, tolerationTimes int64, jitterFactor float64,
+) (err error) {
+	// the max interval between two fires is enlarged by jitter
+	maxInterval := interval
+	if jitterFactor > 0 {
+		maxInterval = time.Duration(float64(interval) * (1 + jitterFactor))
+	}
+	general.RegisterHeartbeatCheck(handlerName, time.Duration(tolerationTimes)*maxInterval, initState, time.Duration(tolerationTimes)*maxInterval)
+	return RegisterJitteredPeriodicalHandler(groupName, handlerName, handler, interval, jitterFactor)
 }
 
 func RegisterPeriodicalHandler(groupName, handlerName string, handler Handler, interval time.Duration) (err error) {
+	return RegisterJitteredPeriodicalHandler(groupName, handlerName, handler, interval, 0)
+}
+
+// RegisterJitteredPeriodicalHandler registers a periodical handler whose first fire is delayed by a random
+// duration in [0, interval*jitterFactor), and the following intervals are in [interval, interval*(1+jitterFactor))
+func RegisterJitteredPeriodicalHandler(groupName, handlerName string, handler Handler, interval time.Duration,
+	jitterFactor float64,
+) (err error) {
 	if groupName == "" || handlerName == "" {
 		return fmt.Errorf("emptry groupName: %s or handlerName: %s", groupName, handlerName)
 	} else if handler == nil {
 		return fmt.Errorf("nil handler")
 	} else if interval <= 0 {
 		return fmt.Errorf("invalid interval: %v", interval)
+	} else if jitterFactor < 0 {
+		return fmt.Errorf("invalid jitter factor: %v", jitterFactor)
 	}
 
 	defer func() {
@@ -153,7 +198,8 @@ func RegisterPeriodicalHandler(groupName, handlerName string, handler Handler, i
 			"oldFuncName", handlerCtxs[groupName][handlerName].funcName,
 			"oldInterval", handlerCtxs[groupName][handlerName].interval,
 			"newFuncName", newFuncName,
-			"newInterval", interval)
+			"newInterval", interval,
+			"jitterFactor", jitterFactor)
 
 		if handlerCtxs[groupName][handlerName].cancel != nil {
 			handlerCtxs[groupName][handlerName].cancel()
@@ -163,7 +209,8 @@ func RegisterPeriodicalHandler(groupName, handlerName string, handler Handler, i
 			"groupName", groupName,
 			"handlerName", handlerName,
 			"newFuncName", newFuncName,
-			"newInterval", interval)
+			"newInterval", interval,
+			"jitterFactor", jitterFactor)
 
 		if handlerCtxs[groupName] == nil {
 			handlerCtxs[groupName] = make(map[string]*HandlerCtx)
@@ -171,9 +218,10 @@ func RegisterPeriodicalHandler(groupName, handlerName string, handler Handler, i
 	}
 
 	handlerCtxs[groupName][handlerName] = &HandlerCtx{
-		handler:  handler,
-		interval: interval,
-		funcName: newFuncName,
+		handler:      handler,
+		interval:     interval,
+		funcName:     newFuncName,
+		jitterFactor: jitterFactor,
 	}
 
 	return nil
@@ -239,3 +287,11 @@ func StopHandlersByGroup(groupName string) {
 		handlerCtx.ready = false
 	}
 }
+
+// IsPeriodicalHandlerRegistered returns whether the handler is registered in the group
+func IsPeriodicalHandlerRegistered(groupName, handlerName string) bool {
+	handlerMtx.Lock()
+	defer handlerMtx.Unlock()
+
+	return handlerCtxs[groupName][handlerName] != nil
+}
@@ -153,3 +153,39 @@ testLoop2:
 	lock.RUnlock()
 	cancel()
 }
+
+func TestFirstFireDelay(t *testing.T) {
+	t.Parallel()
+
+	interval := 10 * time.Second
+	require.Equal(t, time.Duration(0), firstFireDelay(interval, 0))
+
+	jitterFactor := 0.5
+	for i := 0; i < 100; i++ {
+		delay := firstFireDelay(interval, jitterFactor)
+		require.GreaterOrEqual(t, delay, time.Duration(0))
+		require.Less(t, delay, time.Duration(float64(interval)*jitterFactor))
+	}
+}
+
+func TestRegisterJitteredPeriodicalHandler(t *testing.T) {
+	t.Parallel()
+
+	handler := func(coreConf *config.Configuration,
+		extraConf interface{},
+		dynamicConf *dynamicconfig.DynamicAgentConfiguration,
+		emitter metrics.MetricEmitter,
+		metaServer *metaserver.MetaServer,
+	) {
+	}
+
+	gName := "test_jittered_group"
+	require.Error(t, RegisterJitteredPeriodicalHandler(gName, "test_handler", handler, time.Second, -0.1))
+
+	require.NoError(t, RegisterJitteredPeriodicalHandler(gName, "test_handler", handler, time.Second, 0.2))
+
+	handlerMtx.Lock()
+	defer handlerMtx.Unlock()
+	require.Equal(t, 0.2, handlerCtxs[gName]["test_handler"].jitterFactor)
+	require.Equal(t, time.Second, handlerCtxs[gName]["test_handler"].interval)
+}
@@ -31,6 +31,8 @@ type BaseConfiguration struct {
 	LockFileName string
 	// if LockWaitingEnabled set as true, will not panic and report agent as healthy instead
 	LockWaitingEnabled bool
+	// HealthzStateFile is the file to persist healthz states across restarts; empty means disabled
+	HealthzStateFile string
 
 	// ReclaimRelativeRootCgroupPath is configurable since we may need to
 	// specify a customized path for reclaimed-cores to enrich qos-management ways
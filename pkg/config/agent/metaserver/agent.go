@@ -36,6 +36,10 @@ type MetricConfiguration struct {
 	DefaultInterval      time.Duration
 	ProvisionerIntervals map[string]time.Duration
 
+	// MetricStoreStalenessThreshold is the max period allowed without any update of
+	// the metric store before healthz reports not ready; 0 means no check
+	MetricStoreStalenessThreshold time.Duration
+
 	*MalachiteMetricConfiguration
 	*CgroupMetricConfiguration
 	*KubeletMetricConfiguration
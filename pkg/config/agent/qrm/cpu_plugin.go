@@ -45,6 +45,21 @@ type CPUDynamicPolicyConfig struct {
 	// CPUNUMAHintPreferPolicy indicates threshold to apply CPUNUMAHintPreferPolicy dynamically,
 	// and it's working when CPUNUMAHintPreferPolicy is set to dynamic_packing
 	CPUNUMAHintPreferLowThreshold float64
+	// AsyncHandlerJitterFactor spreads the first fire and intervals of periodical async handlers,
+	// e.g. checkCPUSet, clearResidualState and syncCPUIdle, 0 means no jitter
+	AsyncHandlerJitterFactor float64
+	// EnableCheckCPUSet and EnableClearResidualState indicate whether to run the corresponding
+	// async handlers, and syncCPUIdle is controlled by EnableSyncingCPUIdle
+	EnableCheckCPUSet        bool
+	EnableClearResidualState bool
+	// EnableCheckReclaimPoolDrift indicates whether to periodically compare the reclaim pool in state with
+	// cpusets of reclaimed_cores containers and cpu quota of reclaim cgroup, and ReclaimPoolDriftTolerance
+	// is the max tolerated difference in cores
+	EnableCheckReclaimPoolDrift bool
+	ReclaimPoolDriftTolerance   int
+	// ResidualStateMinMissCount is the min number of consecutive checks a pod must be missing from
+	// pod watcher before its residual state is cleared, in addition to the max residual time
+	ResidualStateMinMissCount int
 }
 
 type CPUNativePolicyConfig struct {
@@ -16,12 +16,41 @@ limitations under the License.
 
 package reporter
 
+import "time"
+
 type KubeletPluginConfiguration struct {
 	PodResourcesServerEndpoints []string
 	KubeletResourcePluginPaths  []string
 	EnableReportTopologyPolicy  bool
 	ResourceNameToZoneTypeMap   map[string]string
 	NeedValidationResources     []string
+
+	// NUMAAllocationImbalanceThreshold is the threshold of numa allocation imbalance ratio
+	// to report healthz warning, zero means disabled
+	NUMAAllocationImbalanceThreshold float64
+
+	// TopologyReportHealthzTimeout is the timeout of topology report healthz heartbeat,
+	// zero means no timeout check
+	TopologyReportHealthzTimeout time.Duration
+
+	// PodResourcesCallTimeout is the timeout of each call to pod resources server,
+	// zero means only bounded by the timeout of the whole report cycle
+	PodResourcesCallTimeout time.Duration
+
+	// EnableReportTopologyOnlyOnChange indicates whether to skip reporting topology status
+	// if it's not changed since last report, and TopologyForceReportPeriod is the period
+	// to report it anyway in case of missed updates
+	EnableReportTopologyOnlyOnChange bool
+	TopologyForceReportPeriod        time.Duration
+
+	// EnableReportContainerLevelTopology indicates whether to report topology allocations
+	// per container, whose consumer is namespace/name/uid/container, instead of per pod
+	EnableReportContainerLevelTopology bool
+
+	// EnableReportTopologylessDevices indicates whether to count devices without topology
+	// into a synthetic numa zone with TopologylessDeviceNumaID instead of dropping them
+	EnableReportTopologylessDevices bool
+	TopologylessDeviceNumaID        int
 }
 
 func NewKubeletPluginConfiguration() *KubeletPluginConfiguration {
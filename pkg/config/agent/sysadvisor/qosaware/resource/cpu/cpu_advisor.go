@@ -17,12 +17,16 @@ limitations under the License.
 package cpu
 
 import (
+	"time"
+
 	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/types"
 	"github.com/kubewharf/katalyst-core/pkg/config/agent/sysadvisor/qosaware/resource/cpu/headroom"
 	"github.com/kubewharf/katalyst-core/pkg/config/agent/sysadvisor/qosaware/resource/cpu/provision"
 	"github.com/kubewharf/katalyst-core/pkg/config/agent/sysadvisor/qosaware/resource/cpu/region"
 )
 
+const defaultMetricsBufferSize = 10
+
 // CPUAdvisorConfiguration stores configurations of cpu advisors in qos aware plugin
 type CPUAdvisorConfiguration struct {
 	ProvisionPolicies  map[types.QoSRegionType][]types.CPUProvisionPolicyName
@@ -30,6 +34,62 @@ type CPUAdvisorConfiguration struct {
 	ProvisionAssembler types.CPUProvisionAssemblerName
 	HeadroomAssembler  types.CPUHeadroomAssemblerName
 
+	// PoolProvisionPolicies overrides the provision policy of share regions by owner pool name
+	PoolProvisionPolicies map[string]types.CPUProvisionPolicyName
+
+	// MetricsBufferSize is the max number of pending metric batches emitted asynchronously,
+	// the oldest batch will be dropped if the buffer is full
+	MetricsBufferSize int
+	// MaxReclaimCoreNum caps the total size of reclaim pool across all numas, 0 means no limit
+	MaxReclaimCoreNum int
+	// MaxIsolationCoreNum caps the size of each isolation region, 0 means no limit
+	MaxIsolationCoreNum int
+	// MinSharePoolCoreNum is the floor of each non-binding share pool, which is kept by shrinking
+	// isolation pools to their lower sizes first; 0 means no floor
+	MinSharePoolCoreNum int
+	// ClampProvisionByEssentials makes assembler clamp non-reclaimed cpu size of each region into
+	// [ResourceLowerBound, ResourceUpperBound] of its essentials, to tolerate out-of-bound policy results
+	ClampProvisionByEssentials bool
+	// ExtraReservedPoolNames are pools managed out of advisor (e.g. by operators), which are
+	// protected like the reserve pool, i.e. share and reclaim pools never expand over them
+	ExtraReservedPoolNames []string
+	// ZeroNumaAvailablePolicy defines how assembler handles numa binding regions on a numa with zero
+	// available resource while other numas have, to tolerate a numa not populated yet at startup
+	ZeroNumaAvailablePolicy types.CPUZeroNumaAvailablePolicy
+
+	// EnableAdaptiveReservedForReclaim makes reserved for reclaim of each numa scale with its cpu idle,
+	// bounded by [AdaptiveReservedForReclaimMin, AdaptiveReservedForReclaimMax]; otherwise the static
+	// value from dynamic configuration is used
+	EnableAdaptiveReservedForReclaim bool
+	AdaptiveReservedForReclaimMin    int
+	AdaptiveReservedForReclaimMax    int
+
+	// DrainedNUMAs are excluded from non-binding numas for hardware maintenance, so that no share
+	// or reclaim pool is placed on them; numa binding regions already there are left alone
+	DrainedNUMAs []int
+
+	// NUMAEnableReclaim overrides the node-wide reclaim switch from dynamic configuration on
+	// the given numas, and numas with reclaim disabled only keep reserved for reclaim
+	NUMAEnableReclaim map[int]bool
+
+	// NUMAReservedForAllocate overrides the cpu cores reserved for allocate on the given numas,
+	// which otherwise share the node-wide value from dynamic configuration evenly
+	NUMAReservedForAllocate map[int]float64
+
+	// MaxHeadroomCores and MaxHeadroomRatio cap the reported headroom by absolute cores and by
+	// fraction of node cpus respectively, per-numa headroom is capped in proportion; 0 means no limit
+	MaxHeadroomCores float64
+	MaxHeadroomRatio float64
+
+	// ServeDefaultHeadroomBeforeUpdated makes advisor serve DefaultHeadroomCores with a degraded
+	// healthz state before its first successful update, instead of returning error
+	ServeDefaultHeadroomBeforeUpdated bool
+	DefaultHeadroomCores              float64
+
+	// FreezeOnStaleMetricsThreshold makes advisor keep the last calculation result with a degraded
+	// healthz state if numa or container metrics are older than it; 0 means disabled
+	FreezeOnStaleMetricsThreshold time.Duration
+
 	*headroom.CPUHeadroomPolicyConfiguration
 	*provision.CPUProvisionPolicyConfiguration
 	*region.CPURegionConfiguration
@@ -41,8 +101,12 @@ func NewCPUAdvisorConfiguration() *CPUAdvisorConfiguration {
 	return &CPUAdvisorConfiguration{
 		ProvisionPolicies:               map[types.QoSRegionType][]types.CPUProvisionPolicyName{},
 		HeadroomPolicies:                map[types.QoSRegionType][]types.CPUHeadroomPolicyName{},
+		PoolProvisionPolicies:           map[string]types.CPUProvisionPolicyName{},
+		NUMAEnableReclaim:               map[int]bool{},
+		NUMAReservedForAllocate:         map[int]float64{},
 		ProvisionAssembler:              types.CPUProvisionAssemblerCommon,
 		HeadroomAssembler:               types.CPUHeadroomAssemblerCommon,
+		MetricsBufferSize:               defaultMetricsBufferSize,
 		CPUHeadroomPolicyConfiguration:  headroom.NewCPUHeadroomPolicyConfiguration(),
 		CPUProvisionPolicyConfiguration: provision.NewCPUProvisionPolicyConfiguration(),
 		CPURegionConfiguration:          region.NewCPURegionConfiguration(),
@@ -17,6 +17,8 @@ limitations under the License.
 package cpu
 
 import (
+	"time"
+
 	"k8s.io/apimachinery/pkg/util/sets"
 )
 
@@ -47,6 +49,16 @@ type CPUIsolationConfiguration struct {
 	IsolationDisabledPools     sets.String
 	IsolationForceEnablePools  sets.String
 	IsolationNonExclusivePools sets.String
+
+	// IsolationSafetyFailuresToSkip and IsolationSafetySkipCooldown defines that after the given
+	// consecutive safety check failures, updating with isolation is skipped during the cooldown
+	// before probing it again; zero IsolationSafetyFailuresToSkip means never skipping
+	IsolationSafetyFailuresToSkip int
+	IsolationSafetySkipCooldown   time.Duration
+
+	// IsolationMaxRegionsPerNuma caps the number of isolation regions on each numa to bound
+	// fragmentation, and extra isolation candidates are kept in share pool; 0 means no limit
+	IsolationMaxRegionsPerNuma int
 }
 
 // NewCPUIsolationConfiguration creates new resource advisor configurations
@@ -17,6 +17,8 @@ limitations under the License.
 package provision
 
 import (
+	"time"
+
 	"github.com/kubewharf/katalyst-api/pkg/apis/workload/v1alpha1"
 	"github.com/kubewharf/katalyst-core/pkg/agent/sysadvisor/types"
 )
@@ -24,6 +26,13 @@ import (
 type CPUProvisionPolicyConfiguration struct {
 	RegionIndicatorTargetConfiguration map[types.QoSRegionType][]types.IndicatorTargetConfiguration
 	PolicyRama                         *PolicyRamaConfiguration
+
+	// ProvisionFallbackFailedThreshold is the number of consecutive provision failures of a region
+	// before it falls back to none policy temporarily, 0 means never fall back
+	ProvisionFallbackFailedThreshold int
+	// ProvisionFallbackCooldown is the duration for a region to stay in fallback before retrying
+	// its configured provision policies
+	ProvisionFallbackCooldown time.Duration
 }
 
 func NewCPUProvisionPolicyConfiguration() *CPUProvisionPolicyConfiguration {
@@ -46,6 +55,7 @@ func NewCPUProvisionPolicyConfiguration() *CPUProvisionPolicyConfiguration {
 				},
 			},
 		},
-		PolicyRama: NewPolicyRamaConfiguration(),
+		PolicyRama:                NewPolicyRamaConfiguration(),
+		ProvisionFallbackCooldown: 5 * time.Minute,
 	}
 }
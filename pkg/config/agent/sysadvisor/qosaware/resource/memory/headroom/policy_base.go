@@ -16,7 +16,24 @@ limitations under the License.
 
 package headroom
 
+import "time"
+
 type MemoryHeadroomPolicyConfiguration struct {
+	// NUMAAwareHealthCheckTimeout is the duration that numa-aware headroom policy is allowed to
+	// be not updated successfully before its healthz check turns not ready
+	NUMAAwareHealthCheckTimeout time.Duration
+	// NUMAAwareExcludedNUMAs are numas whose memory is never reported as reclaimable by numa-aware
+	// headroom policy, e.g. numas reserved for special tenants
+	NUMAAwareExcludedNUMAs []int
+	// NUMAAwareAllowPartialCoverage allows numa-aware headroom policy to compute headroom from numas
+	// with memory metrics populated, and report the uncovered ones as zero instead of failing
+	NUMAAwareAllowPartialCoverage bool
+	// NUMAAwareBlendWeight is the weight of numa-aware result in numa-aware-blended headroom policy,
+	// and the canonical result takes the rest; NUMAAwareBlendBoundedBySafer makes headroom of each numa
+	// never exceed the smaller one of the two results, which leaves NUMAAwareBlendWeight no effect
+	NUMAAwareBlendWeight         float64
+	NUMAAwareBlendBoundedBySafer bool
+
 	*MemoryPolicyCanonicalConfiguration
 }
 
@@ -18,10 +18,34 @@ package plugins
 
 type CacheReaperConfiguration struct {
 	MinCacheUtilizationThreshold float64
+
+	// ExcludedPodLabels and ExcludedPodAnnotations protect reclaimed pods from being
+	// selected by cache-reaper, a pod matching any of the key-value pairs is excluded
+	ExcludedPodLabels      map[string]string
+	ExcludedPodAnnotations map[string]string
+
+	// EnableGraduatedDropCache makes cache-reaper advise dropping part of the container cache
+	// according to how much cache is expected to be reclaimed from it, instead of all of it
+	EnableGraduatedDropCache bool
+
+	// IneffectiveDropCacheTimesToEvict is the number of ineffective drops, i.e. the container cache is
+	// observed to be dropped and then refilled while it keeps being selected, after which cache-reaper
+	// advises evicting the container along with dropping its cache; 0 means never escalating to eviction
+	IneffectiveDropCacheTimesToEvict int
+
+	// HardDropCacheTarget is the target to reclaim at or above which cache-reaper works in hard tier,
+	// selecting containers with the most cache until the target is covered; below it, cache-reaper
+	// works in soft tier and only selects at most SoftDropCacheMaxContainers containers with the most
+	// cache; 0 means always working in hard tier
+	HardDropCacheTarget        int64
+	SoftDropCacheMaxContainers int
 }
 
 func NewCacheReaperConfiguration() *CacheReaperConfiguration {
 	return &CacheReaperConfiguration{
 		MinCacheUtilizationThreshold: 0,
+		ExcludedPodLabels:            map[string]string{},
+		ExcludedPodAnnotations:       map[string]string{},
+		SoftDropCacheMaxContainers:   1,
 	}
 }
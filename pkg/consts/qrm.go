@@ -21,4 +21,9 @@ const (
 	KubeletQoSResourceManagerCheckpoint = "kubelet_qrm_checkpoint"
 
 	MainContainerNameAnnotationKey = "kubernetes.io/main-container-name"
+
+	// PodAnnotationCPUIsolationOptInKey marks the pod to be always isolated by cpu advisor
+	// when set as PodAnnotationCPUIsolationOptInEnabled, regardless of the load-based isolator
+	PodAnnotationCPUIsolationOptInKey     = "katalyst.kubewharf.io/cpu_isolation_opt_in"
+	PodAnnotationCPUIsolationOptInEnabled = "true"
 )
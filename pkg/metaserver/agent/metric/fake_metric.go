@@ -33,6 +33,7 @@ package metric
 import (
 	"context"
 	"sync"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 
@@ -44,11 +45,13 @@ import (
 
 // NewFakeMetricsFetcher returns a fake MetricsFetcher.
 func NewFakeMetricsFetcher(emitter metrics.MetricEmitter) types.MetricsFetcher {
+	metricStore := metric.NewMetricStore()
 	return &FakeMetricsFetcher{
-		metricStore:           metric.NewMetricStore(),
-		emitter:               emitter,
-		hasSynced:             true,
-		checkMetricDataExpire: checkMetricDataExpireFunc(minimumMetricInsurancePeriod),
+		metricStore:            metricStore,
+		emitter:                emitter,
+		metricsNotifierManager: NewMetricsNotifierManager(metricStore, emitter),
+		hasSynced:              true,
+		checkMetricDataExpire:  checkMetricDataExpireFunc(minimumMetricInsurancePeriod),
 	}
 }
 
@@ -59,6 +62,10 @@ type FakeMetricsFetcher struct {
 	registeredMetric      []func(store *metric.MetricStore)
 	checkMetricDataExpire CheckMetricDataExpireFunc
 
+	// metricsNotifierManager only serves threshold requests, since
+	// the fake fetcher has no periodical collecting to drive notifiers
+	metricsNotifierManager types.MetricsNotifierManager
+
 	hasSynced bool
 }
 
@@ -78,11 +85,20 @@ func (f *FakeMetricsFetcher) HasSynced() bool {
 	return f.hasSynced
 }
 
-func (f *FakeMetricsFetcher) RegisterNotifier(_ types.MetricsScope, _ types.NotifiedRequest, _ chan types.NotifiedResponse) string {
-	return ""
+func (f *FakeMetricsFetcher) RegisterNotifier(scope types.MetricsScope, req types.NotifiedRequest, response chan types.NotifiedResponse) string {
+	if req.Comparison == types.ThresholdComparisonNone {
+		return ""
+	}
+	return f.metricsNotifierManager.RegisterNotifier(scope, req, response)
+}
+
+func (f *FakeMetricsFetcher) DeRegisterNotifier(scope types.MetricsScope, key string) {
+	f.metricsNotifierManager.DeRegisterNotifier(scope, key)
 }
 
-func (f *FakeMetricsFetcher) DeRegisterNotifier(_ types.MetricsScope, _ string) {}
+func (f *FakeMetricsFetcher) DeRegisterAllNotifiers(owner string) {
+	f.metricsNotifierManager.DeRegisterAllNotifiers(owner)
+}
 
 func (f *FakeMetricsFetcher) RegisterExternalMetric(fu func(store *metric.MetricStore)) {
 	f.Lock()
@@ -90,6 +106,19 @@ func (f *FakeMetricsFetcher) RegisterExternalMetric(fu func(store *metric.Metric
 	f.registeredMetric = append(f.registeredMetric, fu)
 }
 
+func (f *FakeMetricsFetcher) NewestSampleAge(scope types.MetricsScope) time.Duration {
+	return newestSampleAge(f.metricStore, scope)
+}
+
+func (f *FakeMetricsFetcher) Snapshot() types.MetricsReader {
+	return &FakeMetricsFetcher{
+		metricStore:           f.metricStore.Clone(),
+		emitter:               f.emitter,
+		checkMetricDataExpire: f.checkMetricDataExpire,
+		hasSynced:             f.HasSynced(),
+	}
+}
+
 func (f *FakeMetricsFetcher) GetNodeMetric(metricName string) (metric.MetricData, error) {
 	return f.checkMetricDataExpire(f.metricStore.GetNodeMetric(metricName))
 }
@@ -98,6 +127,25 @@ func (f *FakeMetricsFetcher) GetNumaMetric(numaID int, metricName string) (metri
 	return f.checkMetricDataExpire(f.metricStore.GetNumaMetric(numaID, metricName))
 }
 
+// GetNumaMetricRate returns an error if the newest sample of the metric is expired,
+// since the rate would not reflect the current trend then
+func (f *FakeMetricsFetcher) GetNumaMetricRate(numaID int, metricName string, window time.Duration) (float64, error) {
+	if _, err := f.GetNumaMetric(numaID, metricName); err != nil {
+		return 0, err
+	}
+	return f.metricStore.GetNumaMetricRate(numaID, metricName, window)
+}
+
+func (f *FakeMetricsFetcher) ListNumasWithMetric(metricName string) []int {
+	numaIDs := make([]int, 0)
+	for _, numaID := range f.metricStore.ListNumasWithMetric(metricName) {
+		if _, err := f.GetNumaMetric(numaID, metricName); err == nil {
+			numaIDs = append(numaIDs, numaID)
+		}
+	}
+	return numaIDs
+}
+
 func (f *FakeMetricsFetcher) GetDeviceMetric(deviceName string, metricName string) (metric.MetricData, error) {
 	return f.checkMetricDataExpire(f.metricStore.GetDeviceMetric(deviceName, metricName))
 }
@@ -120,32 +168,47 @@ func (f *FakeMetricsFetcher) GetPodVolumeMetric(podUID, volumeName, metricName s
 
 func (f *FakeMetricsFetcher) SetNodeMetric(metricName string, data metric.MetricData) {
 	f.metricStore.SetNodeMetric(metricName, data)
+	f.metricsNotifierManager.Notify()
 }
 
 func (f *FakeMetricsFetcher) SetNumaMetric(numaID int, metricName string, data metric.MetricData) {
 	f.metricStore.SetNumaMetric(numaID, metricName, data)
+	f.metricsNotifierManager.Notify()
+}
+
+func (f *FakeMetricsFetcher) SetNumaMetricBatch(metricName string, data map[int]metric.MetricData) {
+	f.metricStore.SetNumaMetricBatch(metricName, data)
+	f.metricsNotifierManager.Notify()
 }
 
 func (f *FakeMetricsFetcher) SetCPUMetric(cpu int, metricName string, data metric.MetricData) {
 	f.metricStore.SetCPUMetric(cpu, metricName, data)
+	f.metricsNotifierManager.Notify()
 }
 
 func (f *FakeMetricsFetcher) SetDeviceMetric(deviceName string, metricName string, data metric.MetricData) {
 	f.metricStore.SetDeviceMetric(deviceName, metricName, data)
+	f.metricsNotifierManager.Notify()
 }
 
 func (f *FakeMetricsFetcher) SetContainerMetric(podUID, containerName, metricName string, data metric.MetricData) {
 	f.metricStore.SetContainerMetric(podUID, containerName, metricName, data)
+	f.metricsNotifierManager.Notify()
 }
 
 func (f *FakeMetricsFetcher) SetContainerNumaMetric(podUID, containerName, numaNode, metricName string, data metric.MetricData) {
 	f.metricStore.SetContainerNumaMetric(podUID, containerName, numaNode, metricName, data)
+	f.metricsNotifierManager.Notify()
 }
 
 func (f *FakeMetricsFetcher) AggregatePodNumaMetric(podList []*v1.Pod, numaNode, metricName string, agg metric.Aggregator, filter metric.ContainerMetricFilter) metric.MetricData {
 	return f.metricStore.AggregatePodNumaMetric(podList, numaNode, metricName, agg, filter)
 }
 
+func (f *FakeMetricsFetcher) AggregatePodNumasMetric(podList []*v1.Pod, numas machine.CPUSet, metricName string, agg metric.Aggregator, filter metric.ContainerMetricFilter) metric.MetricData {
+	return f.metricStore.AggregatePodNumasMetric(podList, numas, metricName, agg, filter)
+}
+
 func (f *FakeMetricsFetcher) AggregatePodMetric(podList []*v1.Pod, metricName string, agg metric.Aggregator, filter metric.ContainerMetricFilter) metric.MetricData {
 	return f.metricStore.AggregatePodMetric(podList, metricName, agg, filter)
 }
@@ -19,6 +19,7 @@ package metric
 import (
 	"context"
 	"fmt"
+	"math"
 	"math/rand"
 	"sync"
 	"time"
@@ -31,11 +32,14 @@ import (
 	"github.com/kubewharf/katalyst-core/pkg/metaserver/agent/metric/types"
 	"github.com/kubewharf/katalyst-core/pkg/metaserver/agent/pod"
 	"github.com/kubewharf/katalyst-core/pkg/metrics"
+	"github.com/kubewharf/katalyst-core/pkg/util/general"
 	"github.com/kubewharf/katalyst-core/pkg/util/machine"
 	utilmetric "github.com/kubewharf/katalyst-core/pkg/util/metric"
 	"github.com/kubewharf/katalyst-core/pkg/util/syntax"
 )
 
+const healthzNameMetricStoreStaleness = "metric_store_staleness"
+
 type MetricsNotifierManagerImpl struct {
 	*syntax.RWMutex
 	metricStore        *utilmetric.MetricStore
@@ -86,6 +90,24 @@ func (m *MetricsNotifierManagerImpl) DeRegisterNotifier(scope types.MetricsScope
 	delete(m.registeredNotifier[scope], key)
 }
 
+func (m *MetricsNotifierManagerImpl) DeRegisterAllNotifiers(owner string) {
+	// notifiers registered without owner can only be deregistered by key
+	if owner == "" {
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	for _, notifiers := range m.registeredNotifier {
+		for key, reg := range notifiers {
+			if reg.Req.Owner == owner {
+				delete(notifiers, key)
+			}
+		}
+	}
+}
+
 func (m *MetricsNotifierManagerImpl) Notify() {
 	m.notifySystem()
 	m.notifyPods()
@@ -105,10 +127,8 @@ func (m *MetricsNotifierManagerImpl) notifySystem() {
 			v.Time = &now
 		}
 
-		if reg.LastNotify.Equal(*v.Time) {
+		if !shouldNotify(reg, v) {
 			continue
-		} else {
-			reg.LastNotify = *v.Time
 		}
 
 		reg.Response <- types.NotifiedResponse{
@@ -125,10 +145,8 @@ func (m *MetricsNotifierManagerImpl) notifySystem() {
 			v.Time = &now
 		}
 
-		if reg.LastNotify.Equal(*v.Time) {
+		if !shouldNotify(reg, v) {
 			continue
-		} else {
-			reg.LastNotify = *v.Time
 		}
 
 		reg.Response <- types.NotifiedResponse{
@@ -137,7 +155,7 @@ func (m *MetricsNotifierManagerImpl) notifySystem() {
 		}
 	}
 
-	for n, reg := range m.registeredNotifier[types.MetricsScopeNuma] {
+	for _, reg := range m.registeredNotifier[types.MetricsScopeNuma] {
 		v, err := m.metricStore.GetNumaMetric(reg.Req.NumaID, reg.Req.MetricName)
 		if err != nil {
 			continue
@@ -145,10 +163,8 @@ func (m *MetricsNotifierManagerImpl) notifySystem() {
 			v.Time = &now
 		}
 
-		if m.registeredNotifier[types.MetricsScopeNuma][n].LastNotify.Equal(*v.Time) {
+		if !shouldNotify(reg, v) {
 			continue
-		} else {
-			reg.LastNotify = *v.Time
 		}
 
 		reg.Response <- types.NotifiedResponse{
@@ -157,7 +173,7 @@ func (m *MetricsNotifierManagerImpl) notifySystem() {
 		}
 	}
 
-	for n, reg := range m.registeredNotifier[types.MetricsScopeCPU] {
+	for _, reg := range m.registeredNotifier[types.MetricsScopeCPU] {
 		v, err := m.metricStore.GetCPUMetric(reg.Req.CoreID, reg.Req.MetricName)
 		if err != nil {
 			continue
@@ -165,10 +181,8 @@ func (m *MetricsNotifierManagerImpl) notifySystem() {
 			v.Time = &now
 		}
 
-		if reg.LastNotify.Equal(*v.Time) {
+		if !shouldNotify(reg, v) {
 			continue
-		} else {
-			m.registeredNotifier[types.MetricsScopeCPU][n].LastNotify = *v.Time
 		}
 
 		reg.Response <- types.NotifiedResponse{
@@ -192,10 +206,8 @@ func (m *MetricsNotifierManagerImpl) notifyPods() {
 			v.Time = &now
 		}
 
-		if reg.LastNotify.Equal(*v.Time) {
+		if !shouldNotify(reg, v) {
 			continue
-		} else {
-			reg.LastNotify = *v.Time
 		}
 
 		reg.Response <- types.NotifiedResponse{
@@ -216,10 +228,8 @@ func (m *MetricsNotifierManagerImpl) notifyPods() {
 			v.Time = &now
 		}
 
-		if reg.LastNotify.Equal(*v.Time) {
+		if !shouldNotify(reg, v) {
 			continue
-		} else {
-			reg.LastNotify = *v.Time
 		}
 
 		reg.Response <- types.NotifiedResponse{
@@ -229,6 +239,31 @@ func (m *MetricsNotifierManagerImpl) notifyPods() {
 	}
 }
 
+// shouldNotify checks whether the registered notifier should be fired by the given metric data;
+// for threshold requests, it only fires when the metric newly crosses the threshold
+func shouldNotify(reg *types.NotifiedData, v utilmetric.MetricData) bool {
+	var satisfied bool
+	switch reg.Req.Comparison {
+	case types.ThresholdComparisonAbove:
+		satisfied = v.Value > reg.Req.Threshold
+	case types.ThresholdComparisonBelow:
+		satisfied = v.Value < reg.Req.Threshold
+	default:
+		if reg.LastNotify.Equal(*v.Time) {
+			return false
+		}
+		reg.LastNotify = *v.Time
+		return true
+	}
+
+	crossed := satisfied && !reg.Crossed
+	reg.Crossed = satisfied
+	if crossed {
+		reg.LastNotify = *v.Time
+	}
+	return crossed
+}
+
 type ExternalMetricManagerImpl struct {
 	*syntax.RWMutex
 	metricStore      *utilmetric.MetricStore
@@ -268,6 +303,10 @@ type MetricsFetcherImpl struct {
 	defaultInterval time.Duration
 	provisioners    map[string]types.MetricsProvisioner
 	intervals       map[string]time.Duration
+
+	// storeStalenessThreshold is the max period allowed without any update of
+	// the metric store before healthz reports not ready; 0 means no check
+	storeStalenessThreshold time.Duration
 }
 
 func NewMetricsFetcher(baseConf *global.BaseConfiguration, metricConf *metaserver.MetricConfiguration, emitter metrics.MetricEmitter, podFetcher pod.PodFetcher) types.MetricsFetcher {
@@ -288,6 +327,10 @@ func NewMetricsFetcher(baseConf *global.BaseConfiguration, metricConf *metaserve
 		}
 	}
 
+	if metricConf.MetricStoreStalenessThreshold > 0 {
+		general.RegisterHeartbeatCheck(healthzNameMetricStoreStaleness, 0, general.HealthzCheckStateReady, 0)
+	}
+
 	return &MetricsFetcherImpl{
 		metricStore:            metricStore,
 		metricsNotifierManager: metricsNotifierManager,
@@ -297,6 +340,8 @@ func NewMetricsFetcher(baseConf *global.BaseConfiguration, metricConf *metaserve
 		defaultInterval: metricConf.DefaultInterval,
 		provisioners:    provisioners,
 		intervals:       intervals,
+
+		storeStalenessThreshold: metricConf.MetricStoreStalenessThreshold,
 	}
 }
 
@@ -308,6 +353,25 @@ func (f *MetricsFetcherImpl) GetNumaMetric(numaID int, metricName string) (utilm
 	return f.checkMetricDataExpire(f.metricStore.GetNumaMetric(numaID, metricName))
 }
 
+// GetNumaMetricRate returns an error if the newest sample of the metric is expired,
+// since the rate would not reflect the current trend then
+func (f *MetricsFetcherImpl) GetNumaMetricRate(numaID int, metricName string, window time.Duration) (float64, error) {
+	if _, err := f.GetNumaMetric(numaID, metricName); err != nil {
+		return 0, err
+	}
+	return f.metricStore.GetNumaMetricRate(numaID, metricName, window)
+}
+
+func (f *MetricsFetcherImpl) ListNumasWithMetric(metricName string) []int {
+	numaIDs := make([]int, 0)
+	for _, numaID := range f.metricStore.ListNumasWithMetric(metricName) {
+		if _, err := f.GetNumaMetric(numaID, metricName); err == nil {
+			numaIDs = append(numaIDs, numaID)
+		}
+	}
+	return numaIDs
+}
+
 func (f *MetricsFetcherImpl) GetDeviceMetric(deviceName string, metricName string) (utilmetric.MetricData, error) {
 	return f.checkMetricDataExpire(f.metricStore.GetDeviceMetric(deviceName, metricName))
 }
@@ -342,6 +406,12 @@ func (f *MetricsFetcherImpl) AggregatePodNumaMetric(podList []*v1.Pod, numaNode,
 	return f.metricStore.AggregatePodNumaMetric(podList, numaNode, metricName, agg, filter)
 }
 
+func (f *MetricsFetcherImpl) AggregatePodNumasMetric(podList []*v1.Pod, numas machine.CPUSet, metricName string,
+	agg utilmetric.Aggregator, filter utilmetric.ContainerMetricFilter,
+) utilmetric.MetricData {
+	return f.metricStore.AggregatePodNumasMetric(podList, numas, metricName, agg, filter)
+}
+
 func (f *MetricsFetcherImpl) AggregatePodMetric(podList []*v1.Pod, metricName string,
 	agg utilmetric.Aggregator, filter utilmetric.ContainerMetricFilter,
 ) utilmetric.MetricData {
@@ -360,6 +430,22 @@ func (f *MetricsFetcherImpl) DeRegisterNotifier(scope types.MetricsScope, key st
 	f.metricsNotifierManager.DeRegisterNotifier(scope, key)
 }
 
+func (f *MetricsFetcherImpl) DeRegisterAllNotifiers(owner string) {
+	f.metricsNotifierManager.DeRegisterAllNotifiers(owner)
+}
+
+func (f *MetricsFetcherImpl) NewestSampleAge(scope types.MetricsScope) time.Duration {
+	return newestSampleAge(f.metricStore, scope)
+}
+
+func (f *MetricsFetcherImpl) Snapshot() types.MetricsReader {
+	return &MetricsFetcherImpl{
+		metricStore:           f.metricStore.Clone(),
+		checkMetricDataExpire: f.checkMetricDataExpire,
+		hasSynced:             f.HasSynced(),
+	}
+}
+
 func (f *MetricsFetcherImpl) RegisterExternalMetric(externalMetricFunc func(store *utilmetric.MetricStore)) {
 	f.externalMetricManager.RegisterExternalMetric(externalMetricFunc)
 }
@@ -424,8 +510,53 @@ func (f *MetricsFetcherImpl) run(ctx context.Context) {
 			}
 		}, f.defaultInterval, ctx.Done())
 	}
+
+	if f.storeStalenessThreshold > 0 {
+		go wait.Until(f.checkMetricStoreStaleness, f.defaultInterval, ctx.Done())
+	}
 }
 
 func (f *MetricsFetcherImpl) HasSynced() bool {
 	return f.hasSynced
 }
+
+// checkMetricStoreStaleness reports healthz as not ready if the metric store
+// hasn't received any update for more than the staleness threshold
+func (f *MetricsFetcherImpl) checkMetricStoreStaleness() {
+	age := f.NewestSampleAge("")
+	if age > f.storeStalenessThreshold {
+		general.Warningf("metric store has not been updated for %v", age)
+		_ = general.UpdateHealthzState(healthzNameMetricStoreStaleness, general.HealthzCheckStateNotReady,
+			fmt.Sprintf("metric store has not been updated for more than %v", f.storeStalenessThreshold))
+		return
+	}
+	_ = general.UpdateHealthzState(healthzNameMetricStoreStaleness, general.HealthzCheckStateReady, "")
+}
+
+// newestSampleAge returns the age of the newest sample in the given scope of the store,
+// or in all scopes if the scope is empty
+func newestSampleAge(store *utilmetric.MetricStore, scope types.MetricsScope) time.Duration {
+	newestTimeGetters := map[types.MetricsScope]func() time.Time{
+		types.MetricsScopeNode:          store.GetNewestNodeMetricTime,
+		types.MetricsScopeNuma:          store.GetNewestNumaMetricTime,
+		types.MetricsScopeCPU:           store.GetNewestCPUMetricTime,
+		types.MetricsScopeDevice:        store.GetNewestDeviceMetricTime,
+		types.MetricsScopeContainer:     store.GetNewestContainerMetricTime,
+		types.MetricsScopeContainerNUMA: store.GetNewestContainerNumaMetricTime,
+	}
+
+	newest := time.Time{}
+	for s, getter := range newestTimeGetters {
+		if scope != "" && s != scope {
+			continue
+		}
+		if t := getter(); t.After(newest) {
+			newest = t
+		}
+	}
+
+	if newest.IsZero() {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Since(newest)
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package metric
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -30,6 +31,7 @@ import (
 	metrictypes "github.com/kubewharf/katalyst-core/pkg/metaserver/agent/metric/types"
 	"github.com/kubewharf/katalyst-core/pkg/metaserver/agent/pod"
 	"github.com/kubewharf/katalyst-core/pkg/metrics"
+	"github.com/kubewharf/katalyst-core/pkg/util/general"
 	"github.com/kubewharf/katalyst-core/pkg/util/machine"
 	"github.com/kubewharf/katalyst-core/pkg/util/metric"
 )
@@ -151,6 +153,103 @@ func Test_notifySystem(t *testing.T) {
 	assert.Equal(t, 8, totalNotification)
 }
 
+func Test_notifyNumaThreshold(t *testing.T) {
+	t.Parallel()
+
+	f := NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*FakeMetricsFetcher)
+
+	rChan := make(chan metrictypes.NotifiedResponse, 10)
+	key := f.RegisterNotifier(metrictypes.MetricsScopeNuma, metrictypes.NotifiedRequest{
+		MetricName: "test-numa-free-memory",
+		NumaID:     0,
+		Comparison: metrictypes.ThresholdComparisonBelow,
+		Threshold:  100,
+	}, rChan)
+	require.NotEmpty(t, key)
+
+	// metric stays above the threshold, expect no notification
+	now := time.Now()
+	f.SetNumaMetric(0, "test-numa-free-memory", metric.MetricData{Value: 200, Time: &now})
+	assert.Len(t, rChan, 0)
+
+	// metric crosses below the threshold, expect a notification
+	now = now.Add(time.Second)
+	f.SetNumaMetric(0, "test-numa-free-memory", metric.MetricData{Value: 50, Time: &now})
+	require.Len(t, rChan, 1)
+	response := <-rChan
+	assert.Equal(t, "test-numa-free-memory", response.Req.MetricName)
+	assert.Equal(t, float64(50), response.Value)
+
+	// metric keeps below the threshold, expect no repeated notification
+	now = now.Add(time.Second)
+	f.SetNumaMetric(0, "test-numa-free-memory", metric.MetricData{Value: 40, Time: &now})
+	assert.Len(t, rChan, 0)
+
+	// metric recovers and crosses below the threshold again, expect another notification
+	now = now.Add(time.Second)
+	f.SetNumaMetric(0, "test-numa-free-memory", metric.MetricData{Value: 150, Time: &now})
+	now = now.Add(time.Second)
+	f.SetNumaMetric(0, "test-numa-free-memory", metric.MetricData{Value: 30, Time: &now})
+	require.Len(t, rChan, 1)
+	response = <-rChan
+	assert.Equal(t, float64(30), response.Value)
+
+	f.DeRegisterNotifier(metrictypes.MetricsScopeNuma, key)
+	now = now.Add(time.Second)
+	f.SetNumaMetric(0, "test-numa-free-memory", metric.MetricData{Value: 150, Time: &now})
+	now = now.Add(time.Second)
+	f.SetNumaMetric(0, "test-numa-free-memory", metric.MetricData{Value: 30, Time: &now})
+	assert.Len(t, rChan, 0)
+}
+
+func TestDeRegisterAllNotifiers(t *testing.T) {
+	t.Parallel()
+
+	f := NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*FakeMetricsFetcher)
+
+	rChan := make(chan metrictypes.NotifiedResponse, 10)
+	register := func(scope metrictypes.MetricsScope, owner, metricName string) string {
+		key := f.RegisterNotifier(scope, metrictypes.NotifiedRequest{
+			MetricName: metricName,
+			Owner:      owner,
+			Comparison: metrictypes.ThresholdComparisonBelow,
+			Threshold:  100,
+		}, rChan)
+		require.NotEmpty(t, key)
+		return key
+	}
+	register(metrictypes.MetricsScopeNuma, "test-owner", "test-numa-metric-1")
+	register(metrictypes.MetricsScopeNuma, "test-owner", "test-numa-metric-2")
+	register(metrictypes.MetricsScopeNode, "test-owner", "test-node-metric")
+	register(metrictypes.MetricsScopeNuma, "test-other-owner", "test-numa-metric-3")
+	register(metrictypes.MetricsScopeNuma, "", "test-numa-metric-4")
+
+	registered := func(scope metrictypes.MetricsScope) int {
+		manager := f.metricsNotifierManager.(*MetricsNotifierManagerImpl)
+		manager.RLock()
+		defer manager.RUnlock()
+		return len(manager.registeredNotifier[scope])
+	}
+	require.Equal(t, 4, registered(metrictypes.MetricsScopeNuma))
+	require.Equal(t, 1, registered(metrictypes.MetricsScopeNode))
+
+	// notifiers without owner can't be deregistered by owner
+	f.DeRegisterAllNotifiers("")
+	require.Equal(t, 4, registered(metrictypes.MetricsScopeNuma))
+
+	f.DeRegisterAllNotifiers("test-owner")
+	require.Equal(t, 2, registered(metrictypes.MetricsScopeNuma))
+	require.Equal(t, 0, registered(metrictypes.MetricsScopeNode))
+
+	// notifiers of other owners are still notified
+	now := time.Now()
+	for _, metricName := range []string{"test-numa-metric-1", "test-numa-metric-2", "test-numa-metric-3"} {
+		f.SetNumaMetric(0, metricName, metric.MetricData{Value: 50, Time: &now})
+	}
+	require.Len(t, rChan, 1)
+	assert.Equal(t, "test-numa-metric-3", (<-rChan).Req.MetricName)
+}
+
 func TestStore_Aggregate(t *testing.T) {
 	t.Parallel()
 
@@ -210,6 +309,10 @@ func TestStore_Aggregate(t *testing.T) {
 	assert.Equal(t, float64(2), sum.Value)
 	avg = f.AggregatePodNumaMetric([]*v1.Pod{pod1, pod2, pod3}, "1", "test-pod-numa-metric", metric.AggregatorAvg, metric.DefaultContainerMetricFilter)
 	assert.Equal(t, float64(1), avg.Value)
+	sum = f.AggregatePodNumasMetric([]*v1.Pod{pod1, pod2, pod3}, machine.NewCPUSet(0, 1), "test-pod-numa-metric", metric.AggregatorSum, metric.DefaultContainerMetricFilter)
+	assert.Equal(t, float64(5), sum.Value)
+	sum = f.AggregatePodNumasMetric([]*v1.Pod{pod1}, machine.NewCPUSet(0, 1), "test-pod-numa-metric", metric.AggregatorSum, metric.DefaultContainerMetricFilter)
+	assert.Equal(t, float64(3), sum.Value)
 
 	f.metricStore.SetCPUMetric(1, "test-cpu-metric", metric.MetricData{Value: 1, Time: &now})
 	f.metricStore.SetCPUMetric(1, "test-cpu-metric", metric.MetricData{Value: 2, Time: &now})
@@ -220,3 +323,124 @@ func TestStore_Aggregate(t *testing.T) {
 	avg = f.AggregateCoreMetric(machine.NewCPUSet(0, 1, 2, 3), "test-cpu-metric", metric.AggregatorAvg)
 	assert.Equal(t, float64(4/3.), avg.Value)
 }
+
+func TestNewestSampleAge(t *testing.T) {
+	t.Parallel()
+
+	conf := generateTestConfiguration(t)
+	f := NewMetricsFetcher(conf.BaseConfiguration, conf.MetricConfiguration, metrics.DummyMetrics{}, &pod.PodFetcherStub{}).(*MetricsFetcherImpl)
+
+	// no sample in store
+	assert.Equal(t, time.Duration(math.MaxInt64), f.NewestSampleAge(""))
+
+	now := time.Now()
+	older := now.Add(-time.Hour)
+	f.metricStore.SetNodeMetric("test-node-metric", metric.MetricData{Value: 1, Time: &older})
+	f.metricStore.SetContainerMetric("pod1", "container1", "test-pod-metric", metric.MetricData{Value: 1, Time: &now})
+
+	assert.GreaterOrEqual(t, f.NewestSampleAge(metrictypes.MetricsScopeNode), time.Hour)
+	assert.Less(t, f.NewestSampleAge(metrictypes.MetricsScopeContainer), time.Minute)
+	assert.Less(t, f.NewestSampleAge(""), time.Minute)
+	assert.Equal(t, time.Duration(math.MaxInt64), f.NewestSampleAge(metrictypes.MetricsScopeNuma))
+
+	fake := NewFakeMetricsFetcher(metrics.DummyMetrics{}).(*FakeMetricsFetcher)
+	fake.SetNumaMetric(0, "test-numa-metric", metric.MetricData{Value: 1, Time: &older})
+	assert.GreaterOrEqual(t, fake.NewestSampleAge(metrictypes.MetricsScopeNuma), time.Hour)
+	assert.GreaterOrEqual(t, fake.NewestSampleAge(""), time.Hour)
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	conf := generateTestConfiguration(t)
+	fetchers := []metrictypes.MetricsFetcher{
+		NewMetricsFetcher(conf.BaseConfiguration, conf.MetricConfiguration, metrics.DummyMetrics{}, &pod.PodFetcherStub{}),
+		NewFakeMetricsFetcher(metrics.DummyMetrics{}),
+	}
+
+	const numaCount = 4
+	for _, f := range fetchers {
+		var store *metric.MetricStore
+		switch fetcher := f.(type) {
+		case *MetricsFetcherImpl:
+			store = fetcher.metricStore
+		case *FakeMetricsFetcher:
+			store = fetcher.metricStore
+		}
+
+		// the collector keeps updating all numas with the same value in a batch
+		stopCh := make(chan struct{})
+		doneCh := make(chan struct{})
+		go func() {
+			defer close(doneCh)
+			for i := 0; ; i++ {
+				select {
+				case <-stopCh:
+					return
+				default:
+				}
+
+				now := time.Now()
+				data := make(map[int]metric.MetricData, numaCount)
+				for numaID := 0; numaID < numaCount; numaID++ {
+					data[numaID] = metric.MetricData{Value: float64(i), Time: &now}
+				}
+				store.SetNumaMetricBatch("test-numa-metric", data)
+			}
+		}()
+
+		for i := 0; i < 100; i++ {
+			snapshot := f.Snapshot()
+			numas := snapshot.ListNumasWithMetric("test-numa-metric")
+			if len(numas) == 0 {
+				continue
+			}
+			require.Len(t, numas, numaCount)
+
+			first, err := snapshot.GetNumaMetric(0, "test-numa-metric")
+			require.NoError(t, err)
+			for numaID := 1; numaID < numaCount; numaID++ {
+				data, err := snapshot.GetNumaMetric(numaID, "test-numa-metric")
+				require.NoError(t, err)
+				require.Equal(t, first.Value, data.Value)
+			}
+
+			// the snapshot is frozen even if the store keeps being updated
+			time.Sleep(time.Millisecond)
+			again, err := snapshot.GetNumaMetric(0, "test-numa-metric")
+			require.NoError(t, err)
+			require.Equal(t, first.Value, again.Value)
+		}
+
+		close(stopCh)
+		<-doneCh
+	}
+}
+
+func TestCheckMetricStoreStaleness(t *testing.T) {
+	t.Parallel()
+
+	conf := generateTestConfiguration(t)
+	conf.MetricStoreStalenessThreshold = time.Minute
+	f := NewMetricsFetcher(conf.BaseConfiguration, conf.MetricConfiguration, metrics.DummyMetrics{}, &pod.PodFetcherStub{}).(*MetricsFetcherImpl)
+
+	// store without any update is stale
+	f.checkMetricStoreStaleness()
+	result, ok := general.GetReadinessCheckResult(healthzNameMetricStoreStaleness)
+	require.True(t, ok)
+	assert.False(t, result.Ready)
+
+	now := time.Now()
+	f.metricStore.SetCPUMetric(0, "test-cpu-metric", metric.MetricData{Value: 1, Time: &now})
+	f.checkMetricStoreStaleness()
+	result, ok = general.GetReadinessCheckResult(healthzNameMetricStoreStaleness)
+	require.True(t, ok)
+	assert.True(t, result.Ready)
+
+	older := now.Add(-time.Hour)
+	f.metricStore.SetCPUMetric(0, "test-cpu-metric", metric.MetricData{Value: 1, Time: &older})
+	f.checkMetricStoreStaleness()
+	result, ok = general.GetReadinessCheckResult(healthzNameMetricStoreStaleness)
+	require.True(t, ok)
+	assert.False(t, result.Ready)
+}
@@ -37,10 +37,32 @@ const (
 	MetricsScopeContainerNUMA MetricsScope = "container-numa"
 )
 
+// ThresholdComparison defines how a metric value is compared against
+// the threshold of a NotifiedRequest
+type ThresholdComparison string
+
+const (
+	// ThresholdComparisonNone means notifying whenever the metric is updated
+	ThresholdComparisonNone ThresholdComparison = ""
+	// ThresholdComparisonAbove means notifying when the metric crosses above the threshold
+	ThresholdComparisonAbove ThresholdComparison = "above"
+	// ThresholdComparisonBelow means notifying when the metric crosses below the threshold
+	ThresholdComparisonBelow ThresholdComparison = "below"
+)
+
 // NotifiedRequest defines the structure as requests for notifier
 type NotifiedRequest struct {
 	MetricName string
 
+	// Owner identifies the component registering the notifier, so that all
+	// notifiers of the same owner can be deregistered at once
+	Owner string
+
+	// Comparison and Threshold make the notifier only fire when the metric
+	// crosses the threshold, rather than each time it is updated
+	Comparison ThresholdComparison
+	Threshold  float64
+
 	DeviceID string
 	NumaID   int
 	CoreID   int
@@ -56,6 +78,10 @@ type NotifiedData struct {
 	Req        NotifiedRequest
 	Response   chan NotifiedResponse
 	LastNotify time.Time
+
+	// Crossed records whether the threshold condition has been satisfied
+	// at last check, to avoid notifying repeatedly before recovering
+	Crossed bool
 }
 
 type NotifiedResponse struct {
@@ -68,6 +94,10 @@ type MetricsReader interface {
 	GetNodeMetric(metricName string) (metric.MetricData, error)
 	// GetNumaMetric get metric of numa.
 	GetNumaMetric(numaID int, metricName string) (metric.MetricData, error)
+	// ListNumasWithMetric lists ids of numas which have the given metric (not expired).
+	ListNumasWithMetric(metricName string) []int
+	// GetNumaMetricRate get the per-second rate of change of numa metric over the window.
+	GetNumaMetricRate(numaID int, metricName string, window time.Duration) (float64, error)
 	// GetDeviceMetric get metric of device.
 	GetDeviceMetric(deviceName string, metricName string) (metric.MetricData, error)
 	// GetCPUMetric get metric of cpu.
@@ -81,6 +111,8 @@ type MetricsReader interface {
 
 	// AggregatePodNumaMetric handles numa-level metric for all pods
 	AggregatePodNumaMetric(podList []*v1.Pod, numaNode, metricName string, agg metric.Aggregator, filter metric.ContainerMetricFilter) metric.MetricData
+	// AggregatePodNumasMetric handles numa-level metric for all pods, summed up across the given numas
+	AggregatePodNumasMetric(podList []*v1.Pod, numas machine.CPUSet, metricName string, agg metric.Aggregator, filter metric.ContainerMetricFilter) metric.MetricData
 	// AggregatePodMetric handles metric for all pods
 	AggregatePodMetric(podList []*v1.Pod, metricName string, agg metric.Aggregator, filter metric.ContainerMetricFilter) metric.MetricData
 	// AggregateCoreMetric handles metric for all cores
@@ -110,6 +142,8 @@ type MetricsNotifierManager interface {
 	// is at, but it indeed is the most precise time katalyst system can provide.
 	RegisterNotifier(scope MetricsScope, req NotifiedRequest, response chan NotifiedResponse) string
 	DeRegisterNotifier(scope MetricsScope, key string)
+	// DeRegisterAllNotifiers deregisters notifiers of all scopes registered with the given owner.
+	DeRegisterAllNotifiers(owner string)
 	Notify()
 }
 
@@ -133,10 +167,23 @@ type MetricsFetcher interface {
 	// is at, but it indeed is the most precise time katalyst system can provide.
 	RegisterNotifier(scope MetricsScope, req NotifiedRequest, response chan NotifiedResponse) string
 	DeRegisterNotifier(scope MetricsScope, key string)
+	// DeRegisterAllNotifiers deregisters notifiers of all scopes registered with the given owner,
+	// so that a component can clean up its subscriptions on teardown without tracking every key.
+	DeRegisterAllNotifiers(owner string)
 
 	// RegisterExternalMetric register a function to set metric that can
 	// only be obtained from external sources
 	RegisterExternalMetric(f func(store *metric.MetricStore))
 
+	// NewestSampleAge returns the age of the most-recently-updated sample in the
+	// given scope, or in the whole store if scope is empty. it's used to diagnose
+	// collector stalls, and the max duration is returned if there is no sample.
+	NewestSampleAge(scope MetricsScope) time.Duration
+
+	// Snapshot returns a frozen view of all metrics at the time it's called, so that
+	// callers reading several metrics in sequence get them from one consistent point
+	// in time; later updates of the fetcher are never reflected in the snapshot.
+	Snapshot() MetricsReader
+
 	MetricsReader
 }
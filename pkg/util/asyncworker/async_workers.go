@@ -35,6 +35,9 @@ func NewAsyncWorkers(name string, emitter metrics.MetricEmitter) *AsyncWorkers {
 		emitter:             emitter,
 		lastUndeliveredWork: make(map[string]*Work),
 		workStatuses:        make(map[string]*workStatus),
+		delayedWorkTimers:   make(map[string]*time.Timer),
+		workStats:           make(map[string]*WorkStats),
+		workStatsUpdateTime: make(map[string]time.Time),
 	}
 }
 
@@ -47,6 +50,19 @@ func (aws *AsyncWorkers) AddWork(workName string, work *Work, policy DuplicateWo
 		return fmt.Errorf("validateWork for: %s failed with error: %v", workName, err)
 	}
 
+	return aws.addWork(workName, work, policy)
+}
+
+// addWork adds a validated work, and it must be called with workLock held
+func (aws *AsyncWorkers) addWork(workName string, work *Work, policy DuplicateWorkPolicy) error {
+	// immediate work preempts the pending delayed one with the same name
+	if timer, ok := aws.delayedWorkTimers[workName]; ok {
+		general.InfoS("preempt pending delayed work",
+			"AsyncWorkers", aws.name, "workName", workName)
+		timer.Stop()
+		delete(aws.delayedWorkTimers, workName)
+	}
+
 	general.InfoS("add work",
 		"AsyncWorkers", aws.name,
 		"workName", workName,
@@ -92,6 +108,7 @@ func (aws *AsyncWorkers) AddWork(workName string, work *Work, policy DuplicateWo
 			"old deliveredAt", undelivered.DeliveredAt,
 			"new params", work.Params,
 			"new deliveredAt", work.DeliveredAt)
+		aws.getWorkStats(workName).SupersedeCount++
 	}
 
 	// always set the most recent work
@@ -113,6 +130,47 @@ func (aws *AsyncWorkers) AddWork(workName string, work *Work, policy DuplicateWo
 	return nil
 }
 
+// AddDelayedWork adds work to be handled after delay of quiet, if works with the same
+// name are delivered repeatedly within the delay, only the last one will be handled.
+func (aws *AsyncWorkers) AddDelayedWork(workName string, work *Work, delay time.Duration) error {
+	aws.workLock.Lock()
+	defer aws.workLock.Unlock()
+
+	err := validateWork(work)
+	if err != nil {
+		return fmt.Errorf("validateWork for: %s failed with error: %v", workName, err)
+	}
+
+	if timer, ok := aws.delayedWorkTimers[workName]; ok {
+		general.InfoS("re-arm delayed work",
+			"AsyncWorkers", aws.name,
+			"workName", workName,
+			"params", work.Params,
+			"deliveredAt", work.DeliveredAt)
+		timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		aws.workLock.Lock()
+		defer aws.workLock.Unlock()
+
+		// skip if the timer has been re-armed or preempted
+		if aws.delayedWorkTimers[workName] != timer {
+			return
+		}
+		delete(aws.delayedWorkTimers, workName)
+
+		// enqueue with the lock held, so that no work re-armed meanwhile is overridden by this stale one
+		if err := aws.addWork(workName, work, DuplicateWorkPolicyOverride); err != nil {
+			general.Errorf("[AsyncWorkers: %s] add delayed work %s failed with error: %v", aws.name, workName, err)
+		}
+	})
+	aws.delayedWorkTimers[workName] = timer
+
+	return nil
+}
+
 func (aws *AsyncWorkers) handleWork(ctx context.Context, workName string, work *Work) {
 	var handleErr error
 
@@ -189,6 +247,12 @@ func (aws *AsyncWorkers) completeWork(workName string, completedWork *Work, work
 	aws.workLock.Lock()
 	defer aws.workLock.Unlock()
 
+	stats := aws.getWorkStats(workName)
+	stats.RunCount++
+	if workErr != nil {
+		stats.ErrorCount++
+	}
+
 	if work, exists := aws.lastUndeliveredWork[workName]; exists {
 
 		ctx := aws.contextForWork(workName, work)
@@ -247,7 +311,8 @@ func (aws *AsyncWorkers) Start(stopCh <-chan struct{}) error {
 	return nil
 }
 
-// cleanupWorkStatus cleans up work status not in working
+// cleanupWorkStatus cleans up work status not in working, and stats of works
+// without pending or running ones which are not updated for the retention period
 func (aws *AsyncWorkers) cleanupWorkStatus() {
 	aws.workLock.Lock()
 	defer aws.workLock.Unlock()
@@ -261,6 +326,43 @@ func (aws *AsyncWorkers) cleanupWorkStatus() {
 			delete(aws.workStatuses, workName)
 		}
 	}
+
+	now := time.Now()
+	for workName := range aws.workStats {
+		if _, ok := aws.workStatuses[workName]; ok {
+			continue
+		} else if _, ok := aws.delayedWorkTimers[workName]; ok {
+			continue
+		} else if now.Sub(aws.workStatsUpdateTime[workName]) <= workStatsRetentionPeriod {
+			continue
+		}
+		delete(aws.workStats, workName)
+		delete(aws.workStatsUpdateTime, workName)
+	}
+}
+
+// GetWorkStats returns stats of works with the given name, and the stats are kept until there is
+// no pending or running work of the name and they are not updated for workStatsRetentionPeriod
+func (aws *AsyncWorkers) GetWorkStats(workName string) WorkStats {
+	aws.workLock.Lock()
+	defer aws.workLock.Unlock()
+
+	if stats, ok := aws.workStats[workName]; ok {
+		return *stats
+	}
+	return WorkStats{}
+}
+
+// getWorkStats returns stats of works with the given name to be updated, and initializes it if not exists.
+// It should be called in function protected by aws.workLock.
+func (aws *AsyncWorkers) getWorkStats(workName string) *WorkStats {
+	stats, ok := aws.workStats[workName]
+	if !ok {
+		stats = &WorkStats{}
+		aws.workStats[workName] = stats
+	}
+	aws.workStatsUpdateTime[workName] = time.Now()
+	return stats
 }
 
 func (aws *AsyncWorkers) WorkExists(workName string) bool {
@@ -277,5 +379,10 @@ func (aws *AsyncWorkers) WorkExists(workName string) bool {
 		return true
 	}
 
+	_, hasDelayedWork := aws.delayedWorkTimers[workName]
+	if hasDelayedWork {
+		return true
+	}
+
 	return false
 }
@@ -313,3 +313,245 @@ func TestAsyncLimitedWorkers(t *testing.T) {
 		})
 	}
 }
+
+func TestAsyncWorkers_AddDelayedWork(t *testing.T) {
+	t.Parallel()
+
+	rt := require.New(t)
+
+	asw := NewAsyncWorkers("test-delayed", metrics.DummyMetrics{})
+
+	var (
+		mutex   sync.Mutex
+		count   int
+		results []int
+	)
+	fn := func(ctx context.Context, i int) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		count++
+		results = append(results, i)
+		return nil
+	}
+
+	delay := 100 * time.Millisecond
+	workName := "delayed-work"
+
+	// three events delivered within the delay collapse into one execution
+	for i := 0; i < 3; i++ {
+		err := asw.AddDelayedWork(workName, &Work{
+			Fn:          fn,
+			Params:      []interface{}{i},
+			DeliveredAt: time.Now(),
+		}, delay)
+		rt.NoError(err)
+		time.Sleep(10 * time.Millisecond)
+	}
+	rt.True(asw.WorkExists(workName))
+
+	rt.Eventually(func() bool {
+		return !asw.WorkExists(workName)
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(2 * delay)
+
+	mutex.Lock()
+	rt.Equal(1, count)
+	rt.Equal([]int{2}, results)
+	mutex.Unlock()
+
+	// immediate work preempts the pending delayed one
+	err := asw.AddDelayedWork(workName, &Work{
+		Fn:          fn,
+		Params:      []interface{}{3},
+		DeliveredAt: time.Now(),
+	}, delay)
+	rt.NoError(err)
+	err = asw.AddWork(workName, &Work{
+		Fn:          fn,
+		Params:      []interface{}{4},
+		DeliveredAt: time.Now(),
+	}, DuplicateWorkPolicyOverride)
+	rt.NoError(err)
+	time.Sleep(2 * delay)
+
+	mutex.Lock()
+	rt.Equal(2, count)
+	rt.Equal([]int{2, 4}, results)
+	mutex.Unlock()
+}
+
+func TestAsyncWorkers_ReArmDelayedWork(t *testing.T) {
+	t.Parallel()
+
+	asw := NewAsyncWorkers("test-rearm-delayed", metrics.DummyMetrics{})
+
+	var (
+		mutex sync.Mutex
+		last  = make(map[string]int)
+	)
+	fn := func(ctx context.Context, workName string, i int) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		last[workName] = i
+		return nil
+	}
+
+	// the work re-armed while the previous timer is firing must be handled
+	// instead of being preempted by the stale one
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		workName := fmt.Sprintf("delayed-work-%d", w)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i += 2 {
+				assert.NoError(t, asw.AddDelayedWork(workName, &Work{
+					Fn:          fn,
+					Params:      []interface{}{workName, i},
+					DeliveredAt: time.Now(),
+				}, 0))
+				assert.NoError(t, asw.AddDelayedWork(workName, &Work{
+					Fn:          fn,
+					Params:      []interface{}{workName, i + 1},
+					DeliveredAt: time.Now(),
+				}, time.Millisecond))
+
+				want := i + 1
+				if !assert.Eventually(t, func() bool {
+					mutex.Lock()
+					defer mutex.Unlock()
+					return last[workName] == want
+				}, time.Second, time.Millisecond) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestAsyncWorkers_GetWorkStats(t *testing.T) {
+	t.Parallel()
+
+	rt := require.New(t)
+
+	asw := NewAsyncWorkers("test-stats", metrics.DummyMetrics{})
+
+	var (
+		mutex   sync.Mutex
+		results []int
+	)
+	release := make(chan struct{})
+	fn := func(ctx context.Context, i int) error {
+		<-release
+		mutex.Lock()
+		defer mutex.Unlock()
+		results = append(results, i)
+		return fmt.Errorf("work %v failed", i)
+	}
+
+	workName := "stats-work"
+	rt.Equal(WorkStats{}, asw.GetWorkStats(workName))
+
+	// the first work keeps running, and the following ones are queued while
+	// each of them supersedes the pending one except the first queued
+	for i := 0; i < 4; i++ {
+		err := asw.AddWork(workName, &Work{
+			Fn:          fn,
+			Params:      []interface{}{i},
+			DeliveredAt: time.Now(),
+		}, DuplicateWorkPolicyOverride)
+		rt.NoError(err)
+	}
+	rt.Equal(int64(2), asw.GetWorkStats(workName).SupersedeCount)
+
+	close(release)
+	rt.Eventually(func() bool {
+		return !asw.WorkExists(workName)
+	}, time.Second, 10*time.Millisecond)
+
+	mutex.Lock()
+	rt.Equal([]int{0, 3}, results)
+	mutex.Unlock()
+	rt.Equal(WorkStats{RunCount: 2, ErrorCount: 2, SupersedeCount: 2}, asw.GetWorkStats(workName))
+}
+
+func TestAsyncWorkers_CleanupWorkStats(t *testing.T) {
+	t.Parallel()
+
+	rt := require.New(t)
+
+	asw := NewAsyncWorkers("test-cleanup-stats", metrics.DummyMetrics{})
+
+	release := make(chan struct{})
+	blockingFn := func(ctx context.Context) error {
+		<-release
+		return nil
+	}
+	fn := func(ctx context.Context) error {
+		return nil
+	}
+
+	runningName, doneName, delayedName := "running-work", "done-work", "delayed-work"
+	// the first work keeps running, and the last one supersedes the pending one
+	for i := 0; i < 3; i++ {
+		rt.NoError(asw.AddWork(runningName, &Work{
+			Fn:          blockingFn,
+			Params:      []interface{}{},
+			DeliveredAt: time.Now(),
+		}, DuplicateWorkPolicyOverride))
+	}
+	rt.NoError(asw.AddWork(doneName, &Work{
+		Fn:          fn,
+		Params:      []interface{}{},
+		DeliveredAt: time.Now(),
+	}, DuplicateWorkPolicyOverride))
+	rt.NoError(asw.AddDelayedWork(delayedName, &Work{
+		Fn:          fn,
+		Params:      []interface{}{},
+		DeliveredAt: time.Now(),
+	}, time.Hour))
+	asw.workLock.Lock()
+	asw.getWorkStats(delayedName).SupersedeCount = 1
+	asw.workLock.Unlock()
+
+	rt.Eventually(func() bool {
+		return asw.GetWorkStats(doneName).RunCount == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// stats of finished works are kept within the retention period
+	asw.cleanupWorkStatus()
+	rt.Equal(WorkStats{RunCount: 1}, asw.GetWorkStats(doneName))
+
+	// stats of finished works are cleaned up after the retention period, while
+	// stats of works in working or waiting for delay are kept
+	expireWorkStats := func() {
+		asw.workLock.Lock()
+		defer asw.workLock.Unlock()
+		for workName := range asw.workStatsUpdateTime {
+			asw.workStatsUpdateTime[workName] = time.Now().Add(-2 * workStatsRetentionPeriod)
+		}
+	}
+	expireWorkStats()
+	asw.cleanupWorkStatus()
+	rt.Equal(WorkStats{}, asw.GetWorkStats(doneName))
+	rt.Equal(int64(1), asw.GetWorkStats(delayedName).SupersedeCount)
+	rt.Equal(int64(1), asw.GetWorkStats(runningName).SupersedeCount)
+
+	close(release)
+	rt.Eventually(func() bool {
+		return !asw.WorkExists(runningName)
+	}, time.Second, 10*time.Millisecond)
+	rt.Equal(WorkStats{RunCount: 2, SupersedeCount: 1}, asw.GetWorkStats(runningName))
+
+	asw.cleanupWorkStatus()
+	rt.Equal(WorkStats{RunCount: 2, SupersedeCount: 1}, asw.GetWorkStats(runningName))
+
+	expireWorkStats()
+	asw.cleanupWorkStatus()
+	rt.Equal(WorkStats{}, asw.GetWorkStats(runningName))
+	asw.workLock.Lock()
+	rt.Len(asw.workStats, 1)
+	rt.Len(asw.workStatsUpdateTime, 1)
+	asw.workLock.Unlock()
+}
@@ -52,6 +52,10 @@ const (
 	metricNameAsyncWorkPanic      = "async_work_panic"
 )
 
+// workStatsRetentionPeriod is the period to keep stats of works after they are last
+// updated, if there is no pending or running work of the same name
+const workStatsRetentionPeriod = 10 * time.Minute
+
 // workStatus tracks worker is working or not
 // and containers context to cancel work
 type workStatus struct {
@@ -70,6 +74,17 @@ type workStatus struct {
 	work *Work
 }
 
+// WorkStats counts how works with the same name are handled
+type WorkStats struct {
+	// RunCount is the number of works completed
+	RunCount int64
+	// ErrorCount is the number of works completed with error
+	ErrorCount int64
+	// SupersedeCount is the number of undelivered works overwritten by
+	// newer ones before getting a chance to run
+	SupersedeCount int64
+}
+
 // Work contains details to handle by workers
 type Work struct {
 	Name string
@@ -97,6 +112,12 @@ type AsyncWorkers struct {
 	lastUndeliveredWork map[string]*Work
 	// Tracks work status by work name
 	workStatuses map[string]*workStatus
+	// Tracks timers of pending delayed work by work name
+	delayedWorkTimers map[string]*time.Timer
+	// Tracks stats of work by work name
+	workStats map[string]*WorkStats
+	// Tracks the last time stats of work are updated by work name
+	workStatsUpdateTime map[string]time.Time
 }
 
 type AsyncLimitedWorkers struct {
@@ -74,6 +74,22 @@ func GetAbsCgroupPath(subsys, suffix string) string {
 	return filepath.Join(GetCgroupRootPath(subsys), suffix)
 }
 
+// GetNUMABindingReclaimRelativeRootCgroupPath returns the relative root cgroup path
+// for reclaimed_cores running on the given numa node with numa-binding pods.
+func GetNUMABindingReclaimRelativeRootCgroupPath(reclaimRelativeRootCgroupPath string, numaID int) string {
+	return fmt.Sprintf("%s-%d", reclaimRelativeRootCgroupPath, numaID)
+}
+
+// GetNUMABindingReclaimRelativeRootCgroupPaths returns the numa-binding reclaim relative
+// root cgroup paths for all the given numa nodes, keyed by numa id.
+func GetNUMABindingReclaimRelativeRootCgroupPaths(reclaimRelativeRootCgroupPath string, numaNodes []int) map[int]string {
+	paths := make(map[int]string, len(numaNodes))
+	for _, numaID := range numaNodes {
+		paths[numaID] = GetNUMABindingReclaimRelativeRootCgroupPath(reclaimRelativeRootCgroupPath, numaID)
+	}
+	return paths
+}
+
 // GetKubernetesCgroupRootPathWithSubSys returns all Cgroup paths to run container for
 // kubernetes, and the returned values are merged with subsys.
 func GetKubernetesCgroupRootPathWithSubSys(subsys string) []string {
@@ -352,15 +352,30 @@ func MergeTopologyZone(dst, src []*apis.TopologyZone) []*apis.TopologyZone {
 	}
 
 	sort.SliceStable(zones, func(i, j int) bool {
-		if zones[i].Type == zones[j].Type {
-			return zones[i].Name < zones[j].Name
-		}
-		return zones[i].Type < zones[j].Type
+		return lessTopologyZone(zones[i], zones[j])
 	})
 
 	return zones
 }
 
+// lessTopologyZone orders topology zones by type and then by name, while socket
+// and numa zones are ordered by their numeric id to keep the output stable
+func lessTopologyZone(a, b *apis.TopologyZone) bool {
+	if a.Type != b.Type {
+		return a.Type < b.Type
+	}
+
+	if a.Type == apis.TopologyTypeSocket || a.Type == apis.TopologyTypeNuma {
+		aID, aErr := strconv.Atoi(a.Name)
+		bID, bErr := strconv.Atoi(b.Name)
+		if aErr == nil && bErr == nil {
+			return aID < bID
+		}
+	}
+
+	return a.Name < b.Name
+}
+
 // NewNumaSocketTopologyZoneGenerator constructs topology generator by the numa zone node to socket zone node map
 func NewNumaSocketTopologyZoneGenerator(numaSocketZoneNodeMap map[ZoneNode]ZoneNode) (*TopologyZoneGenerator, error) {
 	var errList []error
@@ -1428,6 +1428,31 @@ func TestMergeTopologyZone(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "sort socket and numa zones by id",
+			args: args{
+				dst: []*nodeapis.TopologyZone{
+					{Type: nodeapis.TopologyTypeSocket, Name: "10"},
+					{Type: nodeapis.TopologyTypeNIC, Name: "eth2"},
+				},
+				src: []*nodeapis.TopologyZone{
+					{Type: nodeapis.TopologyTypeSocket, Name: "2"},
+					{Type: nodeapis.TopologyTypeNuma, Name: "10"},
+					{Type: nodeapis.TopologyTypeNuma, Name: "-1"},
+					{Type: nodeapis.TopologyTypeNuma, Name: "2"},
+					{Type: nodeapis.TopologyTypeNIC, Name: "eth10"},
+				},
+			},
+			want: []*nodeapis.TopologyZone{
+				{Type: nodeapis.TopologyTypeNIC, Name: "eth10"},
+				{Type: nodeapis.TopologyTypeNIC, Name: "eth2"},
+				{Type: nodeapis.TopologyTypeNuma, Name: "-1"},
+				{Type: nodeapis.TopologyTypeNuma, Name: "2"},
+				{Type: nodeapis.TopologyTypeNuma, Name: "10"},
+				{Type: nodeapis.TopologyTypeSocket, Name: "2"},
+				{Type: nodeapis.TopologyTypeSocket, Name: "10"},
+			},
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
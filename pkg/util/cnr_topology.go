@@ -169,10 +169,7 @@ func generateTopologyZoneStatus(
 	}
 
 	sort.SliceStable(result, func(i, j int) bool {
-		if result[i].Type == result[j].Type {
-			return result[i].Name < result[j].Name
-		}
-		return result[i].Type < result[j].Type
+		return lessTopologyZone(result[i], result[j])
 	})
 
 	return result
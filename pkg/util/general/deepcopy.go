@@ -39,3 +39,15 @@ func DeepCopyFload64Map(origin map[string]float64) map[string]float64 {
 	}
 	return res
 }
+
+func DeepCopyIntToIntMap(origin map[int]int) map[int]int {
+	if origin == nil {
+		return nil
+	}
+
+	res := make(map[int]int, len(origin))
+	for key, val := range origin {
+		res[key] = val
+	}
+	return res
+}
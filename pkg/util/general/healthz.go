@@ -17,15 +17,76 @@ limitations under the License.
 package general
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"k8s.io/utils/clock"
 )
 
-var (
-	healthzCheckMap  = make(map[HealthzCheckName]*healthzCheckStatus)
+// defaultHealthzRegistry is the process-global registry used by package level functions
+var defaultHealthzRegistry = NewHealthzRegistry()
+
+// healthzEventBufferSize is the capacity of channels returned by WatchHealthz,
+// events are dropped if the consumer falls behind it
+const healthzEventBufferSize = 100
+
+// HealthzRegistry holds a set of healthz checks, and it's safe for concurrent use
+type HealthzRegistry struct {
+	healthzCheckMap  map[HealthzCheckName]*healthzCheckStatus
 	healthzCheckLock sync.RWMutex
-)
+
+	// persistFile is the file that unhealthy states are persisted to, so that they can be restored
+	// when checks are registered after restart; empty means persistence is disabled
+	persistFile     string
+	persistedStates map[HealthzCheckName]persistedHealthzState
+	persistLock     sync.Mutex
+
+	// clock is used for all timestamps of checks, and it can be replaced by a fake one in tests
+	clock clock.PassiveClock
+
+	// transitionCallbacks are called in order of state transitions of any check
+	transitionCallbacks map[int]HealthzTransitionCallback
+	nextCallbackID      int
+	callbackLock        sync.Mutex
+
+	// droppedEvents is the number of events dropped since watchers didn't consume them in time
+	droppedEvents int64
+}
+
+// HealthzEvent describes a state transition of a check
+type HealthzEvent struct {
+	Name     HealthzCheckName
+	OldState HealthzCheckState
+	NewState HealthzCheckState
+	Message  string
+	Time     time.Time
+}
+
+// HealthzTransitionCallback is called on each state transition of checks, and
+// it must not block since it's called synchronously in UpdateHealthzState
+type HealthzTransitionCallback func(event HealthzEvent)
+
+// persistedHealthzState is the part of check status persisted across restarts
+type persistedHealthzState struct {
+	State               HealthzCheckState `json:"state"`
+	Message             string            `json:"message"`
+	UnhealthyStartTime  time.Time         `json:"unhealthyStartTime"`
+	LatestUnhealthyTime time.Time         `json:"latestUnhealthyTime"`
+}
+
+func NewHealthzRegistry() *HealthzRegistry {
+	return &HealthzRegistry{
+		healthzCheckMap:     make(map[HealthzCheckName]*healthzCheckStatus),
+		clock:               clock.RealClock{},
+		transitionCallbacks: make(map[int]HealthzTransitionCallback),
+	}
+}
 
 // HealthzCheckName describes which rule name for this check
 type HealthzCheckName string
@@ -35,15 +96,24 @@ type HealthzCheckState string
 
 type HealthzCheckMode string
 
+// HealthzCheckReason describes why a check is considered ready or not, so that callers
+// can tell different failures apart without parsing the message
+type HealthzCheckReason string
+
 type HealthzCheckResult struct {
-	Ready   bool   `json:"ready"`
-	Message string `json:"message"`
+	Ready   bool               `json:"ready"`
+	Reason  HealthzCheckReason `json:"reason"`
+	Message string             `json:"message"`
+	// LastTransitionTime is the last time the check state changed
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
 }
 
 type healthzCheckStatus struct {
 	State          HealthzCheckState `json:"state"`
 	Message        string            `json:"message"`
 	LastUpdateTime time.Time         `json:"lastUpdateTime"`
+	// LastTransitionTime is only updated when State changes, while LastUpdateTime is updated on every update
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
 
 	Mode HealthzCheckMode `json:"mode"`
 
@@ -62,11 +132,11 @@ type healthzCheckStatus struct {
 	mutex             sync.RWMutex
 }
 
-func (h *healthzCheckStatus) update(state HealthzCheckState, message string) {
+// update updates the status, and returns the old state and whether the state is changed
+func (h *healthzCheckStatus) update(now time.Time, state HealthzCheckState, message string) (HealthzCheckState, bool) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
-	now := time.Now()
 	h.Message = message
 	h.LastUpdateTime = now
 	if h.State == HealthzCheckStateReady && state != HealthzCheckStateReady {
@@ -75,7 +145,38 @@ func (h *healthzCheckStatus) update(state HealthzCheckState, message string) {
 	if state != HealthzCheckStateReady {
 		h.LatestUnhealthyTime = now
 	}
+	oldState := h.State
+	changed := oldState != state
+	if changed {
+		h.LastTransitionTime = now
+	}
 	h.State = state
+	return oldState, changed
+}
+
+// restore restores the persisted state if it's unhealthy, so that a known-bad check
+// doesn't report ready after restart until it's evaluated again
+func (h *healthzCheckStatus) restore(persisted persistedHealthzState) {
+	if persisted.State == HealthzCheckStateReady {
+		return
+	}
+
+	h.State = persisted.State
+	h.Message = persisted.Message
+	h.UnhealthyStartTime = persisted.UnhealthyStartTime
+	h.LatestUnhealthyTime = persisted.LatestUnhealthyTime
+}
+
+func (h *healthzCheckStatus) persistedState() persistedHealthzState {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return persistedHealthzState{
+		State:               h.State,
+		Message:             h.Message,
+		UnhealthyStartTime:  h.UnhealthyStartTime,
+		LatestUnhealthyTime: h.LatestUnhealthyTime,
+	}
 }
 
 const (
@@ -94,95 +195,344 @@ const (
 	// when the LatestUnhealthyTime is not earlier than the GracePeriod ago, we consider this rule as unhealthy.
 	// if caller doesn't report new failed state for more than GracePeriod, we consider the exception recovered.
 	HealthzCheckModeReport HealthzCheckMode = "report"
+
+	// HealthzCheckReasonHealthy means the check is ready
+	HealthzCheckReasonHealthy HealthzCheckReason = "Healthy"
+	// HealthzCheckReasonHeartbeatTimeout means the heartbeat check is not updated for more than TimeoutPeriod
+	HealthzCheckReasonHeartbeatTimeout HealthzCheckReason = "HeartbeatTimeout"
+	// HealthzCheckReasonTolerationExceeded means the heartbeat check is not ready for more than TolerationPeriod
+	HealthzCheckReasonTolerationExceeded HealthzCheckReason = "TolerationExceeded"
+	// HealthzCheckReasonReportedFailure means the heartbeat check reports a not ready state without any toleration
+	HealthzCheckReasonReportedFailure HealthzCheckReason = "ReportedFailure"
+	// HealthzCheckReasonReportTimeout means the report check reported a failure within AutoRecoverPeriod
+	HealthzCheckReasonReportTimeout HealthzCheckReason = "ReportTimeout"
 )
 
 // HealthzCheckFunc defined as a common function to define whether the corresponding component is healthy.
 type HealthzCheckFunc func() (healthzCheckStatus, error)
 
 func RegisterHeartbeatCheck(name string, timeout time.Duration, initState HealthzCheckState, tolerationPeriod time.Duration) {
-	healthzCheckLock.Lock()
-	defer healthzCheckLock.Unlock()
+	defaultHealthzRegistry.RegisterHeartbeatCheck(name, timeout, initState, tolerationPeriod)
+}
 
-	healthzCheckMap[HealthzCheckName(name)] = &healthzCheckStatus{
-		State:            initState,
-		Message:          InitMessage,
-		LastUpdateTime:   time.Now(),
-		TimeoutPeriod:    timeout,
-		TolerationPeriod: tolerationPeriod,
-		Mode:             HealthzCheckModeHeartBeat,
+func RegisterReportCheck(name string, autoRecoverPeriod time.Duration) {
+	defaultHealthzRegistry.RegisterReportCheck(name, autoRecoverPeriod)
+}
+
+func UpdateHealthzStateByError(name string, err error) error {
+	return defaultHealthzRegistry.UpdateHealthzStateByError(name, err)
+}
+
+func UpdateHealthzState(name string, state HealthzCheckState, message string) error {
+	return defaultHealthzRegistry.UpdateHealthzState(name, state, message)
+}
+
+func GetRegisterReadinessCheckResult() map[HealthzCheckName]HealthzCheckResult {
+	return defaultHealthzRegistry.GetRegisterReadinessCheckResult()
+}
+
+// GetReadinessCheckResult returns the readiness result of the check with the given name,
+// and false is returned if the check is not registered yet
+func GetReadinessCheckResult(name string) (HealthzCheckResult, bool) {
+	return defaultHealthzRegistry.GetReadinessCheckResult(name)
+}
+
+// GetReadinessCheckResultWithDefault returns the readiness result of the check with the given name,
+// and a check not registered yet is treated as ready or not according to notFoundAsReady, so that
+// probes behave predictably when racing with check registration during startup
+func GetReadinessCheckResultWithDefault(name string, notFoundAsReady bool) HealthzCheckResult {
+	return defaultHealthzRegistry.GetReadinessCheckResultWithDefault(name, notFoundAsReady)
+}
+
+// EnableHealthzStatePersistence makes the global registry persist check states into the file,
+// and restore unhealthy states in it when checks are registered
+func EnableHealthzStatePersistence(file string) error {
+	return defaultHealthzRegistry.EnablePersistence(file)
+}
+
+// ResetHealthzForTest removes all checks registered in the global registry, it should
+// only be used by tests which don't run in parallel with others relying on the global registry
+func ResetHealthzForTest() {
+	defaultHealthzRegistry.Reset()
+}
+
+// RegisterHealthzTransitionCallback registers a callback of state transitions to the global registry,
+// and returns a func to unregister it
+func RegisterHealthzTransitionCallback(cb HealthzTransitionCallback) func() {
+	return defaultHealthzRegistry.RegisterTransitionCallback(cb)
+}
+
+// WatchHealthz returns a channel of state transitions of checks in the global registry,
+// and a func to stop watching and close the channel
+func WatchHealthz() (<-chan HealthzEvent, func()) {
+	return defaultHealthzRegistry.WatchHealthz()
+}
+
+// SetHealthzClockForTest replaces the clock of the global registry, it should only be used
+// by tests which don't run in parallel with others relying on the global registry
+func SetHealthzClockForTest(c clock.PassiveClock) {
+	defaultHealthzRegistry.SetClock(c)
+}
+
+func (r *HealthzRegistry) RegisterHeartbeatCheck(name string, timeout time.Duration, initState HealthzCheckState, tolerationPeriod time.Duration) {
+	r.healthzCheckLock.Lock()
+	defer r.healthzCheckLock.Unlock()
+
+	now := r.clock.Now()
+	status := &healthzCheckStatus{
+		State:              initState,
+		Message:            InitMessage,
+		LastUpdateTime:     now,
+		LastTransitionTime: now,
+		TimeoutPeriod:      timeout,
+		TolerationPeriod:   tolerationPeriod,
+		Mode:               HealthzCheckModeHeartBeat,
+	}
+	if persisted, ok := r.persistedStates[HealthzCheckName(name)]; ok {
+		status.restore(persisted)
 	}
+	r.healthzCheckMap[HealthzCheckName(name)] = status
 }
 
-func RegisterReportCheck(name string, autoRecoverPeriod time.Duration) {
-	healthzCheckLock.Lock()
-	defer healthzCheckLock.Unlock()
+func (r *HealthzRegistry) RegisterReportCheck(name string, autoRecoverPeriod time.Duration) {
+	r.healthzCheckLock.Lock()
+	defer r.healthzCheckLock.Unlock()
 
-	healthzCheckMap[HealthzCheckName(name)] = &healthzCheckStatus{
-		State:             HealthzCheckStateReady,
-		Message:           InitMessage,
-		AutoRecoverPeriod: autoRecoverPeriod,
-		Mode:              HealthzCheckModeReport,
+	status := &healthzCheckStatus{
+		State:              HealthzCheckStateReady,
+		Message:            InitMessage,
+		LastTransitionTime: r.clock.Now(),
+		AutoRecoverPeriod:  autoRecoverPeriod,
+		Mode:               HealthzCheckModeReport,
 	}
+	if persisted, ok := r.persistedStates[HealthzCheckName(name)]; ok {
+		status.restore(persisted)
+	}
+	r.healthzCheckMap[HealthzCheckName(name)] = status
 }
 
-func UpdateHealthzStateByError(name string, err error) error {
+func (r *HealthzRegistry) UpdateHealthzStateByError(name string, err error) error {
 	if err != nil {
-		return UpdateHealthzState(name, HealthzCheckStateNotReady, err.Error())
+		return r.UpdateHealthzState(name, HealthzCheckStateNotReady, err.Error())
 	} else {
-		return UpdateHealthzState(name, HealthzCheckStateReady, "")
+		return r.UpdateHealthzState(name, HealthzCheckStateReady, "")
 	}
 }
 
-func UpdateHealthzState(name string, state HealthzCheckState, message string) error {
-	healthzCheckLock.RLock()
-	defer healthzCheckLock.RUnlock()
+func (r *HealthzRegistry) UpdateHealthzState(name string, state HealthzCheckState, message string) error {
+	r.healthzCheckLock.RLock()
+	defer r.healthzCheckLock.RUnlock()
 
-	status, ok := healthzCheckMap[HealthzCheckName(name)]
+	status, ok := r.healthzCheckMap[HealthzCheckName(name)]
 	if !ok {
 		Errorf("check rule %v not found", name)
 		return fmt.Errorf("check rule %v not found", name)
 	}
-	status.update(state, message)
+	now := r.clock.Now()
+	oldState, changed := status.update(now, state, message)
+	if !changed {
+		return nil
+	}
+
+	if r.persistFile != "" {
+		if err := r.persist(); err != nil {
+			Errorf("persist healthz states to %v failed: %v", r.persistFile, err)
+		}
+	}
+	r.notifyTransition(HealthzEvent{
+		Name:     HealthzCheckName(name),
+		OldState: oldState,
+		NewState: state,
+		Message:  message,
+		Time:     now,
+	})
 	return nil
 }
 
-func GetRegisterReadinessCheckResult() map[HealthzCheckName]HealthzCheckResult {
-	healthzCheckLock.RLock()
-	defer healthzCheckLock.RUnlock()
+// RegisterTransitionCallback registers a callback of state transitions, and returns a func to unregister it
+func (r *HealthzRegistry) RegisterTransitionCallback(cb HealthzTransitionCallback) func() {
+	r.callbackLock.Lock()
+	defer r.callbackLock.Unlock()
+
+	id := r.nextCallbackID
+	r.nextCallbackID++
+	r.transitionCallbacks[id] = cb
+	return func() {
+		r.callbackLock.Lock()
+		defer r.callbackLock.Unlock()
 
+		delete(r.transitionCallbacks, id)
+	}
+}
+
+// WatchHealthz returns a channel of state transitions based on transition callbacks, and a func
+// to stop watching and close the channel; events are dropped and counted if the channel is full
+func (r *HealthzRegistry) WatchHealthz() (<-chan HealthzEvent, func()) {
+	ch := make(chan HealthzEvent, healthzEventBufferSize)
+	unregister := r.RegisterTransitionCallback(func(event HealthzEvent) {
+		select {
+		case ch <- event:
+		default:
+			atomic.AddInt64(&r.droppedEvents, 1)
+		}
+	})
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			// no more events are sent after unregistered, so it's safe to close the channel
+			unregister()
+			close(ch)
+		})
+	}
+}
+
+// DroppedEvents returns the number of events dropped due to slow watchers
+func (r *HealthzRegistry) DroppedEvents() int64 {
+	return atomic.LoadInt64(&r.droppedEvents)
+}
+
+// notifyTransition calls all transition callbacks with the event, and callbacks are
+// serialized so that each of them sees events in the order they are notified
+func (r *HealthzRegistry) notifyTransition(event HealthzEvent) {
+	r.callbackLock.Lock()
+	defer r.callbackLock.Unlock()
+
+	for _, cb := range r.transitionCallbacks {
+		cb(event)
+	}
+}
+
+func (r *HealthzRegistry) GetRegisterReadinessCheckResult() map[HealthzCheckName]HealthzCheckResult {
+	r.healthzCheckLock.RLock()
+	defer r.healthzCheckLock.RUnlock()
+
+	now := r.clock.Now()
 	results := make(map[HealthzCheckName]HealthzCheckResult)
-	for name, checkStatus := range healthzCheckMap {
-		func() {
-			checkStatus.mutex.RLock()
-			defer checkStatus.mutex.RUnlock()
-
-			ready := true
-			message := checkStatus.Message
-			switch checkStatus.Mode {
-			case HealthzCheckModeHeartBeat:
-				if checkStatus.TimeoutPeriod > 0 && time.Now().Sub(checkStatus.LastUpdateTime) > checkStatus.TimeoutPeriod {
-					ready = false
-					message = fmt.Sprintf("the status has not been updated for more than %v, last update time is %v", checkStatus.TimeoutPeriod, checkStatus.LastUpdateTime)
-				}
-
-				if checkStatus.TolerationPeriod <= 0 && checkStatus.State != HealthzCheckStateReady {
-					ready = false
-				}
-
-				if checkStatus.TolerationPeriod > 0 && time.Now().Sub(checkStatus.UnhealthyStartTime) > checkStatus.TolerationPeriod &&
-					checkStatus.State != HealthzCheckStateReady {
-					ready = false
-				}
-			case HealthzCheckModeReport:
-				if checkStatus.LatestUnhealthyTime.After(time.Now().Add(-checkStatus.TolerationPeriod)) {
-					ready = false
-				}
-			}
-			results[name] = HealthzCheckResult{
-				Ready:   ready,
-				Message: message,
-			}
-		}()
+	for name, checkStatus := range r.healthzCheckMap {
+		results[name] = checkStatus.readinessResult(now)
 	}
 	return results
 }
+
+func (r *HealthzRegistry) GetReadinessCheckResult(name string) (HealthzCheckResult, bool) {
+	r.healthzCheckLock.RLock()
+	defer r.healthzCheckLock.RUnlock()
+
+	checkStatus, ok := r.healthzCheckMap[HealthzCheckName(name)]
+	if !ok {
+		return HealthzCheckResult{}, false
+	}
+	return checkStatus.readinessResult(r.clock.Now()), true
+}
+
+func (r *HealthzRegistry) GetReadinessCheckResultWithDefault(name string, notFoundAsReady bool) HealthzCheckResult {
+	result, ok := r.GetReadinessCheckResult(name)
+	if !ok {
+		return HealthzCheckResult{
+			Ready:   notFoundAsReady,
+			Message: fmt.Sprintf("check rule %v not found", name),
+		}
+	}
+	return result
+}
+
+// EnablePersistence makes the registry persist check states into the file whenever a state changes,
+// and unhealthy states already in the file are restored when the checks are registered
+func (r *HealthzRegistry) EnablePersistence(file string) error {
+	r.healthzCheckLock.Lock()
+	defer r.healthzCheckLock.Unlock()
+
+	persistedStates := make(map[HealthzCheckName]persistedHealthzState)
+	data, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read healthz states from %v failed: %v", file, err)
+	} else if err == nil && len(data) > 0 {
+		if err := json.Unmarshal(data, &persistedStates); err != nil {
+			return fmt.Errorf("unmarshal healthz states from %v failed: %v", file, err)
+		}
+	}
+
+	r.persistFile = file
+	r.persistedStates = persistedStates
+	return nil
+}
+
+// persist writes states of all checks into the persist file.
+// It should be called with r.healthzCheckLock held.
+func (r *HealthzRegistry) persist() error {
+	r.persistLock.Lock()
+	defer r.persistLock.Unlock()
+
+	states := make(map[HealthzCheckName]persistedHealthzState, len(r.healthzCheckMap))
+	for name, status := range r.healthzCheckMap {
+		states[name] = status.persistedState()
+	}
+	data, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file and rename it, so that the file is never partially written
+	tmpFile, err := ioutil.TempFile(filepath.Dir(r.persistFile), filepath.Base(r.persistFile))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), r.persistFile)
+}
+
+// SetClock replaces the clock used for timestamps of checks, e.g. with a fake one in tests
+func (r *HealthzRegistry) SetClock(c clock.PassiveClock) {
+	r.healthzCheckLock.Lock()
+	defer r.healthzCheckLock.Unlock()
+
+	r.clock = c
+}
+
+// Reset removes all checks registered in the registry
+func (r *HealthzRegistry) Reset() {
+	r.healthzCheckLock.Lock()
+	defer r.healthzCheckLock.Unlock()
+
+	r.healthzCheckMap = make(map[HealthzCheckName]*healthzCheckStatus)
+}
+
+func (h *healthzCheckStatus) readinessResult(now time.Time) HealthzCheckResult {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	reason := HealthzCheckReasonHealthy
+	message := h.Message
+	switch h.Mode {
+	case HealthzCheckModeHeartBeat:
+		// heartbeat timeout takes precedence, since the reported state is stale in that case
+		if h.TimeoutPeriod > 0 && now.Sub(h.LastUpdateTime) > h.TimeoutPeriod {
+			reason = HealthzCheckReasonHeartbeatTimeout
+			message = fmt.Sprintf("the status has not been updated for more than %v, last update time is %v", h.TimeoutPeriod, h.LastUpdateTime)
+		} else if h.TolerationPeriod <= 0 && h.State != HealthzCheckStateReady {
+			reason = HealthzCheckReasonReportedFailure
+		} else if h.TolerationPeriod > 0 && now.Sub(h.UnhealthyStartTime) > h.TolerationPeriod &&
+			h.State != HealthzCheckStateReady {
+			reason = HealthzCheckReasonTolerationExceeded
+		}
+	case HealthzCheckModeReport:
+		if h.LatestUnhealthyTime.After(now.Add(-h.AutoRecoverPeriod)) {
+			reason = HealthzCheckReasonReportTimeout
+		}
+	}
+	return HealthzCheckResult{
+		Ready:              reason == HealthzCheckReasonHealthy,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: h.LastTransitionTime,
+	}
+}
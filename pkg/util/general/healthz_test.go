@@ -0,0 +1,254 @@
+/*
+Copyright 2022 The Katalyst Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package general
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestHealthzCheckLastTransitionTime(t *testing.T) {
+	t.Parallel()
+
+	as := require.New(t)
+
+	fakeClock := testingclock.NewFakePassiveClock(time.Now())
+	r := NewHealthzRegistry()
+	r.SetClock(fakeClock)
+
+	name := "test-healthz-last-transition-time"
+	r.RegisterHeartbeatCheck(name, time.Minute, HealthzCheckStateNotReady, time.Minute)
+
+	initTransitionTime := r.GetRegisterReadinessCheckResult()[HealthzCheckName(name)].LastTransitionTime
+	as.Equal(fakeClock.Now(), initTransitionTime)
+
+	// the transition time advances when state changes
+	fakeClock.SetTime(fakeClock.Now().Add(time.Second))
+	as.NoError(r.UpdateHealthzState(name, HealthzCheckStateReady, ""))
+	readyTransitionTime := r.GetRegisterReadinessCheckResult()[HealthzCheckName(name)].LastTransitionTime
+	as.Equal(fakeClock.Now(), readyTransitionTime)
+
+	// the transition time stays the same when state doesn't change
+	fakeClock.SetTime(fakeClock.Now().Add(time.Second))
+	as.NoError(r.UpdateHealthzState(name, HealthzCheckStateReady, "still ready"))
+	result := r.GetRegisterReadinessCheckResult()[HealthzCheckName(name)]
+	as.True(result.Ready)
+	as.Equal(readyTransitionTime, result.LastTransitionTime)
+
+	fakeClock.SetTime(fakeClock.Now().Add(time.Second))
+	as.NoError(r.UpdateHealthzState(name, HealthzCheckStateNotReady, "not ready"))
+	as.Equal(fakeClock.Now(), r.GetRegisterReadinessCheckResult()[HealthzCheckName(name)].LastTransitionTime)
+}
+
+func TestGetReadinessCheckResultWithDefault(t *testing.T) {
+	t.Parallel()
+
+	as := require.New(t)
+
+	name := "test-healthz-not-found"
+	_, ok := GetReadinessCheckResult(name)
+	as.False(ok)
+	as.True(GetReadinessCheckResultWithDefault(name, true).Ready)
+	as.False(GetReadinessCheckResultWithDefault(name, false).Ready)
+
+	// registered and not ready is distinguished from not found
+	RegisterHeartbeatCheck(name, time.Minute, HealthzCheckStateNotReady, 0)
+	result, ok := GetReadinessCheckResult(name)
+	as.True(ok)
+	as.False(result.Ready)
+	as.False(GetReadinessCheckResultWithDefault(name, true).Ready)
+
+	as.NoError(UpdateHealthzState(name, HealthzCheckStateReady, ""))
+	as.True(GetReadinessCheckResultWithDefault(name, false).Ready)
+}
+
+func TestHealthzRegistryReset(t *testing.T) {
+	t.Parallel()
+
+	as := require.New(t)
+
+	r := NewHealthzRegistry()
+	r.RegisterHeartbeatCheck("test-heartbeat", time.Minute, HealthzCheckStateReady, 0)
+	r.RegisterReportCheck("test-report", time.Minute)
+	as.Len(r.GetRegisterReadinessCheckResult(), 2)
+
+	// checks in a registry are isolated from the global one
+	_, ok := GetReadinessCheckResult("test-heartbeat")
+	as.False(ok)
+
+	r.Reset()
+	as.Empty(r.GetRegisterReadinessCheckResult())
+	_, ok = r.GetReadinessCheckResult("test-heartbeat")
+	as.False(ok)
+	as.Error(r.UpdateHealthzState("test-report", HealthzCheckStateNotReady, "not ready"))
+}
+
+func TestHealthzRegistryPersistence(t *testing.T) {
+	t.Parallel()
+
+	as := require.New(t)
+
+	dir, err := ioutil.TempDir("", "test-healthz-persistence")
+	as.NoError(err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "healthz")
+
+	r := NewHealthzRegistry()
+	as.NoError(r.EnablePersistence(file))
+	r.RegisterHeartbeatCheck("test-bad", 0, HealthzCheckStateReady, 0)
+	r.RegisterHeartbeatCheck("test-good", 0, HealthzCheckStateNotReady, 0)
+	as.NoError(r.UpdateHealthzState("test-bad", HealthzCheckStateNotReady, "cpuset corrupted"))
+	as.NoError(r.UpdateHealthzState("test-good", HealthzCheckStateReady, ""))
+
+	// restart with a new registry loading the persisted states
+	restarted := NewHealthzRegistry()
+	as.NoError(restarted.EnablePersistence(file))
+	restarted.RegisterHeartbeatCheck("test-bad", 0, HealthzCheckStateReady, 0)
+	restarted.RegisterHeartbeatCheck("test-good", 0, HealthzCheckStateNotReady, 0)
+
+	result, ok := restarted.GetReadinessCheckResult("test-bad")
+	as.True(ok)
+	as.False(result.Ready)
+	as.Equal("cpuset corrupted", result.Message)
+
+	// ready state is not restored, and init state takes effect
+	result, ok = restarted.GetReadinessCheckResult("test-good")
+	as.True(ok)
+	as.False(result.Ready)
+
+	// without persistence enabled nothing is restored
+	fresh := NewHealthzRegistry()
+	fresh.RegisterHeartbeatCheck("test-bad", 0, HealthzCheckStateReady, 0)
+	result, ok = fresh.GetReadinessCheckResult("test-bad")
+	as.True(ok)
+	as.True(result.Ready)
+}
+
+func TestHealthzCheckReason(t *testing.T) {
+	t.Parallel()
+
+	as := require.New(t)
+
+	fakeClock := testingclock.NewFakePassiveClock(time.Now())
+	r := NewHealthzRegistry()
+	r.SetClock(fakeClock)
+	r.RegisterHeartbeatCheck("healthy", time.Hour, HealthzCheckStateReady, time.Hour)
+	r.RegisterHeartbeatCheck("heartbeat-timeout", time.Minute, HealthzCheckStateReady, time.Hour)
+	r.RegisterHeartbeatCheck("toleration-exceeded", time.Hour, HealthzCheckStateReady, time.Minute)
+	r.RegisterHeartbeatCheck("in-toleration", time.Hour, HealthzCheckStateReady, time.Hour)
+	r.RegisterHeartbeatCheck("reported-failure", time.Hour, HealthzCheckStateReady, 0)
+	r.RegisterReportCheck("report-timeout", time.Hour)
+	r.RegisterReportCheck("report-recovered", time.Minute)
+
+	as.NoError(r.UpdateHealthzState("toleration-exceeded", HealthzCheckStateNotReady, "toleration exceeded"))
+	as.NoError(r.UpdateHealthzState("in-toleration", HealthzCheckStateNotReady, "in toleration"))
+	as.NoError(r.UpdateHealthzState("reported-failure", HealthzCheckStateNotReady, "reported failure"))
+	as.NoError(r.UpdateHealthzState("report-timeout", HealthzCheckStateNotReady, "report timeout"))
+	as.NoError(r.UpdateHealthzState("report-recovered", HealthzCheckStateNotReady, "report recovered"))
+
+	// nothing is expired before the clock advances
+	for name, result := range r.GetRegisterReadinessCheckResult() {
+		as.NotEqual(HealthzCheckReasonHeartbeatTimeout, result.Reason, "check %v", name)
+		as.NotEqual(HealthzCheckReasonTolerationExceeded, result.Reason, "check %v", name)
+	}
+
+	fakeClock.SetTime(fakeClock.Now().Add(2 * time.Minute))
+	as.NoError(r.UpdateHealthzState("healthy", HealthzCheckStateReady, ""))
+
+	expected := map[HealthzCheckName]HealthzCheckReason{
+		"healthy":             HealthzCheckReasonHealthy,
+		"heartbeat-timeout":   HealthzCheckReasonHeartbeatTimeout,
+		"toleration-exceeded": HealthzCheckReasonTolerationExceeded,
+		"in-toleration":       HealthzCheckReasonHealthy,
+		"reported-failure":    HealthzCheckReasonReportedFailure,
+		"report-timeout":      HealthzCheckReasonReportTimeout,
+		"report-recovered":    HealthzCheckReasonHealthy,
+	}
+	results := r.GetRegisterReadinessCheckResult()
+	as.Len(results, len(expected))
+	for name, reason := range expected {
+		as.Equal(reason, results[name].Reason, "check %v", name)
+		as.Equal(reason == HealthzCheckReasonHealthy, results[name].Ready, "check %v", name)
+	}
+
+	// the message is kept for humans along with the reason
+	as.Equal("reported failure", results["reported-failure"].Message)
+	as.Contains(results["heartbeat-timeout"].Message, "has not been updated")
+}
+
+func TestWatchHealthz(t *testing.T) {
+	t.Parallel()
+
+	as := require.New(t)
+
+	fakeClock := testingclock.NewFakePassiveClock(time.Now())
+	r := NewHealthzRegistry()
+	r.SetClock(fakeClock)
+
+	name := "test-healthz-watch"
+	r.RegisterHeartbeatCheck(name, 0, HealthzCheckStateReady, 0)
+
+	ch, cancel := r.WatchHealthz()
+
+	// only state transitions are emitted
+	as.NoError(r.UpdateHealthzState(name, HealthzCheckStateReady, "still ready"))
+	fakeClock.SetTime(fakeClock.Now().Add(time.Second))
+	as.NoError(r.UpdateHealthzState(name, HealthzCheckStateNotReady, "broken"))
+	fakeClock.SetTime(fakeClock.Now().Add(time.Second))
+	as.NoError(r.UpdateHealthzState(name, HealthzCheckStateReady, "recovered"))
+
+	as.Equal(HealthzEvent{
+		Name:     HealthzCheckName(name),
+		OldState: HealthzCheckStateReady,
+		NewState: HealthzCheckStateNotReady,
+		Message:  "broken",
+		Time:     fakeClock.Now().Add(-time.Second),
+	}, <-ch)
+	as.Equal(HealthzEvent{
+		Name:     HealthzCheckName(name),
+		OldState: HealthzCheckStateNotReady,
+		NewState: HealthzCheckStateReady,
+		Message:  "recovered",
+		Time:     fakeClock.Now(),
+	}, <-ch)
+	as.Equal(0, len(ch))
+
+	// events are dropped and counted if the watcher falls behind
+	for i := 0; i < healthzEventBufferSize+1; i++ {
+		state := HealthzCheckStateNotReady
+		if i%2 == 1 {
+			state = HealthzCheckStateReady
+		}
+		as.NoError(r.UpdateHealthzState(name, state, ""))
+	}
+	as.Equal(healthzEventBufferSize, len(ch))
+	as.Equal(int64(1), r.DroppedEvents())
+
+	// the channel is closed after canceled
+	cancel()
+	cancel()
+	for range ch {
+	}
+	_, ok := <-ch
+	as.False(ok)
+}
@@ -19,6 +19,7 @@ package metric
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 )
@@ -33,6 +34,13 @@ type MetricData struct {
 	Time *time.Time
 }
 
+const (
+	// numaMetricHistoryRetention is how long numa metric samples are retained to calculate rates
+	numaMetricHistoryRetention = 10 * time.Minute
+	// numaMetricHistoryMaxSamples bounds the retained samples of each numa metric
+	numaMetricHistoryMaxSamples = 600
+)
+
 // MetricStore stores those metric data. Including:
 // 1. raw data collected from agent.MetricsFetcher.
 // 2. data calculated based on raw data.
@@ -49,6 +57,8 @@ type MetricStore struct {
 	podVolumeMetricMap        map[string]map[string]map[string]MetricData            // map[podUID]map[volumeName]map[metricName]data
 	cgroupMetricMap           map[string]map[string]MetricData                       // map[cgroupPath]map[metricName]value
 	cgroupNumaMetricMap       map[string]map[int]map[string]MetricData               // map[cgroupPath]map[numaNode]map[metricName]value
+
+	numaMetricHistoryMap map[int]map[string][]MetricData // map[numaID]map[metricName]samples sorted by time
 }
 
 func NewMetricStore() *MetricStore {
@@ -63,7 +73,77 @@ func NewMetricStore() *MetricStore {
 		podVolumeMetricMap:        make(map[string]map[string]map[string]MetricData),
 		cgroupMetricMap:           make(map[string]map[string]MetricData),
 		cgroupNumaMetricMap:       make(map[string]map[int]map[string]MetricData),
+		numaMetricHistoryMap:      make(map[int]map[string][]MetricData),
+	}
+}
+
+// Clone returns a deep copy of the store taken under a single read lock, so that
+// readers can get several metrics from one consistent point in time
+func (c *MetricStore) Clone() *MetricStore {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	clone := NewMetricStore()
+	clone.nodeMetricMap = cloneMetrics(c.nodeMetricMap)
+	for numaID, metrics := range c.numaMetricMap {
+		clone.numaMetricMap[numaID] = cloneMetrics(metrics)
+	}
+	for deviceName, metrics := range c.deviceMetricMap {
+		clone.deviceMetricMap[deviceName] = cloneMetrics(metrics)
+	}
+	for networkName, metrics := range c.networkMetricMap {
+		clone.networkMetricMap[networkName] = cloneMetrics(metrics)
+	}
+	for cpuID, metrics := range c.cpuMetricMap {
+		clone.cpuMetricMap[cpuID] = cloneMetrics(metrics)
+	}
+	for podUID, containers := range c.podContainerMetricMap {
+		clone.podContainerMetricMap[podUID] = make(map[string]map[string]MetricData, len(containers))
+		for containerName, metrics := range containers {
+			clone.podContainerMetricMap[podUID][containerName] = cloneMetrics(metrics)
+		}
+	}
+	for podUID, containers := range c.podContainerNumaMetricMap {
+		clone.podContainerNumaMetricMap[podUID] = make(map[string]map[string]map[string]MetricData, len(containers))
+		for containerName, numas := range containers {
+			clone.podContainerNumaMetricMap[podUID][containerName] = make(map[string]map[string]MetricData, len(numas))
+			for numaNode, metrics := range numas {
+				clone.podContainerNumaMetricMap[podUID][containerName][numaNode] = cloneMetrics(metrics)
+			}
+		}
+	}
+	for podUID, volumes := range c.podVolumeMetricMap {
+		clone.podVolumeMetricMap[podUID] = make(map[string]map[string]MetricData, len(volumes))
+		for volumeName, metrics := range volumes {
+			clone.podVolumeMetricMap[podUID][volumeName] = cloneMetrics(metrics)
+		}
+	}
+	for cgroupPath, metrics := range c.cgroupMetricMap {
+		clone.cgroupMetricMap[cgroupPath] = cloneMetrics(metrics)
+	}
+	for cgroupPath, numas := range c.cgroupNumaMetricMap {
+		clone.cgroupNumaMetricMap[cgroupPath] = make(map[int]map[string]MetricData, len(numas))
+		for numaNode, metrics := range numas {
+			clone.cgroupNumaMetricMap[cgroupPath][numaNode] = cloneMetrics(metrics)
+		}
+	}
+	for numaID, histories := range c.numaMetricHistoryMap {
+		clone.numaMetricHistoryMap[numaID] = make(map[string][]MetricData, len(histories))
+		for metricName, samples := range histories {
+			clone.numaMetricHistoryMap[numaID][metricName] = append([]MetricData(nil), samples...)
+		}
 	}
+	return clone
+}
+
+// cloneMetrics copies the metric map; time pointers are shared since
+// metric data is always replaced rather than modified in place
+func cloneMetrics(metrics map[string]MetricData) map[string]MetricData {
+	clone := make(map[string]MetricData, len(metrics))
+	for metricName, data := range metrics {
+		clone[metricName] = data
+	}
+	return clone
 }
 
 func (c *MetricStore) SetNodeMetric(metricName string, data MetricData) {
@@ -79,6 +159,48 @@ func (c *MetricStore) SetNumaMetric(numaID int, metricName string, data MetricDa
 		c.numaMetricMap[numaID] = make(map[string]MetricData)
 	}
 	c.numaMetricMap[numaID][metricName] = data
+	c.appendNumaMetricHistory(numaID, metricName, data)
+}
+
+// SetNumaMetricBatch sets the metric of multiple numas under a single lock,
+// to reduce lock contention when collectors write all numas at once
+func (c *MetricStore) SetNumaMetricBatch(metricName string, data map[int]MetricData) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for numaID, numaData := range data {
+		if _, ok := c.numaMetricMap[numaID]; !ok {
+			c.numaMetricMap[numaID] = make(map[string]MetricData)
+		}
+		c.numaMetricMap[numaID][metricName] = numaData
+		c.appendNumaMetricHistory(numaID, metricName, numaData)
+	}
+}
+
+// appendNumaMetricHistory retains the sample for rate calculation, and drops samples
+// out of the retention. It should be called with c.mutex held.
+func (c *MetricStore) appendNumaMetricHistory(numaID int, metricName string, data MetricData) {
+	if data.Time == nil {
+		return
+	}
+	if _, ok := c.numaMetricHistoryMap[numaID]; !ok {
+		c.numaMetricHistoryMap[numaID] = make(map[string][]MetricData)
+	}
+
+	samples := c.numaMetricHistoryMap[numaID][metricName]
+	// a sample not newer than the latest one is a duplicate write or clock skew, and is ignored
+	if len(samples) > 0 && !data.Time.After(*samples[len(samples)-1].Time) {
+		return
+	}
+	samples = append(samples, data)
+
+	expired := 0
+	for expired < len(samples) && data.Time.Sub(*samples[expired].Time) > numaMetricHistoryRetention {
+		expired++
+	}
+	if overflow := len(samples) - expired - numaMetricHistoryMaxSamples; overflow > 0 {
+		expired += overflow
+	}
+	c.numaMetricHistoryMap[numaID][metricName] = samples[expired:]
 }
 
 func (c *MetricStore) SetDeviceMetric(deviceName string, metricName string, data MetricData) {
@@ -177,6 +299,54 @@ func (c *MetricStore) GetNumaMetric(numaID int, metricName string) (MetricData,
 	return MetricData{}, errors.New(fmt.Sprintf("[MetricStore] empty map, metric=%v, numaID=%v", metricName, numaID))
 }
 
+// GetNumaMetricRate returns the per-second slope of the numa metric, fitted by least squares
+// over the retained samples within the window before the newest sample
+func (c *MetricStore) GetNumaMetricRate(numaID int, metricName string, window time.Duration) (float64, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	samples := c.numaMetricHistoryMap[numaID][metricName]
+	if len(samples) == 0 {
+		return 0, fmt.Errorf("[MetricStore] no samples, metric=%v, numaID=%v", metricName, numaID)
+	}
+
+	newest := *samples[len(samples)-1].Time
+	start := sort.Search(len(samples), func(i int) bool {
+		return newest.Sub(*samples[i].Time) <= window
+	})
+	samples = samples[start:]
+	if len(samples) < 2 {
+		return 0, fmt.Errorf("[MetricStore] not enough samples in window %v, metric=%v, numaID=%v", window, metricName, numaID)
+	}
+
+	// use seconds relative to the first sample to keep the sums small
+	var sumX, sumY, sumXY, sumXX float64
+	for _, sample := range samples {
+		x := sample.Time.Sub(*samples[0].Time).Seconds()
+		sumX += x
+		sumY += sample.Value
+		sumXY += x * sample.Value
+		sumXX += x * x
+	}
+	n := float64(len(samples))
+	return (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX), nil
+}
+
+// ListNumasWithMetric returns the sorted ids of numas which have the given metric
+func (c *MetricStore) ListNumasWithMetric(metricName string) []int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	numaIDs := make([]int, 0, len(c.numaMetricMap))
+	for numaID, numaMetrics := range c.numaMetricMap {
+		if _, ok := numaMetrics[metricName]; ok {
+			numaIDs = append(numaIDs, numaID)
+		}
+	}
+	sort.Ints(numaIDs)
+	return numaIDs
+}
+
 func (c *MetricStore) GetDeviceMetric(deviceName string, metricName string) (MetricData, error) {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
@@ -264,6 +434,84 @@ func (c *MetricStore) GetPodVolumeMetric(podUID, volumeName, metricName string)
 	return MetricData{}, errors.New(fmt.Sprintf("[MetricStore] empty map, metric=%v, podUID=%v, volumeName=%v", metricName, podUID, volumeName))
 }
 
+// GetNewestNodeMetricTime returns the newest sample time among node metrics,
+// and zero time is returned if there is no sample
+func (c *MetricStore) GetNewestNodeMetricTime() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return newestMetricTime(time.Time{}, c.nodeMetricMap)
+}
+
+// GetNewestNumaMetricTime returns the newest sample time among numa metrics
+func (c *MetricStore) GetNewestNumaMetricTime() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	newest := time.Time{}
+	for _, metrics := range c.numaMetricMap {
+		newest = newestMetricTime(newest, metrics)
+	}
+	return newest
+}
+
+// GetNewestDeviceMetricTime returns the newest sample time among device metrics
+func (c *MetricStore) GetNewestDeviceMetricTime() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	newest := time.Time{}
+	for _, metrics := range c.deviceMetricMap {
+		newest = newestMetricTime(newest, metrics)
+	}
+	return newest
+}
+
+// GetNewestCPUMetricTime returns the newest sample time among cpu metrics
+func (c *MetricStore) GetNewestCPUMetricTime() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	newest := time.Time{}
+	for _, metrics := range c.cpuMetricMap {
+		newest = newestMetricTime(newest, metrics)
+	}
+	return newest
+}
+
+// GetNewestContainerMetricTime returns the newest sample time among container metrics
+func (c *MetricStore) GetNewestContainerMetricTime() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	newest := time.Time{}
+	for _, containers := range c.podContainerMetricMap {
+		for _, metrics := range containers {
+			newest = newestMetricTime(newest, metrics)
+		}
+	}
+	return newest
+}
+
+// GetNewestContainerNumaMetricTime returns the newest sample time among container numa metrics
+func (c *MetricStore) GetNewestContainerNumaMetricTime() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	newest := time.Time{}
+	for _, containers := range c.podContainerNumaMetricMap {
+		for _, numas := range containers {
+			for _, metrics := range numas {
+				newest = newestMetricTime(newest, metrics)
+			}
+		}
+	}
+	return newest
+}
+
+func newestMetricTime(newest time.Time, metrics map[string]MetricData) time.Time {
+	for _, data := range metrics {
+		if data.Time != nil && data.Time.After(newest) {
+			newest = *data.Time
+		}
+	}
+	return newest
+}
+
 func (c *MetricStore) GCPodsMetric(livingPodUIDSet map[string]bool) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
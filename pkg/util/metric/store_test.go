@@ -38,6 +38,21 @@ func TestStore_SetAndGetNodeMetric(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestStore_ListNumasWithMetric(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	store := NewMetricStore()
+	store.SetNumaMetric(1, "test-metric-name", MetricData{Value: 1.0, Time: &now})
+	store.SetNumaMetric(0, "test-metric-name", MetricData{Value: 1.0, Time: &now})
+	store.SetNumaMetric(2, "test-other-metric-name", MetricData{Value: 1.0, Time: &now})
+
+	assert.Equal(t, []int{0, 1}, store.ListNumasWithMetric("test-metric-name"))
+	assert.Equal(t, []int{2}, store.ListNumasWithMetric("test-other-metric-name"))
+	assert.Empty(t, store.ListNumasWithMetric("test-not-exist"))
+}
+
 func TestStore_SetAndGetNumaMetric(t *testing.T) {
 	t.Parallel()
 
@@ -51,6 +66,84 @@ func TestStore_SetAndGetNumaMetric(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestStore_SetNumaMetricBatch(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	store := NewMetricStore()
+	store.SetNumaMetric(0, "test-other-metric-name", MetricData{Value: 4.0, Time: &now})
+	store.SetNumaMetricBatch("test-metric-name", map[int]MetricData{
+		0: {Value: 1.0, Time: &now},
+		1: {Value: 2.0, Time: &now},
+		3: {Value: 3.0, Time: &now},
+	})
+
+	for numaID, expected := range map[int]float64{0: 1.0, 1: 2.0, 3: 3.0} {
+		value, err := store.GetNumaMetric(numaID, "test-metric-name")
+		assert.NoError(t, err)
+		assert.Equal(t, MetricData{Value: expected, Time: &now}, value)
+	}
+	_, err := store.GetNumaMetric(2, "test-metric-name")
+	assert.Error(t, err)
+
+	// metrics of other names are kept
+	value, err := store.GetNumaMetric(0, "test-other-metric-name")
+	assert.NoError(t, err)
+	assert.Equal(t, MetricData{Value: 4.0, Time: &now}, value)
+	assert.Equal(t, []int{0, 1, 3}, store.ListNumasWithMetric("test-metric-name"))
+}
+
+func TestStore_GetNumaMetricRate(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+
+	store := NewMetricStore()
+	_, err := store.GetNumaMetricRate(0, "test-metric-name", time.Minute)
+	assert.Error(t, err)
+
+	// the value grows by 2 per second during the first minute, and by 5 per second after that
+	value := 100.0
+	for i := 0; i <= 120; i += 10 {
+		sampleTime := start.Add(time.Duration(i) * time.Second)
+		if i > 0 && i <= 60 {
+			value += 20
+		} else if i > 60 {
+			value += 50
+		}
+		store.SetNumaMetric(0, "test-metric-name", MetricData{Value: value, Time: &sampleTime})
+	}
+
+	rate, err := store.GetNumaMetricRate(0, "test-metric-name", time.Minute)
+	assert.NoError(t, err)
+	assert.InDelta(t, 5.0, rate, 1e-9)
+
+	rate, err = store.GetNumaMetricRate(0, "test-metric-name", 10*time.Second)
+	assert.NoError(t, err)
+	assert.InDelta(t, 5.0, rate, 1e-9)
+
+	// only the newest sample is in the window
+	_, err = store.GetNumaMetricRate(0, "test-metric-name", time.Second)
+	assert.Error(t, err)
+	_, err = store.GetNumaMetricRate(1, "test-metric-name", time.Minute)
+	assert.Error(t, err)
+
+	// samples written in batch are retained as well
+	store.SetNumaMetricBatch("test-batch-metric-name", map[int]MetricData{0: {Value: 1, Time: &start}})
+	later := start.Add(4 * time.Second)
+	store.SetNumaMetricBatch("test-batch-metric-name", map[int]MetricData{0: {Value: 3, Time: &later}})
+	rate, err = store.GetNumaMetricRate(0, "test-batch-metric-name", time.Minute)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.5, rate, 1e-9)
+
+	// samples out of retention are dropped
+	expired := start.Add(numaMetricHistoryRetention + 5*time.Second)
+	store.SetNumaMetricBatch("test-batch-metric-name", map[int]MetricData{0: {Value: 3, Time: &expired}})
+	_, err = store.GetNumaMetricRate(0, "test-batch-metric-name", 2*numaMetricHistoryRetention)
+	assert.Error(t, err)
+}
+
 func TestStore_SetAndGeDeviceMetric(t *testing.T) {
 	t.Parallel()
 
@@ -108,3 +201,53 @@ func TestStore_SetAndGetPodVolumeMetric(t *testing.T) {
 	_, err = store.GetPodVolumeMetric("podUID", "volumeName", consts.MetricsPodVolumeInodesUsed)
 	assert.Error(t, err)
 }
+
+func TestStore_GetNewestMetricTime(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	older := now.Add(-time.Minute)
+
+	store := NewMetricStore()
+	assert.True(t, store.GetNewestNodeMetricTime().IsZero())
+
+	store.SetNodeMetric("test-metric-name", MetricData{Value: 1.0, Time: &older})
+	store.SetNodeMetric("test-other-metric-name", MetricData{Value: 1.0, Time: &now})
+	store.SetContainerNumaMetric("pod1", "container1", "0", "test-metric-name", MetricData{Value: 1.0, Time: &older})
+	assert.Equal(t, now, store.GetNewestNodeMetricTime())
+	assert.Equal(t, older, store.GetNewestContainerNumaMetricTime())
+	assert.True(t, store.GetNewestContainerMetricTime().IsZero())
+}
+
+func TestStore_Clone(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	store := NewMetricStore()
+	store.SetNodeMetric("test-metric-name", MetricData{Value: 1.0, Time: &now})
+	store.SetNumaMetric(0, "test-metric-name", MetricData{Value: 1.0, Time: &now})
+	store.SetContainerNumaMetric("pod1", "container1", "0", "test-metric-name", MetricData{Value: 1.0, Time: &now})
+	store.SetCgroupNumaMetric("/kubepods", 0, "test-metric-name", MetricData{Value: 1.0, Time: &now})
+
+	clone := store.Clone()
+	store.SetNodeMetric("test-metric-name", MetricData{Value: 2.0, Time: &now})
+	store.SetNumaMetric(0, "test-metric-name", MetricData{Value: 2.0, Time: &now})
+	store.SetContainerNumaMetric("pod1", "container1", "0", "test-metric-name", MetricData{Value: 2.0, Time: &now})
+	store.SetCgroupNumaMetric("/kubepods", 0, "test-metric-name", MetricData{Value: 2.0, Time: &now})
+	store.SetNumaMetric(1, "test-metric-name", MetricData{Value: 2.0, Time: &now})
+
+	value, err := clone.GetNodeMetric("test-metric-name")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, value.Value)
+	value, err = clone.GetNumaMetric(0, "test-metric-name")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, value.Value)
+	value, err = clone.GetContainerNumaMetric("pod1", "container1", "0", "test-metric-name")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, value.Value)
+	value, err = clone.GetCgroupNumaMetric("/kubepods", 0, "test-metric-name")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, value.Value)
+	_, err = clone.GetNumaMetric(1, "test-metric-name")
+	assert.Error(t, err)
+}
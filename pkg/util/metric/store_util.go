@@ -17,6 +17,7 @@ limitations under the License.
 package metric
 
 import (
+	"strconv"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
@@ -77,6 +78,20 @@ func (c *MetricStore) AggregatePodNumaMetric(podList []*v1.Pod, numa, metricName
 	return data
 }
 
+// AggregatePodNumasMetric handles numa-level metric for all pods across the given numas,
+// metric of each numa is aggregated by AggregatePodNumaMetric and then summed up
+func (c *MetricStore) AggregatePodNumasMetric(podList []*v1.Pod, numas machine.CPUSet, metricName string, agg Aggregator, filter ContainerMetricFilter) MetricData {
+	now := time.Now()
+	data := MetricData{Value: .0, Time: &now}
+
+	for _, numa := range numas.ToSliceInt() {
+		metric := c.AggregatePodNumaMetric(podList, strconv.Itoa(numa), metricName, agg, filter)
+		data.Value += metric.Value
+		data.Time = general.MaxTimePtr(data.Time, metric.Time)
+	}
+	return data
+}
+
 // AggregatePodMetric handles metric for all pods
 func (c *MetricStore) AggregatePodMetric(podList []*v1.Pod, metricName string, agg Aggregator, filter ContainerMetricFilter) MetricData {
 	now := time.Now()